@@ -0,0 +1,156 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// AliasArgsInvalid はalias setにエイリアスの名前と展開後の引数が指定されていない場合のエラーメッセージです。
+const AliasArgsInvalid service.MessageID = "cmd.alias.args_invalid"
+
+// AliasNameInvalid はエイリアスの名前に使えない文字が含まれる場合のエラーメッセージです。
+const AliasNameInvalid service.MessageID = "cmd.alias.name_invalid"
+
+// AliasShadowsBuiltin はエイリアスの名前が組み込みのサブコマンドと同じ場合のエラーメッセージです。
+const AliasShadowsBuiltin service.MessageID = "cmd.alias.shadows_builtin"
+
+// AliasNotFound は削除するエイリアスが設定ファイルに無い場合のエラーメッセージです。
+const AliasNotFound service.MessageID = "cmd.alias.not_found"
+
+// aliasName はエイリアスの名前に使える文字です。設定ファイルのキーは大文字と小文字を区別しないため小文字に限ります。
+var aliasName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// aliasCmd represents the alias command
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "cmd.alias.short",
+	Long:  "cmd.alias.long",
+}
+
+// aliasSetCmd represents the alias set command
+var aliasSetCmd = &cobra.Command{
+	Use:   "set NAME -- ARGS...",
+	Short: "cmd.alias.set.short",
+	Long:  "cmd.alias.set.long",
+	RunE:  aliasSet,
+}
+
+// aliasListCmd represents the alias list command
+var aliasListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "cmd.alias.list.short",
+	Long:  "cmd.alias.list.long",
+	RunE:  aliasList,
+}
+
+// aliasDeleteCmd represents the alias delete command
+var aliasDeleteCmd = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "cmd.alias.delete.short",
+	Long:  "cmd.alias.delete.long",
+	RunE:  aliasDelete,
+}
+
+func init() {
+	rootCmd.AddCommand(aliasCmd)
+	aliasCmd.AddCommand(aliasSetCmd)
+	aliasCmd.AddCommand(aliasListCmd)
+	aliasCmd.AddCommand(aliasDeleteCmd)
+}
+
+func aliasSet(cmd *cobra.Command, args []string) error {
+	if len(args) < 2 {
+		return usageError(AliasArgsInvalid)
+	}
+	name := args[0]
+	if !aliasName.MatchString(name) {
+		return usageError(AliasNameInvalid, name)
+	}
+	if builtinCommandNames()[name] {
+		return usageError(AliasShadowsBuiltin, name)
+	}
+
+	config, err := loadConfigForEdit()
+	if err != nil {
+		return err
+	}
+	config.Set("aliases."+name, args[1:])
+	if err := config.Save(); err != nil {
+		return err
+	}
+	logger.Info(service.T("cmd.alias.saved", name, quoteArgs(args[1:])))
+	return nil
+}
+
+func aliasList(cmd *cobra.Command, args []string) error {
+	aliases := viper.GetStringMapStringSlice("aliases")
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s\t%s\n", name, quoteArgs(aliases[name]))
+	}
+	return nil
+}
+
+func aliasDelete(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return usageError(AliasArgsInvalid)
+	}
+
+	config, err := loadConfigForEdit()
+	if err != nil {
+		return err
+	}
+	if !config.Delete("aliases." + args[0]) {
+		return usageError(AliasNotFound, args[0])
+	}
+	if err := config.Save(); err != nil {
+		return err
+	}
+	logger.Info(service.T("cmd.alias.deleted", args[0]))
+	return nil
+}
+
+// loadConfigForEdit はコマンドラインオプション(--config)で指定された設定ファイルを編集するために読み込みます。
+func loadConfigForEdit() (*service.ConfigFile, error) {
+	path, err := rootCmd.PersistentFlags().GetString("config")
+	if err != nil {
+		return nil, err
+	}
+	return service.LoadConfigFile(path)
+}
+
+// quoteArgs は引数をシェルで入力する形式で表示するため、空白や$などを含む引数を単一引用符で囲んで連結します。
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$*?;&|<>()`") {
+			arg = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// TestSubstituteAliasArgs ではエイリアスの位置パラメータが引数で置き換えられることを確認する。
+func TestSubstituteAliasArgs(t *testing.T) {
+	cases := []struct {
+		expansion []string
+		params    []string
+		want      []string
+	}{
+		{[]string{"get", "--fingerprint"}, nil, []string{"get", "--fingerprint"}},
+		{[]string{"get"}, []string{"--id", "3"}, []string{"get", "--id", "3"}},
+		{[]string{"update", "--id", "$1", "--status", "RUNNING"}, []string{"42", "--title", "x"}, []string{"update", "--id", "42", "--status", "RUNNING", "--title", "x"}},
+		{[]string{"create", "--title=[$2] $1"}, []string{"a", "b"}, []string{"create", "--title=[b] a"}},
+		{[]string{"update", "$@", "--status", "DONE"}, []string{"--id", "1"}, []string{"update", "--id", "1", "--status", "DONE"}},
+		{[]string{"create", "--title", "$@"}, []string{"a", "b"}, []string{"create", "--title", "a", "b"}},
+		{[]string{"create", "--title=$@"}, []string{"a", "b"}, []string{"create", "--title=a b"}},
+	}
+	for _, c := range cases {
+		got, err := substituteAliasArgs("alias", c.expansion, c.params)
+		if err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("substituteAliasArgs(%v, %v) = %v, %v, want %v", c.expansion, c.params, got, err, c.want)
+		}
+	}
+
+	if _, err := substituteAliasArgs("alias", []string{"get", "--id", "$2"}, []string{"1"}); service.KindOf(err) != service.ErrorUsage {
+		t.Errorf("missing argument: err = %v", err)
+	}
+}
+
+// TestResolveArgs では設定ファイルのエイリアスを展開し、指定されていないオプションだけを既定の値で補うことを確認する。
+func TestResolveArgs(t *testing.T) {
+	file, err := ioutil.TempFile("", "config*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`
+aliases:
+  start: [update, --id, $1, --status, RUNNING]
+  get: [get, --fingerprint]
+  ready: ping --until-ready
+defaults:
+  get:
+    fingerprint: true
+  ping:
+    count: 3
+    timeout: 2s
+  plugin list:
+    json: true
+`)
+	file.Close()
+	config := []string{"--config", file.Name()}
+
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"start", "42"}, []string{"update", "--id", "42", "--status", "RUNNING"}},
+		{[]string{"--host", "todo-server", "-v", "1", "start", "42"}, []string{"--host", "todo-server", "-v", "1", "update", "--id", "42", "--status", "RUNNING"}},
+		// 組み込みのサブコマンドと同じ名前のエイリアスは展開しない
+		{[]string{"get", "--id", "1"}, []string{"get", "--id", "1", "--fingerprint=true"}},
+		{[]string{"ready"}, []string{"ping", "--until-ready", "--count=3", "--timeout=2s"}},
+		{[]string{"ping", "-c", "1", "--", "extra"}, []string{"ping", "-c", "1", "--timeout=2s", "--", "extra"}},
+		{[]string{"plugin", "list"}, []string{"plugin", "list", "--json=true"}},
+		{[]string{"unknown", "--count", "1"}, []string{"unknown", "--count", "1"}},
+	}
+	for _, c := range cases {
+		got, err := resolveArgs(append(append([]string{}, config...), c.args...))
+		if err != nil || !reflect.DeepEqual(got[2:], c.want) {
+			t.Errorf("resolveArgs(%v) = %v, %v, want %v", c.args, got, err, c.want)
+		}
+	}
+
+	unknown := viper.New()
+	unknown.Set("defaults", map[string]interface{}{"get": map[string]interface{}{"output": "wide"}})
+	if _, err := applyDefaults([]string{"get"}, unknown); service.KindOf(err) != service.ErrorUsage {
+		t.Errorf("unknown flag: err = %v", err)
+	}
+}
+
+// TestAliasCommands ではalias set/list/deleteで他の設定を残したまま設定ファイルのエイリアスを編集できることを確認する。
+func TestAliasCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alias")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte("token: test_token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) int {
+		defer resetCommandsForTest()
+		return execute(append([]string{"--config", path, "--quiet"}, args...))
+	}
+
+	if code := run("alias", "set", "start", "--", "update", "--id", "$1", "--status", "RUNNING"); code != ExitOK {
+		t.Errorf("set: exit code = %d", code)
+	}
+	for _, args := range [][]string{{"alias", "set", "start"}, {"alias", "set", "Start", "--", "get"}, {"alias", "set", "get", "--", "get"}, {"alias", "delete", "missing"}} {
+		if code := run(args...); code != ExitUsage {
+			t.Errorf("%v: exit code = %d", args, code)
+		}
+	}
+
+	content, _ := ioutil.ReadFile(path)
+	want := "token: test_token\naliases:\n  start:\n  - update\n  - --id\n  - $1\n  - --status\n  - RUNNING\n"
+	if string(content) != want {
+		t.Errorf("config = %q, want %q", content, want)
+	}
+
+	if code := run("alias", "delete", "start"); code != ExitOK {
+		t.Errorf("delete: exit code = %d", code)
+	}
+	content, _ = ioutil.ReadFile(path)
+	if string(content) != "token: test_token\naliases: []\n" && string(content) != "token: test_token\naliases: {}\n" {
+		t.Errorf("config = %q", content)
+	}
+}
+
+// TestQuoteArgs ではシェルで解釈される文字を含む引数を単一引用符で囲むことを確認する。
+func TestQuoteArgs(t *testing.T) {
+	got := quoteArgs([]string{"update", "--id", "$1", "--title", "it's done", ""})
+	if want := `update --id '$1' --title 'it'\''s done' ''`; got != want {
+		t.Errorf("quoteArgs() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// AliasArgumentMissing はエイリアスの位置パラメータ($1など)に対応する引数が無い場合のエラーメッセージです。
+const AliasArgumentMissing service.MessageID = "cmd.alias.argument_missing"
+
+// DefaultsFlagUnknown は設定ファイルのdefaultsにサブコマンドに無いオプションが指定された場合のエラーメッセージです。
+const DefaultsFlagUnknown service.MessageID = "cmd.defaults.flag_unknown"
+
+// aliasParameter はエイリアスの位置パラメータ($1, $2, ...)と残りのすべての引数($@)です。
+var aliasParameter = regexp.MustCompile(`\$([0-9]+|@)`)
+
+// resolveArgs はCobraがコマンドライン引数を解釈する前に、設定ファイルのエイリアス(aliases)を展開し、
+// サブコマンドごとの既定のオプション(defaults)のうち指定されていないものを補います。
+func resolveArgs(args []string) ([]string, error) {
+	config := argsConfig(args)
+	args, err := expandAlias(args, config.GetStringMapStringSlice("aliases"))
+	if err != nil {
+		return nil, err
+	}
+	return applyDefaults(args, config)
+}
+
+// argsConfig はCobraが設定ファイルを読み込む前に、コマンドライン引数(--config)で指定された設定ファイルを読み込みます。
+// 読み込めない場合は空の設定を返し、エラーはサブコマンドの実行時に読み込む際の扱いに任せます。
+func argsConfig(args []string) *viper.Viper {
+	path := flagFromArgs(args, "config")
+	if path == "" {
+		path = rootCmd.PersistentFlags().Lookup("config").DefValue
+	}
+	config := viper.New()
+	config.SetConfigFile(path)
+	config.ReadInConfig()
+	return config
+}
+
+// expandAlias はサブコマンドの位置にエイリアスの名前がある場合に、その位置から後ろの引数をエイリアスの展開後の引数に置き換えます。
+// 組み込みのサブコマンドと同じ名前のエイリアスは展開しません。展開後の引数のエイリアスは展開しません。
+func expandAlias(args []string, aliases map[string][]string) ([]string, error) {
+	i := positionalIndex(args, rootCmd.PersistentFlags())
+	if i < 0 || builtinCommandNames()[args[i]] {
+		return args, nil
+	}
+	expansion, ok := aliases[args[i]]
+	if !ok {
+		return args, nil
+	}
+	// 展開後の引数を文字列で指定した場合は空白で区切ります。
+	if len(expansion) == 1 {
+		expansion = strings.Fields(expansion[0])
+	}
+
+	expanded, err := substituteAliasArgs(args[i], expansion, args[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]string{}, args[:i]...), expanded...), nil
+}
+
+// substituteAliasArgs はエイリアスの位置パラメータを引数で置き換えます。
+// $1, $2, ...はその位置の引数に、$@は残りのすべての引数に置き換えます。
+// $@を使わない場合、位置パラメータで参照されなかった後ろの引数は末尾に追加します。
+func substituteAliasArgs(name string, expansion []string, params []string) ([]string, error) {
+	var expanded []string
+	used, all := 0, false
+	var err error
+	for _, arg := range expansion {
+		if arg == "$@" {
+			expanded = append(expanded, params...)
+			all = true
+			continue
+		}
+		arg = aliasParameter.ReplaceAllStringFunc(arg, func(parameter string) string {
+			if parameter == "$@" {
+				all = true
+				return strings.Join(params, " ")
+			}
+			n, _ := strconv.Atoi(parameter[1:])
+			if n < 1 || n > len(params) {
+				if err == nil {
+					err = usageError(AliasArgumentMissing, name, parameter)
+				}
+				return parameter
+			}
+			if n > used {
+				used = n
+			}
+			return params[n-1]
+		})
+		expanded = append(expanded, arg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !all {
+		expanded = append(expanded, params[used:]...)
+	}
+	return expanded, nil
+}
+
+// applyDefaults は設定ファイルのdefaults.<サブコマンド>に指定されたオプションのうち、
+// コマンドライン引数で指定されていないものを引数の末尾("--"がある場合はその前)に追加します。
+// 入れ子のサブコマンドは"plugin list"のように空白で区切った名前で指定します。
+func applyDefaults(args []string, config *viper.Viper) ([]string, error) {
+	c := findCommand(args)
+	if c == nil {
+		return args, nil
+	}
+	key := strings.TrimPrefix(c.CommandPath(), rootCmd.Name()+" ")
+	defaults := config.GetStringMap("defaults." + key)
+	if len(defaults) == 0 {
+		return args, nil
+	}
+
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var added []string
+	for _, name := range names {
+		flag := c.Flags().Lookup(name)
+		if flag == nil {
+			flag = c.InheritedFlags().Lookup(name)
+		}
+		if flag == nil {
+			return nil, usageError(DefaultsFlagUnknown, key, name)
+		}
+		if flagGiven(args, flag) {
+			continue
+		}
+		values, ok := defaults[name].([]interface{})
+		if !ok {
+			values = []interface{}{defaults[name]}
+		}
+		for _, value := range values {
+			added = append(added, fmt.Sprintf("--%s=%v", name, value))
+		}
+	}
+
+	end := len(args)
+	for i, arg := range args {
+		if arg == "--" {
+			end = i
+			break
+		}
+	}
+	resolved := append(append([]string{}, args[:end]...), added...)
+	return append(resolved, args[end:]...), nil
+}
+
+// findCommand はコマンドライン引数で指定された組み込みのサブコマンドを返します。見つからない場合はnilを返します。
+func findCommand(args []string) *cobra.Command {
+	var found *cobra.Command
+	c := rootCmd
+	flags := []*pflag.FlagSet{rootCmd.PersistentFlags()}
+	for {
+		i := positionalIndex(args, flags...)
+		if i < 0 {
+			return found
+		}
+		var sub *cobra.Command
+		for _, candidate := range c.Commands() {
+			if candidate.Name() == args[i] || candidate.HasAlias(args[i]) {
+				sub = candidate
+				break
+			}
+		}
+		if sub == nil {
+			return found
+		}
+		found, c, args = sub, sub, args[i+1:]
+		flags = append(flags, sub.PersistentFlags())
+	}
+}
+
+// positionalIndex はオプションとその値を除いた最初の引数(サブコマンドの名前)の位置を返します。
+// 値を取るオプションの判断にはflagsを利用します。"--"より前に見つからない場合は-1を返します。
+func positionalIndex(args []string, flags ...*pflag.FlagSet) int {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return -1
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return i
+		}
+		if strings.Contains(arg, "=") {
+			continue
+		}
+		// 値を取るオプションは次の引数を値として読み飛ばします。
+		for _, set := range flags {
+			flag := set.Lookup(strings.TrimLeft(arg, "-"))
+			if !strings.HasPrefix(arg, "--") && len(arg) == 2 {
+				flag = set.ShorthandLookup(arg[1:])
+			}
+			if flag != nil {
+				if flag.NoOptDefVal == "" {
+					i++
+				}
+				break
+			}
+		}
+	}
+	return -1
+}
+
+// flagGiven はコマンドライン引数でオプションが指定されているかどうかを返します。
+func flagGiven(args []string, flag *pflag.Flag) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--"+flag.Name || strings.HasPrefix(arg, "--"+flag.Name+"=") {
+			return true
+		}
+		if flag.Shorthand != "" && !strings.HasPrefix(arg, "--") && strings.HasPrefix(arg, "-"+flag.Shorthand) {
+			return true
+		}
+	}
+	return false
+}
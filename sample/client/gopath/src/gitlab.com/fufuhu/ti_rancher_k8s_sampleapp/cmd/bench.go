@@ -0,0 +1,253 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// BenchOptionInvalid は並行数や時間などに範囲外の値が指定された場合のエラーメッセージです。
+const BenchOptionInvalid service.MessageID = "cmd.bench.option_invalid"
+
+// BenchDryRunUnsupported はドライランで負荷をかけようとした場合のエラーメッセージです。
+const BenchDryRunUnsupported service.MessageID = "cmd.bench.dry_run_unsupported"
+
+// benchPercentiles は出力するパーセンタイルです。
+var benchPercentiles = []float64{50, 90, 99, 99.9}
+
+// BenchStatsReport は操作の種類ひとつ分の集計結果です。--jsonを指定した場合に出力します。
+type BenchStatsReport struct {
+	Count     int64              `json:"count"`
+	Errors    int64              `json:"errors"`
+	ErrorRate float64            `json:"error_rate"`
+	Min       float64            `json:"min_ms"`
+	Avg       float64            `json:"avg_ms"`
+	Max       float64            `json:"max_ms"`
+	StdDev    float64            `json:"stddev_ms"`
+	Latency   map[string]float64 `json:"percentiles_ms"` // p50, p90, p99, p99.9
+}
+
+// BenchJSONReport は負荷をかけた結果の全体です。--jsonを指定した場合に出力します。
+type BenchJSONReport struct {
+	Target         string                      `json:"target"`
+	Elapsed        float64                     `json:"elapsed_seconds"`
+	Throughput     float64                     `json:"throughput_rps"`
+	Operations     map[string]BenchStatsReport `json:"operations"`
+	Total          BenchStatsReport            `json:"total"`
+	ErrorsByStatus map[string]int64            `json:"errors_by_status"`
+	Created        int                         `json:"remaining_tasks"` // benchで作成して残っているタスクの数
+	CleanedUp      int                         `json:"cleaned_up"`      // 後片付けで削除したタスクの数
+}
+
+// benchCmd represents the bench command
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "cmd.bench.short",
+	Long:  "cmd.bench.long",
+	RunE:  bench,
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// benchCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// benchCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	benchCmd.Flags().String("mix", "create=2,get=4,list=2,update=1,delete=1", "cmd.bench.flag.mix")
+	benchCmd.Flags().IntP("concurrency", "c", 4, "cmd.bench.flag.concurrency")
+	benchCmd.Flags().DurationP("duration", "d", 30*time.Second, "cmd.bench.flag.duration")
+	benchCmd.Flags().Float64("rps", 0, "cmd.bench.flag.rps")
+	benchCmd.Flags().Duration("timeout", 10*time.Second, "cmd.bench.flag.timeout")
+	benchCmd.Flags().Bool("cleanup", false, "cmd.bench.flag.cleanup")
+	benchCmd.Flags().Bool("json", false, "cmd.bench.flag.json")
+}
+
+func bench(cmd *cobra.Command, args []string) error {
+	protocol, err := clientSetting.Protocol()
+	if err != nil {
+		return err
+	}
+	host, err := clientSetting.Host()
+	if err != nil {
+		return err
+	}
+	port, err := clientSetting.Port()
+	if err != nil {
+		return err
+	}
+
+	token, err := clientSetting.Token()
+	if err != nil {
+		return service.WrapError(service.ErrorAuth, SettingErrorMessageTokenInvalid, err)
+	}
+
+	mixValue, _ := cmd.Flags().GetString("mix")
+	mix, err := service.ParseBenchMix(mixValue)
+	if err != nil {
+		return err
+	}
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	duration, _ := cmd.Flags().GetDuration("duration")
+	rps, _ := cmd.Flags().GetFloat64("rps")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	cleanup, _ := cmd.Flags().GetBool("cleanup")
+	asJSON, _ := cmd.Flags().GetBool("json")
+	if concurrency < 1 || duration <= 0 || rps < 0 || timeout < 0 {
+		return usageError(BenchOptionInvalid)
+	}
+	if service.DryRun {
+		return usageError(BenchDryRunUnsupported)
+	}
+
+	// 応答時間と失敗をそのまま計測するため、接続先を固定して再試行も行いません。
+	protocol, host, port, token = pinEndpoint(protocol, host, port, token)
+	defer limitRequests(timeout)()
+
+	logger.Info(service.T("cmd.bench.started"), "concurrency", concurrency, "duration", duration, "rps", rps)
+	report := service.RunBench(protocol, host, port, token, service.BenchOptions{
+		Mix:         mix,
+		Concurrency: concurrency,
+		Duration:    duration,
+		Rate:        rps,
+	})
+
+	// 後片付けはbulkと同じ仕組みで並行して削除します。
+	cleanedUp := 0
+	if cleanup && len(report.Created) > 0 {
+		operations := make([]service.BulkOperation, len(report.Created))
+		for i, id := range report.Created {
+			operations[i] = service.BulkOperation{Operation: service.JournalDelete, ID: id}
+		}
+		results := service.RunBulk(protocol, host, port, token, operations, service.BulkOptions{Parallel: concurrency})
+		summary := service.SummarizeBulkResults(results)
+		cleanedUp = summary.Succeeded
+		if summary.Failed > 0 {
+			logger.Warn(service.T("cmd.bench.cleanup_failure", summary.Failed))
+		}
+	}
+
+	target := protocol + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+	if asJSON {
+		jsonReport := BenchJSONReport{
+			Target:         target,
+			Elapsed:        report.Elapsed.Seconds(),
+			Throughput:     report.Throughput(),
+			Operations:     map[string]BenchStatsReport{},
+			Total:          newBenchStatsReport(report.Total),
+			ErrorsByStatus: report.ErrorsByStatus,
+			Created:        len(report.Created) - cleanedUp,
+			CleanedUp:      cleanedUp,
+		}
+		for operation, stats := range report.Operations {
+			if stats.Count > 0 {
+				jsonReport.Operations[operation] = newBenchStatsReport(stats)
+			}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(jsonReport)
+	}
+
+	printBenchReport(target, report, cleanedUp)
+	return nil
+}
+
+func newBenchStatsReport(stats *service.BenchStats) BenchStatsReport {
+	summary := stats.Latency.Summary()
+	report := BenchStatsReport{
+		Count:     stats.Count,
+		Errors:    stats.Errors,
+		ErrorRate: stats.ErrorRate(),
+		Min:       milliseconds(summary.Min),
+		Avg:       milliseconds(summary.Avg),
+		Max:       milliseconds(summary.Max),
+		StdDev:    milliseconds(summary.StdDev),
+		Latency:   map[string]float64{},
+	}
+	for _, p := range benchPercentiles {
+		report.Latency[fmt.Sprintf("p%g", p)] = milliseconds(stats.Latency.Percentile(p))
+	}
+	return report
+}
+
+// printBenchReport は操作の種類ごとの応答時間のパーセンタイルと失敗率を表にして出力します。
+func printBenchReport(target string, report service.BenchReport, cleanedUp int) {
+	fmt.Println(service.T("cmd.bench.summary", target, roundDuration(report.Elapsed), report.Total.Count, report.Throughput()))
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "Operation\tCount\tErrors\tError%%\tMin\tAvg\tp50\tp90\tp99\tp99.9\tMax\t\n")
+	row := func(name string, stats *service.BenchStats) {
+		summary := stats.Latency.Summary()
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.2f\t%s\t%s", name, stats.Count, stats.Errors, stats.ErrorRate()*100,
+			roundLatency(summary.Min), roundLatency(summary.Avg))
+		for _, p := range benchPercentiles {
+			fmt.Fprintf(w, "\t%s", roundLatency(stats.Latency.Percentile(p)))
+		}
+		fmt.Fprintf(w, "\t%s\t\n", roundLatency(summary.Max))
+	}
+	for _, operation := range service.BenchOperations {
+		if stats := report.Operations[operation]; stats.Count > 0 {
+			row(operation, stats)
+		}
+	}
+	row("total", report.Total)
+	w.Flush()
+
+	if len(report.ErrorsByStatus) > 0 {
+		fmt.Println()
+		fmt.Println(service.T("cmd.bench.errors_by_status"))
+		statuses := make([]string, 0, len(report.ErrorsByStatus))
+		for status := range report.ErrorsByStatus {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Printf("  %s\t%d\n", status, report.ErrorsByStatus[status])
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(service.T("cmd.bench.remaining", len(report.Created)-cleanedUp, cleanedUp))
+}
+
+// roundLatency は表示用に応答時間をおよそ3桁に丸めます。
+func roundLatency(d time.Duration) time.Duration {
+	switch {
+	case d >= 100*time.Millisecond:
+		return d.Round(time.Millisecond)
+	case d >= 10*time.Millisecond:
+		return d.Round(100 * time.Microsecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
@@ -0,0 +1,209 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// BulkFileRequired は操作の一覧を記述したファイルが指定されていない場合のエラーメッセージです。
+const BulkFileRequired service.MessageID = "cmd.bulk.file_required"
+
+// BulkFileReadFailure は操作の一覧を読み込めなかった場合のエラーメッセージです。
+const BulkFileReadFailure service.MessageID = "cmd.bulk.file_read_failure"
+
+// BulkOperationInvalid は操作の一覧に誤りがある場合のエラーメッセージです。何件目の操作かを埋め込みます。
+const BulkOperationInvalid service.MessageID = "cmd.bulk.operation_invalid"
+
+// BulkOperationsFailed は一部の操作が失敗した場合のエラーメッセージです。失敗した件数を埋め込みます。
+const BulkOperationsFailed service.MessageID = "cmd.bulk.operations_failed"
+
+// bulkCmd represents the bulk command
+var bulkCmd = &cobra.Command{
+	Use:   "bulk",
+	Short: "cmd.bulk.short",
+	Long:  "cmd.bulk.long",
+	RunE:  bulk,
+}
+
+func init() {
+	rootCmd.AddCommand(bulkCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// bulkCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// bulkCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	bulkCmd.Flags().StringP("file", "f", "", "cmd.bulk.flag.file")
+	bulkCmd.Flags().Int("parallel", 4, "cmd.bulk.flag.parallel")
+	bulkCmd.Flags().Float64("rate", 0, "cmd.bulk.flag.rate")
+}
+
+func bulk(cmd *cobra.Command, args []string) error {
+	protocol, err := clientSetting.Protocol()
+	if err != nil {
+		return err
+	}
+	host, err := clientSetting.Host()
+	if err != nil {
+		return err
+	}
+	port, err := clientSetting.Port()
+	if err != nil {
+		return err
+	}
+
+	token, err := clientSetting.Token()
+	if err != nil {
+		return service.WrapError(service.ErrorAuth, SettingErrorMessageTokenInvalid, err)
+	}
+
+	path, _ := cmd.Flags().GetString("file")
+	if path == "" {
+		return usageError(BulkFileRequired)
+	}
+	operations, err := readBulkOperations(path)
+	if err != nil {
+		return service.WrapError(service.ErrorUsage, BulkFileReadFailure, err)
+	}
+
+	// 誤りのある操作が含まれている場合は、ひとつも実行せずに終了します。
+	for i, operation := range operations {
+		if err := operation.Validate(); err != nil {
+			return service.WrapError(service.ErrorValidation, BulkOperationInvalid, err, i+1)
+		}
+	}
+
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	rate, _ := cmd.Flags().GetFloat64("rate")
+
+	fmt.Printf("No\tOperation\tID\tResult\tTitle\tStatus\n")
+	results := service.RunBulk(protocol, host, port, token, operations, service.BulkOptions{
+		Parallel: parallel,
+		Rate:     rate,
+		Progress: bulkProgressWriter(),
+		OnResult: func(result service.BulkResult) {
+			printBulkResult(result)
+			recordBulkResult(protocol, host, port, token, result)
+		},
+	})
+
+	summary := service.SummarizeBulkResults(results)
+	fmt.Print(service.T("cmd.bulk.summary", summary.Succeeded, summary.Failed))
+	if summary.Skipped > 0 {
+		fmt.Print(service.T("cmd.bulk.summary_skipped", summary.Skipped))
+	}
+	fmt.Println()
+
+	if summary.Failed > 0 {
+		return service.NewError(service.ErrorUnknown, BulkOperationsFailed, summary.Failed)
+	}
+	return nil
+}
+
+// bulkProgressWriter は進捗の出力先を返します。
+// 進捗は復帰文字(\r)で同じ行を書き換えるため、標準エラー出力が端末の場合に限り出力し、
+// --quietなどでinfoより上のレベルのログだけを出力する場合や、JSON形式でログを出力する場合は出力しません。
+func bulkProgressWriter() io.Writer {
+	if level, err := clientSetting.LogLevel(); err != nil || level > service.LogLevelInfo {
+		return nil
+	}
+	if format, err := clientSetting.LogFormat(); err != nil || format == service.LogFormatJSON {
+		return nil
+	}
+	if !stderrIsTerminal() {
+		return nil
+	}
+	return os.Stderr
+}
+
+// stderrIsTerminal は標準エラー出力が端末かどうかを返します。テストでは置き換えます。
+var stderrIsTerminal = func() bool {
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// readBulkOperations は操作の一覧をファイルまたは標準入力から読み込みます。
+func readBulkOperations(path string) ([]service.BulkOperation, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []service.BulkOperation
+	if err := yaml.UnmarshalStrict(data, &operations); err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+// printBulkResult は操作1件分の結果を出力します。
+func printBulkResult(result service.BulkResult) {
+	id := result.Operation.ID
+	if result.Task.ID != 0 {
+		id = result.Task.ID
+	}
+
+	status := "OK"
+	switch {
+	case result.Err == service.ErrDryRun:
+		status = "DRY-RUN"
+	case result.Err != nil:
+		status = "ERROR: " + result.Err.Error()
+	}
+	fmt.Printf("%d\t%s\t%d\t%s\t%s\t%s\n", result.Index+1, result.Operation.Operation, id, status, result.Task.Title, result.Task.Status)
+}
+
+// recordBulkResult は成功した操作を操作ジャーナルに記録します。
+func recordBulkResult(protocol string, host string, port int, token string, result service.BulkResult) {
+	if result.Err != nil {
+		return
+	}
+
+	entry := service.JournalEntry{Operation: result.Operation.Operation, TaskID: result.Task.ID}
+	switch result.Operation.Operation {
+	case service.JournalCreate:
+		task := result.Task
+		entry.Current = &task
+	case service.JournalUpdate:
+		// 変更が無かった場合は記録しません。
+		if result.Previous == nil || service.TaskFingerprint(*result.Previous) == service.TaskFingerprint(result.Task) {
+			return
+		}
+		task := result.Task
+		entry.Previous = result.Previous
+		entry.Current = &task
+	case service.JournalDelete:
+		entry.Previous = result.Previous
+	}
+	recordJournal(protocol, host, port, token, entry)
+}
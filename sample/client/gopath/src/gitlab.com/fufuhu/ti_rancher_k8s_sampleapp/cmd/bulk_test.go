@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+)
+
+// TestBulkProgressWriter では標準エラー出力が端末の場合に限り進捗を出力し、
+// --quietやJSON形式のログでは出力しないことを確認する。
+func TestBulkProgressWriter(t *testing.T) {
+	resetCommandsForTest()
+	defer resetCommandsForTest()
+	defer func(original func() bool) { stderrIsTerminal = original }(stderrIsTerminal)
+	terminal := true
+	stderrIsTerminal = func() bool { return terminal }
+
+	if w := bulkProgressWriter(); w != os.Stderr {
+		t.Errorf("progress writer = %v, want stderr", w)
+	}
+	terminal = false
+	if w := bulkProgressWriter(); w != nil {
+		t.Errorf("progress is written to a non-terminal: %v", w)
+	}
+
+	terminal = true
+	rootCmd.PersistentFlags().Set("quiet", "true")
+	if w := bulkProgressWriter(); w != nil {
+		t.Errorf("progress is written with --quiet: %v", w)
+	}
+	resetCommandsForTest()
+	rootCmd.PersistentFlags().Set("log-format", "json")
+	if w := bulkProgressWriter(); w != nil {
+		t.Errorf("progress is written with --log-format json: %v", w)
+	}
+}
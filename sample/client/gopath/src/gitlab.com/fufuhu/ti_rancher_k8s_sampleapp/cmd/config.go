@@ -0,0 +1,624 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ConfigArgsInvalid はconfig set/unsetにキーや値が指定されていない場合のエラーメッセージです。
+const ConfigArgsInvalid service.MessageID = "cmd.config.args_invalid"
+
+// ConfigKeyUnknown は設定ファイルのスキーマに無いキーが指定された場合のエラーメッセージです。
+const ConfigKeyUnknown service.MessageID = "cmd.config.key_unknown"
+
+// ConfigKeyNotSettable はconfig setで値を指定できないキー(エンドポイントの一覧など)が指定された場合のエラーメッセージです。
+const ConfigKeyNotSettable service.MessageID = "cmd.config.key_not_settable"
+
+// ConfigValueInvalid は設定の値がスキーマの型や選択肢に合わない場合のエラーメッセージです。
+const ConfigValueInvalid service.MessageID = "cmd.config.value_invalid"
+
+// ConfigKeyNotSet はconfig unsetで指定したキーが設定ファイルに無い場合のエラーメッセージです。
+const ConfigKeyNotSet service.MessageID = "cmd.config.key_not_set"
+
+// ConfigFileInvalid は設定ファイルにスキーマに合わないキーや値がある場合のエラーメッセージです。
+const ConfigFileInvalid service.MessageID = "cmd.config.file_invalid"
+
+// ConfigFileNotFound はconfig migrateで変換する設定ファイルが無い場合のエラーメッセージです。
+const ConfigFileNotFound service.MessageID = "cmd.config.file_not_found"
+
+// ConfigHeaderNotSettable はconfig setで設定ファイルのヘッダ(apiVersion, kind)が指定された場合のエラーメッセージです。
+const ConfigHeaderNotSettable service.MessageID = "cmd.config.header_not_settable"
+
+// 設定ファイルの値の型です。
+const (
+	configHeader   = "header"
+	configString   = "string"
+	configInt      = "int"
+	configBool     = "bool"
+	configDuration = "duration"
+	configList     = "list"
+	configArgs     = "args"
+	configScalar   = "scalar"
+)
+
+// configKey は設定ファイルのキーとその値の型です。
+type configKey struct {
+	Key    string
+	Type   string
+	Values []string // 値の選択肢(空の場合は制限しない)
+	Fields []string // Typeがlistの場合の要素のマップのキー(空の場合は要素は文字列)
+}
+
+// configSchema は設定ファイルで利用できるキーです。
+// aliases.<名前>とdefaults.<サブコマンド>.<オプション>は名前を自由に指定できるためlookupConfigKeyで扱います。
+var configSchema = []configKey{
+	{Key: "apiversion", Type: configHeader, Values: []string{service.ConfigAPIVersion}},
+	{Key: "kind", Type: configHeader, Values: []string{service.ConfigKind}},
+	{Key: "protocol", Type: configString, Values: []string{"http", "https"}},
+	{Key: "host", Type: configString},
+	{Key: "port", Type: configInt},
+	{Key: "token", Type: configString},
+	{Key: "context", Type: configString},
+	{Key: "lang", Type: configString, Values: []string{service.LanguageJapanese, service.LanguageEnglish}},
+	{Key: "journal_dir", Type: configString},
+	{Key: "template_dir", Type: configString},
+	{Key: "retry.max", Type: configInt},
+	{Key: "retry.base_delay", Type: configDuration},
+	{Key: "retry.max_delay", Type: configDuration},
+	{Key: "retry.post", Type: configBool},
+	{Key: "failover.failure_threshold", Type: configInt},
+	{Key: "failover.open_timeout", Type: configDuration},
+	{Key: "failover.state_file", Type: configString},
+	{Key: "log.level", Type: configString, Values: []string{"debug", "info", "warn", "error"}},
+	{Key: "log.format", Type: configString, Values: []string{service.LogFormatText, service.LogFormatJSON}},
+	{Key: "endpoints", Type: configList, Fields: []string{"name", "url", "priority", "token"}},
+	{Key: "exporter.listen", Type: configString},
+	{Key: "exporter.interval", Type: configDuration},
+	{Key: "exporter.contexts", Type: configList, Fields: []string{"name", "url", "token"}},
+	{Key: "remind.interval", Type: configDuration},
+	{Key: "remind.before", Type: configDuration},
+	{Key: "remind.sinks", Type: configList},
+	{Key: "remind.notify_command", Type: configArgs},
+	{Key: "remind.webhook_url", Type: configString},
+	{Key: "remind.state_file", Type: configString},
+	{Key: "remind.contexts", Type: configList, Fields: []string{"name", "url", "token"}},
+	{Key: "discovery.service", Type: configString},
+	{Key: "discovery.dir", Type: configString},
+	{Key: "plugin.handshake", Type: configList},
+}
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "cmd.config.short",
+	Long:  "cmd.config.long",
+	// 誤った設定ファイルを調べて直せるよう、設定の読み込みに失敗しても警告だけでサブコマンドを実行します。
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		commandStarted = true
+		if cmd == configMigrateCmd {
+			// 変換するコマンドのため、以前の形式であることは知らせません。
+			configFileState.Legacy = false
+		}
+		if err := applyServiceSettings(); err != nil {
+			logger.Warn(err.Error())
+		}
+		return nil
+	},
+}
+
+// configViewCmd represents the config view command
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "cmd.config.view.short",
+	Long:  "cmd.config.view.long",
+	RunE:  configView,
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "cmd.config.set.short",
+	Long:  "cmd.config.set.long",
+	RunE:  configSet,
+}
+
+// configUnsetCmd represents the config unset command
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset KEY",
+	Short: "cmd.config.unset.short",
+	Long:  "cmd.config.unset.long",
+	RunE:  configUnset,
+}
+
+// configMigrateCmd represents the config migrate command
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "cmd.config.migrate.short",
+	Long:  "cmd.config.migrate.long",
+	RunE:  configMigrate,
+}
+
+// configExplainCmd represents the config explain command
+var configExplainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "cmd.config.explain.short",
+	Long:  "cmd.config.explain.long",
+	RunE:  configExplain,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configViewCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configExplainCmd)
+	configCmd.AddCommand(configMigrateCmd)
+
+	configViewCmd.Flags().Bool("raw", false, "cmd.config.view.flag.raw")
+	configExplainCmd.Flags().Bool("json", false, "cmd.config.explain.flag.json")
+}
+
+func configView(cmd *cobra.Command, args []string) error {
+	config, err := loadConfigForEdit()
+	if err != nil {
+		return err
+	}
+	if raw, _ := cmd.Flags().GetBool("raw"); !raw {
+		redactConfig(config)
+	}
+	out, err := config.Marshal()
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+func configSet(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return usageError(ConfigArgsInvalid)
+	}
+	key := strings.ToLower(args[0])
+	k, ok := lookupConfigKey(key)
+	if !ok {
+		return usageError(ConfigKeyUnknown, key)
+	}
+	value, err := parseConfigValue(k, args[1])
+	if err != nil {
+		return err
+	}
+
+	config, err := loadConfigForEdit()
+	if err != nil {
+		return err
+	}
+	config.Set(key, value)
+	if err := config.Save(); err != nil {
+		return err
+	}
+	logger.Info(service.T("cmd.config.saved", key, config.Path))
+	return nil
+}
+
+func configUnset(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return usageError(ConfigArgsInvalid)
+	}
+	key := strings.ToLower(args[0])
+
+	config, err := loadConfigForEdit()
+	if err != nil {
+		return err
+	}
+	if !config.Delete(key) {
+		return usageError(ConfigKeyNotSet, key, config.Path)
+	}
+	if err := config.Save(); err != nil {
+		return err
+	}
+	logger.Info(service.T("cmd.config.unset", key, config.Path))
+	return nil
+}
+
+func configMigrate(cmd *cobra.Command, args []string) error {
+	config, err := loadConfigForEdit()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(config.Path); os.IsNotExist(err) {
+		return usageError(ConfigFileNotFound, config.Path)
+	}
+	migrated, err := config.Migrate()
+	if err != nil {
+		return err
+	}
+	if !migrated {
+		logger.Info(service.T("cmd.config.migrate.current", config.Path, service.ConfigAPIVersion))
+		return nil
+	}
+
+	if service.DryRun {
+		// ドライラン時は変換後の内容を出力するだけで、設定ファイルは更新しません。
+		out, err := config.Marshal()
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+		logger.Info(service.T(DryRunMessage))
+		return nil
+	}
+	backup, err := config.Backup()
+	if err != nil {
+		return err
+	}
+	if err := config.Save(); err != nil {
+		return err
+	}
+	logger.Info(service.T("cmd.config.migrate.done", config.Path, service.ConfigAPIVersion, backup))
+	return nil
+}
+
+// explainedSetting は実際に利用される設定の値とその出どころです。
+type explainedSetting struct {
+	Key    string        `json:"key"`
+	Value  string        `json:"value"`
+	Source SettingSource `json:"source"`
+	Error  string        `json:"error,omitempty"`
+}
+
+func configExplain(cmd *cobra.Command, args []string) error {
+	settings := explainSettings()
+	problems := validateConfigFile(viper.ConfigFileUsed())
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(struct {
+			File     string             `json:"file"`
+			Settings []explainedSetting `json:"settings"`
+			Problems []string           `json:"problems"`
+		}{viper.ConfigFileUsed(), settings, problems}); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		for _, s := range settings {
+			value := s.Value
+			if s.Error != "" {
+				value = s.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, value, s.Source)
+		}
+		w.Flush()
+		for _, problem := range problems {
+			logger.Warn(problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return usageError(ConfigFileInvalid, viper.ConfigFileUsed(), len(problems))
+	}
+	return nil
+}
+
+// explainSettings はClientSettingの各設定を解決し、実際に利用される値とその出どころを返します。
+// 認証トークンは値を伏せます。複数のエンドポイントを利用しない場合、failoverの設定は含めません。
+func explainSettings() []explainedSetting {
+	var settings []explainedSetting
+	add := func(key string, value interface{}, err error) {
+		s := explainedSetting{Key: key, Value: fmt.Sprint(value), Source: defaultSource()}
+		if source, ok := sourceOf(key); ok {
+			s.Source = source
+		}
+		if err != nil {
+			s.Value, s.Error = "", err.Error()
+		}
+		settings = append(settings, s)
+	}
+
+	protocol, err := clientSetting.Protocol()
+	add("protocol", protocol, err)
+	host, err := clientSetting.Host()
+	add("host", host, err)
+	port, err := clientSetting.Port()
+	add("port", port, err)
+	context, err := clientSetting.Context()
+	add("context", context, err)
+	// 認証トークンが無いことは設定の誤りではないため、空の値として表示します。
+	if _, err := clientSetting.Token(); err == nil {
+		add("token", service.RedactedValue, nil)
+	} else {
+		noteSource("token", defaultSource())
+		add("token", "", nil)
+	}
+	lang, err := clientSetting.Language()
+	add("lang", lang, err)
+	level, err := clientSetting.LogLevel()
+	add("log.level", level, err)
+	format, err := clientSetting.LogFormat()
+	add("log.format", format, err)
+	dryRun, err := clientSetting.DryRun()
+	add("dry_run", dryRun, err)
+	verbosity, err := clientSetting.Verbosity()
+	add("verbosity", verbosity, err)
+	traceFile, err := clientSetting.TraceFile()
+	add("trace_file", traceFile, err)
+	journalDir, err := clientSetting.JournalDir()
+	add("journal_dir", journalDir, err)
+	templateDir, err := clientSetting.TemplateDir()
+	add("template_dir", templateDir, err)
+
+	retry, err := clientSetting.RetryPolicy()
+	add("retry.max", retry.MaxRetries, err)
+	add("retry.base_delay", retry.BaseDelay, err)
+	add("retry.max_delay", retry.MaxDelay, err)
+	add("retry.post", retry.RetryPost, err)
+
+	if failover, err := clientSetting.Failover(); err != nil {
+		add("failover", "", err)
+	} else if failover != nil {
+		add("failover.failure_threshold", failover.FailureThreshold, nil)
+		add("failover.open_timeout", failover.OpenTimeout, nil)
+		add("failover.state_file", failover.StateFile, nil)
+	}
+
+	// 接続先を見つけられなかった場合のエラーはprotocolなどで表示するため、探索の設定だけを表示します。
+	clientSetting.Discovery()
+	for _, key := range []string{"discovery.service", "discovery.dir"} {
+		source, _ := sourceOf(key)
+		var value string
+		switch source.Kind {
+		case SourceEnv:
+			value = os.Getenv(source.Detail)
+		case SourceConfig:
+			value = viper.GetString(key)
+		default:
+			if key == "discovery.dir" {
+				value = DefaultDiscoveryDir
+			}
+		}
+		add(key, value, nil)
+	}
+	return settings
+}
+
+// lookupConfigKey は設定ファイルのキーをスキーマから探します。
+func lookupConfigKey(key string) (configKey, bool) {
+	for _, k := range configSchema {
+		if k.Key == key {
+			return k, true
+		}
+	}
+	names := strings.Split(key, ".")
+	switch {
+	case names[0] == "aliases" && len(names) == 2:
+		return configKey{Key: key, Type: configArgs}, true
+	case names[0] == "defaults" && len(names) >= 3:
+		return configKey{Key: key, Type: configScalar}, true
+	}
+	return configKey{}, false
+}
+
+// parseConfigValue はconfig setで指定された文字列をキーの型の値に変換します。
+func parseConfigValue(k configKey, raw string) (interface{}, error) {
+	var value interface{} = raw
+	var err error
+	switch k.Type {
+	case configInt:
+		value, err = strconv.Atoi(raw)
+	case configBool:
+		value, err = strconv.ParseBool(raw)
+	case configDuration:
+		_, err = time.ParseDuration(raw)
+	case configScalar:
+		// サブコマンドのオプションの型は様々なため、YAMLとして数値や真偽値に変換します。
+		err = yaml.Unmarshal([]byte(raw), &value)
+	case configHeader:
+		// 設定ファイルの形式はconfig migrateで変換します。
+		return nil, usageError(ConfigHeaderNotSettable, k.Key)
+	case configList:
+		if len(k.Fields) > 0 {
+			return nil, usageError(ConfigKeyNotSettable, k.Key)
+		}
+		value = strings.Split(raw, ",")
+	}
+	if err != nil || !checkConfigValue(k, value) {
+		return nil, usageError(ConfigValueInvalid, k.Key, k.Type, raw)
+	}
+	return value, nil
+}
+
+// validateConfigFile は設定ファイルのキーと値をスキーマと照合し、問題の一覧を返します。
+func validateConfigFile(path string) []string {
+	if path == "" {
+		return nil
+	}
+	config := viper.New()
+	config.SetConfigFile(path)
+	if err := config.ReadInConfig(); err != nil {
+		if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
+			return nil
+		}
+		return []string{err.Error()}
+	}
+	return validateConfig(path, "", config.AllSettings())
+}
+
+// validateConfig はマップのキーと値をスキーマと照合し、問題の一覧を返します。prefixは入れ子のマップのキーです。
+func validateConfig(path string, prefix string, settings map[string]interface{}) []string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		key, value := prefix+name, settings[name]
+		if k, ok := lookupConfigKey(key); ok {
+			if !checkConfigValue(k, value) {
+				problems = append(problems, service.T(ConfigValueInvalid, path+": "+key, k.Type, value))
+				continue
+			}
+			problems = append(problems, validateConfigFields(path, k, value)...)
+			continue
+		}
+		child, isMap := value.(map[string]interface{})
+		if isMap && hasConfigChildren(key) {
+			problems = append(problems, validateConfig(path, key+".", child)...)
+			continue
+		}
+		problems = append(problems, service.T(ConfigKeyUnknown, path+": "+key))
+	}
+	return problems
+}
+
+// validateConfigFields は一覧の要素のマップに、スキーマに無いキーがあれば問題として返します。
+func validateConfigFields(path string, k configKey, value interface{}) []string {
+	if len(k.Fields) == 0 {
+		return nil
+	}
+	items, _ := value.([]interface{})
+	var problems []string
+	for i, item := range items {
+		fields, _ := item.(map[interface{}]interface{})
+		names := make([]string, 0, len(fields))
+		for field := range fields {
+			names = append(names, fmt.Sprint(field))
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !containsString(k.Fields, name) {
+				problems = append(problems, service.T(ConfigKeyUnknown, fmt.Sprintf("%s: %s[%d].%s", path, k.Key, i, name)))
+			}
+		}
+	}
+	return problems
+}
+
+// hasConfigChildren はキーの下に入れ子のキーがあるかどうかを返します。
+func hasConfigChildren(key string) bool {
+	if key == "aliases" || key == "defaults" || strings.HasPrefix(key, "defaults.") {
+		return true
+	}
+	for _, k := range configSchema {
+		if strings.HasPrefix(k.Key, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConfigValue は値がキーの型と選択肢に合うかどうかを返します。
+func checkConfigValue(k configKey, value interface{}) bool {
+	switch k.Type {
+	case configInt:
+		_, ok := value.(int)
+		return ok
+	case configBool:
+		_, ok := value.(bool)
+		return ok
+	case configDuration:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		_, err := time.ParseDuration(s)
+		return err == nil
+	case configList:
+		items, ok := value.([]interface{})
+		if !ok {
+			_, isStrings := value.([]string)
+			return isStrings && len(k.Fields) == 0
+		}
+		for _, item := range items {
+			if len(k.Fields) == 0 {
+				if _, ok := item.(string); !ok {
+					return false
+				}
+				continue
+			}
+			if _, ok := item.(map[interface{}]interface{}); !ok {
+				return false
+			}
+		}
+		return true
+	case configArgs:
+		switch args := value.(type) {
+		case string, []string:
+			return true
+		case []interface{}:
+			for _, arg := range args {
+				if _, isMap := arg.(map[interface{}]interface{}); isMap {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	}
+	// 文字列の値は、マップや一覧でなければ数値なども文字列として扱います。
+	switch value.(type) {
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}, nil:
+		return false
+	}
+	return len(k.Values) == 0 || containsString(k.Values, fmt.Sprint(value))
+}
+
+// containsString は一覧に文字列が含まれるかどうかを返します。
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// redactConfig は設定ファイルの認証トークン(token, endpoints[].token, exporter.contexts[].token, remind.contexts[].token)の値を伏せます。
+func redactConfig(config *service.ConfigFile) {
+	if _, ok := config.Get("token"); ok {
+		config.Set("token", service.RedactedValue)
+	}
+	for _, key := range []string{"endpoints", "exporter.contexts", "remind.contexts"} {
+		items, _ := config.Get(key)
+		list, _ := items.([]interface{})
+		for _, item := range list {
+			fields, ok := item.(yaml.MapSlice)
+			if !ok {
+				continue
+			}
+			for i := range fields {
+				if fields[i].Key == "token" {
+					fields[i].Value = service.RedactedValue
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// TestValidateConfig では設定ファイルの不明なキーや型の合わない値を問題として報告することを確認する。
+func TestValidateConfig(t *testing.T) {
+	settings := map[string]interface{}{
+		"protocol": "https",
+		"port":     "8000",
+		"bogus":    1,
+		"retry":    map[string]interface{}{"max": 3, "base_delay": "1s", "max_delay": 5},
+		"log":      map[string]interface{}{"formt": "json", "level": "trace"},
+		"endpoints": []interface{}{
+			map[interface{}]interface{}{"name": "a", "url": "https://a.example.com", "priority": 1},
+			map[interface{}]interface{}{"name": "b", "url": "https://b.example.com", "weight": 2},
+		},
+		"aliases":  map[string]interface{}{"ready": []interface{}{"ping", "--until-ready"}, "st": "update --id $1"},
+		"defaults": map[string]interface{}{"get": map[string]interface{}{"fingerprint": true}},
+	}
+	got := validateConfig("config.yaml", "", settings)
+	want := []string{
+		service.T(ConfigKeyUnknown, "config.yaml: bogus"),
+		service.T(ConfigKeyUnknown, "config.yaml: endpoints[1].weight"),
+		service.T(ConfigKeyUnknown, "config.yaml: log.formt"),
+		service.T(ConfigValueInvalid, "config.yaml: log.level", "string", "trace"),
+		service.T(ConfigValueInvalid, "config.yaml: port", "int", "8000"),
+		service.T(ConfigValueInvalid, "config.yaml: retry.max_delay", "duration", 5),
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("validateConfig() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestExplainSettings では各設定の値の出どころがコマンドラインオプション、環境変数、設定ファイル、既定の値の順に判断されることを確認する。
+func TestExplainSettings(t *testing.T) {
+	defer resetCommandsForTest()
+	viper.SetConfigFile("config.yaml")
+	bindEnv()
+	viper.Set("host", "todo.example.com")
+	viper.Set("retry.max", 5)
+	os.Setenv("TODO_PORT", "9000")
+	defer os.Unsetenv("TODO_PORT")
+	rootCmd.PersistentFlags().Set("retry-post", "true")
+
+	want := map[string]explainedSetting{
+		"host":       {Key: "host", Value: "todo.example.com", Source: SettingSource{Kind: SourceConfig, Detail: "config.yaml: host"}},
+		"port":       {Key: "port", Value: "9000", Source: SettingSource{Kind: SourceEnv, Detail: "TODO_PORT"}},
+		"retry.max":  {Key: "retry.max", Value: "5", Source: SettingSource{Kind: SourceConfig, Detail: "config.yaml: retry.max"}},
+		"retry.post": {Key: "retry.post", Value: "true", Source: SettingSource{Kind: SourceFlag, Detail: "--retry-post"}},
+		"log.format": {Key: "log.format", Value: service.LogFormatText, Source: SettingSource{Kind: SourceDefault}},
+	}
+	for _, s := range explainSettings() {
+		if w, ok := want[s.Key]; ok && s != w {
+			t.Errorf("%s = %+v, want %+v", s.Key, s, w)
+		}
+	}
+}
+
+// TestConfigCommands ではconfig set/unset/view/explainで他の設定を残したまま設定ファイルを編集し、
+// 誤ったキーや値を終了コード2で拒否することを確認する。
+func TestConfigCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte("token: test_token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) int {
+		defer resetCommandsForTest()
+		return execute(append([]string{"--config", path, "--quiet"}, args...))
+	}
+
+	for _, args := range [][]string{
+		{"config", "set", "retry.max", "5"},
+		{"config", "set", "Retry.Base_Delay", "500ms"},
+		{"config", "set", "aliases.ready", "ping --until-ready"},
+		{"config", "set", "defaults.get.fingerprint", "true"},
+		{"config", "view"},
+		{"config", "explain"},
+	} {
+		if code := run(args...); code != ExitOK {
+			t.Errorf("%v: exit code = %d", args, code)
+		}
+	}
+	for _, args := range [][]string{
+		{"config", "set", "retry.max", "many"},
+		{"config", "set", "retry.max_delay", "5"},
+		{"config", "set", "protocol", "ftp"},
+		{"config", "set", "endpoints", "https://todo.example.com"},
+		{"config", "set", "bogus", "1"},
+		{"config", "set", "retry.max"},
+		{"config", "unset", "lang"},
+	} {
+		if code := run(args...); code != ExitUsage {
+			t.Errorf("%v: exit code = %d", args, code)
+		}
+	}
+
+	content, _ := ioutil.ReadFile(path)
+	want := "token: test_token\nretry:\n  max: 5\n  base_delay: 500ms\naliases:\n  ready: ping --until-ready\ndefaults:\n  get:\n    fingerprint: true\n"
+	if string(content) != want {
+		t.Errorf("config = %q, want %q", content, want)
+	}
+
+	if code := run("config", "unset", "retry.max"); code != ExitOK {
+		t.Errorf("unset: exit code = %d", code)
+	}
+	content, _ = ioutil.ReadFile(path)
+	if strings.Contains(string(content), "max:") {
+		t.Errorf("config = %q", content)
+	}
+
+	// 設定ファイルに不明なキーがある場合、explainは終了コード2で終了する
+	if err := ioutil.WriteFile(path, append(content, "bogus: 1\n"...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if code := run("config", "explain", "--json"); code != ExitUsage {
+		t.Errorf("explain: exit code = %d", code)
+	}
+}
+
+// TestConfigMigrate ではconfig migrateで以前の形式の設定ファイルを変換し、変換前のファイルを残すことを確認する。
+func TestConfigMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	legacy := "protocol: https\nhost: example.com\ntoken: test_token\n"
+	if err := ioutil.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) int {
+		defer resetCommandsForTest()
+		return execute(append([]string{"--config", path, "--quiet"}, args...))
+	}
+
+	// 以前の形式のまま読み込んで利用できる
+	if code := run("config", "explain"); code != ExitOK {
+		t.Errorf("explain: exit code = %d", code)
+	}
+	if code := run("config", "migrate", "--dry-run"); code != ExitOK {
+		t.Errorf("migrate --dry-run: exit code = %d", code)
+	}
+	if content, _ := ioutil.ReadFile(path); string(content) != legacy {
+		t.Errorf("migrate --dry-run changed the config file: %q", content)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := run("config", "migrate"); code != ExitOK {
+			t.Errorf("migrate: exit code = %d", code)
+		}
+	}
+	content, _ := ioutil.ReadFile(path)
+	if want := "apiVersion: " + service.ConfigAPIVersion + "\nkind: " + service.ConfigKind + "\n" + legacy; string(content) != want {
+		t.Errorf("config = %q, want %q", content, want)
+	}
+	if content, _ := ioutil.ReadFile(path + service.ConfigBackupSuffix); string(content) != legacy {
+		t.Errorf("backup = %q, want %q", content, legacy)
+	}
+	if code := run("config", "set", "apiversion", "v2"); code != ExitUsage {
+		t.Errorf("set apiversion: exit code = %d", code)
+	}
+	if code := run("config", "migrate", "--config", filepath.Join(dir, "missing.yaml")); code != ExitUsage {
+		t.Errorf("migrate missing: exit code = %d", code)
+	}
+
+	// 設定ファイルに不明なキーがある場合、サブコマンドは終了コード2で終了する
+	if err := ioutil.WriteFile(path, append(content, "prot: https\n"...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if code := run("get"); code != ExitUsage {
+		t.Errorf("get: exit code = %d", code)
+	}
+}
+
+// TestDefaultConfigPath では既定の設定ファイルがTODO_CONFIG、XDG Base Directoryの場所、以前の場所の順に選ばれることを確認する。
+func TestDefaultConfigPath(t *testing.T) {
+	home, err := ioutil.TempDir("", "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	defer os.Unsetenv(EnvXDGConfigHome)
+	os.Unsetenv(EnvXDGConfigHome)
+	xdg := filepath.Join(home, ".config", "todo", "config.yaml")
+	legacy := filepath.Join(home, LegacyConfigName)
+
+	if path := defaultConfigPath(home); path != xdg {
+		t.Errorf("no config: %s, want %s", path, xdg)
+	}
+	if err := ioutil.WriteFile(legacy, []byte("token: test_token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if path := defaultConfigPath(home); path != legacy {
+		t.Errorf("legacy only: %s, want %s", path, legacy)
+	}
+	os.MkdirAll(filepath.Dir(xdg), 0700)
+	if err := ioutil.WriteFile(xdg, []byte("token: test_token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if path := defaultConfigPath(home); path != xdg {
+		t.Errorf("both: %s, want %s", path, xdg)
+	}
+	// XDG_CONFIG_HOMEの場所に無い場合は以前の場所を使う
+	os.Setenv(EnvXDGConfigHome, filepath.Join(home, "xdg"))
+	if path := defaultConfigPath(home); path != legacy {
+		t.Errorf("XDG_CONFIG_HOME without config: %s, want %s", path, legacy)
+	}
+	os.Remove(legacy)
+	if path, want := defaultConfigPath(home), filepath.Join(home, "xdg", "todo", "config.yaml"); path != want {
+		t.Errorf("XDG_CONFIG_HOME: %s, want %s", path, want)
+	}
+	os.Setenv(EnvConfig, "/etc/todo/config.yaml")
+	defer os.Unsetenv(EnvConfig)
+	if path := defaultConfigPath(home); path != "/etc/todo/config.yaml" {
+		t.Errorf("TODO_CONFIG: %s", path)
+	}
+}
@@ -16,18 +16,48 @@ package cmd
 
 import (
 	"fmt"
-	"log"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
 )
 
+// CreateTitleInvalid はタスクの名前の指定を読み込めなかった場合のエラーメッセージです。
+const CreateTitleInvalid service.MessageID = "cmd.create.title_invalid"
+
+// CreateDescriptionInvalid はタスクの概要の指定を読み込めなかった場合のエラーメッセージです。
+const CreateDescriptionInvalid service.MessageID = "cmd.create.description_invalid"
+
+// CreateTemplateConflict はテンプレートと--title/--descriptionが同時に指定された場合のエラーメッセージです。
+const CreateTemplateConflict service.MessageID = "cmd.create.template_conflict"
+
+// CreateTemplatePartial はテンプレートから作成するタスクの一部を作成できなかった場合のエラーメッセージです。
+// 作成できた件数と作成するタスクの件数を埋め込みます。
+const CreateTemplatePartial service.MessageID = "cmd.create.template_partial"
+
+// CreateInputConflict はタスクの名前や概要を複数の方法で指定した場合のエラーメッセージです。
+const CreateInputConflict service.MessageID = "cmd.create.input_conflict"
+
+// CreateDescriptionTooLarge はタスクの概要が大きすぎる場合のエラーメッセージです。上限のバイト数を埋め込みます。
+const CreateDescriptionTooLarge service.MessageID = "cmd.create.description_too_large"
+
+// MaxDescriptionSize はタスクの概要として送信できる大きさ(バイト)の上限です。
+const MaxDescriptionSize = 64 * 1024
+
+// descriptionStdin は--description-file -や--from-stdinで概要を読み込む標準入力です。
+var descriptionStdin io.Reader = os.Stdin
+
 // createCmd represents the create command
 var createCmd = &cobra.Command{
 	Use:   "create",
-	Short: "TODOタスクを作成します。",
-	Long:  `TODOタスクを作成します。`,
-	Run:   create,
+	Short: "cmd.create.short",
+	Long:  "cmd.create.long",
+	RunE:  create,
 	// Run: func(cmd *cobra.Command, args []string) {
 	// 	fmt.Println("create called")
 	// },
@@ -45,48 +75,237 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// createCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	createCmd.Flags().String("title", "", "タスクのタイトル")
-	createCmd.Flags().String("description", "", "タスクの概要")
+	createCmd.Flags().String("title", "", "cmd.create.flag.title")
+	createCmd.Flags().String("description", "", "cmd.create.flag.description")
+	createCmd.Flags().String("template", "", "cmd.create.flag.template")
+	createCmd.Flags().StringArray("set", nil, "cmd.template.flag.set")
+	createCmd.Flags().String("description-file", "", "cmd.create.flag.description-file")
+	createCmd.Flags().Bool("title-from-first-line", false, "cmd.create.flag.title-from-first-line")
+	createCmd.Flags().Bool("from-stdin", false, "cmd.create.flag.from-stdin")
+	createCmd.Flags().StringSlice("tag", nil, "flag.task.tag")
+	createCmd.Flags().String("priority", "", "flag.task.priority")
+	createCmd.Flags().String("due", "", "flag.task.due")
 }
 
-func create(cmd *cobra.Command, args []string) {
+func create(cmd *cobra.Command, args []string) error {
 
 	protocol, err := clientSetting.Protocol()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	host, err := clientSetting.Host()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	port, err := clientSetting.Port()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	token, err := clientSetting.Token()
+	if err != nil {
+		return service.WrapError(service.ErrorAuth, SettingErrorMessageTokenInvalid, err)
+	}
 
-	title, err := taskRequestSetting.Title()
+	metadata, err := taskRequestSetting.Metadata(cmd.Flags())
 	if err != nil {
-		log.Println("タスクの名前指定(--title)が不正です。")
-		log.Fatal(err)
+		return err
+	}
+
+	if name, _ := cmd.Flags().GetString("template"); name != "" {
+		if changedFlag(cmd.Flags(), "title", "description", "description-file", "title-from-first-line", "from-stdin") {
+			return usageError(CreateTemplateConflict)
+		}
+		sets, _ := cmd.Flags().GetStringArray("set")
+		return createFromTemplate(protocol, host, port, token, name, sets, metadata)
 	}
 
-	description, err := taskRequestSetting.Description()
+	title, description, err := createTaskFields(cmd.Flags(), metadata)
 	if err != nil {
-		log.Println("タスクの概要指定(--description)が不正です。")
-		log.Fatal(err)
+		return err
 	}
 
 	task, err := service.CreateTask(protocol, host, port, token, title, description)
 
+	if err == service.ErrDryRun {
+		logger.Info(service.T(DryRunMessage))
+		return nil
+	}
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
+	printCreatedTask(protocol, host, port, token, task)
+	return nil
+}
+
+// createTaskFields はタスクの名前と概要を、--title/--descriptionまたは--description-file/--from-stdinの入力から組み立てます。
+// --title-from-first-lineを指定した場合(--from-stdinで--titleを指定しない場合を含む)は、概要の最初の行を名前とします。
+// メタデータ(--tag, --priority, --due)は概要の先頭にフロントマターとして記述します。
+func createTaskFields(flags *pflag.FlagSet, metadata service.MetadataUpdate) (string, string, error) {
+	path, _ := flags.GetString("description-file")
+	firstLine, _ := flags.GetBool("title-from-first-line")
+	if fromStdin, _ := flags.GetBool("from-stdin"); fromStdin {
+		if path != "" && path != "-" {
+			return "", "", usageError(CreateInputConflict, "--from-stdin", "--description-file")
+		}
+		path = "-"
+		firstLine = firstLine || !flags.Changed("title")
+	}
+	if path != "" && flags.Changed("description") {
+		return "", "", usageError(CreateInputConflict, "--description", "--description-file")
+	}
+	if firstLine && flags.Changed("title") {
+		return "", "", usageError(CreateInputConflict, "--title", "--title-from-first-line")
+	}
+
+	var title, description string
+	var err error
+	switch {
+	case path != "":
+		if description, err = readDescriptionFile(path); err != nil {
+			return "", "", service.WrapError(service.ErrorUsage, CreateDescriptionInvalid, err)
+		}
+	case firstLine:
+		description, _ = flags.GetString("description")
+	default:
+		if description, err = taskRequestSetting.Description(); err != nil {
+			return "", "", service.WrapError(service.ErrorUsage, CreateDescriptionInvalid, err)
+		}
+	}
+
+	if firstLine {
+		title, description = splitFirstLine(description)
+		if title == "" {
+			return "", "", service.WrapError(service.ErrorUsage, CreateTitleInvalid, service.NewError(service.ErrorUsage, SettingTaskTitleNotFound))
+		}
+	} else if title, err = taskRequestSetting.Title(); err != nil {
+		return "", "", service.WrapError(service.ErrorUsage, CreateTitleInvalid, err)
+	}
+	if description == "" {
+		return "", "", service.WrapError(service.ErrorUsage, CreateDescriptionInvalid, service.NewError(service.ErrorUsage, SettingTaskDescriptionNotFound))
+	}
+
+	// ToDoサーバにはJSONで送信するため、UTF-8として不正なバイト列は置き換えます。
+	title, _ = sanitizeUTF8(title)
+	description, sanitized := sanitizeUTF8(description)
+	if sanitized {
+		logger.Warn(service.T("cmd.create.description_sanitized"))
+	}
+	description = metadata.Apply("", description)
+	if len(description) > MaxDescriptionSize {
+		return "", "", usageError(CreateDescriptionTooLarge, MaxDescriptionSize)
+	}
+	return title, description, nil
+}
+
+// readDescriptionFile はタスクの概要をファイルまたは標準入力(-)から読み込みます。
+// 大きなファイルをすべて読み込まないよう、上限を超えた時点で読み込みをやめます。
+func readDescriptionFile(path string) (string, error) {
+	var r io.Reader = descriptionStdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		r = file
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, MaxDescriptionSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > MaxDescriptionSize {
+		return "", usageError(CreateDescriptionTooLarge, MaxDescriptionSize)
+	}
+	return string(data), nil
+}
+
+// splitFirstLine は空でない最初の行を名前とし、残りを概要として返します。概要の前後の空行は取り除きます。
+func splitFirstLine(text string) (string, string) {
+	text = strings.TrimLeft(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	lines := strings.SplitN(text, "\n", 2)
+	title := strings.TrimSpace(lines[0])
+	if len(lines) == 1 {
+		return title, ""
+	}
+	return title, strings.Trim(lines[1], "\n")
+}
+
+// sanitizeUTF8 はUTF-8として不正なバイト列をU+FFFDに置き換え、置き換えたかどうかを返します。
+func sanitizeUTF8(s string) (string, bool) {
+	if utf8.ValidString(s) {
+		return s, false
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b.WriteRune(utf8.RuneError)
+		} else {
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String(), true
+}
+
+// changedFlag は指定したオプションのいずれかが指定されているかどうかを返します。
+func changedFlag(flags *pflag.FlagSet, names ...string) bool {
+	for _, name := range names {
+		if flags.Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// createFromTemplate はテンプレートを展開したタスクを順に作成します。
+// すべてのタスクを展開できることを確認してから作成するため、テンプレートに誤りがあればタスクを1件も作成しません。
+func createFromTemplate(protocol string, host string, port int, token string, name string, sets []string, metadata service.MetadataUpdate) error {
+	dir, err := clientSetting.TemplateDir()
+	if err != nil {
+		return err
+	}
+	tmpl, err := service.LoadTaskTemplate(dir, name)
+	if err != nil {
+		return err
+	}
+	tasks, err := renderTaskTemplate(tmpl, sets)
+	if err != nil {
+		return err
+	}
+
+	for i, task := range tasks {
+		created, err := service.CreateTask(protocol, host, port, token, task.Title, metadata.Apply("", task.Description))
+		if err == service.ErrDryRun {
+			logger.Info(service.T(DryRunMessage))
+			continue
+		}
+		if err != nil {
+			return service.WrapError(service.KindOf(err), CreateTemplatePartial, err, i, len(tasks))
+		}
+		printCreatedTask(protocol, host, port, token, created)
+	}
+	return nil
+}
+
+// printCreatedTask は作成したタスクを操作ジャーナルに記録して出力します。
+func printCreatedTask(protocol string, host string, port int, token string, task service.CreatedTask) {
+	recordJournal(protocol, host, port, token, service.JournalEntry{
+		Operation: service.JournalCreate,
+		TaskID:    task.ID,
+		Current:   &service.Task{ID: task.ID, Title: task.Title, Description: task.Description, Status: "TODO"},
+	})
+
+	description := service.ParseDescription(task.Description)
 	fmt.Printf("ID: %d\n", task.ID)
 	fmt.Println("TITLE: " + task.Title)
+	if metadata := description.Metadata; !metadata.IsZero() {
+		fmt.Println("TAGS: " + strings.Join(metadata.Tags, ","))
+		fmt.Println("PRIORITY: " + metadata.Priority)
+		fmt.Println("DUE: " + metadata.Due)
+	}
 	fmt.Println("DESCRIPTION: ")
-	fmt.Println(task.Description)
-
+	fmt.Println(description.Body)
 }
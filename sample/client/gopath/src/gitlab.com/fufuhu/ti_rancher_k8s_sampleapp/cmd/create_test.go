@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// TestCreateFromInput では概要をファイルや標準入力から読み込み、--from-stdinでは最初の行を名前とすることを確認する。
+func TestCreateFromInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "create")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TODO_JOURNAL_DIR", filepath.Join(dir, "journal"))
+	defer os.Unsetenv("TODO_JOURNAL_DIR")
+	defer func() { descriptionStdin = os.Stdin }()
+
+	path := filepath.Join(dir, "notes.md")
+	if err := ioutil.WriteFile(path, []byte("# Notes\n\n- step 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	large := filepath.Join(dir, "large.log")
+	if err := ioutil.WriteFile(large, []byte(strings.Repeat("x", MaxDescriptionSize+1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	todo := &smoketestServer{tasks: map[int]service.Task{}, nextID: 1}
+	server := httptest.NewServer(todo)
+	defer server.Close()
+	create := func(stdin string, args ...string) int {
+		descriptionStdin = strings.NewReader(stdin)
+		return executeForTest(append(append([]string{"create"}, args...), serverArgs(server)...)...)
+	}
+
+	for _, c := range []struct {
+		stdin string
+		args  []string
+		want  service.Task
+	}{
+		{"", []string{"--title", "notes", "--description-file", path}, service.Task{Title: "notes", Description: "# Notes\n\n- step 1\n"}},
+		{"", []string{"--description-file", path, "--title-from-first-line"}, service.Task{Title: "# Notes", Description: "- step 1"}},
+		{"commit abc\r\nAuthor: a\r\n\r\n    fix\r\n", []string{"--from-stdin"}, service.Task{Title: "commit abc", Description: "Author: a\n\n    fix"}},
+		{"log excerpt\n", []string{"--from-stdin", "--title", "crash"}, service.Task{Title: "crash", Description: "log excerpt\n"}},
+		{"bad \xff byte", []string{"--title", "bad", "--description-file", "-"}, service.Task{Title: "bad", Description: "bad � byte"}},
+	} {
+		id := todo.nextID
+		if code := create(c.stdin, c.args...); code != ExitOK {
+			t.Errorf("%v: exit code = %d", c.args, code)
+			continue
+		}
+		c.want.ID, c.want.Status = id, "TODO"
+		if got := todo.tasks[id]; got != c.want {
+			t.Errorf("%v: task = %+v, want %+v", c.args, got, c.want)
+		}
+	}
+
+	created := len(todo.tasks)
+	for _, c := range []struct {
+		stdin string
+		args  []string
+	}{
+		{"", []string{"--title", "large", "--description-file", large}},
+		{"", []string{"--title", "missing", "--description-file", filepath.Join(dir, "missing")}},
+		{"", []string{"--description", "x", "--description-file", path}},
+		{"", []string{"--title", "x", "--title-from-first-line", "--description", "y"}},
+		{"", []string{"--from-stdin", "--description-file", path}},
+		{"only title\n", []string{"--from-stdin"}},
+		{"\n\n", []string{"--from-stdin"}},
+	} {
+		if code := create(c.stdin, c.args...); code != ExitUsage {
+			t.Errorf("%v: exit code = %d", c.args, code)
+		}
+	}
+	if len(todo.tasks) != created {
+		t.Errorf("tasks created from invalid input: %v", todo.tasks)
+	}
+}
+
+// TestTaskMetadataFlags では--tag/--priority/--dueを概要のフロントマターとして送信し、
+// updateで--descriptionを指定してもメタデータが残ることを確認する。
+func TestTaskMetadataFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TODO_JOURNAL_DIR", dir)
+	defer os.Unsetenv("TODO_JOURNAL_DIR")
+
+	todo := &smoketestServer{tasks: map[int]service.Task{}, nextID: 1}
+	server := httptest.NewServer(todo)
+	defer server.Close()
+	run := func(args ...string) int {
+		return executeForTest(append(args, serverArgs(server)...)...)
+	}
+
+	if code := run("create", "--title", "deploy", "--description", "body", "--tag", "ops,api", "--priority", "high", "--due", "2019-04-01"); code != ExitOK {
+		t.Fatalf("create: exit code = %d", code)
+	}
+	want := "---\ntodo:\n  tags: [ops, api]\n  priority: high\n  due: \"2019-04-01\"\n---\nbody"
+	if got := todo.tasks[1].Description; got != want {
+		t.Errorf("created description = %q, want %q", got, want)
+	}
+
+	if code := run("update", "--id", "1", "--title", "deploy", "--description", "new body", "--status", "TODO", "--priority", "low", "--tag="); code != ExitOK {
+		t.Fatalf("update: exit code = %d", code)
+	}
+	want = "---\ntodo:\n  priority: low\n  due: \"2019-04-01\"\n---\nnew body"
+	if got := todo.tasks[1].Description; got != want {
+		t.Errorf("updated description = %q, want %q", got, want)
+	}
+
+	for _, args := range [][]string{
+		{"create", "--title", "x", "--description", "y", "--priority", "urgent"},
+		{"create", "--title", "x", "--description", "y", "--tag", "a b"},
+		{"update", "--id", "1", "--title", "x", "--status", "TODO", "--due", "someday"},
+		{"get", "--due-before", "someday"},
+		{"get", "--priority", "urgent"},
+	} {
+		if code := run(args...); code != ExitUsage {
+			t.Errorf("%v: exit code = %d, want %d", args, code, ExitUsage)
+		}
+	}
+	if len(todo.tasks) != 1 {
+		t.Errorf("tasks created from invalid metadata: %v", todo.tasks)
+	}
+}
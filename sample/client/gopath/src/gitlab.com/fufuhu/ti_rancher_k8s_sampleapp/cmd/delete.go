@@ -16,19 +16,20 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/spf13/cobra"
 	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
 )
 
+// DeleteIDRequired は削除対象のタスクのIDが指定されていない場合のエラーメッセージです。
+const DeleteIDRequired service.MessageID = "cmd.delete.id_required"
+
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
-	Short: "ユーザに紐づくTODOタスクを削除します。",
-	Long: `ユーザに紐づくTODOタスクを削除します。
-		--id指定なしの場合は、エラーを返します。`,
-	Run: delete,
+	Short: "cmd.delete.short",
+	Long:  "cmd.delete.long",
+	RunE:  delete,
 	// Run: func(cmd *cobra.Command, args []string) {
 	// 	fmt.Println("delete called")
 	// },
@@ -46,40 +47,59 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// deleteCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	deleteCmd.Flags().Int("id", 0, "削除したいタスクのID")
+	deleteCmd.Flags().Int("id", 0, "cmd.delete.flag.id")
 }
 
-func delete(cmd *cobra.Command, args []string) {
+func delete(cmd *cobra.Command, args []string) error {
 	protocol, err := clientSetting.Protocol()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	host, err := clientSetting.Host()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	port, err := clientSetting.Port()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	token, err := clientSetting.Token()
 	if err != nil {
-		log.Println("JWTトークンの設定が異常です。loginサブコマンドで再取得してください。")
-		log.Fatal(err)
+		return service.WrapError(service.ErrorAuth, SettingErrorMessageTokenInvalid, err)
 	}
 
 	id, _ := taskRequestSetting.ID()
 
 	if id != 0 {
+		// 削除APIのレスポンスにはステータスが含まれないため、
+		// undoで復元できるように削除前のタスクを取得しておきます。
+		var previous *service.Task
+		if tasks, err := service.GetTask(protocol, host, port, token, id); err == nil && len(tasks) > 0 {
+			previous = &tasks[0]
+		}
+
 		task, err := service.DeleteTask(protocol, host, port, token, id)
+		if err == service.ErrDryRun {
+			logger.Info(service.T(DryRunMessage))
+			return nil
+		}
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+		if previous == nil {
+			previous = &task
 		}
-		log.Printf("Task(ID=%d) is deleted.\n", task.ID)
+		recordJournal(protocol, host, port, token, service.JournalEntry{
+			Operation: service.JournalDelete,
+			TaskID:    task.ID,
+			Previous:  previous,
+		})
+		logger.Info(service.T("cmd.delete.done"), "id", task.ID)
 		fmt.Printf("ID\tTitle\tDescription\n")
 		fmt.Printf("%d\t%s\t%s\n", task.ID, task.Title, task.Description)
 	} else {
-		log.Fatal("削除対象のタスクのIDが正しく指定されていません(--id)")
+		return usageError(DeleteIDRequired)
 	}
+	return nil
 }
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// ServerDiscovery はKubernetesのPod内で実行された場合に、
+// 環境から自動的に見つけたToDoサーバの接続先と認証情報です。
+// 見つからなかった項目はゼロ値のままになります。
+type ServerDiscovery struct {
+	Protocol string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Token    string
+	// Source は接続先を見つけた環境変数またはファイルです。
+	Source string
+	// TokenSource は認証トークンを読み込んだファイルです。
+	TokenSource string
+}
+
+// SettingDiscoveryAmbiguous はToDoサーバのServiceが複数見つかり、接続先を決められない場合のエラーメッセージです。
+const SettingDiscoveryAmbiguous service.MessageID = "settings.discovery_ambiguous"
+
+// SettingDiscoveryInvalid はマウントされたディレクトリの接続先の指定が不正な場合のエラーメッセージです。
+const SettingDiscoveryInvalid service.MessageID = "settings.discovery_invalid"
+
+// 接続先の探索に利用する環境変数です。
+const (
+	// EnvDiscoveryService は探索するServiceの名前です(例: service-todoserver-prod)。
+	EnvDiscoveryService = "TODO_DISCOVERY_SERVICE"
+	// EnvDiscoveryDir は接続先と認証情報を読み込むConfigMapやSecretをマウントしたディレクトリです。
+	EnvDiscoveryDir = "TODO_DISCOVERY_DIR"
+)
+
+// DefaultDiscoveryDir はConfigMapやSecretをマウントするディレクトリの既定値です。
+const DefaultDiscoveryDir = "/etc/todo"
+
+// discoveryServicePrefix はchartのtodoserver-service.yamlが作成するService(service-todoserver-<リリース名>)の
+// 環境変数の接頭辞です。Serviceの名前が指定されない場合はこの接頭辞を持つServiceを探します。
+const discoveryServicePrefix = "SERVICE_TODOSERVER_"
+
+// マウントされたディレクトリから読み込むファイルの名前(ConfigMapやSecretのキー)です。
+const (
+	discoveryFileURL      = "url"
+	discoveryFileUsername = "username"
+	discoveryFilePassword = "password"
+	discoveryFileToken    = "token"
+)
+
+// discoveryEnviron は探索に利用する環境変数の一覧を返します。テストで差し替えるための変数です。
+var discoveryEnviron = os.Environ
+
+// discoverServer はマウントされたディレクトリとKubernetesのServiceの環境変数から接続先と認証情報を探します。
+// 明示的に用意されたディレクトリの内容を、環境変数から見つけた接続先より優先します。
+// serviceNameが空の場合はchartが作成するServiceを探します。
+func discoverServer(environ []string, serviceName string, dir string) (ServerDiscovery, error) {
+	discovery, err := discoverFromEnviron(environ, serviceName)
+	if err != nil {
+		return discovery, err
+	}
+
+	read := func(name string) string {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(content))
+	}
+	if url := read(discoveryFileURL); url != "" {
+		protocol, host, port, err := service.Endpoint{URL: url}.Target()
+		if err != nil {
+			return discovery, service.NewError(service.ErrorUsage, SettingDiscoveryInvalid, filepath.Join(dir, discoveryFileURL))
+		}
+		discovery.Protocol, discovery.Host, discovery.Port = protocol, host, port
+		discovery.Source = filepath.Join(dir, discoveryFileURL)
+	}
+	discovery.Username = read(discoveryFileUsername)
+	discovery.Password = read(discoveryFilePassword)
+	if discovery.Token = read(discoveryFileToken); discovery.Token != "" {
+		discovery.TokenSource = filepath.Join(dir, discoveryFileToken)
+	}
+	return discovery, nil
+}
+
+// discoverFromEnviron はKubernetesがPodに設定するServiceの環境変数(<NAME>_SERVICE_HOST, <NAME>_SERVICE_PORT)から接続先を探します。
+// Pod内で実行されていない場合(KUBERNETES_SERVICE_HOSTが無い場合)は探しません。
+func discoverFromEnviron(environ []string, serviceName string) (ServerDiscovery, error) {
+	var discovery ServerDiscovery
+	env := map[string]string{}
+	for _, item := range environ {
+		if i := strings.Index(item, "="); i > 0 {
+			env[item[:i]] = item[i+1:]
+		}
+	}
+	if env["KUBERNETES_SERVICE_HOST"] == "" {
+		return discovery, nil
+	}
+
+	var names []string
+	if serviceName != "" {
+		names = append(names, envName(serviceName))
+	} else {
+		for key := range env {
+			if strings.HasPrefix(key, discoveryServicePrefix) && strings.HasSuffix(key, "_SERVICE_HOST") {
+				names = append(names, strings.TrimSuffix(key, "_SERVICE_HOST"))
+			}
+		}
+		sort.Strings(names)
+	}
+	if len(names) > 1 {
+		return discovery, service.NewError(service.ErrorUsage, SettingDiscoveryAmbiguous, strings.Join(names, ", "), EnvDiscoveryService)
+	}
+	if len(names) == 0 || env[names[0]+"_SERVICE_HOST"] == "" {
+		return discovery, nil
+	}
+
+	port, err := strconv.Atoi(env[names[0]+"_SERVICE_PORT"])
+	if err != nil {
+		return discovery, nil
+	}
+	discovery.Protocol = "http"
+	discovery.Host = env[names[0]+"_SERVICE_HOST"]
+	discovery.Port = port
+	discovery.Source = names[0] + "_SERVICE_HOST"
+	return discovery, nil
+}
+
+// discoveredTarget は設定ファイルに接続先が無い場合に利用する、環境から見つけた接続先を返します。
+// コマンドラインオプション(--protocol, --host, --port)でアクセス先が明示された場合は
+// 見つけた接続先と組み合わせないよう、探索を行いません。
+func discoveredTarget() (ServerDiscovery, error) {
+	flags := rootCmd.PersistentFlags()
+	if flags.Changed("protocol") || flags.Changed("host") || flags.Changed("port") {
+		return ServerDiscovery{}, nil
+	}
+	return clientSetting.Discovery()
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// discoveryDirForTest はConfigMapやSecretをマウントしたディレクトリを模擬したディレクトリを作成する。
+func discoveryDirForTest(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "discovery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// TestDiscoverServer では環境変数とマウントされたディレクトリから接続先を見つけられることを確認する。
+func TestDiscoverServer(t *testing.T) {
+	pod := []string{"KUBERNETES_SERVICE_HOST=10.43.0.1", "KUBERNETES_SERVICE_PORT=443"}
+	release := append(pod, "SERVICE_TODOSERVER_PROD_SERVICE_HOST=10.43.12.34", "SERVICE_TODOSERVER_PROD_SERVICE_PORT=8000")
+	dir := discoveryDirForTest(t, map[string]string{"url": "https://todo.example.com\n", "username": "test_user\n", "password": "test_password"})
+	defer os.RemoveAll(dir)
+	missing := filepath.Join(dir, "missing")
+
+	cases := []struct {
+		name        string
+		environ     []string
+		serviceName string
+		dir         string
+		want        ServerDiscovery
+		kind        service.ErrorKind
+	}{
+		{"outside pod", release[2:], "", missing, ServerDiscovery{}, -1},
+		{"no service", pod, "", missing, ServerDiscovery{}, -1},
+		{"service", release, "", missing, ServerDiscovery{Protocol: "http", Host: "10.43.12.34", Port: 8000, Source: "SERVICE_TODOSERVER_PROD_SERVICE_HOST"}, -1},
+		{"named service", append(release, "OTHER_SERVICE_HOST=10.43.0.9", "OTHER_SERVICE_PORT=80"), "other", missing, ServerDiscovery{Protocol: "http", Host: "10.43.0.9", Port: 80, Source: "OTHER_SERVICE_HOST"}, -1},
+		{"ambiguous", append(release, "SERVICE_TODOSERVER_STAGING_SERVICE_HOST=10.43.0.5", "SERVICE_TODOSERVER_STAGING_SERVICE_PORT=8000"), "", missing, ServerDiscovery{}, service.ErrorUsage},
+		{"service name resolves ambiguity", append(release, "SERVICE_TODOSERVER_STAGING_SERVICE_HOST=10.43.0.5", "SERVICE_TODOSERVER_STAGING_SERVICE_PORT=8000"), "service-todoserver-staging", missing, ServerDiscovery{Protocol: "http", Host: "10.43.0.5", Port: 8000, Source: "SERVICE_TODOSERVER_STAGING_SERVICE_HOST"}, -1},
+		{"directory overrides service", release, "", dir, ServerDiscovery{Protocol: "https", Host: "todo.example.com", Port: 443, Username: "test_user", Password: "test_password", Source: filepath.Join(dir, "url")}, -1},
+	}
+
+	for _, c := range cases {
+		got, err := discoverServer(c.environ, c.serviceName, c.dir)
+		if c.kind >= 0 {
+			if service.KindOf(err) != c.kind {
+				t.Errorf("%s: err = %v", c.name, err)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: discoverServer() = %+v, %v, want %+v", c.name, got, err, c.want)
+		}
+	}
+
+	invalid := discoveryDirForTest(t, map[string]string{"url": "todo-server:8000"})
+	defer os.RemoveAll(invalid)
+	if _, err := discoverServer(pod, "", invalid); service.KindOf(err) != service.ErrorUsage {
+		t.Errorf("invalid url: err = %v", err)
+	}
+}
+
+// TestClientSettingDiscovery では設定ファイルやコマンドラインオプションで接続先が指定されていない場合に
+// 環境から見つけた接続先と認証トークンが利用されることを確認する。
+func TestClientSettingDiscovery(t *testing.T) {
+	// 他のテストで読み込んだ設定ファイルやコマンドラインオプションの指定を一時的に取り消す
+	flags := rootCmd.PersistentFlags()
+	for _, name := range []string{"protocol", "host", "port"} {
+		flag := flags.Lookup(name)
+		value, changed := flag.Value.String(), flag.Changed
+		flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+		defer func() {
+			flag.Value.Set(value)
+			flag.Changed = changed
+		}()
+	}
+	for _, key := range []string{"protocol", "host", "port", "token", "endpoints"} {
+		value := viper.Get(key)
+		viper.Set(key, nil)
+		defer viper.Set(key, value)
+	}
+
+	dir := discoveryDirForTest(t, map[string]string{"token": "discovered_token"})
+	defer os.RemoveAll(dir)
+	bindEnv()
+	os.Setenv(EnvDiscoveryDir, dir)
+	defer os.Unsetenv(EnvDiscoveryDir)
+	discoveryEnviron = func() []string {
+		return []string{"KUBERNETES_SERVICE_HOST=10.43.0.1", "SERVICE_TODOSERVER_PROD_SERVICE_HOST=10.43.12.34", "SERVICE_TODOSERVER_PROD_SERVICE_PORT=8000"}
+	}
+	defer func() { discoveryEnviron = os.Environ }()
+
+	protocol, _ := clientSetting.Protocol()
+	host, _ := clientSetting.Host()
+	port, _ := clientSetting.Port()
+	token, _ := clientSetting.Token()
+	if protocol != "http" || host != "10.43.12.34" || port != 8000 || token != "discovered_token" {
+		t.Errorf("discovered = %s://%s:%d %s", protocol, host, port, token)
+	}
+
+	// 設定ファイルの指定を優先する
+	viper.Set("port", 9000)
+	if port, _ := clientSetting.Port(); port != 9000 {
+		t.Errorf("port from config = %d", port)
+	}
+
+	// コマンドラインオプションで接続先を指定した場合は見つけた接続先と組み合わせない
+	flags.Set("host", "todo-server")
+	viper.Set("port", nil)
+	if port, _ := clientSetting.Port(); port != 80 {
+		t.Errorf("port with --host = %d", port)
+	}
+}
@@ -0,0 +1,422 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// DoctorChecksFailed は診断項目のいずれかが失敗した場合のエラーメッセージです。
+const DoctorChecksFailed service.MessageID = "cmd.doctor.failed"
+
+// 診断項目の結果です。
+const (
+	DoctorPass = "pass" // 問題なし
+	DoctorWarn = "warn" // 動作はするが対処が望ましい
+	DoctorFail = "fail" // 対処が必要
+	DoctorSkip = "skip" // 前の項目の失敗などにより確認していない
+)
+
+// 警告や失敗と判断する閾値です。
+var (
+	// doctorTokenExpiryWarning は認証トークンの有効期限が近づいていると判断する残り時間です。
+	doctorTokenExpiryWarning = time.Minute
+	// doctorCertificateExpiryWarning はサーバ証明書の有効期限が近づいていると判断する残り時間です。
+	doctorCertificateExpiryWarning = 14 * 24 * time.Hour
+	// doctorClockSkewWarning はToDoサーバとの時刻のずれを警告する閾値です。
+	doctorClockSkewWarning = 30 * time.Second
+	// doctorClockSkewFailure はToDoサーバとの時刻のずれを失敗とする閾値です。
+	doctorClockSkewFailure = 5 * time.Minute
+)
+
+// DoctorCheck は診断項目ひとつの結果を表します。
+type DoctorCheck struct {
+	Name    string `json:"name"`             // 診断項目の名前(config, dns, tcp, tls, ping, token, token_expiry, clock_skew)
+	Target  string `json:"target,omitempty"` // 診断したエンドポイント(複数のエンドポイントを利用する場合のみ)
+	Status  string `json:"status"`           // 結果(pass/warn/fail/skip)
+	Message string `json:"message"`          // 結果の説明
+	Hint    string `json:"hint,omitempty"`   // 警告や失敗の場合の対処方法
+
+	err error // 失敗の原因(終了コードの判断に利用する)
+}
+
+// DoctorReport は診断結果の全体を表します。--jsonを指定した場合はこの内容を出力します。
+type DoctorReport struct {
+	Checks []DoctorCheck `json:"checks"`
+	Pass   int           `json:"pass"`
+	Warn   int           `json:"warn"`
+	Fail   int           `json:"fail"`
+	Skip   int           `json:"skip"`
+}
+
+// doctorTarget は診断するエンドポイントです。
+type doctorTarget struct {
+	name     string
+	protocol string
+	host     string
+	port     int
+	token    string
+}
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "cmd.doctor.short",
+	Long:  "cmd.doctor.long",
+	RunE:  doctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// doctorCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// doctorCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	doctorCmd.Flags().Bool("json", false, "cmd.doctor.flag.json")
+	doctorCmd.Flags().Duration("timeout", 5*time.Second, "cmd.doctor.flag.timeout")
+}
+
+func doctor(cmd *cobra.Command, args []string) error {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	// エンドポイントを明示して診断するため、振り分けは行いません。
+	failover := service.Endpoints
+	service.Endpoints = nil
+	defer func() {
+		service.Endpoints = failover
+	}()
+	defer limitRequests(timeout)()
+
+	report := runDoctor(failover, timeout)
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printDoctorReport(report)
+	}
+
+	if report.Fail == 0 {
+		return nil
+	}
+	kind := service.ErrorUnknown
+	for _, check := range report.Checks {
+		if check.Status == DoctorFail && check.err != nil {
+			kind = service.KindOf(check.err)
+			break
+		}
+	}
+	return service.NewError(kind, DoctorChecksFailed, report.Fail)
+}
+
+// runDoctor は設定の解決から順に診断を行います。
+// failoverが指定された場合は、エンドポイントごとに接続に関する診断を行います。
+func runDoctor(failover *service.Failover, timeout time.Duration) DoctorReport {
+	var report DoctorReport
+
+	config, targets := checkConfig(failover)
+	report.add(config)
+	for _, target := range targets {
+		for _, check := range diagnoseTarget(target, timeout) {
+			if len(targets) > 1 {
+				check.Target = target.name
+			}
+			report.add(check)
+		}
+	}
+	return report
+}
+
+func (r *DoctorReport) add(check DoctorCheck) {
+	r.Checks = append(r.Checks, check)
+	switch check.Status {
+	case DoctorPass:
+		r.Pass++
+	case DoctorWarn:
+		r.Warn++
+	case DoctorFail:
+		r.Fail++
+	default:
+		r.Skip++
+	}
+}
+
+// checkConfig は実際のコマンドと同じClientSettingの解決を行い、診断するエンドポイントを返します。
+func checkConfig(failover *service.Failover) (DoctorCheck, []doctorTarget) {
+	check := DoctorCheck{Name: "config"}
+
+	protocol, err := clientSetting.Protocol()
+	if err != nil {
+		return check.fail(err, "cmd.doctor.config.fail", "cmd.doctor.config.hint", err), nil
+	}
+	host, err := clientSetting.Host()
+	if err != nil {
+		return check.fail(err, "cmd.doctor.config.fail", "cmd.doctor.config.hint", err), nil
+	}
+	port, err := clientSetting.Port()
+	if err != nil {
+		return check.fail(err, "cmd.doctor.config.fail", "cmd.doctor.config.hint", err), nil
+	}
+	// トークンが無いことはtokenの項目で報告します。
+	token, _ := clientSetting.Token()
+
+	targets := []doctorTarget{{protocol + "://" + net.JoinHostPort(host, strconv.Itoa(port)), protocol, host, port, token}}
+	if failover != nil {
+		targets = nil
+		for _, endpoint := range failover.Endpoints {
+			p, h, n, err := endpoint.Target()
+			if err != nil {
+				return check.fail(err, "cmd.doctor.config.endpoint_invalid", "cmd.doctor.config.hint", endpoint), nil
+			}
+			target := doctorTarget{endpoint.String(), p, h, n, endpoint.Token}
+			if target.token == "" {
+				target.token = token
+			}
+			targets = append(targets, target)
+		}
+	}
+
+	for _, target := range targets {
+		if target.protocol != "http" && target.protocol != "https" {
+			err := service.NewError(service.ErrorUsage, "cmd.doctor.config.protocol_invalid", target.protocol)
+			return check.fail(err, "cmd.doctor.config.protocol_invalid", "cmd.doctor.config.protocol_hint", target.protocol), nil
+		}
+	}
+
+	names := make([]string, len(targets))
+	for i, target := range targets {
+		names[i] = target.name
+	}
+	if file := viper.ConfigFileUsed(); file != "" {
+		return check.pass("cmd.doctor.config.pass", strings.Join(names, ", "), file), targets
+	}
+	return check.pass("cmd.doctor.config.pass_without_file", strings.Join(names, ", ")), targets
+}
+
+// diagnoseTarget はエンドポイントひとつについて、名前解決からToDoサーバの時刻までを順に診断します。
+// 接続に失敗した場合、以降の接続を必要とする項目は確認しません。
+func diagnoseTarget(target doctorTarget, timeout time.Duration) []DoctorCheck {
+	var checks []DoctorCheck
+	address := net.JoinHostPort(target.host, strconv.Itoa(target.port))
+	reachable := true
+
+	// 名前解決
+	dns := DoctorCheck{Name: "dns"}
+	if net.ParseIP(target.host) != nil {
+		dns = dns.pass("cmd.doctor.dns.ip_address", target.host)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		addrs, err := net.DefaultResolver.LookupHost(ctx, target.host)
+		cancel()
+		if err != nil {
+			dns = dns.fail(service.WrapError(service.ErrorNetwork, "cmd.doctor.dns.fail", err, target.host), "cmd.doctor.dns.fail", "cmd.doctor.dns.hint", target.host, err)
+			reachable = false
+		} else {
+			dns = dns.pass("cmd.doctor.dns.pass", target.host, strings.Join(addrs, ", "))
+		}
+	}
+	checks = append(checks, dns)
+
+	// TCPの接続
+	tcp := DoctorCheck{Name: "tcp"}
+	if !reachable {
+		tcp = tcp.skip("cmd.doctor.skip_unreachable")
+	} else {
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			tcp = tcp.fail(service.WrapError(service.ErrorNetwork, "cmd.doctor.tcp.fail", err, address), "cmd.doctor.tcp.fail", "cmd.doctor.tcp.hint", address, err)
+			reachable = false
+		} else {
+			conn.Close()
+			tcp = tcp.pass("cmd.doctor.tcp.pass", address, roundDuration(time.Since(start)))
+		}
+	}
+	checks = append(checks, tcp)
+
+	// TLSのハンドシェイクとサーバ証明書の有効期限
+	tlsCheck := DoctorCheck{Name: "tls"}
+	switch {
+	case target.protocol != "https":
+		tlsCheck = tlsCheck.skip("cmd.doctor.tls.not_https")
+	case !reachable:
+		tlsCheck = tlsCheck.skip("cmd.doctor.skip_unreachable")
+	default:
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", address, &tls.Config{ServerName: target.host})
+		if err != nil {
+			tlsCheck = tlsCheck.fail(service.WrapError(service.ErrorNetwork, "cmd.doctor.tls.fail", err), "cmd.doctor.tls.fail", "cmd.doctor.tls.hint", err)
+			reachable = false
+		} else {
+			certificates := conn.ConnectionState().PeerCertificates
+			conn.Close()
+			notAfter := certificates[0].NotAfter
+			if time.Until(notAfter) < doctorCertificateExpiryWarning {
+				tlsCheck = tlsCheck.warn("cmd.doctor.tls.expiring", "cmd.doctor.tls.expiring_hint", formatTime(notAfter))
+			} else {
+				tlsCheck = tlsCheck.pass("cmd.doctor.tls.pass", formatTime(notAfter))
+			}
+		}
+	}
+	checks = append(checks, tlsCheck)
+
+	// ping-pong API
+	ping := DoctorCheck{Name: "ping"}
+	var result service.PingResult
+	if !reachable {
+		ping = ping.skip("cmd.doctor.skip_unreachable")
+	} else {
+		var err error
+		result, err = service.Ping(target.protocol, target.host, target.port)
+		if err != nil {
+			ping = ping.fail(err, "cmd.doctor.ping.fail", "cmd.doctor.ping.hint", err)
+			reachable = false
+		} else {
+			ping = ping.pass("cmd.doctor.ping.pass", result.Pong.Message, roundDuration(result.RTT))
+		}
+	}
+	checks = append(checks, ping)
+
+	// 有効期限はToDoサーバの時刻で判断します。時刻が分からない場合は手元の時刻を利用します。
+	skew := time.Duration(0)
+	if reachable && !result.ServerTime.IsZero() {
+		skew = result.ClockSkew()
+	}
+
+	// 認証トークンの有無と有効期限
+	token := DoctorCheck{Name: "token"}
+	expiry := DoctorCheck{Name: "token_expiry"}
+	if target.token == "" {
+		token = token.fail(service.NewError(service.ErrorAuth, SettingErrorMessageTokenNotFound), "cmd.doctor.token.fail", "cmd.doctor.token.hint")
+		expiry = expiry.skip("cmd.doctor.token_expiry.no_token")
+	} else {
+		token = token.pass("cmd.doctor.token.pass")
+		expiry = checkTokenExpiry(target.token, time.Now().Add(skew))
+	}
+	checks = append(checks, token, expiry)
+
+	// ToDoサーバとの時刻のずれ
+	clock := DoctorCheck{Name: "clock_skew"}
+	switch {
+	case !reachable:
+		clock = clock.skip("cmd.doctor.skip_unreachable")
+	case result.ServerTime.IsZero():
+		clock = clock.skip("cmd.doctor.clock_skew.no_date")
+	default:
+		// Dateヘッダの精度は1秒のため、秒の単位で表示します。
+		abs := skew
+		if abs < 0 {
+			abs = -abs
+		}
+		switch {
+		case abs >= doctorClockSkewFailure:
+			clock = clock.fail(nil, "cmd.doctor.clock_skew.fail", "cmd.doctor.clock_skew.hint", skew.Round(time.Second))
+		case abs >= doctorClockSkewWarning:
+			clock = clock.warn("cmd.doctor.clock_skew.warn", "cmd.doctor.clock_skew.hint", skew.Round(time.Second))
+		default:
+			clock = clock.pass("cmd.doctor.clock_skew.pass", skew.Round(time.Second))
+		}
+	}
+	checks = append(checks, clock)
+
+	return checks
+}
+
+// checkTokenExpiry は認証トークンの有効期限をnowの時点で判断します。
+func checkTokenExpiry(token string, now time.Time) DoctorCheck {
+	check := DoctorCheck{Name: "token_expiry"}
+
+	claims, err := service.ParseTokenClaims(token)
+	if err != nil {
+		return check.fail(err, "cmd.doctor.token_expiry.malformed", "cmd.doctor.token_expiry.hint", err)
+	}
+
+	expiresAt := claims.ExpiresAt()
+	switch {
+	case expiresAt.IsZero():
+		return check.pass("cmd.doctor.token_expiry.none")
+	case !now.Before(expiresAt):
+		err := service.NewError(service.ErrorAuth, "cmd.doctor.token_expiry.fail", formatTime(expiresAt))
+		return check.fail(err, "cmd.doctor.token_expiry.fail", "cmd.doctor.token_expiry.hint", formatTime(expiresAt))
+	case expiresAt.Sub(now) < doctorTokenExpiryWarning:
+		return check.warn("cmd.doctor.token_expiry.warn", "cmd.doctor.token_expiry.hint", formatTime(expiresAt))
+	}
+	return check.pass("cmd.doctor.token_expiry.pass", formatTime(expiresAt))
+}
+
+func (c DoctorCheck) pass(id service.MessageID, args ...interface{}) DoctorCheck {
+	c.Status, c.Message = DoctorPass, service.T(id, args...)
+	return c
+}
+
+func (c DoctorCheck) skip(id service.MessageID, args ...interface{}) DoctorCheck {
+	c.Status, c.Message = DoctorSkip, service.T(id, args...)
+	return c
+}
+
+func (c DoctorCheck) warn(id service.MessageID, hint service.MessageID, args ...interface{}) DoctorCheck {
+	c.Status, c.Message, c.Hint = DoctorWarn, service.T(id, args...), service.T(hint)
+	return c
+}
+
+func (c DoctorCheck) fail(err error, id service.MessageID, hint service.MessageID, args ...interface{}) DoctorCheck {
+	c.Status, c.Message, c.Hint, c.err = DoctorFail, service.T(id, args...), service.T(hint), err
+	return c
+}
+
+// printDoctorReport は診断結果を項目ごとに1行で出力し、警告や失敗の場合は対処方法を続けて出力します。
+func printDoctorReport(report DoctorReport) {
+	for _, check := range report.Checks {
+		name := service.T(service.MessageID("cmd.doctor.check." + check.Name))
+		if check.Target != "" {
+			name += " [" + check.Target + "]"
+		}
+		fmt.Printf("[%s] %s: %s\n", strings.ToUpper(check.Status), name, check.Message)
+		if check.Hint != "" {
+			fmt.Printf("       %s\n", service.T("cmd.doctor.hint", check.Hint))
+		}
+	}
+	fmt.Println(service.T("cmd.doctor.summary", report.Pass, report.Warn, report.Fail, report.Skip))
+}
+
+// roundDuration は表示用に時間を0.1ミリ秒の単位に丸めます。
+func roundDuration(d time.Duration) time.Duration {
+	return d.Round(100 * time.Microsecond)
+}
+
+// formatTime は表示用に時刻を手元のタイムゾーンで整形します。
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04:05 MST")
+}
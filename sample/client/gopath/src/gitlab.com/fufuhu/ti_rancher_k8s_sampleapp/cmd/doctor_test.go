@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testToken はexpを有効期限とするJWTを返す。署名は検証しないため固定の値を使う。
+func testToken(exp int64) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"user_id":1,"username":"test_user","exp":%d}`, exp)))
+	return "eyJ0eXAiOiJKV1QiLCJhbGciOiJIUzI1NiJ9." + payload + ".signature"
+}
+
+// testDoctorTarget はテスト用サーバを診断対象とするdoctorTargetを返す。
+func testDoctorTarget(server *httptest.Server, token string) doctorTarget {
+	args := serverArgs(server)
+	port, _ := strconv.Atoi(args[5])
+	return doctorTarget{server.URL, "http", args[3], port, token}
+}
+
+// doctorStatuses は診断項目の名前ごとの結果を返す。
+func doctorStatuses(checks []DoctorCheck) map[string]string {
+	statuses := map[string]string{}
+	for _, check := range checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+// TestCheckTokenExpiry では認証トークンの有効期限までの残り時間に応じた結果になることを確認する。
+func TestCheckTokenExpiry(t *testing.T) {
+	now := time.Unix(1560000000, 0)
+	cases := []struct {
+		token  string
+		status string
+	}{
+		{testToken(now.Add(time.Hour).Unix()), DoctorPass},
+		{testToken(now.Add(30 * time.Second).Unix()), DoctorWarn},
+		{testToken(now.Unix()), DoctorFail},
+		{testToken(0), DoctorPass},
+		{"WrongToken", DoctorFail},
+	}
+	for _, c := range cases {
+		if check := checkTokenExpiry(c.token, now); check.Status != c.status {
+			t.Errorf("checkTokenExpiry(%s) = %s, want %s", c.token, check.Status, c.status)
+		}
+	}
+}
+
+// TestDiagnoseTarget ではToDoサーバの時刻で認証トークンの有効期限を判断し、
+// 時刻のずれを報告することを確認する。
+func TestDiagnoseTarget(t *testing.T) {
+	skew := time.Duration(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/ping" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+		w.Write([]byte(`{"message": "pong"}`))
+	}))
+	defer server.Close()
+
+	token := testToken(time.Now().Add(time.Hour).Unix())
+	statuses := doctorStatuses(diagnoseTarget(testDoctorTarget(server, token), time.Second))
+	want := map[string]string{
+		"dns": DoctorPass, "tcp": DoctorPass, "tls": DoctorSkip, "ping": DoctorPass,
+		"token": DoctorPass, "token_expiry": DoctorPass, "clock_skew": DoctorPass,
+	}
+	for name, status := range want {
+		if statuses[name] != status {
+			t.Errorf("%s = %s, want %s", name, statuses[name], status)
+		}
+	}
+
+	// ToDoサーバの時刻では有効期限を過ぎている
+	skew = 2 * time.Hour
+	statuses = doctorStatuses(diagnoseTarget(testDoctorTarget(server, token), time.Second))
+	if statuses["token_expiry"] != DoctorFail || statuses["clock_skew"] != DoctorFail {
+		t.Errorf("statuses = %v", statuses)
+	}
+
+	skew = time.Minute
+	statuses = doctorStatuses(diagnoseTarget(testDoctorTarget(server, ""), time.Second))
+	if statuses["token"] != DoctorFail || statuses["token_expiry"] != DoctorSkip || statuses["clock_skew"] != DoctorWarn {
+		t.Errorf("statuses = %v", statuses)
+	}
+}
+
+// TestDiagnoseTargetUnreachable では接続できない場合に、接続を必要とする項目を確認しないことを確認する。
+func TestDiagnoseTargetUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	target := testDoctorTarget(server, testToken(0))
+	server.Close()
+
+	statuses := doctorStatuses(diagnoseTarget(target, time.Second))
+	want := map[string]string{
+		"dns": DoctorPass, "tcp": DoctorFail, "tls": DoctorSkip, "ping": DoctorSkip,
+		"token": DoctorPass, "token_expiry": DoctorPass, "clock_skew": DoctorSkip,
+	}
+	for name, status := range want {
+		if statuses[name] != status {
+			t.Errorf("%s = %s, want %s", name, statuses[name], status)
+		}
+	}
+}
+
+// TestDoctorExitCode では最初に失敗した項目の原因に応じた終了コードで終了することを確認する。
+func TestDoctorExitCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": "pong"}`))
+	}))
+	defer server.Close()
+
+	// テスト用の設定ファイルの認証トークンはJWTの形式ではない
+	if code := executeForTest(append([]string{"doctor"}, serverArgs(server)...)...); code != ExitAuth {
+		t.Errorf("exit code = %d, want %d", code, ExitAuth)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedArgs := serverArgs(closed)
+	closed.Close()
+	if code := executeForTest(append([]string{"doctor"}, closedArgs...)...); code != ExitNetwork {
+		t.Errorf("exit code = %d, want %d", code, ExitNetwork)
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// activeTarget は実際にリクエストを処理するエンドポイントのプロトコル・ホスト・ポート番号・認証トークンを返します。
+// 複数のエンドポイントを利用しない場合は引数の値をそのまま返します。
+// 操作ジャーナルなど、エンドポイントごとに分けて保管する情報の保管先を決めるために利用します。
+func activeTarget(protocol string, host string, port int, token string) (string, string, int, string) {
+	if service.Endpoints == nil {
+		return protocol, host, port, token
+	}
+
+	endpoint := service.Endpoints.Current()
+	p, h, n, err := endpoint.Target()
+	if err != nil {
+		return protocol, host, port, token
+	}
+	if endpoint.Token != "" {
+		token = endpoint.Token
+	}
+	return p, h, n, token
+}
+
+// pinEndpoint は以降のリクエストを現在のエンドポイントに固定し、そのエンドポイントの情報を返します。
+// 取り消し(undo)のように、特定のエンドポイントで記録した操作を同じエンドポイントに対して行う場合に利用します。
+func pinEndpoint(protocol string, host string, port int, token string) (string, string, int, string) {
+	protocol, host, port, token = activeTarget(protocol, host, port, token)
+	if service.Endpoints != nil {
+		logger.Info(service.T("cmd.endpoint.pinned"), "endpoint", service.Endpoints.Current())
+		service.Endpoints = nil
+	}
+	return protocol, host, port, token
+}
+
+// loginEndpoints は複数のエンドポイントを利用する場合に、エンドポイントごとにログインして認証トークンを取得します。
+// ログインに失敗したエンドポイントは以前の認証トークンを残します。
+// ひとつもログインできなかった場合は最後のエラーを返します。
+func loginEndpoints(username string, password string) ([]service.Endpoint, string, error) {
+	endpoints := make([]service.Endpoint, len(service.Endpoints.Endpoints))
+	copy(endpoints, service.Endpoints.Endpoints)
+
+	// エンドポイントを明示して送信するため、振り分けは行いません。
+	failover := service.Endpoints
+	service.Endpoints = nil
+	defer func() {
+		service.Endpoints = failover
+	}()
+
+	var token string
+	var lastErr error
+	for i, endpoint := range endpoints {
+		protocol, host, port, err := endpoint.Target()
+		if err != nil {
+			return nil, "", err
+		}
+
+		loginConfig, err := service.Login(protocol, host, port, username, password)
+		if err != nil {
+			if err != service.ErrDryRun {
+				logger.Warn(service.T("cmd.endpoint.login_failure"), "endpoint", endpoint, "error", err)
+			}
+			lastErr = err
+			continue
+		}
+
+		endpoints[i].Token = loginConfig.Token
+		if token == "" {
+			token = loginConfig.Token
+		}
+		logger.Info(service.T("cmd.endpoint.login_done"), "endpoint", endpoint)
+	}
+
+	if token == "" {
+		return endpoints, "", lastErr
+	}
+	return endpoints, token, nil
+}
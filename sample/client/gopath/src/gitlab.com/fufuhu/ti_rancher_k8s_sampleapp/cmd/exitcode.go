@@ -0,0 +1,62 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// コマンドの終了コードです。値を変更した場合はルートコマンドのヘルプ(cmd.root.long)の一覧も更新してください。
+// シェルスクリプトなどから失敗の原因を判別できるよう、エラーの原因の種類ごとに値を割り当てています。
+const (
+	ExitOK         = 0 // 正常終了
+	ExitFailure    = 1 // 分類できないエラー(bulkで一部の操作が失敗した場合を含む)
+	ExitUsage      = 2 // コマンドラインオプションや設定ファイルの指定の誤り
+	ExitAuth       = 3 // 認証が必要(トークンが無い、期限切れなど)。loginサブコマンドで再取得してください
+	ExitNotFound   = 4 // 指定したタスクが見つからない
+	ExitValidation = 5 // ToDoサーバが受け付けない入力
+	ExitNetwork    = 6 // ToDoサーバに接続できない
+	ExitServer     = 7 // ToDoサーバのエラー、または想定外の応答
+	ExitConflict   = 8 // 他の変更を検知したため更新を中止した
+)
+
+// exitCode はエラーの原因の種類から終了コードを返します。
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	switch service.KindOf(err) {
+	case service.ErrorUsage:
+		return ExitUsage
+	case service.ErrorAuth:
+		return ExitAuth
+	case service.ErrorNotFound:
+		return ExitNotFound
+	case service.ErrorValidation:
+		return ExitValidation
+	case service.ErrorNetwork:
+		return ExitNetwork
+	case service.ErrorServer:
+		return ExitServer
+	case service.ErrorConflict:
+		return ExitConflict
+	}
+	return ExitFailure
+}
+
+// usageError はコマンドラインオプションの指定の誤りを表すエラーを返します。
+func usageError(id service.MessageID, args ...interface{}) error {
+	return service.NewError(service.ErrorUsage, id, args...)
+}
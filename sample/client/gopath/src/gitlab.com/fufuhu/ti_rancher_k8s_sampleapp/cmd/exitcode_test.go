@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// TestExitCode はエラーの原因の種類ごとに終了コードの一覧どおりの値が得られることを確認する。
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		code int
+	}{
+		{nil, ExitOK},
+		{errors.New("unknown"), ExitFailure},
+		{usageError(UsageInvalid, "usage"), ExitUsage},
+		{service.NewError(service.ErrorAuth, SettingErrorMessageTokenNotFound), ExitAuth},
+		{service.NewError(service.ErrorNotFound, service.TaskGetReturnedNotFoundStatusCode), ExitNotFound},
+		{service.NewError(service.ErrorValidation, service.TaskUpdateReturnedBadRequestStatusCode), ExitValidation},
+		{&url.Error{Op: "Get", URL: "http://localhost", Err: errors.New("connection refused")}, ExitNetwork},
+		{service.ErrEndpointUnavailable, ExitNetwork},
+		{service.NewError(service.ErrorServer, service.ResponseBodyParseFailure), ExitServer},
+		{service.NewError(service.ErrorConflict, service.TaskUpdateConflict), ExitConflict},
+	}
+	for _, c := range cases {
+		if code := exitCode(c.err); code != c.code {
+			t.Errorf("exitCode(%v) = %d, want %d", c.err, code, c.code)
+		}
+	}
+}
+
+// TestWrapErrorKeepsKind はメッセージを前置しても原因の種類が引き継がれることを確認する。
+func TestWrapErrorKeepsKind(t *testing.T) {
+	err := service.WrapError(service.ErrorAuth, SettingErrorMessageTokenInvalid, service.NewError(service.ErrorNotFound, service.TaskGetReturnedNotFoundStatusCode))
+	if exitCode(err) != ExitNotFound || !service.IsMessage(err, SettingErrorMessageTokenInvalid) {
+		t.Fail()
+	}
+
+	err = service.WrapError(service.ErrorAuth, SettingErrorMessageTokenInvalid, errors.New("unknown"))
+	if exitCode(err) != ExitAuth {
+		t.Fail()
+	}
+}
+
+// resetFlags はフラグを既定値に戻し、未指定の状態にする。
+func resetFlags(flags *pflag.FlagSet) {
+	flags.VisitAll(func(flag *pflag.Flag) {
+		// 複数回指定できるフラグ(--setなど)はSetで値が追加されるため、空にする
+		if values, ok := flag.Value.(pflag.SliceValue); ok {
+			values.Replace(nil)
+		} else {
+			flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	})
+}
+
+// executeForTest は引数を指定してコマンドを実行し、終了コードを返す。
+// 実行後はフラグと設定ファイルの読み込み状態を元に戻す。
+func executeForTest(args ...string) int {
+	defer resetCommandsForTest()
+	return execute(append(args, "--config", DefaultTestFilename+".yaml", "--quiet", "--retry-max", "0"))
+}
+
+// resetCommandsForTest はフラグと設定ファイルの読み込み状態を元に戻す。
+func resetCommandsForTest() {
+	commands := []*cobra.Command{rootCmd}
+	for i := 0; i < len(commands); i++ {
+		resetFlags(commands[i].PersistentFlags())
+		resetFlags(commands[i].Flags())
+		commands = append(commands, commands[i].Commands()...)
+	}
+	cfgFile = ""
+	viper.Reset()
+	service.Endpoints = nil
+}
+
+// serverArgs はテスト用サーバにアクセスするためのオプションを返す。
+func serverArgs(server *httptest.Server) []string {
+	u, _ := url.Parse(server.URL)
+	host, port, _ := net.SplitHostPort(u.Host)
+	return []string{"--protocol", "http", "--host", host, "--port", port}
+}
+
+// TestExecuteExitCode はToDoサーバの応答やオプションの指定の誤りに応じた終了コードで
+// コマンドが終了することを確認する。
+func TestExecuteExitCode(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte(`[{"id": 1, "title": "test", "description": "", "status": "TODO"}]`))
+		}
+	}))
+	defer server.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedArgs := serverArgs(closed)
+	closed.Close()
+
+	cases := []struct {
+		name   string
+		status int
+		args   []string
+		code   int
+	}{
+		{"ok", http.StatusOK, append([]string{"get", "--id", "1"}, serverArgs(server)...), ExitOK},
+		{"unknown command", http.StatusOK, []string{"unknown"}, ExitUsage},
+		{"unknown flag", http.StatusOK, []string{"get", "--unknown"}, ExitUsage},
+		{"invalid flag value", http.StatusOK, []string{"get", "--port", "abc"}, ExitUsage},
+		{"id required", http.StatusOK, append([]string{"update", "--status", "DONE"}, serverArgs(server)...), ExitUsage},
+		{"auth", http.StatusUnauthorized, append([]string{"get"}, serverArgs(server)...), ExitAuth},
+		{"not found", http.StatusNotFound, append([]string{"get", "--id", "1"}, serverArgs(server)...), ExitNotFound},
+		{"validation", http.StatusBadRequest, append([]string{"get"}, serverArgs(server)...), ExitValidation},
+		{"network", http.StatusOK, append([]string{"get"}, closedArgs...), ExitNetwork},
+		{"server", http.StatusInternalServerError, append([]string{"get"}, serverArgs(server)...), ExitServer},
+	}
+	for _, c := range cases {
+		status = c.status
+		if code := executeForTest(c.args...); code != c.code {
+			t.Errorf("%s: exit code = %d, want %d", c.name, code, c.code)
+		}
+	}
+}
+
+// TestUndoConflictExitCode では取り消す操作の後にタスクが変更されていた場合に、
+// 取り消しの失敗を競合として終了コード8で終了することを確認する。
+func TestUndoConflictExitCode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "undo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TODO_JOURNAL_DIR", dir)
+	defer os.Unsetenv("TODO_JOURNAL_DIR")
+
+	todo := &smoketestServer{tasks: map[int]service.Task{}, nextID: 1}
+	server := httptest.NewServer(todo)
+	defer server.Close()
+
+	if code := executeForTest(append([]string{"create", "--title", "deploy", "--description", "release"}, serverArgs(server)...)...); code != ExitOK {
+		t.Fatalf("create: exit code = %d", code)
+	}
+	if code := executeForTest(append([]string{"update", "--id", "1", "--status", "RUNNING"}, serverArgs(server)...)...); code != ExitOK {
+		t.Fatalf("update: exit code = %d", code)
+	}
+	// 更新の後に他のクライアントがタスクを変更する
+	task := todo.tasks[1]
+	task.Title = "deploy v2"
+	todo.tasks[1] = task
+
+	if code := executeForTest(append([]string{"undo"}, serverArgs(server)...)...); code != ExitConflict {
+		t.Errorf("undo: exit code = %d, want %d", code, ExitConflict)
+	}
+	if todo.tasks[1].Title != "deploy v2" || todo.tasks[1].Status != "RUNNING" {
+		t.Errorf("task = %+v", todo.tasks[1])
+	}
+}
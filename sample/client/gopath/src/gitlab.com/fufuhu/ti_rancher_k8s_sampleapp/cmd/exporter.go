@@ -0,0 +1,312 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// ExporterListenFailure はメトリクスを公開するアドレスで待ち受けられなかった場合のエラーメッセージです。
+const ExporterListenFailure service.MessageID = "cmd.exporter.listen_failure"
+
+// exporterShutdownTimeout は終了の指示を受けてから、処理中のリクエストの完了を待つ時間です。
+var exporterShutdownTimeout = 10 * time.Second
+
+// exporterCmd represents the exporter command
+var exporterCmd = &cobra.Command{
+	Use:   "exporter",
+	Short: "cmd.exporter.short",
+	Long:  "cmd.exporter.long",
+	RunE:  exporter,
+}
+
+func init() {
+	rootCmd.AddCommand(exporterCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// exporterCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// exporterCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	exporterCmd.Flags().String("listen", ":9101", "cmd.exporter.flag.listen")
+	exporterCmd.Flags().Duration("interval", 30*time.Second, "cmd.exporter.flag.interval")
+}
+
+func exporter(cmd *cobra.Command, args []string) error {
+	listen, err := exporterSetting.Listen()
+	if err != nil {
+		return err
+	}
+	interval, err := exporterSetting.Interval()
+	if err != nil {
+		return err
+	}
+	contexts, err := exporterSetting.Contexts()
+	if err != nil {
+		return err
+	}
+
+	// コンテキストごとにToDoサーバを明示するため振り分けは行わず、
+	// 次の集計までに終わるよう応答を待つ時間を集計の間隔に制限します。
+	failover := service.Endpoints
+	service.Endpoints = nil
+	defer func() {
+		service.Endpoints = failover
+	}()
+	defer limitRequests(interval)()
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return service.WrapError(service.ErrorUsage, ExporterListenFailure, err, listen)
+	}
+
+	collector := newTaskExporter(contexts)
+	server := &http.Server{Handler: collector.handler()}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	logger.Info(service.T("cmd.exporter.started"), "listen", listener.Addr(), "interval", interval, "contexts", len(contexts))
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		collector.run(interval, done)
+		close(finished)
+	}()
+
+	// コンテナの停止(SIGTERM)やCtrl-Cで、集計と処理中のリクエストの完了を待って終了します。
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	select {
+	case sig := <-stop:
+		logger.Info(service.T("cmd.exporter.stopping"), "signal", sig)
+	case err := <-served:
+		close(done)
+		<-finished
+		return err
+	}
+
+	close(done)
+	<-finished
+	ctx, cancel := context.WithTimeout(context.Background(), exporterShutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+// exporterState はコンテキストひとつの集計結果です。
+type exporterState struct {
+	tasks       map[string]int // ステータスごとのタスクの数(一度現れたステータスは0件になっても残します)
+	success     bool           // 直近の集計が成功したかどうか
+	duration    time.Duration  // 直近の集計にかかった時間
+	lastSuccess time.Time      // 最後に集計が成功した時刻
+	errors      map[string]int // 原因の種類ごとのAPIの呼び出しの失敗の累計
+	tokenExpiry time.Time      // 認証トークンの有効期限(分からない場合はゼロ値)
+}
+
+// taskExporter はToDoサーバのタスクを定期的に集計し、Prometheusの形式で公開します。
+type taskExporter struct {
+	contexts []ServerContext
+
+	mu        sync.Mutex
+	states    map[string]*exporterState
+	collected bool
+}
+
+func newTaskExporter(contexts []ServerContext) *taskExporter {
+	e := &taskExporter{contexts: contexts, states: map[string]*exporterState{}}
+	for _, c := range contexts {
+		e.states[c.Name] = &exporterState{tasks: map[string]int{}, errors: map[string]int{}}
+	}
+	return e
+}
+
+// run はdoneが閉じられるまでintervalごとに集計します。最初の集計はすぐに行います。
+func (e *taskExporter) run(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		e.collect()
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// collect はすべてのコンテキストのタスクを集計します。
+func (e *taskExporter) collect() {
+	for _, c := range e.contexts {
+		e.collectContext(c)
+	}
+	e.mu.Lock()
+	e.collected = true
+	e.mu.Unlock()
+}
+
+// collectContext は実際のgetコマンドと同じservice.GetTasksでタスクを取得し、ステータスごとに数えます。
+func (e *taskExporter) collectContext(c ServerContext) {
+	start := time.Now()
+	var tasks []service.Task
+	protocol, host, port, err := c.Target()
+	if err == nil {
+		tasks, err = service.GetTasks(protocol, host, port, c.Token)
+	}
+	duration := time.Since(start)
+
+	var expiry time.Time
+	if claims, err := service.ParseTokenClaims(c.Token); err == nil {
+		expiry = claims.ExpiresAt()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	state := e.states[c.Name]
+	state.duration = duration
+	state.tokenExpiry = expiry
+	state.success = err == nil
+	if err != nil {
+		state.errors[service.KindOf(err).String()]++
+		logger.Warn(service.T("cmd.exporter.collect_failure"), "context", c.Name, "error", err)
+		return
+	}
+
+	state.lastSuccess = time.Now()
+	for status := range state.tasks {
+		state.tasks[status] = 0
+	}
+	for _, task := range tasks {
+		state.tasks[task.Status]++
+	}
+}
+
+// handler はメトリクス(/metrics)と状態の確認(/healthz)を提供するハンドラを返します。
+func (e *taskExporter) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		e.writeMetrics(w)
+	})
+	// 最初の集計が終わるまではメトリクスが揃っていないため、503を返します。
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		e.mu.Lock()
+		collected := e.collected
+		e.mu.Unlock()
+		if !collected {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, "collecting")
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// writeMetrics はPrometheusのテキスト形式でメトリクスを出力します。
+func (e *taskExporter) writeMetrics(w io.Writer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	names := make([]string, 0, len(e.contexts))
+	for _, c := range e.contexts {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+
+	writeMetricHeader(w, "todo_tasks", "gauge", "Number of tasks by status.")
+	for _, name := range names {
+		state := e.states[name]
+		for _, status := range sortedKeys(state.tasks) {
+			fmt.Fprintf(w, "todo_tasks{context=\"%s\",status=\"%s\"} %d\n", escapeLabel(name), escapeLabel(status), state.tasks[status])
+		}
+	}
+
+	writeMetricHeader(w, "todo_scrape_success", "gauge", "Whether the last collection from the ToDo server succeeded.")
+	for _, name := range names {
+		success := 0
+		if e.states[name].success {
+			success = 1
+		}
+		fmt.Fprintf(w, "todo_scrape_success{context=\"%s\"} %d\n", escapeLabel(name), success)
+	}
+
+	writeMetricHeader(w, "todo_scrape_duration_seconds", "gauge", "Duration of the last collection from the ToDo server.")
+	for _, name := range names {
+		fmt.Fprintf(w, "todo_scrape_duration_seconds{context=\"%s\"} %g\n", escapeLabel(name), e.states[name].duration.Seconds())
+	}
+
+	writeMetricHeader(w, "todo_last_success_timestamp_seconds", "gauge", "Unix time of the last successful collection.")
+	for _, name := range names {
+		if last := e.states[name].lastSuccess; !last.IsZero() {
+			fmt.Fprintf(w, "todo_last_success_timestamp_seconds{context=\"%s\"} %d\n", escapeLabel(name), last.Unix())
+		}
+	}
+
+	writeMetricHeader(w, "todo_api_errors_total", "counter", "Number of failed ToDo server API calls by error kind.")
+	for _, name := range names {
+		state := e.states[name]
+		for _, kind := range sortedKeys(state.errors) {
+			fmt.Fprintf(w, "todo_api_errors_total{context=\"%s\",kind=\"%s\"} %d\n", escapeLabel(name), kind, state.errors[kind])
+		}
+	}
+
+	writeMetricHeader(w, "todo_token_expiry_timestamp_seconds", "gauge", "Unix time when the authentication token expires.")
+	for _, name := range names {
+		if expiry := e.states[name].tokenExpiry; !expiry.IsZero() {
+			fmt.Fprintf(w, "todo_token_expiry_timestamp_seconds{context=\"%s\"} %d\n", escapeLabel(name), expiry.Unix())
+		}
+	}
+}
+
+func writeMetricHeader(w io.Writer, name string, metricType string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel はPrometheusのテキスト形式のラベルの値として使えるようエスケープします。
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
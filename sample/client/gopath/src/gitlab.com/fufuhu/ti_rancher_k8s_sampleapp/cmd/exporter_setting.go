@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// ExporterSetting はexporterの動作に関する設定を格納します。
+// コンテナで動かすことを想定し、コマンドラインオプション、環境変数、設定ファイルの順に値を探します。
+type ExporterSetting struct {
+	// Listen メトリクスを公開するアドレス
+	Listen func() (string, error)
+	// Interval タスクを集計する間隔
+	Interval func() (time.Duration, error)
+	// Contexts タスクを集計するToDoサーバと認証トークンの一覧
+	Contexts func() ([]ServerContext, error)
+}
+
+// SettingExporterIntervalInvalid は集計の間隔に正でない値が指定された場合のエラーメッセージです。
+const SettingExporterIntervalInvalid service.MessageID = "settings.exporter_interval_invalid"
+
+// SettingExporterContextInvalid はexporterのコンテキストの指定が不正な場合のエラーメッセージです。
+const SettingExporterContextInvalid service.MessageID = "settings.exporter_context_invalid"
+
+// exporterの設定を読み込む環境変数です。
+const (
+	EnvExporterListen   = "TODO_EXPORTER_LISTEN"
+	EnvExporterInterval = "TODO_EXPORTER_INTERVAL"
+	// EnvExporterContexts は"名前=URL"をカンマで区切って並べたコンテキストの一覧です。
+	EnvExporterContexts = "TODO_EXPORTER_CONTEXTS"
+	// EnvExporterToken はEnvExporterContextsで指定したコンテキストに共通する認証トークンです。
+	// コンテキストごとの認証トークンはTODO_EXPORTER_TOKEN_<名前を大文字にしたもの>で指定します。
+	EnvExporterToken = "TODO_EXPORTER_TOKEN"
+)
+
+var exporterSetting ExporterSetting
+
+// parseExporterContexts は環境変数で指定されたコンテキストの一覧を解釈します。
+func parseExporterContexts(value string, getenv func(string) string) ([]ServerContext, error) {
+	return parseContexts(value, EnvExporterToken, SettingExporterContextInvalid, getenv)
+}
+
+func init() {
+
+	// Listen コマンドラインオプション(--listen)、環境変数(TODO_EXPORTER_LISTEN)、
+	// 設定ファイル(exporter.listen)の順にメトリクスを公開するアドレスを読み込む。
+	// いずれも指定が無い場合は:9101を利用します。
+	exporterSetting.Listen = func() (string, error) {
+		if exporterCmd.Flags().Changed("listen") {
+			return exporterCmd.Flags().GetString("listen")
+		}
+		if listen := os.Getenv(EnvExporterListen); listen != "" {
+			return listen, nil
+		}
+		if listen := viper.GetString("exporter.listen"); listen != "" {
+			return listen, nil
+		}
+		return exporterCmd.Flags().GetString("listen")
+	}
+
+	// Interval コマンドラインオプション(--interval)、環境変数(TODO_EXPORTER_INTERVAL)、
+	// 設定ファイル(exporter.interval)の順にタスクを集計する間隔を読み込む。
+	// いずれも指定が無い場合は30秒ごとに集計します。
+	exporterSetting.Interval = func() (time.Duration, error) {
+		interval, err := exporterCmd.Flags().GetDuration("interval")
+		if err != nil {
+			return 0, err
+		}
+		if !exporterCmd.Flags().Changed("interval") {
+			if value := os.Getenv(EnvExporterInterval); value != "" {
+				if interval, err = time.ParseDuration(value); err != nil {
+					return 0, service.NewError(service.ErrorUsage, SettingExporterIntervalInvalid)
+				}
+			} else if viper.IsSet("exporter.interval") {
+				interval = viper.GetDuration("exporter.interval")
+			}
+		}
+		if interval <= 0 {
+			return 0, service.NewError(service.ErrorUsage, SettingExporterIntervalInvalid)
+		}
+		return interval, nil
+	}
+
+	// Contexts 環境変数(TODO_EXPORTER_CONTEXTS)、設定ファイル(exporter.contexts)の順に
+	// タスクを集計するコンテキストを読み込む。いずれも指定が無い場合は、
+	// 他のサブコマンドと同じ設定(--protocol, --host, --portと認証トークン)をdefaultという名前で利用します。
+	exporterSetting.Contexts = func() ([]ServerContext, error) {
+		return loadContexts(EnvExporterContexts, EnvExporterToken, "exporter.contexts", SettingExporterContextInvalid)
+	}
+}
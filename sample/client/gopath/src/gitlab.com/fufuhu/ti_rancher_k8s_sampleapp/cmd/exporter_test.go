@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseExporterContexts では環境変数で指定したコンテキストと、名前ごとの認証トークンを読み込めることを確認する。
+func TestParseExporterContexts(t *testing.T) {
+	env := map[string]string{
+		EnvExporterToken:              "common_token",
+		EnvExporterToken + "_PROD_EU": "prod_token",
+	}
+	getenv := func(name string) string { return env[name] }
+
+	contexts, err := parseExporterContexts("prod-eu=https://todo.example.com, staging=http://todo-server:8000", getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ServerContext{
+		{"prod-eu", "https://todo.example.com", "prod_token"},
+		{"staging", "http://todo-server:8000", "common_token"},
+	}
+	if len(contexts) != len(want) {
+		t.Fatalf("contexts = %v", contexts)
+	}
+	for i := range want {
+		if contexts[i] != want[i] {
+			t.Errorf("contexts[%d] = %v, want %v", i, contexts[i], want[i])
+		}
+	}
+
+	if _, err := parseExporterContexts("http://todo-server:8000", getenv); err == nil {
+		t.Error("context without name must be rejected")
+	}
+}
+
+// TestTaskExporter ではステータスごとのタスクの数とAPIの呼び出しの失敗がメトリクスとして公開されることを確認する。
+func TestTaskExporter(t *testing.T) {
+	body := `[{"id": 1, "title": "a", "status": "TODO"}, {"id": 2, "title": "b", "status": "TODO"}, {"id": 3, "title": "c", "status": "DONE"}]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "JWT valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	defer limitRequests(time.Second)()
+
+	expiry := time.Now().Add(time.Hour).Unix()
+	collector := newTaskExporter([]ServerContext{
+		{"prod", server.URL, "valid"},
+		{"expired", server.URL, testToken(expiry)},
+	})
+	handler := collector.handler()
+
+	get := func(path string) (int, string) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		return recorder.Code, recorder.Body.String()
+	}
+
+	if code, _ := get("/healthz"); code != http.StatusServiceUnavailable {
+		t.Errorf("healthz before collection = %d", code)
+	}
+
+	collector.collect()
+	body = `[{"id": 1, "title": "a", "status": "RUNNING"}]`
+	collector.collect()
+
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("healthz after collection = %d", code)
+	}
+
+	_, metrics := get("/metrics")
+	for _, line := range []string{
+		`todo_tasks{context="prod",status="DONE"} 0`,
+		`todo_tasks{context="prod",status="RUNNING"} 1`,
+		`todo_tasks{context="prod",status="TODO"} 0`,
+		`todo_scrape_success{context="prod"} 1`,
+		`todo_scrape_success{context="expired"} 0`,
+		`todo_api_errors_total{context="expired",kind="auth"} 2`,
+		`todo_token_expiry_timestamp_seconds{context="expired"} ` + strconv.FormatInt(expiry, 10),
+		"# TYPE todo_api_errors_total counter",
+	} {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("metrics do not contain %q:\n%s", line, metrics)
+		}
+	}
+	if strings.Contains(metrics, `todo_api_errors_total{context="prod"`) {
+		t.Errorf("unexpected errors for prod:\n%s", metrics)
+	}
+}
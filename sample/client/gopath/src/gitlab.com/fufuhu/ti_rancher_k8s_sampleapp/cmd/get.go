@@ -16,7 +16,7 @@ package cmd
 
 import (
 	"fmt"
-	"log"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
@@ -25,11 +25,9 @@ import (
 // getCmd represents the get command
 var getCmd = &cobra.Command{
 	Use:   "get",
-	Short: "ユーザに紐づくTODOタスクを取得します。",
-	Long: `ユーザに紐づくTODOタスクを取得します。
-		--id指定なしの場合は、当該ユーザに紐づく全ての
-		TODOタスクを取得します。`,
-	Run: get,
+	Short: "cmd.get.short",
+	Long:  "cmd.get.long",
+	RunE:  get,
 }
 
 func init() {
@@ -44,53 +42,71 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// getCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	getCmd.Flags().Int("id", 0, "タスクのID")
+	getCmd.Flags().Int("id", 0, "cmd.get.flag.id")
+	getCmd.Flags().Bool("fingerprint", false, "cmd.get.flag.fingerprint")
+	getCmd.Flags().StringSlice("tag", nil, "cmd.get.flag.tag")
+	getCmd.Flags().String("priority", "", "cmd.get.flag.priority")
+	getCmd.Flags().String("due-before", "", "cmd.get.flag.due-before")
 }
 
-func get(cmd *cobra.Command, args []string) {
+func get(cmd *cobra.Command, args []string) error {
 
 	protocol, err := clientSetting.Protocol()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	host, err := clientSetting.Host()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	port, err := clientSetting.Port()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	token, err := clientSetting.Token()
 	if err != nil {
-		log.Println("JWTトークンの設定が異常です。loginサブコマンドで再取得してください。")
-		log.Fatal(err)
+		return service.WrapError(service.ErrorAuth, SettingErrorMessageTokenInvalid, err)
 	}
 
 	// IDの値が取れなくても0が入るだけなのでerrorは無視する。
 	id, _ := taskRequestSetting.ID()
+	filter, err := taskRequestSetting.MetadataFilter()
+	if err != nil {
+		return err
+	}
 
+	var tasks []service.Task
 	if id != 0 {
-		tasks, err := service.GetTask(protocol, host, port, token, id)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		fmt.Printf("ID\tTitle\tStatus\tDescription\n")
-		for _, v := range tasks {
-			fmt.Printf("%d\t%s\t%s\t%s\n", v.ID, v.Title, v.Status, v.Description)
-		}
+		tasks, err = service.GetTask(protocol, host, port, token, id)
 	} else {
-		tasks, err := service.GetTasks(protocol, host, port, token)
-		if err != nil {
-			log.Fatal(err)
-		}
+		tasks, err = service.GetTasks(protocol, host, port, token)
+	}
+	if err != nil {
+		return err
+	}
 
-		fmt.Printf("ID\tTitle\tStatus\tDescription\n")
+	withFingerprint, _ := cmd.Flags().GetBool("fingerprint")
+	printTasks(service.FilterTasks(tasks, filter), withFingerprint)
+	return nil
+}
+
+// printTasks はタスクの一覧をタブ区切りで出力します。
+// 概要のメタデータは優先度、期限、タグの列に分けて出力し、概要の列には本文だけを出力します。
+// withFingerprintがtrueの場合はフィンガープリントの列を追加します。
+func printTasks(tasks []service.Task, withFingerprint bool) {
+	if withFingerprint {
+		fmt.Printf("ID\tTitle\tStatus\tPriority\tDue\tTags\tFingerprint\tDescription\n")
 		for _, v := range tasks {
-			fmt.Printf("%d\t%s\t%s\t%s\n", v.ID, v.Title, v.Status, v.Description)
+			d := service.ParseDescription(v.Description)
+			fmt.Printf("%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", v.ID, v.Title, v.Status, d.Metadata.Priority, d.Metadata.Due, strings.Join(d.Metadata.Tags, ","), service.TaskFingerprint(v), d.Body)
 		}
+		return
 	}
 
+	fmt.Printf("ID\tTitle\tStatus\tPriority\tDue\tTags\tDescription\n")
+	for _, v := range tasks {
+		d := service.ParseDescription(v.Description)
+		fmt.Printf("%d\t%s\t%s\t%s\t%s\t%s\t%s\n", v.ID, v.Title, v.Status, d.Metadata.Priority, d.Metadata.Due, strings.Join(d.Metadata.Tags, ","), d.Body)
+	}
 }
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// JournalUnknownUser は認証トークンからユーザ名を読み取れなかった場合に
+// ジャーナルのファイル名として利用するユーザ名です。
+const JournalUnknownUser = "_unknown"
+
+// openJournal はアクセス先のToDoサーバと認証トークンのユーザに対応する操作ジャーナルを返します。
+// 複数のエンドポイントを利用する場合は、実際にリクエストを処理したエンドポイントのジャーナルを返します。
+func openJournal(protocol string, host string, port int, token string) (*service.Journal, error) {
+	protocol, host, port, token = activeTarget(protocol, host, port, token)
+
+	dir, err := clientSetting.JournalDir()
+	if err != nil {
+		return nil, err
+	}
+
+	username := JournalUnknownUser
+	if claims, err := service.ParseTokenClaims(token); err == nil && claims.Username != "" {
+		username = claims.Username
+	}
+
+	return service.NewJournal(service.JournalPath(dir, protocol, host, port, username)), nil
+}
+
+// recordJournal は操作をジャーナルに記録します。
+// 記録に失敗しても操作自体は完了しているため、警告を出力するだけに留めます。
+func recordJournal(protocol string, host string, port int, token string, entry service.JournalEntry) {
+	journal, err := openJournal(protocol, host, port, token)
+	if err == nil {
+		_, err = journal.Append(entry)
+	}
+	if err != nil {
+		logger.Warn(service.T("cmd.journal.record_failure"), "error", err)
+	}
+}
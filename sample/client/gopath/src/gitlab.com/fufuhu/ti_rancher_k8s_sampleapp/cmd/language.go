@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// helpMessageIDs はコマンドとフラグのヘルプに指定したメッセージのIDを保持します。
+// 言語を切り替えて翻訳しなおせるよう、最初に翻訳する前の値を記録しておきます。
+var helpMessageIDs = map[interface{}][]service.MessageID{}
+
+// localizeCommands はコマンドとフラグのヘルプを現在の言語に翻訳します。
+// ヘルプにはメッセージのIDを指定しておき、コマンドの実行前に呼び出します。
+func localizeCommands(c *cobra.Command) {
+	c.InitDefaultHelpFlag()
+
+	ids, ok := helpMessageIDs[c]
+	if !ok {
+		ids = []service.MessageID{service.MessageID(c.Short), service.MessageID(c.Long)}
+		helpMessageIDs[c] = ids
+	}
+	c.Short = service.T(ids[0])
+	c.Long = service.T(ids[1])
+
+	localizeFlags := func(flag *pflag.Flag) {
+		ids, ok := helpMessageIDs[flag]
+		if !ok {
+			ids = []service.MessageID{service.MessageID(flag.Usage)}
+			helpMessageIDs[flag] = ids
+		}
+		if flag.Name == "help" {
+			flag.Usage = service.T("cmd.help_flag", c.Name())
+			return
+		}
+		flag.Usage = service.T(ids[0])
+	}
+	c.LocalFlags().VisitAll(localizeFlags)
+	c.PersistentFlags().VisitAll(localizeFlags)
+
+	for _, sub := range c.Commands() {
+		localizeCommands(sub)
+	}
+}
+
+// flagFromArgs はCobraがコマンドライン引数を解釈する前に、引数から--<name>の値を取り出します。指定が無い場合は空文字列を返します。
+func flagFromArgs(args []string, name string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--"+name && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(arg, "--"+name+"=") {
+			return strings.TrimPrefix(arg, "--"+name+"=")
+		}
+	}
+	return ""
+}
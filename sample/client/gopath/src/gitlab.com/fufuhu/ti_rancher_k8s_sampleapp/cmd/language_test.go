@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"testing"
+
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// TestFlagFromArgs はコマンドライン引数から--langの値を取り出せることを確認する。
+func TestFlagFromArgs(t *testing.T) {
+	cases := []struct {
+		args []string
+		lang string
+	}{
+		{[]string{"get", "--lang", "en"}, "en"},
+		{[]string{"--lang=ja", "get"}, "ja"},
+		{[]string{"get"}, ""},
+		{[]string{"get", "--lang"}, ""},
+		{[]string{"create", "--", "--lang", "en"}, ""},
+	}
+	for _, c := range cases {
+		if lang := flagFromArgs(c.args, "lang"); lang != c.lang {
+			t.Errorf("flagFromArgs(%v, lang) = %q, want %q", c.args, lang, c.lang)
+		}
+	}
+}
+
+// TestLocalizeCommands はヘルプが現在の言語に翻訳され、言語を切り替えると翻訳しなおされることを確認する。
+func TestLocalizeCommands(t *testing.T) {
+	previous := service.Language()
+	defer func() {
+		service.SetLanguage(previous)
+		localizeCommands(rootCmd)
+	}()
+
+	for _, lang := range []string{service.LanguageEnglish, service.LanguageJapanese} {
+		service.SetLanguage(lang)
+		localizeCommands(rootCmd)
+		if getCmd.Short != service.T("cmd.get.short") || getCmd.Short == "cmd.get.short" {
+			t.Errorf("%s: %q", lang, getCmd.Short)
+		}
+		if usage := getCmd.Flags().Lookup("id").Usage; usage != service.T("cmd.get.flag.id") {
+			t.Errorf("%s: %q", lang, usage)
+		}
+		if usage := rootCmd.PersistentFlags().Lookup("host").Usage; usage != service.T("flag.host") {
+			t.Errorf("%s: %q", lang, usage)
+		}
+	}
+}
+
+// TestCommandMessagesTranslated はコマンドのメッセージとヘルプに英語の翻訳が揃っていることを確認する。
+func TestCommandMessagesTranslated(t *testing.T) {
+	previous := service.Language()
+	defer service.SetLanguage(previous)
+
+	ids := []service.MessageID{SettingErrorMessageTokenInvalid, DryRunMessage, UsageInvalid, "flag.lang"}
+	for _, name := range []string{"root", "alias", "alias.set", "alias.list", "alias.delete", "bench", "bulk", "config", "config.view", "config.set", "config.unset", "config.explain", "config.migrate", "create", "delete", "doctor", "exporter", "get", "log", "login", "ping", "plugin", "plugin.list", "remind", "remind.daemon", "smoketest", "template", "template.list", "template.show", "undo", "update"} {
+		ids = append(ids, service.MessageID("cmd."+name+".short"), service.MessageID("cmd."+name+".long"))
+	}
+
+	for _, id := range ids {
+		service.SetLanguage(service.LanguageJapanese)
+		ja := service.T(id)
+		service.SetLanguage(service.LanguageEnglish)
+		en := service.T(id)
+		if ja == string(id) || en == ja {
+			t.Errorf("%s: ja=%q en=%q", id, ja, en)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// logCmd represents the log command
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "cmd.log.short",
+	Long:  "cmd.log.long",
+	RunE:  journalLog,
+}
+
+func init() {
+	rootCmd.AddCommand(logCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// logCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// logCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	logCmd.Flags().IntP("count", "n", 0, "cmd.log.flag.count")
+}
+
+func journalLog(cmd *cobra.Command, args []string) error {
+	protocol, err := clientSetting.Protocol()
+	if err != nil {
+		return err
+	}
+	host, err := clientSetting.Host()
+	if err != nil {
+		return err
+	}
+	port, err := clientSetting.Port()
+	if err != nil {
+		return err
+	}
+
+	token, err := clientSetting.Token()
+	if err != nil {
+		return service.WrapError(service.ErrorAuth, SettingErrorMessageTokenInvalid, err)
+	}
+
+	journal, err := openJournal(protocol, host, port, token)
+	if err != nil {
+		return err
+	}
+
+	entries, err := journal.Entries()
+	if err != nil {
+		return err
+	}
+
+	undone := map[int]bool{}
+	for _, entry := range entries {
+		if entry.Operation == service.JournalUndo {
+			undone[entry.Undoes] = true
+		}
+	}
+
+	count, _ := cmd.Flags().GetInt("count")
+	if count > 0 && len(entries) > count {
+		entries = entries[len(entries)-count:]
+	}
+
+	fmt.Printf("Seq\tTime\tOperation\tID\tTitle\tStatus\tUndone\n")
+	for _, entry := range entries {
+		task := entry.Current
+		if task == nil {
+			task = entry.Previous
+		}
+		if task == nil {
+			task = &service.Task{}
+		}
+
+		operation := entry.Operation
+		if entry.Operation == service.JournalUndo {
+			operation = fmt.Sprintf("%s(%d)", entry.Operation, entry.Undoes)
+		}
+
+		mark := ""
+		if undone[entry.Seq] {
+			mark = "*"
+		}
+		fmt.Printf("%d\t%s\t%s\t%d\t%s\t%s\t%s\n", entry.Seq, entry.Time.Format("2006-01-02 15:04:05"),
+			operation, entry.TaskID, task.Title, task.Status, mark)
+	}
+	return nil
+}
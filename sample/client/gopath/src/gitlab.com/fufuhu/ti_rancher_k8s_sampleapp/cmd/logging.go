@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"os"
+
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// logger はコマンドの経過やエラーを標準エラー出力に出力するLoggerです。
+// --log-level, --log-format, --quietの指定に従ってapplyServiceSettingsで設定しなおします。
+var logger service.Logger = newDefaultLogger()
+
+func newDefaultLogger() service.Logger {
+	l, _ := service.NewStreamLogger(os.Stderr, service.LogLevelInfo, service.LogFormatText)
+	return l
+}
+
+// fatal はエラーを出力し、エラーの原因の種類に応じた終了コードでコマンドを終了します。
+// サブコマンドの実行前など、Executeにエラーを返せない場合に利用します。
+func fatal(err error) {
+	logger.Error(err.Error())
+	os.Exit(exitCode(err))
+}
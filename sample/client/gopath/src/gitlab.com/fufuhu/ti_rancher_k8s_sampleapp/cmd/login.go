@@ -15,7 +15,7 @@
 package cmd
 
 import (
-	"log"
+	"fmt"
 
 	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
 
@@ -25,9 +25,9 @@ import (
 // loginCmd represents the login command
 var loginCmd = &cobra.Command{
 	Use:   "login",
-	Short: "Todo Serverにログインし、JWTトークンを取得します。",
-	Long:  `Todo Serverにログインし、JWTトークンを取得します。`,
-	Run:   login,
+	Short: "cmd.login.short",
+	Long:  "cmd.login.long",
+	RunE:  login,
 	//Run: func(cmd *cobra.Command, args []string) {
 	//	fmt.Println("login called")
 	//},
@@ -45,51 +45,63 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// loginCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	loginCmd.Flags().String("username", "", "Todoサーバにログインするためのユーザ名")
-	loginCmd.Flags().String("password", "", "Todoサーバにログインするためのパスワード")
+	loginCmd.Flags().String("username", "", "cmd.login.flag.username")
+	loginCmd.Flags().String("password", "", "cmd.login.flag.password")
 }
 
-func login(cmd *cobra.Command, args []string) {
+func login(cmd *cobra.Command, args []string) error {
 
 	protocol, err := clientSetting.Protocol()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	host, err := clientSetting.Host()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	port, err := clientSetting.Port()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	username, err := clientSetting.Username()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	password, err := clientSetting.Password()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	//loginMessage := service.RequestPing(protocol, host, port)
-	loginConfig, err := service.Login(protocol, host, port, username, password)
+	var loginConfig service.LoginConfig
+	if service.Endpoints != nil {
+		// 複数のエンドポイントを利用する場合は、それぞれのエンドポイントで認証トークンを取得します。
+		loginConfig = service.LoginConfig{Protocol: protocol, Host: host, Port: port}
+		loginConfig.Endpoints, loginConfig.Token, err = loginEndpoints(username, password)
+	} else {
+		loginConfig, err = service.Login(protocol, host, port, username, password)
+	}
+	if err == service.ErrDryRun {
+		// ドライラン時はトークンを取得していないため設定ファイルも更新しません。
+		logger.Info(service.T(DryRunMessage))
+		return nil
+	}
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	if loginConfig.Filepath, err = rootCmd.PersistentFlags().GetString("config"); err != nil {
-		log.Fatal(err)
+		return err
 	}
 	config, err := service.CreateConfigFile(loginConfig)
 
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	log.Println("認証トークンを取得しました。")
-	log.Println(config.Token)
-
+	logger.Info(service.T("cmd.login.done"))
+	fmt.Println(config.Token)
+	return nil
 }
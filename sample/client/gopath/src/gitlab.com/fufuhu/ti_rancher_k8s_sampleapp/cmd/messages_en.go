@@ -0,0 +1,576 @@
+package cmd
+
+import (
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+func init() {
+	// コマンドの英語のメッセージとヘルプです。
+	service.RegisterMessages(service.LanguageEnglish, map[service.MessageID]string{
+		SettingErrorMessageUsernameNotFound: "No username is specified.",
+		SettingErrorMessagePasswordNotFound: "No password is specified.",
+		SettingErrorMessageTokenNotFound:    "No authentication token was found.",
+		SettingErrorMessageTokenInvalid:     "The JWT token setting is invalid. Get a new token with the login subcommand.",
+		SettingRetryMaxInvalid:              "The maximum number of retries (--retry-max) must be 0 or greater.",
+		SettingServerInvalid:                "The value %[2]q of the environment variable %[1]s is not a ToDo server URL (e.g. https://todo.example.com:8443)",
+		SettingContextNotFound:              "The context %s is not in endpoints in the config file",
+		SettingEnvInvalid:                   "The value %[2]q of the environment variable %[1]s is invalid",
+		SettingFailureThresholdInvalid:      "The number of failures before opening the circuit breaker (failover.failure_threshold) must be 1 or greater.",
+		DryRunMessage:                       "The request above was not sent because of dry run.",
+		SettingTaskTitleNotFound:            "No task title is specified",
+		SettingTaskDescriptionNotFound:      "No task description is specified",
+		SettingTaskConflictPolicyInvalid:    "The behavior on conflict (--on-conflict) must be retry or abort",
+		"cmd.bulk.short":                    "Create, update and delete TODO tasks in bulk, in parallel.",
+		"cmd.bulk.long": `Reads a list of operations written in YAML (or JSON) and runs them in parallel.
+Write the list of operations as follows.
+
+- operation: create
+  title: title of the task
+  description: description of the task
+- operation: update
+  id: 1
+  status: RUNNING
+- operation: delete
+  id: 2
+
+Results are written to standard output in the order of the operations, and progress to standard error.`,
+		"cmd.create.short": "Create a TODO task.",
+		"cmd.create.long": `Create a TODO task.
+With --template, creates all the tasks written in the template.
+Give the parameters of the template as --set service=api. See template for details.
+
+The description can be read from a file (or standard input for -) with --description-file.
+With --title-from-first-line, the first line of the description becomes the title and the rest the description.
+--from-stdin is the same as --description-file -, and also implies --title-from-first-line unless --title is given.
+  git log -1 | todo create --from-stdin
+The description is limited to 64KiB. Bytes that are not valid UTF-8 are replaced with U+FFFD before sending.
+
+Tags (--tag), priority (--priority) and due date (--due) are written at the top of the description as front matter:
+---
+todo:
+  tags: [ops, api]
+  priority: high
+  due: "2019-04-01"
+---
+They are stored on the ToDo server as part of the description, so other clients can still read them.`,
+		"cmd.delete.short": "Delete the user's TODO task.",
+		"cmd.delete.long": `Delete the user's TODO task.
+Returns an error when --id is not specified.`,
+		"cmd.get.short": "Get the user's TODO tasks.",
+		"cmd.get.long": `Get the user's TODO tasks.
+When --id is not specified, gets all TODO tasks
+of the user.
+Metadata in the description (tags, priority, due date) is shown in separate columns,
+and tasks can be filtered with --tag, --priority and --due-before.`,
+		"cmd.log.short": "Show the task operations recorded in the operation journal.",
+		"cmd.log.long": `Show the task operations recorded in the operation journal, oldest first.
+A journal is kept for each pair of ToDo server and user.`,
+		"cmd.login.short": "Log in to the Todo Server and get a JWT token.",
+		"cmd.login.long":  "Log in to the Todo Server and get a JWT token.",
+		"cmd.ping.short":  "Check that the ToDo server is up using the ping-pong API",
+		"cmd.ping.long": `Checks connectivity to the ToDo server by accessing
+its ping-pong API.
+Like ping(8), shows the response time of each call and min/avg/max/stddev statistics.
+
+To be usable as a Kubernetes exec probe or for waiting during a Helm upgrade,
+exits with 0 if at least one response was received, and otherwise with the code
+for the cause (6: unreachable, 7: ToDo server error).
+
+Examples:
+  todo ping -c 5 -i 500ms
+  todo ping --until-ready --timeout 2s`,
+		"cmd.undo.short": "Undo the last task operations.",
+		"cmd.undo.long": `Undoes the operations recorded in the operation journal, newest first.
+Created tasks are deleted, deleted tasks are created again with their status restored,
+and updated tasks are reverted to their previous content.`,
+		"cmd.update.short": "Update the user's TODO task.",
+		"cmd.update.long": `Update the user's TODO task.
+Returns an error when --id is not specified.
+--tag, --priority and --due change the metadata in the description. The metadata is kept when --description is given.`,
+		"cmd.root.short": "Client application for the ToDo application",
+		"cmd.root.long": `A sample client application for the multi-tenant ToDo application.
+The CLI is implemented with Cobra.
+
+When running in a Kubernetes pod and no server is given by options or the config file,
+the client looks for the ToDo server in the environment variables that the Service sets on the pod
+(SERVICE_TODOSERVER_<release name>_SERVICE_HOST/_PORT). Set the Service with TODO_DISCOVERY_SERVICE or discovery.service.
+The url, username, password and token files in a directory where a ConfigMap or Secret is mounted
+(/etc/todo, changed with TODO_DISCOVERY_DIR or discovery.dir) take precedence over the environment variables.
+
+Settings are read in this order of precedence:
+  1. command line options (--host, --retry-max, ...)
+  2. environment variables (TODO_SERVER, TODO_HOST, TODO_RETRY_MAX, ...)
+  3. the config file (--config, otherwise TODO_CONFIG or $XDG_CONFIG_HOME/todo/config.yaml,
+     falling back to $HOME/.todo_config.yaml if only that exists)
+  4. a server discovered in the Kubernetes environment
+  5. default values
+An environment variable is named after the config key in upper case, with "." replaced by "_" and TODO_ prepended
+(TODO_RETRY_MAX for retry.max). Variables without the TODO_ prefix, such as HOST or PORT set in containers, are ignored.
+TODO_SERVER gives protocol, host and port at once as a URL such as https://todo.example.com:8443, and
+TODO_CONTEXT (context in the config file) selects an endpoint in endpoints by name.
+TODO_TOKEN, TODO_LANG, TODO_DRY_RUN, TODO_VERBOSITY and TODO_TRACE_FILE are also available.
+Use config explain to see each value and where it comes from.
+
+Exit codes:
+  0  success
+  1  unclassified error
+  2  invalid command line option or configuration file
+  3  authentication required (get a new token with the login subcommand)
+  4  the task was not found
+  5  input rejected by the ToDo server
+  6  cannot connect to the ToDo server
+  7  ToDo server error or unexpected response
+  8  the update was aborted because another change was detected`,
+		"flag.protocol":                         "protocol used to access the ToDo server",
+		"flag.host":                             "host name/IP address of the ToDo server",
+		"flag.port":                             "port number of the ToDo server",
+		"flag.log-level":                        "log level (debug/info/warn/error)",
+		"flag.log-format":                       "log format (text/json)",
+		"flag.quiet":                            "do not output logs other than errors",
+		"flag.verbosity":                        "verbosity of the output (1: endpoint, 6: requests and timing, 7: request headers, 8: response headers and bodies, 9: full bodies)",
+		"flag.trace-file":                       "path of a file to record the HTTP traffic in HAR format (credentials are redacted)",
+		"flag.dry-run":                          "only show the requests that create/update/delete/login would send, without sending them",
+		"flag.retry-max":                        "maximum number of retries on transient failures (connection errors, 429/502/503/504)",
+		"flag.retry-base-delay":                 "delay before the first retry (grows exponentially afterwards)",
+		"flag.retry-max-delay":                  "maximum delay between retries",
+		"flag.retry-post":                       "also retry POST requests such as task creation (tasks may be created twice)",
+		"cmd.bulk.flag.file":                    "path of the file listing the operations (- for standard input)",
+		"cmd.bulk.flag.parallel":                "number of operations to run at the same time",
+		"cmd.bulk.flag.rate":                    "maximum number of operations started per second (0 for no limit)",
+		"cmd.create.flag.title":                 "title of the task",
+		"cmd.create.flag.description":           "description of the task",
+		"cmd.create.flag.template":              "name of the template to create tasks from",
+		"cmd.create.flag.description-file":      "file to read the description of the task from (- for standard input)",
+		"cmd.create.flag.title-from-first-line": "use the first line of the description as the title",
+		"cmd.create.flag.from-stdin":            "read the title and description from standard input (same as --description-file - --title-from-first-line)",
+		"cmd.create.description_sanitized":      "Replaced bytes that are not valid UTF-8 in the description with U+FFFD",
+		"cmd.delete.flag.id":                    "ID of the task to delete",
+		"cmd.get.flag.id":                       "ID of the task",
+		"cmd.get.flag.fingerprint":              "show the fingerprint used by update --if-match",
+		"cmd.log.flag.count":                    "number of operations to show (0 for all)",
+		"cmd.login.flag.username":               "username to log in to the Todo server",
+		"cmd.login.flag.password":               "password to log in to the Todo server",
+		"cmd.undo.flag.count":                   "number of operations to undo",
+		"cmd.update.flag.id":                    "ID of the task to update",
+		"cmd.update.flag.title":                 "new title of the task",
+		"cmd.update.flag.description":           "new description of the task",
+		"cmd.update.flag.status":                "new status of the task",
+		"cmd.update.flag.if-match":              "fingerprint of the task before the update (see get --fingerprint). The task is not updated if it does not match",
+		"cmd.update.flag.expect-status":         "status of the task before the update. The task is not updated if it does not match",
+		"cmd.update.flag.on-conflict":           "behavior when another change is detected after fetching the task (retry/abort)",
+		"cmd.update.flag.max-retries":           "maximum number of retries with --on-conflict=retry",
+		"cmd.delete.done":                       "Deleted the task.",
+		"cmd.endpoint.pinned":                   "Pinning the endpoint.",
+		"cmd.endpoint.login_failure":            "Failed to log in to the endpoint.",
+		"cmd.endpoint.login_done":               "Got an authentication token for the endpoint.",
+		"cmd.journal.record_failure":            "Failed to record the operation in the journal. This operation cannot be undone.",
+		"cmd.login.done":                        "Got an authentication token.",
+		"cmd.settings.state_load_failure":       "Failed to load the circuit breaker state.",
+		"cmd.undo.nothing":                      "There are no operations to undo.",
+		"cmd.undo.nothing_to_send":              "Undoing Seq %d (%s, ID=%d) sends no request.",
+		"cmd.undo.record_failure":               "Failed to record the operation in the journal.",
+		"cmd.update.done":                       "Updated the task.",
+		"cmd.bulk.summary":                      "succeeded: %d failed: %d",
+		"cmd.bulk.summary_skipped":              " skipped: %d",
+		"cmd.undo.done":                         "Undid Seq %d (%s, ID=%d).",
+		"cmd.undo.restored":                     "The task was restored as ID=%d.",
+		BulkFileRequired:                        "No file listing the operations (--file) is specified",
+		BulkFileReadFailure:                     "Failed to read the list of operations",
+		BulkOperationInvalid:                    "Operation #%d is invalid",
+		BulkOperationsFailed:                    "%d operations failed",
+		CreateTitleInvalid:                      "The task title (--title) is invalid.",
+		CreateDescriptionInvalid:                "The task description (--description) is invalid.",
+		CreateTemplateConflict:                  "--template cannot be used with --title/--description.",
+		CreateInputConflict:                     "%s cannot be used with %s.",
+		CreateDescriptionTooLarge:               "The task description is too large. Keep it within %d bytes.",
+		CreateTemplatePartial:                   "Failed to create a task after creating %d of the %d tasks in the template",
+		DeleteIDRequired:                        "The ID of the task to delete is not specified correctly (--id)",
+		UpdateIDRequired:                        "The ID of the task to update is not specified correctly (--id)",
+		UndoCountInvalid:                        "The number of operations to undo (--count) must be 1 or greater",
+		UndoFailure:                             "Failed to undo the operation (Seq %d, %s, ID=%d)",
+		UsageInvalid:                            "%s (see --help for usage)",
+		"cmd.doctor.short":                      "Diagnose the connection to the ToDo server and the authentication token",
+		"cmd.doctor.long": `Checks, in order, the resolved settings, name resolution, the TCP connection, TLS,
+the ping-pong API, the authentication token and the clock skew against the ToDo server,
+and shows a result (PASS/WARN/FAIL/SKIP) and a remediation hint for each check.
+It uses the same settings and options as the other subcommands, so it helps find out why they cannot connect.
+When multiple endpoints are configured, each endpoint is checked.
+
+If any check fails, exits with the code for the cause of the first failed check.`,
+		"cmd.doctor.flag.json":                "Output the diagnosis as JSON",
+		"cmd.doctor.flag.timeout":             "Time to wait for name resolution, connections and responses",
+		"cmd.doctor.check.config":             "config",
+		"cmd.doctor.check.dns":                "DNS",
+		"cmd.doctor.check.tcp":                "TCP",
+		"cmd.doctor.check.tls":                "TLS",
+		"cmd.doctor.check.ping":               "ping",
+		"cmd.doctor.check.token":              "token",
+		"cmd.doctor.check.token_expiry":       "token expiry",
+		"cmd.doctor.check.clock_skew":         "clock skew",
+		"cmd.doctor.hint":                     "hint: %s",
+		"cmd.doctor.summary":                  "Passed: %d Warnings: %d Failed: %d Skipped: %d",
+		"cmd.doctor.skip_unreachable":         "Not checked because the ToDo server is unreachable",
+		"cmd.doctor.config.pass":              "%s (config file: %s)",
+		"cmd.doctor.config.pass_without_file": "%s (no config file)",
+		"cmd.doctor.config.fail":              "Cannot load the settings: %v",
+		"cmd.doctor.config.endpoint_invalid":  "The URL of endpoint %s is invalid",
+		"cmd.doctor.config.hint":              "Check the config file (--config) and the options (--protocol, --host, --port)",
+		"cmd.doctor.config.protocol_invalid":  "Protocol %s is not supported",
+		"cmd.doctor.config.protocol_hint":     "Specify http or https as the protocol",
+		"cmd.doctor.dns.pass":                 "%s -> %s",
+		"cmd.doctor.dns.ip_address":           "%s is an IP address, so no name resolution is needed",
+		"cmd.doctor.dns.fail":                 "Cannot resolve %s: %v",
+		"cmd.doctor.dns.hint":                 "Check the spelling of the host name and the DNS settings. On Kubernetes, also check the Service name and Namespace",
+		"cmd.doctor.tcp.pass":                 "Connected to %s (%s)",
+		"cmd.doctor.tcp.fail":                 "Cannot connect to %s: %v",
+		"cmd.doctor.tcp.hint":                 "Check that the ToDo server is running, and check the port number and firewall settings",
+		"cmd.doctor.tls.not_https":            "Not checked because the connection uses http",
+		"cmd.doctor.tls.pass":                 "Server certificate expires at %s",
+		"cmd.doctor.tls.expiring":             "The server certificate expires soon: %s",
+		"cmd.doctor.tls.expiring_hint":        "Renew the server certificate",
+		"cmd.doctor.tls.fail":                 "TLS connection failed: %v",
+		"cmd.doctor.tls.hint":                 "Check the host name, expiry and issuing CA of the server certificate. Specify --protocol http if TLS is not used",
+		"cmd.doctor.ping.pass":                "Response: %s (%s)",
+		"cmd.doctor.ping.fail":                "Calling /api/ping failed: %v",
+		"cmd.doctor.ping.hint":                "Check the ToDo server logs. When going through a proxy or Ingress, also check that /api/ping is forwarded",
+		"cmd.doctor.token.pass":               "An authentication token is configured",
+		"cmd.doctor.token.fail":               "No authentication token is configured",
+		"cmd.doctor.token.hint":               "Get an authentication token with the login subcommand",
+		"cmd.doctor.token_expiry.no_token":    "Not checked because there is no authentication token",
+		"cmd.doctor.token_expiry.none":        "The authentication token does not expire",
+		"cmd.doctor.token_expiry.pass":        "Expires at %s",
+		"cmd.doctor.token_expiry.warn":        "Expires soon: %s",
+		"cmd.doctor.token_expiry.fail":        "Expired at %s",
+		"cmd.doctor.token_expiry.malformed":   "Cannot read the authentication token: %v",
+		"cmd.doctor.token_expiry.hint":        "Get a new authentication token with the login subcommand",
+		"cmd.doctor.clock_skew.pass":          "Clock skew against the ToDo server: %s",
+		"cmd.doctor.clock_skew.warn":          "The clock skew against the ToDo server is large: %s",
+		"cmd.doctor.clock_skew.fail":          "The clock is far off from the ToDo server: %s",
+		"cmd.doctor.clock_skew.hint":          "Synchronize the clock, for example with NTP. A skewed clock makes token expiry checks unreliable",
+		"cmd.doctor.clock_skew.no_date":       "Not checked because the ToDo server response has no Date header",
+		DoctorChecksFailed:                    "%d checks failed",
+		"cmd.ping.flag.count":                 "Number of calls (0 repeats until interrupted)",
+		"cmd.ping.flag.interval":              "Interval between calls",
+		"cmd.ping.flag.timeout":               "Time to wait for each response (0 means no limit)",
+		"cmd.ping.flag.until-ready":           "Repeat until a response is received and exit then (no limit on the number of calls unless --count is given)",
+		"cmd.ping.flag.json":                  "Output the result as JSON",
+		"cmd.ping.header":                     "PING %s",
+		"cmd.ping.reply":                      "seq=%d %s time=%s",
+		"cmd.ping.reply_failure":              "seq=%d failed: %s",
+		"cmd.ping.statistics":                 "--- %s ping statistics ---",
+		"cmd.ping.summary":                    "%d transmitted, %d received, %.1f%% loss",
+		PingCountInvalid:                      "The number of calls (--count) must be 0 or greater",
+		PingIntervalInvalid:                   "The interval (--interval) and the timeout (--timeout) must be 0 or greater",
+		PingNoReply:                           "No response from the ToDo server",
+		"cmd.exporter.short":                  "Expose task statistics as Prometheus metrics",
+		"cmd.exporter.long": `Periodically gets the tasks from the ToDo server and exposes the number of tasks by status
+and other metrics at /metrics in the Prometheus format. /healthz returns 200 once the first collection is done.
+On SIGTERM, waits for the running collection and requests to finish before exiting.
+
+Metrics:
+  todo_tasks{context,status}                      Number of tasks by status
+  todo_scrape_success{context}                    Whether the last collection succeeded
+  todo_scrape_duration_seconds{context}           Duration of the last collection
+  todo_last_success_timestamp_seconds{context}    Time of the last successful collection
+  todo_api_errors_total{context,kind}             Number of failed API calls by error kind
+  todo_token_expiry_timestamp_seconds{context}    Expiry time of the authentication token
+
+Settings are read from options, environment variables and the config file, in this order.
+  --listen    TODO_EXPORTER_LISTEN    exporter.listen
+  --interval  TODO_EXPORTER_INTERVAL  exporter.interval
+              TODO_EXPORTER_CONTEXTS  exporter.contexts
+
+TODO_EXPORTER_CONTEXTS is a comma-separated list of "name=URL" (for example prod=http://todo-server:8000).
+Specify the authentication token with TODO_EXPORTER_TOKEN_<upper-case name> or TODO_EXPORTER_TOKEN.
+In the config file, specify them as follows.
+
+exporter:
+  contexts:
+    - name: prod
+      url: http://todo-server:8000
+      token: <authentication token>
+
+Without any context, the same server and authentication token as the other subcommands are used under the name default.`,
+		"cmd.exporter.flag.listen":     "Address to expose the metrics on",
+		"cmd.exporter.flag.interval":   "Interval between collections",
+		"cmd.exporter.started":         "Started exposing metrics.",
+		"cmd.exporter.stopping":        "Received a stop signal. Exiting after the running collection and requests finish.",
+		"cmd.exporter.collect_failure": "Collecting tasks failed.",
+		ExporterListenFailure:          "Cannot listen on %s",
+		SettingExporterIntervalInvalid: "The collection interval (--interval, TODO_EXPORTER_INTERVAL, exporter.interval) must be a positive duration",
+		SettingExporterContextInvalid:  "The exporter context %q is invalid (the name is empty or duplicated, or the URL is invalid)",
+		"cmd.remind.short":             "Notify about tasks that are due soon or overdue",
+		"cmd.remind.long":              `Notifies about tasks based on the due date in their description (--due of create/update).`,
+		"cmd.remind.daemon.short":      "Start a daemon that periodically checks due dates and sends reminders",
+		"cmd.remind.daemon.long": `Periodically gets the tasks of each context from the ToDo server and, among the tasks that are not
+finished (FINISHED), sends reminders for the tasks that are due soon (within --before) and the overdue tasks.
+
+Reminders can be sent to any combination of the following sinks (--sink).
+  stdout   Print one line per reminder to the standard output
+  notify   Run the desktop notification command (--notify-command, notify-send by default) with the reminder
+           as the last argument. The reminder is also passed in the environment variables TODO_REMINDER_CONTEXT,
+           TODO_REMINDER_KIND, TODO_REMINDER_TASK_ID, TODO_REMINDER_TITLE and TODO_REMINDER_DUE
+  webhook  POST the reminder as JSON to the webhook URL (--webhook-url)
+
+Sent reminders are recorded in a file (remind.state_file, $HOME/.todo/remind.json by default),
+so the same reminder is not sent again after restarting the daemon. A task whose due date changed is reminded again.
+Sinks that failed are retried at the next check.
+With --once, checks once and exits (for running from cron and the like).
+
+Settings are read from options, environment variables and the config file, in this order.
+  --interval        TODO_REMIND_INTERVAL        remind.interval
+  --before          TODO_REMIND_BEFORE          remind.before
+  --sink                                        remind.sinks
+  --notify-command  TODO_REMIND_NOTIFY_COMMAND  remind.notify_command
+  --webhook-url     TODO_REMIND_WEBHOOK_URL     remind.webhook_url
+                    TODO_REMIND_STATE_FILE      remind.state_file
+                    TODO_REMIND_CONTEXTS        remind.contexts
+
+TODO_REMIND_CONTEXTS is a comma-separated list of contexts in the form "name=URL",
+and the authentication token is given with TODO_REMIND_TOKEN_<upper-case name> or TODO_REMIND_TOKEN.
+Without any context, the same server and authentication token as the other subcommands are used under the name default.`,
+		"cmd.remind.daemon.flag.interval":       "Interval between checks of the due dates",
+		"cmd.remind.daemon.flag.before":         "How long before the due date to send a reminder (0 sends reminders for overdue tasks only)",
+		"cmd.remind.daemon.flag.sink":           "Where to send reminders (stdout/notify/webhook)",
+		"cmd.remind.daemon.flag.notify-command": "Command used for desktop notifications",
+		"cmd.remind.daemon.flag.webhook-url":    "Webhook URL to POST reminders to",
+		"cmd.remind.daemon.flag.once":           "Check once and exit",
+		"cmd.remind.started":                    "Started checking due dates.",
+		"cmd.remind.stopping":                   "Received a stop signal.",
+		"cmd.remind.check_failure":              "Checking due dates failed.",
+		"cmd.remind.send_failure":               "Sending a reminder failed. It will be retried at the next check.",
+		"cmd.remind.save_failure":               "Recording the sent reminders failed.",
+		RemindNotifyFailure:                     "The notification command %s failed (output: %s)",
+		RemindWebhookStatusUnexpected:           "The webhook URL returned the unexpected status code %d",
+		SettingRemindIntervalInvalid:            "The check interval (--interval, TODO_REMIND_INTERVAL, remind.interval) must be a positive duration",
+		SettingRemindBeforeInvalid:              "The time before the due date (--before, TODO_REMIND_BEFORE, remind.before) must be 0 or greater",
+		SettingRemindSinkInvalid:                "The sink %q is invalid. Give one of stdout/notify/webhook",
+		SettingRemindWebhookInvalid:             "To send reminders to a webhook, give an http or https webhook URL (--webhook-url, TODO_REMIND_WEBHOOK_URL, remind.webhook_url) (given: %q)",
+		SettingRemindContextInvalid:             "The remind context %q is invalid (the name is empty or duplicated, or the URL is invalid)",
+		"cmd.bench.short":                       "Put load on the ToDo server API and measure latency and throughput",
+		"cmd.bench.long": `Repeats creating (create), getting (get), listing (list), updating (update) and deleting (delete)
+tasks in parallel with the given mix to put load on the ToDo server.
+Useful for tuning the number of gunicorn workers and replicas.
+
+Shows latency percentiles (p50/p90/p99/p99.9) and error rates for each operation,
+along with the overall throughput and the number of errors by status code.
+Percentiles are computed like an HDR Histogram and have an error of about 1.6%.
+
+update/delete only target tasks created by bench; other tasks are never changed.
+Each update sends a single PATCH request that changes the task title.
+When there is no task to target, a task is created instead.
+With --cleanup, the tasks created by bench that remain are deleted at the end.
+
+Example:
+  todo bench -c 8 -d 1m --rps 100 --mix create=1,get=8,list=1 --cleanup`,
+		"cmd.bench.flag.mix":         "Operation mix (weights of create/get/list/update/delete)",
+		"cmd.bench.flag.concurrency": "Number of operations to run at the same time",
+		"cmd.bench.flag.duration":    "How long to put load",
+		"cmd.bench.flag.rps":         "Target number of operations started per second (0 means no limit)",
+		"cmd.bench.flag.timeout":     "Time to wait for each response (0 means no limit)",
+		"cmd.bench.flag.cleanup":     "Delete the tasks created by bench at the end",
+		"cmd.bench.flag.json":        "Output the result as JSON",
+		"cmd.bench.started":          "Putting load.",
+		"cmd.bench.cleanup_failure":  "Could not delete %d tasks.",
+		"cmd.bench.summary":          "Put load on %s for %s. Operations: %d Throughput: %.1f/s",
+		"cmd.bench.errors_by_status": "Errors by status code:",
+		"cmd.bench.remaining":        "Remaining tasks: %d (deleted by cleanup: %d)",
+		BenchOptionInvalid:           "The concurrency (--concurrency) and duration (--duration) must be positive, and the target rate (--rps) and timeout (--timeout) must be 0 or greater",
+		BenchDryRunUnsupported:       "Cannot put load in dry run",
+		"cmd.smoketest.short":        "Run a full set of operations against the ToDo server and verify the responses",
+		"cmd.smoketest.long": `Waits for the ToDo server to be ready, then logs in and creates (create), gets (get),
+lists (list), updates (update) and deletes (delete) a task, verifying every response.
+The created task is deleted at the end even if a step fails.
+Without --username/--password, the credentials in the mounted directory (see todo --help) or the authentication token in the config file are used.
+The token obtained by logging in is not saved to the config file.
+
+Results are written as TAP (--format tap) or JUnit XML (--format junit),
+so GitLab CI jobs can display them after a deployment.
+If any step fails, exits with the code for its cause.
+
+Example:
+  todo smoketest --host todo-server --port 8000 --username test_user --password test_password \
+    --wait 2m --format junit --output smoketest.xml`,
+		"cmd.smoketest.flag.username": "Username to log in with",
+		"cmd.smoketest.flag.password": "Password to log in with",
+		"cmd.smoketest.flag.wait":     "How long to wait for the ToDo server to be ready",
+		"cmd.smoketest.flag.timeout":  "Time to wait for each response (0 means no limit)",
+		"cmd.smoketest.flag.format":   "Output format of the results (tap/junit)",
+		"cmd.smoketest.flag.output":   "File to write the results to (standard output if omitted)",
+		"cmd.smoketest.waiting":       "Waiting for the ToDo server to be ready.",
+		"cmd.smoketest.skipped":       "not run because %s failed",
+		"cmd.smoketest.login_skipped": "using the authentication token in the config file",
+		SmoketestFailed:               "smoketest step %s failed",
+		SmoketestFormatInvalid:        "The output format (--format) must be tap or junit",
+		SmoketestMismatch:             "%s differs from the expected value (expected: %v, actual: %v)",
+		SmoketestDryRunUnsupported:    "Cannot run smoketest in dry run",
+		SettingDiscoveryAmbiguous:     "Found more than one ToDo server Service (%s). Specify the name of the Service to use with %s",
+		SettingDiscoveryInvalid:       "The server URL given in %s is invalid. Give a URL starting with http:// or https://",
+		"cmd.plugin.short":            "Manage plugins (todo-<name>) on PATH",
+		"cmd.plugin.long": `When todo <name> does not match a built-in subcommand, the executable todo-<name> is looked up on PATH and run.
+Teams can add their own subcommands, such as reports, without changing the CLI.
+
+Arguments before <name> are parsed as todo options; arguments after it are passed to the plugin unchanged.
+The resolved settings are passed to the plugin in the following environment variables.
+
+  TODO_PLUGIN_NAME  Name of the plugin
+  TODO_SERVER_URL   URL of the ToDo server (e.g. http://todo-server:8000)
+  TODO_PROTOCOL     Protocol
+  TODO_HOST         Host name/IP address
+  TODO_PORT         Port number
+  TODO_TOKEN        Authentication token (not set when none is found)
+  TODO_CONTEXT      Name of the current endpoint when using multiple endpoints, otherwise default
+  TODO_CONFIG       Path of the config file
+  TODO_LANG         Message language (ja/en)
+  TODO_DRY_RUN      Whether this is a dry run (true/false)
+
+Plugins listed in plugin.handshake in the config file also receive the same settings as JSON
+on the first line of standard input (TODO_PLUGIN_HANDSHAKE=stdin is set).
+
+  plugin:
+    handshake:
+      - report
+
+The exit code of the plugin becomes the exit code of todo.`,
+		"cmd.plugin.list.short": "List plugins on PATH",
+		"cmd.plugin.list.long": `Looks up todo-<name> executables in the directories on PATH, in order, and lists their paths.
+Warns about plugins that are never run because they have the same name as a built-in subcommand
+or are shadowed by a plugin of the same name found earlier on PATH.`,
+		"cmd.plugin.list.flag.json":  "Output the result as JSON",
+		"cmd.plugin.none":            "No plugins (todo-<name>) were found on PATH.",
+		"cmd.plugin.shadows_builtin": "%s is never run because it has the same name as the built-in subcommand %s",
+		"cmd.plugin.shadowed":        "%s is never run because it is shadowed by %s found earlier on PATH",
+		PluginExecFailure:            "Failed to run the plugin %s",
+		"cmd.alias.short":            "Edit aliases in the config file",
+		"cmd.alias.long": `Aliases give short names to long command lines you use often. They are stored under aliases in the config file.
+When todo <name> does not match a built-in subcommand, the alias is expanded and then run.
+Aliases take precedence over plugins (todo-<name>).
+
+In the expansion, $1, $2, ... are replaced with the arguments given after the alias, and $@ with all the remaining arguments.
+Without $@, arguments not referenced by a positional parameter are appended at the end.
+An expansion written as a single string is split at spaces.
+
+  aliases:
+    ready: [ping, --until-ready, --timeout, 2s]
+    start: [update, --id, $1, --status, RUNNING]
+
+defaults sets default options per subcommand.
+Only options not given on the command line are added.
+Nested subcommands are written separated by a space, such as "plugin list".
+
+  defaults:
+    get:
+      fingerprint: true
+    bench:
+      concurrency: 8
+      duration: 1m
+
+Examples:
+  todo alias set ready -- ping --until-ready --timeout 2s
+  todo alias set start -- update --id '$1' --status RUNNING
+  todo start 42`,
+		"cmd.alias.set.short":    "Add or change an alias",
+		"cmd.alias.set.long":     `Give the name of the alias and, after "--", the arguments it expands to. Other settings in the config file are kept.`,
+		"cmd.alias.list.short":   "List aliases",
+		"cmd.alias.list.long":    "Shows the aliases in the config file in name order, together with the arguments they expand to.",
+		"cmd.alias.delete.short": "Delete an alias",
+		"cmd.alias.delete.long":  "Deletes the alias with the given name from the config file. Other settings in the config file are kept.",
+		"cmd.alias.saved":        "Saved the alias %s: %s",
+		"cmd.alias.deleted":      "Deleted the alias %s",
+		AliasArgsInvalid:         "Give the name of the alias and the arguments it expands to (e.g. todo alias set ready -- ping --until-ready)",
+		AliasNameInvalid:         "The alias name %q may only contain lowercase letters, digits, - and _",
+		AliasShadowsBuiltin:      "%s cannot be an alias because it has the same name as a built-in subcommand",
+		AliasNotFound:            "The alias %s is not in the config file",
+		AliasArgumentMissing:     "No argument was given for %[2]s of the alias %[1]s",
+		DefaultsFlagUnknown:      "The option --%[2]s given in defaults.%[1]s of the config file does not exist",
+		"cmd.config.short":       "Show and edit the config file, and find out where settings come from",
+		"cmd.config.long": `Shows and edits the config file.
+Each setting comes from a command line option, an environment variable, the config file, a server discovered in the Kubernetes environment, or a default value.
+Use config explain to see the effective values and where they come from.
+
+The config file starts with apiVersion and kind. Files in the older format without them are
+converted in memory when read; use config migrate to convert the file itself.
+The subcommands run with a warning even if the config file cannot be loaded.`,
+		"cmd.config.view.short":    "Show the config file",
+		"cmd.config.view.long":     "Shows the config file in YAML. Authentication tokens are hidden unless --raw is given.",
+		"cmd.config.view.flag.raw": "show authentication tokens as they are",
+		"cmd.config.set.short":     "Set a key in the config file",
+		"cmd.config.set.long": `Sets a key in the config file. Separate nested keys with ".", as in retry.max.
+The value is converted to the type of the key (number, boolean, duration, ...) and rejected if it does not match.
+Other settings in the config file are kept.
+
+Examples:
+  todo config set protocol https
+  todo config set retry.base_delay 500ms
+  todo config set defaults.get.fingerprint true`,
+		"cmd.config.unset.short":   "Remove a key from the config file",
+		"cmd.config.unset.long":    "Removes the given key from the config file. Other settings in the config file are kept.",
+		"cmd.config.explain.short": "Show the effective settings and where they come from",
+		"cmd.config.explain.long": `Shows the effective value of each setting and where it comes from.
+The source is one of:
+  flag       a command line option
+  env        an environment variable
+  config     the config file (path and key)
+  discovery  a server discovered in the Kubernetes environment (variable name or file path)
+  default    the default value
+
+The config file is also checked against the schema, and unknown keys and values of the wrong type are reported as warnings.
+Exits with code 2 if there are problems.`,
+		"cmd.config.explain.flag.json": "output in JSON",
+		"cmd.config.migrate.short":     "Convert the config file to the current format",
+		"cmd.config.migrate.long": `Converts a config file in an older format to the current format (apiVersion, kind) and saves it.
+The file before conversion is kept with .bak appended to its name. Other settings in the config file are kept.
+With --dry-run, the converted config file is shown and the file is not changed.`,
+		"cmd.config.migrate.current": "%s is already in the current format (%s)",
+		"cmd.config.migrate.done":    "Converted %s to %s (backup: %s)",
+		"cmd.config.using":           "Using config file %s",
+		"cmd.config.legacy":          "%s is in an older format. Run todo config migrate to convert it to the current format",
+		"cmd.config.saved":           "Saved %s to %s",
+		"cmd.config.unset":           "Removed %s from %s",
+		ConfigArgsInvalid:            "Give a key and a value (e.g. todo config set retry.max 5, todo config unset retry.max)",
+		ConfigKeyUnknown:             "Unknown config key: %s",
+		ConfigKeyNotSettable:         "%s cannot be set with config set. Edit the config file directly",
+		ConfigValueInvalid:           "Invalid value for %s (%s): %v",
+		ConfigKeyNotSet:              "%s is not in the config file %s",
+		ConfigFileInvalid:            "The config file %s has %d problems",
+		ConfigFileNotFound:           "The config file %s does not exist",
+		ConfigHeaderNotSettable:      "%s cannot be set. Use todo config migrate to convert the config file to the current format",
+		"cmd.template.short":         "Show task templates",
+		"cmd.template.long": `Shows templates describing tasks that are created repeatedly.
+Templates are <name>.yaml files in $XDG_CONFIG_HOME/todo/templates (changed with template_dir in the config file).
+The title and description of each task can contain Go text/template placeholders.
+  {{.Date}}          the date of rendering (in the form 2006-01-02)
+  {{.Now}}           the time of rendering (format it as in {{.Now.Format "01/02"}})
+  {{.Args.service}}  a parameter given as --set service=api
+If params is written, other parameters are rejected. Parameters with an empty default are required.
+
+Example (handover.yaml):
+description: weekly on-call handover
+params:
+  service: ""
+  owner: oncall
+tasks:
+- title: "Check the alerts of {{.Args.service}} ({{.Date}})"
+- title: "Write the handover notes for {{.Args.service}}"
+  description: "owner: {{.Args.owner}}"
+
+todo create --template handover --set service=api creates all the tasks in the template.
+All tasks are rendered before any task is created, so no task is created if the template has an error.`,
+		"cmd.template.list.short":       "List the templates",
+		"cmd.template.list.long":        "Shows the name, number of tasks and description of each template. Templates that cannot be read are reported as warnings.",
+		"cmd.template.show.short":       "Show a template",
+		"cmd.template.show.long":        "Shows the template file. With --render, shows the tasks rendered with the --set parameters and the current time.",
+		"cmd.template.show.flag.render": "show the tasks rendered from the template",
+		"cmd.template.flag.set":         "parameter of the template (KEY=VALUE, can be repeated)",
+		TemplateArgsInvalid:             "Give the name of the template (e.g. todo template show handover)",
+		TemplateSetInvalid:              "Give --set in the form KEY=VALUE: %s",
+		"flag.task.tag":                 "tags of the task (comma separated or repeated; --tag= removes them)",
+		"flag.task.priority":            "priority of the task (low/medium/high; empty removes it)",
+		"flag.task.due":                 "due date of the task (2006-01-02 or an RFC3339 time; empty removes it)",
+		"cmd.get.flag.tag":              "only tasks that have all the given tags",
+		"cmd.get.flag.priority":         "only tasks with the given priority",
+		"cmd.get.flag.due-before":       "only tasks due on or before the given date (or time)",
+		"flag.lang":                     "language of the messages (ja/en). Defaults to LC_ALL, LC_MESSAGES or LANG",
+		"cmd.help_flag":                 "help for %s",
+	})
+}
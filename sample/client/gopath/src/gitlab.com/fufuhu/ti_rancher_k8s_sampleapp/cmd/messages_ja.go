@@ -0,0 +1,574 @@
+package cmd
+
+import (
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+func init() {
+	// コマンドの日本語のメッセージとヘルプです。
+	service.RegisterMessages(service.LanguageJapanese, map[service.MessageID]string{
+		SettingErrorMessageUsernameNotFound: "ユーザ名情報が設定されていません。",
+		SettingErrorMessagePasswordNotFound: "パスワード情報が指定されていません。",
+		SettingErrorMessageTokenNotFound:    "トークン情報が見つかりません。",
+		SettingErrorMessageTokenInvalid:     "JWTトークンの設定が異常です。loginサブコマンドで再取得してください。",
+		SettingRetryMaxInvalid:              "再試行の最大回数(--retry-max)には0以上の値を指定してください。",
+		SettingServerInvalid:                "環境変数%sの値%qはToDoサーバのURLとして解釈できません(例: https://todo.example.com:8443)",
+		SettingContextNotFound:              "コンテキスト%sは設定ファイルのendpointsにありません",
+		SettingEnvInvalid:                   "環境変数%sの値%qが正しくありません",
+		SettingFailureThresholdInvalid:      "サーキットブレーカーを開くまでの失敗の回数(failover.failure_threshold)には1以上の値を指定してください。",
+		DryRunMessage:                       "ドライランのため、上記のリクエストは送信していません。",
+		SettingTaskTitleNotFound:            "タスクの名前が指定されていません",
+		SettingTaskDescriptionNotFound:      "タスクの概要が指定されていません",
+		SettingTaskConflictPolicyInvalid:    "競合時の振る舞い(--on-conflict)にはretryまたはabortを指定してください",
+		"cmd.bulk.short":                    "TODOタスクの作成・更新・削除をまとめて並行に実行します。",
+		"cmd.bulk.long": `YAML(またはJSON)で記述した操作の一覧を読み込み、並行して実行します。
+操作の一覧は次のような形式で記述します。
+
+- operation: create
+  title: タスクの名前
+  description: タスクの概要
+- operation: update
+  id: 1
+  status: RUNNING
+- operation: delete
+  id: 2
+
+結果は操作の並び順どおりに標準出力へ、進捗は標準エラー出力へ出力します。`,
+		"cmd.create.short": "TODOタスクを作成します。",
+		"cmd.create.long": `TODOタスクを作成します。
+--templateを指定した場合は、テンプレートに記述したタスクをまとめて作成します。
+テンプレートのパラメータは--set service=apiのように指定します。詳しくはtemplateを参照してください。
+
+概要は--description-fileでファイル(-の場合は標準入力)から読み込めます。
+--title-from-first-lineを指定した場合は、概要の最初の行を名前とし、残りを概要とします。
+--from-stdinは--description-file -と同じで、--titleを指定しない場合は--title-from-first-lineも指定したものとして扱います。
+  git log -1 | todo create --from-stdin
+概要は64KiBまでです。UTF-8として不正なバイト列はU+FFFDに置き換えて送信します。
+
+タグ(--tag)、優先度(--priority)、期限(--due)は、概要の先頭に次のようなフロントマターとして記述します。
+---
+todo:
+  tags: [ops, api]
+  priority: high
+  due: "2019-04-01"
+---
+ToDoサーバには概要の一部として保存するため、他のクライアントからも概要として読むことができます。`,
+		"cmd.delete.short": "ユーザに紐づくTODOタスクを削除します。",
+		"cmd.delete.long": `ユーザに紐づくTODOタスクを削除します。
+--id指定なしの場合は、エラーを返します。`,
+		"cmd.get.short": "ユーザに紐づくTODOタスクを取得します。",
+		"cmd.get.long": `ユーザに紐づくTODOタスクを取得します。
+--id指定なしの場合は、当該ユーザに紐づく全ての
+TODOタスクを取得します。
+概要のメタデータ(タグ、優先度、期限)は列に分けて表示し、--tag、--priority、--due-beforeで絞り込めます。`,
+		"cmd.log.short": "操作ジャーナルに記録されたタスクの操作を表示します。",
+		"cmd.log.long": `操作ジャーナルに記録されたタスクの操作を古い順に表示します。
+操作ジャーナルはToDoサーバとユーザの組み合わせごとに保管されます。`,
+		"cmd.login.short": "Todo Serverにログインし、JWTトークンを取得します。",
+		"cmd.login.long":  "Todo Serverにログインし、JWTトークンを取得します。",
+		"cmd.ping.short":  "ping-poing APIを使ってToDoサーバの起動を確認します",
+		"cmd.ping.long": `ToDoサーバに対してping-pong APIを使ったアクセス確認を行うことで
+ToDoサーバへの通信の疎通を確認します。
+ping(8)と同様に、呼び出しごとの応答時間と、最小/平均/最大/標準偏差の統計を表示します。
+
+Kubernetesのexec probeやHelmでのアップグレード時の待ち合わせに利用できるよう、
+一度でも応答が得られれば終了コード0で、応答が得られなければ原因に応じた終了コード
+(6: 接続できない、7: ToDoサーバのエラー)で終了します。
+
+例:
+  todo ping -c 5 -i 500ms
+  todo ping --until-ready --timeout 2s`,
+		"cmd.undo.short": "直前に行ったタスクの操作を取り消します。",
+		"cmd.undo.long": `操作ジャーナルに記録された直近の操作を新しいものから順に取り消します。
+作成したタスクは削除し、削除したタスクは作成しなおしてステータスを復元し、
+更新したタスクは更新前の内容に戻します。`,
+		"cmd.update.short": "ユーザに紐づくTODOタスクを更新します。",
+		"cmd.update.long": `ユーザに紐づくTODOタスクを更新します。
+--id指定なしの場合は、エラーを返します。
+--tag、--priority、--dueで概要のメタデータを変更します。--descriptionを指定した場合もメタデータは残します。`,
+		"cmd.root.short": "ToDoアプリケーションのクライアント用アプリケーションです",
+		"cmd.root.long": `マルチテナント型ToDoアプリケーション用クライアントアプリケーションのサンプル実装です。
+Cobraを用いてCLIの実装を行っています。
+
+KubernetesのPod内で実行され、オプションや設定ファイルで接続先が指定されていない場合は、
+ServiceがPodに設定する環境変数(SERVICE_TODOSERVER_<リリース名>_SERVICE_HOST/_PORT)から
+ToDoサーバを探します。ServiceはTODO_DISCOVERY_SERVICEまたはdiscovery.serviceで指定できます。
+また、ConfigMapやSecretをマウントしたディレクトリ(/etc/todo、TODO_DISCOVERY_DIRまたはdiscovery.dirで変更可能)の
+url, username, password, tokenのファイルを、環境変数より優先して接続先と認証情報に利用します。
+
+設定は次の順に優先して読み込みます。
+  1. コマンドラインオプション(--host、--retry-maxなど)
+  2. 環境変数(TODO_SERVER、TODO_HOST、TODO_RETRY_MAXなど)
+  3. 設定ファイル(--config、指定しない場合はTODO_CONFIGまたは$XDG_CONFIG_HOME/todo/config.yaml。
+     それが無く$HOME/.todo_config.yamlだけがある場合はそちら)
+  4. Kubernetesの環境から見つけた接続先
+  5. 既定の値
+環境変数の名前は設定ファイルのキーを大文字にして.を_に置き換え、TODO_を付けたものです(retry.maxの場合はTODO_RETRY_MAX)。
+コンテナの環境にあるHOSTやPORTなど、TODO_の付かない環境変数は読み込みません。
+TODO_SERVERはhttps://todo.example.com:8443のようなURLでprotocol, host, portをまとめて指定し、
+TODO_CONTEXT(設定ファイルのcontext)はendpointsのうち利用するエンドポイントの名前を指定します。
+TODO_TOKEN、TODO_LANG、TODO_DRY_RUN、TODO_VERBOSITY、TODO_TRACE_FILEも利用できます。
+各設定の値と出どころはconfig explainで確認できます。
+
+終了コード:
+  0  正常終了
+  1  分類できないエラー
+  2  コマンドラインオプションや設定ファイルの指定の誤り
+  3  認証が必要(loginサブコマンドでトークンを再取得してください)
+  4  指定したタスクが見つからない
+  5  ToDoサーバが受け付けない入力
+  6  ToDoサーバに接続できない
+  7  ToDoサーバのエラー、または想定外の応答
+  8  他の変更を検知したため更新を中止した`,
+		"flag.protocol":                         "ToDoサーバにアクセスする際のプロトコル",
+		"flag.host":                             "ToDoサーバのホスト名/IPアドレス",
+		"flag.port":                             "ToDoサーバのポート番号",
+		"flag.log-level":                        "ログの出力レベル(debug/info/warn/error)",
+		"flag.log-format":                       "ログの出力形式(text/json)",
+		"flag.quiet":                            "エラー以外のログを出力しません",
+		"flag.verbosity":                        "出力する情報の詳細さ(1: 接続先, 6: リクエストと所要時間, 7: リクエストヘッダ, 8: レスポンスヘッダとボディ, 9: ボディの全体)",
+		"flag.trace-file":                       "HTTP通信の内容をHAR形式で記録するファイルのパス(認証情報は伏せて記録します)",
+		"flag.dry-run":                          "create/update/delete/loginで送信するリクエストを表示するだけで、実際には送信しません",
+		"flag.retry-max":                        "一時的な障害(接続エラー、429/502/503/504)で失敗した場合の再試行の最大回数",
+		"flag.retry-base-delay":                 "初回の再試行までの待ち時間(以降は指数的に伸ばします)",
+		"flag.retry-max-delay":                  "再試行までの待ち時間の上限",
+		"flag.retry-post":                       "タスク作成などのPOSTリクエストも再試行します(タスクが重複して作成されるおそれがあります)",
+		"cmd.bulk.flag.file":                    "操作の一覧を記述したファイルのパス(-の場合は標準入力)",
+		"cmd.bulk.flag.parallel":                "同時に実行する操作の数",
+		"cmd.bulk.flag.rate":                    "1秒あたりに開始する操作の最大数(0の場合は制限なし)",
+		"cmd.create.flag.title":                 "タスクのタイトル",
+		"cmd.create.flag.description":           "タスクの概要",
+		"cmd.create.flag.template":              "タスクを作成するテンプレートの名前",
+		"cmd.create.flag.description-file":      "タスクの概要を読み込むファイル(-の場合は標準入力)",
+		"cmd.create.flag.title-from-first-line": "概要の最初の行をタスクの名前とします",
+		"cmd.create.flag.from-stdin":            "標準入力から名前と概要を読み込みます(--description-file - --title-from-first-lineと同じ)",
+		"cmd.create.description_sanitized":      "概要に含まれるUTF-8として不正なバイト列をU+FFFDに置き換えました",
+		"cmd.delete.flag.id":                    "削除したいタスクのID",
+		"cmd.get.flag.id":                       "タスクのID",
+		"cmd.get.flag.fingerprint":              "update --if-matchで利用するフィンガープリントを表示します",
+		"cmd.log.flag.count":                    "表示する操作の数(0の場合は全て)",
+		"cmd.login.flag.username":               "Todoサーバにログインするためのユーザ名",
+		"cmd.login.flag.password":               "Todoサーバにログインするためのパスワード",
+		"cmd.undo.flag.count":                   "取り消す操作の数",
+		"cmd.update.flag.id":                    "更新したいタスクのID",
+		"cmd.update.flag.title":                 "更新後のタスクの名前",
+		"cmd.update.flag.description":           "更新後のタスクの説明",
+		"cmd.update.flag.status":                "更新後のタスクのステータス",
+		"cmd.update.flag.if-match":              "更新前のタスクのフィンガープリント(get --fingerprintで確認可能)。一致しない場合は更新しません",
+		"cmd.update.flag.expect-status":         "更新前のタスクのステータス。一致しない場合は更新しません",
+		"cmd.update.flag.on-conflict":           "取得後に他の変更を検知した場合の振る舞い(retry/abort)",
+		"cmd.update.flag.max-retries":           "--on-conflict=retryの場合の最大再試行回数",
+		"cmd.delete.done":                       "タスクを削除しました。",
+		"cmd.endpoint.pinned":                   "接続先を固定します。",
+		"cmd.endpoint.login_failure":            "エンドポイントへのログインに失敗しました。",
+		"cmd.endpoint.login_done":               "エンドポイントの認証トークンを取得しました。",
+		"cmd.journal.record_failure":            "操作ジャーナルへの記録に失敗しました。この操作はundoできません。",
+		"cmd.login.done":                        "認証トークンを取得しました。",
+		"cmd.settings.state_load_failure":       "サーキットブレーカーの状態の読み込みに失敗しました。",
+		"cmd.undo.nothing":                      "取り消せる操作がありません。",
+		"cmd.undo.nothing_to_send":              "Seq %d (%s, ID=%d) の取り消しに送信するリクエストはありません。",
+		"cmd.undo.record_failure":               "操作ジャーナルへの記録に失敗しました。",
+		"cmd.update.done":                       "タスクを更新しました。",
+		"cmd.bulk.summary":                      "成功: %d件 失敗: %d件",
+		"cmd.bulk.summary_skipped":              " スキップ: %d件",
+		"cmd.undo.done":                         "Seq %d (%s, ID=%d) を取り消しました。",
+		"cmd.undo.restored":                     "タスクはID=%dとして復元されました。",
+		BulkFileRequired:                        "操作の一覧を記述したファイル(--file)が指定されていません",
+		BulkFileReadFailure:                     "操作の一覧を読み込めませんでした",
+		BulkOperationInvalid:                    "%d件目の操作が不正です",
+		BulkOperationsFailed:                    "%d件の操作が失敗しました",
+		CreateTitleInvalid:                      "タスクの名前指定(--title)が不正です。",
+		CreateDescriptionInvalid:                "タスクの概要指定(--description)が不正です。",
+		CreateTemplateConflict:                  "--templateと--title/--descriptionは同時に指定できません。",
+		CreateInputConflict:                     "%sと%sは同時に指定できません。",
+		CreateDescriptionTooLarge:               "タスクの概要が大きすぎます。%dバイト以下にしてください。",
+		CreateTemplatePartial:                   "テンプレートの%[2]d件のタスクのうち%[1]d件を作成した後、タスクの作成に失敗しました",
+		DeleteIDRequired:                        "削除対象のタスクのIDが正しく指定されていません(--id)",
+		UpdateIDRequired:                        "更新対象のタスクのIDが正しく指定されていません(--id)",
+		UndoCountInvalid:                        "取り消す操作の数(--count)には1以上を指定してください",
+		UndoFailure:                             "操作の取り消しに失敗しました(Seq %d, %s, ID=%d)",
+		UsageInvalid:                            "%s (--helpで使い方を確認できます)",
+		"cmd.doctor.short":                      "ToDoサーバへの接続と認証トークンの状態を診断します",
+		"cmd.doctor.long": `設定の解決から名前解決、TCPの接続、TLS、ping-pong API、認証トークン、
+ToDoサーバとの時刻のずれまでを順に確認し、項目ごとに結果(PASS/WARN/FAIL/SKIP)と対処方法を表示します。
+他のサブコマンドと同じ設定とオプションを利用するため、接続できない原因の切り分けに利用できます。
+複数のエンドポイントを利用する場合は、エンドポイントごとに確認します。
+
+いずれかの項目が失敗した場合は、最初に失敗した項目の原因に応じた終了コードで終了します。`,
+		"cmd.doctor.flag.json":                "診断結果をJSON形式で出力します",
+		"cmd.doctor.flag.timeout":             "名前解決や接続、応答を待つ時間",
+		"cmd.doctor.check.config":             "設定",
+		"cmd.doctor.check.dns":                "名前解決",
+		"cmd.doctor.check.tcp":                "TCP接続",
+		"cmd.doctor.check.tls":                "TLS",
+		"cmd.doctor.check.ping":               "ping",
+		"cmd.doctor.check.token":              "認証トークン",
+		"cmd.doctor.check.token_expiry":       "トークンの有効期限",
+		"cmd.doctor.check.clock_skew":         "時刻のずれ",
+		"cmd.doctor.hint":                     "対処: %s",
+		"cmd.doctor.summary":                  "成功: %d件 警告: %d件 失敗: %d件 スキップ: %d件",
+		"cmd.doctor.skip_unreachable":         "ToDoサーバに接続できないため確認しません",
+		"cmd.doctor.config.pass":              "%s (設定ファイル: %s)",
+		"cmd.doctor.config.pass_without_file": "%s (設定ファイルなし)",
+		"cmd.doctor.config.fail":              "設定を読み込めません: %v",
+		"cmd.doctor.config.endpoint_invalid":  "エンドポイント%sのURLが不正です",
+		"cmd.doctor.config.hint":              "設定ファイル(--config)やオプション(--protocol, --host, --port)の値を確認してください",
+		"cmd.doctor.config.protocol_invalid":  "プロトコル%sには対応していません",
+		"cmd.doctor.config.protocol_hint":     "プロトコルにはhttpまたはhttpsを指定してください",
+		"cmd.doctor.dns.pass":                 "%s → %s",
+		"cmd.doctor.dns.ip_address":           "%sはIPアドレスのため名前解決は不要です",
+		"cmd.doctor.dns.fail":                 "%sの名前解決に失敗しました: %v",
+		"cmd.doctor.dns.hint":                 "ホスト名の綴りとDNSの設定を確認してください。Kubernetes上ではServiceの名前とNamespaceも確認してください",
+		"cmd.doctor.tcp.pass":                 "%sに接続できました (%s)",
+		"cmd.doctor.tcp.fail":                 "%sに接続できません: %v",
+		"cmd.doctor.tcp.hint":                 "ToDoサーバが起動しているか、ポート番号とファイアウォールの設定を確認してください",
+		"cmd.doctor.tls.not_https":            "httpで接続するため確認しません",
+		"cmd.doctor.tls.pass":                 "サーバ証明書の有効期限: %s",
+		"cmd.doctor.tls.expiring":             "サーバ証明書の有効期限が近づいています: %s",
+		"cmd.doctor.tls.expiring_hint":        "サーバ証明書を更新してください",
+		"cmd.doctor.tls.fail":                 "TLSの接続に失敗しました: %v",
+		"cmd.doctor.tls.hint":                 "サーバ証明書のホスト名と有効期限、発行元の認証局を確認してください。TLSを利用しない場合は--protocol httpを指定してください",
+		"cmd.doctor.ping.pass":                "応答: %s (%s)",
+		"cmd.doctor.ping.fail":                "/api/pingの呼び出しに失敗しました: %v",
+		"cmd.doctor.ping.hint":                "ToDoサーバのログを確認してください。プロキシやIngressを経由する場合は/api/pingが転送されているかも確認してください",
+		"cmd.doctor.token.pass":               "認証トークンが設定されています",
+		"cmd.doctor.token.fail":               "認証トークンが設定されていません",
+		"cmd.doctor.token.hint":               "loginサブコマンドで認証トークンを取得してください",
+		"cmd.doctor.token_expiry.no_token":    "認証トークンが無いため確認しません",
+		"cmd.doctor.token_expiry.none":        "有効期限の無い認証トークンです",
+		"cmd.doctor.token_expiry.pass":        "有効期限: %s",
+		"cmd.doctor.token_expiry.warn":        "まもなく有効期限が切れます: %s",
+		"cmd.doctor.token_expiry.fail":        "有効期限が切れています: %s",
+		"cmd.doctor.token_expiry.malformed":   "認証トークンを読み取れません: %v",
+		"cmd.doctor.token_expiry.hint":        "loginサブコマンドで認証トークンを取得し直してください",
+		"cmd.doctor.clock_skew.pass":          "ToDoサーバとの時刻のずれ: %s",
+		"cmd.doctor.clock_skew.warn":          "ToDoサーバとの時刻のずれが大きくなっています: %s",
+		"cmd.doctor.clock_skew.fail":          "ToDoサーバとの時刻が大きくずれています: %s",
+		"cmd.doctor.clock_skew.hint":          "NTPなどで時刻を同期してください。時刻がずれていると認証トークンの有効期限を正しく判断できません",
+		"cmd.doctor.clock_skew.no_date":       "ToDoサーバの応答にDateヘッダが無いため確認しません",
+		DoctorChecksFailed:                    "%d件の確認に失敗しました",
+		"cmd.ping.flag.count":                 "呼び出す回数(0の場合は中断するまで繰り返します)",
+		"cmd.ping.flag.interval":              "呼び出しの間隔",
+		"cmd.ping.flag.timeout":               "1回の呼び出しで応答を待つ時間(0の場合は制限しません)",
+		"cmd.ping.flag.until-ready":           "応答が得られるまで繰り返し、得られた時点で終了します(--countの指定が無ければ回数を制限しません)",
+		"cmd.ping.flag.json":                  "結果をJSON形式で出力します",
+		"cmd.ping.header":                     "PING %s",
+		"cmd.ping.reply":                      "seq=%d %s 時間=%s",
+		"cmd.ping.reply_failure":              "seq=%d 失敗: %s",
+		"cmd.ping.statistics":                 "--- %s のping統計 ---",
+		"cmd.ping.summary":                    "送信: %d件 受信: %d件 損失: %.1f%%",
+		PingCountInvalid:                      "呼び出す回数(--count)には0以上を指定してください",
+		PingIntervalInvalid:                   "呼び出しの間隔(--interval)と応答を待つ時間(--timeout)には0以上を指定してください",
+		PingNoReply:                           "ToDoサーバから応答がありませんでした",
+		"cmd.exporter.short":                  "タスクの集計をPrometheusのメトリクスとして公開します",
+		"cmd.exporter.long": `ToDoサーバのタスクを定期的に取得し、ステータスごとのタスクの数などを
+Prometheusの形式で/metricsに公開します。/healthzは最初の集計が終わると200を返します。
+SIGTERMを受け取ると、処理中の集計とリクエストの完了を待って終了します。
+
+公開するメトリクス:
+  todo_tasks{context,status}                      ステータスごとのタスクの数
+  todo_scrape_success{context}                    直近の集計が成功したかどうか
+  todo_scrape_duration_seconds{context}           直近の集計にかかった時間
+  todo_last_success_timestamp_seconds{context}    最後に集計が成功した時刻
+  todo_api_errors_total{context,kind}             原因の種類ごとのAPIの呼び出しの失敗の累計
+  todo_token_expiry_timestamp_seconds{context}    認証トークンの有効期限
+
+設定はオプション、環境変数、設定ファイルの順に読み込みます。
+  --listen    TODO_EXPORTER_LISTEN    exporter.listen
+  --interval  TODO_EXPORTER_INTERVAL  exporter.interval
+              TODO_EXPORTER_CONTEXTS  exporter.contexts
+
+TODO_EXPORTER_CONTEXTSには"名前=URL"をカンマで区切って指定します(例: prod=http://todo-server:8000)。
+認証トークンはTODO_EXPORTER_TOKEN_<名前を大文字にしたもの>、またはTODO_EXPORTER_TOKENで指定します。
+設定ファイルでは次のように指定します。
+
+exporter:
+  contexts:
+    - name: prod
+      url: http://todo-server:8000
+      token: <認証トークン>
+
+コンテキストの指定が無い場合は、他のサブコマンドと同じ接続先と認証トークンをdefaultという名前で利用します。`,
+		"cmd.exporter.flag.listen":     "メトリクスを公開するアドレス",
+		"cmd.exporter.flag.interval":   "タスクを集計する間隔",
+		"cmd.exporter.started":         "メトリクスの公開を開始しました。",
+		"cmd.exporter.stopping":        "終了の指示を受け取りました。処理中の集計とリクエストの完了を待って終了します。",
+		"cmd.exporter.collect_failure": "タスクの集計に失敗しました。",
+		ExporterListenFailure:          "%sで待ち受けられません",
+		SettingExporterIntervalInvalid: "集計の間隔(--interval, TODO_EXPORTER_INTERVAL, exporter.interval)には正の時間を指定してください",
+		SettingExporterContextInvalid:  "exporterのコンテキスト%qの指定が不正です(名前が空、重複している、またはURLが不正です)",
+		"cmd.remind.short":             "期限が近いタスクや期限を過ぎたタスクを通知します",
+		"cmd.remind.long":              `タスクの概要に記述した期限(create/updateの--due)をもとに、タスクを通知します。`,
+		"cmd.remind.daemon.short":      "タスクの期限を定期的に確認し、通知するデーモンを起動します",
+		"cmd.remind.daemon.long": `コンテキストごとにToDoサーバのタスクを定期的に取得し、完了(FINISHED)していないタスクのうち
+期限が近づいているタスク(--beforeの時間以内)と、期限を過ぎたタスクを通知します。
+
+通知の送信先(--sink)は次のものを組み合わせて指定できます。
+  stdout   標準出力に1行ずつ出力します
+  notify   デスクトップ通知のコマンド(--notify-command、既定はnotify-send)を、通知の文面を最後の引数として実行します。
+           TODO_REMINDER_CONTEXT、TODO_REMINDER_KIND、TODO_REMINDER_TASK_ID、TODO_REMINDER_TITLE、
+           TODO_REMINDER_DUEの環境変数でも通知の内容を渡します
+  webhook  通知をJSONでWebhookのURL(--webhook-url)にPOSTします
+
+送信した通知はファイル(remind.state_file、既定は$HOME/.todo/remind.json)に記録し、
+デーモンを再起動しても同じ通知は送信しません。期限を変更したタスクは改めて通知します。
+送信に失敗した送信先には、次の確認で送信しなおします。
+--onceを指定した場合は、一度だけ確認して終了します(cronなどから実行する場合に利用します)。
+
+設定はオプション、環境変数、設定ファイルの順に読み込みます。
+  --interval        TODO_REMIND_INTERVAL        remind.interval
+  --before          TODO_REMIND_BEFORE          remind.before
+  --sink                                        remind.sinks
+  --notify-command  TODO_REMIND_NOTIFY_COMMAND  remind.notify_command
+  --webhook-url     TODO_REMIND_WEBHOOK_URL     remind.webhook_url
+                    TODO_REMIND_STATE_FILE      remind.state_file
+                    TODO_REMIND_CONTEXTS        remind.contexts
+
+TODO_REMIND_CONTEXTSにはコンテキストを"名前=URL"の形式でカンマで区切って指定し、
+認証トークンはTODO_REMIND_TOKEN_<名前を大文字にしたもの>、またはTODO_REMIND_TOKENで指定します。
+コンテキストの指定が無い場合は、他のサブコマンドと同じ接続先と認証トークンをdefaultという名前で利用します。`,
+		"cmd.remind.daemon.flag.interval":       "タスクの期限を確認する間隔",
+		"cmd.remind.daemon.flag.before":         "期限のどれだけ前から通知するか(0の場合は期限を過ぎたタスクだけを通知します)",
+		"cmd.remind.daemon.flag.sink":           "通知の送信先(stdout/notify/webhook)",
+		"cmd.remind.daemon.flag.notify-command": "デスクトップ通知に利用するコマンド",
+		"cmd.remind.daemon.flag.webhook-url":    "通知をPOSTするWebhookのURL",
+		"cmd.remind.daemon.flag.once":           "一度だけ確認して終了します",
+		"cmd.remind.started":                    "タスクの期限の確認を開始しました。",
+		"cmd.remind.stopping":                   "終了の指示を受け取りました。",
+		"cmd.remind.check_failure":              "タスクの期限を確認できませんでした。",
+		"cmd.remind.send_failure":               "通知を送信できませんでした。次の確認で送信しなおします。",
+		"cmd.remind.save_failure":               "送信済みの通知を記録できませんでした。",
+		RemindNotifyFailure:                     "通知のコマンド%sが失敗しました(出力: %s)",
+		RemindWebhookStatusUnexpected:           "WebhookのURLが想定外のステータスコード%dを返しました",
+		SettingRemindIntervalInvalid:            "確認の間隔(--interval, TODO_REMIND_INTERVAL, remind.interval)には正の時間を指定してください",
+		SettingRemindBeforeInvalid:              "通知を始める期限までの時間(--before, TODO_REMIND_BEFORE, remind.before)には0以上の時間を指定してください",
+		SettingRemindSinkInvalid:                "通知の送信先%qは指定できません。stdout/notify/webhookのいずれかを指定してください",
+		SettingRemindWebhookInvalid:             "webhookに通知するには、WebhookのURL(--webhook-url, TODO_REMIND_WEBHOOK_URL, remind.webhook_url)をhttpまたはhttpsのURLで指定してください(指定された値: %q)",
+		SettingRemindContextInvalid:             "remindのコンテキスト%qの指定が不正です(名前が空、重複している、またはURLが不正です)",
+		"cmd.bench.short":                       "ToDoサーバのAPIに負荷をかけ、応答時間とスループットを計測します",
+		"cmd.bench.long": `タスクの作成(create)、取得(get)、一覧の取得(list)、更新(update)、削除(delete)を
+指定した配分で並行して繰り返し、ToDoサーバに負荷をかけます。
+gunicornのワーカー数やレプリカ数の調整に利用できます。
+
+操作の種類ごとに応答時間のパーセンタイル(p50/p90/p99/p99.9)と失敗率を、
+全体のスループットとステータスコードごとの失敗の数とあわせて表示します。
+パーセンタイルはHDR Histogramと同様の方法で求めるため、1.6%程度の誤差を含みます。
+
+update/deleteはbenchで作成したタスクだけを対象とし、他のタスクは変更しません。
+updateはタスクの名前を変更するPATCHリクエストを1回だけ送信します。
+対象のタスクが無い場合は代わりにタスクを作成します。
+--cleanupを指定すると、終了時にbenchで作成して残っているタスクを削除します。
+
+例:
+  todo bench -c 8 -d 1m --rps 100 --mix create=1,get=8,list=1 --cleanup`,
+		"cmd.bench.flag.mix":         "操作の配分(create/get/list/update/deleteの重み)",
+		"cmd.bench.flag.concurrency": "同時に実行する操作の数",
+		"cmd.bench.flag.duration":    "負荷をかける時間",
+		"cmd.bench.flag.rps":         "1秒あたりに開始する操作の目標値(0の場合は制限なし)",
+		"cmd.bench.flag.timeout":     "1回のリクエストで応答を待つ時間(0の場合は制限しません)",
+		"cmd.bench.flag.cleanup":     "終了時にbenchで作成したタスクを削除します",
+		"cmd.bench.flag.json":        "結果をJSON形式で出力します",
+		"cmd.bench.started":          "負荷をかけています。",
+		"cmd.bench.cleanup_failure":  "%d件のタスクを削除できませんでした。",
+		"cmd.bench.summary":          "%s に %s の間負荷をかけました。操作: %d件 スループット: %.1f件/秒",
+		"cmd.bench.errors_by_status": "ステータスコードごとの失敗:",
+		"cmd.bench.remaining":        "残っているタスク: %d件 (後片付けで削除: %d件)",
+		BenchOptionInvalid:           "並行数(--concurrency)と時間(--duration)には正の値を、目標値(--rps)と応答を待つ時間(--timeout)には0以上を指定してください",
+		BenchDryRunUnsupported:       "ドライランでは負荷をかけられません",
+		"cmd.smoketest.short":        "ToDoサーバに対して一通りの操作を行い、応答を検証します",
+		"cmd.smoketest.long": `ToDoサーバの起動を待ってから、ログインとタスクの作成(create)、取得(get)、
+一覧の取得(list)、更新(update)、削除(delete)を順に行い、すべての応答の内容を検証します。
+作成したタスクは途中で失敗した場合も最後に削除します。
+--username/--passwordを指定しない場合は、マウントされたディレクトリ(todo --helpを参照)の認証情報か設定ファイルの認証トークンを利用します。
+ログインして取得した認証トークンは設定ファイルに保存しません。
+
+結果はTAP(--format tap)またはJUnit XML(--format junit)で出力するため、
+デプロイ後のGitLab CIのジョブで結果を表示できます。
+いずれかの手順が失敗した場合は、その原因に応じた終了コードで終了します。
+
+例:
+  todo smoketest --host todo-server --port 8000 --username test_user --password test_password \
+    --wait 2m --format junit --output smoketest.xml`,
+		"cmd.smoketest.flag.username": "ログインに利用するユーザ名",
+		"cmd.smoketest.flag.password": "ログインに利用するパスワード",
+		"cmd.smoketest.flag.wait":     "ToDoサーバの起動を待つ時間",
+		"cmd.smoketest.flag.timeout":  "1回のリクエストで応答を待つ時間(0の場合は制限しません)",
+		"cmd.smoketest.flag.format":   "結果の出力形式(tap/junit)",
+		"cmd.smoketest.flag.output":   "結果を書き込むファイルのパス(指定しない場合は標準出力)",
+		"cmd.smoketest.waiting":       "ToDoサーバの起動を待っています。",
+		"cmd.smoketest.skipped":       "%sが失敗したため実行しません",
+		"cmd.smoketest.login_skipped": "設定ファイルの認証トークンを利用します",
+		SmoketestFailed:               "smoketestの手順%sが失敗しました",
+		SmoketestFormatInvalid:        "結果の出力形式(--format)にはtapまたはjunitを指定してください",
+		SmoketestMismatch:             "%sが期待した値と異なります(期待値: %v, 実際: %v)",
+		SmoketestDryRunUnsupported:    "ドライランではsmoketestを実行できません",
+		SettingDiscoveryAmbiguous:     "ToDoサーバのServiceが複数見つかりました(%s)。%sで利用するServiceの名前を指定してください",
+		SettingDiscoveryInvalid:       "%sに指定された接続先のURLが不正です。http://またはhttps://から始まるURLを指定してください",
+		"cmd.plugin.short":            "PATHにあるプラグイン(todo-<名前>)を扱います",
+		"cmd.plugin.long": `todo <名前>で組み込みのサブコマンドが見つからない場合は、PATHからtodo-<名前>の実行ファイルを探して実行します。
+CLIを変更せずに、チームごとのレポートなどのサブコマンドを追加できます。
+
+<名前>より前の引数はtodoのオプションとして解釈し、後の引数はそのままプラグインに渡します。
+プラグインには解決済みの設定を次の環境変数で渡します。
+
+  TODO_PLUGIN_NAME  プラグインの名前
+  TODO_SERVER_URL   ToDoサーバのURL(例: http://todo-server:8000)
+  TODO_PROTOCOL     プロトコル
+  TODO_HOST         ホスト名/IPアドレス
+  TODO_PORT         ポート番号
+  TODO_TOKEN        認証トークン(見つからない場合は設定しません)
+  TODO_CONTEXT      複数のエンドポイントを利用する場合は現在のエンドポイントの名前、それ以外はdefault
+  TODO_CONFIG       設定ファイルのパス
+  TODO_LANG         メッセージの言語(ja/en)
+  TODO_DRY_RUN      ドライランかどうか(true/false)
+
+設定ファイルのplugin.handshakeにプラグインの名前を並べると、同じ内容をJSONにして
+標準入力の先頭の1行でも渡します(TODO_PLUGIN_HANDSHAKE=stdinが設定されます)。
+
+  plugin:
+    handshake:
+      - report
+
+プラグインの終了コードをそのままtodoの終了コードとします。`,
+		"cmd.plugin.list.short": "PATHにあるプラグインを一覧表示します",
+		"cmd.plugin.list.long": `PATHのディレクトリの順にtodo-<名前>の実行ファイルを探し、パスを一覧表示します。
+組み込みのサブコマンドと同じ名前のプラグインや、PATHで先に見つかった同じ名前のプラグインに
+隠れているプラグインは実行されないため、警告を出力します。`,
+		"cmd.plugin.list.flag.json":  "結果をJSON形式で出力します",
+		"cmd.plugin.none":            "PATHにプラグイン(todo-<名前>)が見つかりません。",
+		"cmd.plugin.shadows_builtin": "%sは組み込みのサブコマンド%sと同じ名前のため実行されません",
+		"cmd.plugin.shadowed":        "%sはPATHで先に見つかった%sに隠れているため実行されません",
+		PluginExecFailure:            "プラグイン%sを実行できません",
+		"cmd.alias.short":            "設定ファイルのエイリアスを編集します",
+		"cmd.alias.long": `よく使う長いコマンドラインに短い名前を付けるエイリアスを、設定ファイルのaliasesで扱います。
+todo <名前>で組み込みのサブコマンドが見つからない場合は、エイリアスを展開してから実行します。
+エイリアスはプラグイン(todo-<名前>)より優先します。
+
+展開後の引数の$1, $2, ...はエイリアスの後に指定した引数に、$@は残りのすべての引数に置き換えます。
+$@を使わない場合、位置パラメータで参照しなかった引数は末尾に追加します。
+展開後の引数を文字列で指定した場合は空白で区切ります。
+
+  aliases:
+    ready: [ping, --until-ready, --timeout, 2s]
+    start: [update, --id, $1, --status, RUNNING]
+
+また、defaultsにはサブコマンドごとの既定のオプションを指定できます。
+コマンドラインで指定しなかったオプションだけを補います。
+入れ子のサブコマンドは"plugin list"のように空白で区切って指定します。
+
+  defaults:
+    get:
+      fingerprint: true
+    bench:
+      concurrency: 8
+      duration: 1m
+
+例:
+  todo alias set ready -- ping --until-ready --timeout 2s
+  todo alias set start -- update --id '$1' --status RUNNING
+  todo start 42`,
+		"cmd.alias.set.short":    "エイリアスを追加、または変更します",
+		"cmd.alias.set.long":     `エイリアスの名前と、"--"の後に展開後の引数を指定します。設定ファイルの他の設定はそのまま残します。`,
+		"cmd.alias.list.short":   "エイリアスを一覧表示します",
+		"cmd.alias.list.long":    "設定ファイルのエイリアスを名前の順に、展開後の引数とあわせて表示します。",
+		"cmd.alias.delete.short": "エイリアスを削除します",
+		"cmd.alias.delete.long":  "指定した名前のエイリアスを設定ファイルから削除します。設定ファイルの他の設定はそのまま残します。",
+		"cmd.alias.saved":        "エイリアス%sを保存しました: %s",
+		"cmd.alias.deleted":      "エイリアス%sを削除しました",
+		AliasArgsInvalid:         "エイリアスの名前と展開後の引数を指定してください(例: todo alias set ready -- ping --until-ready)",
+		AliasNameInvalid:         "エイリアスの名前%qには英小文字、数字、-、_だけを使ってください",
+		AliasShadowsBuiltin:      "%sは組み込みのサブコマンドと同じ名前のため、エイリアスにできません",
+		AliasNotFound:            "エイリアス%sは設定ファイルにありません",
+		AliasArgumentMissing:     "エイリアス%sの%sに対応する引数が指定されていません",
+		DefaultsFlagUnknown:      "設定ファイルのdefaults.%sに指定されたオプション--%sはありません",
+		"cmd.config.short":       "設定ファイルを表示、編集し、設定の出どころを調べます",
+		"cmd.config.long": `設定ファイルを表示、編集します。
+各設定の値は、コマンドラインオプション、環境変数、設定ファイル、Kubernetesの環境から見つけた接続先、既定の値のいずれかから得られます。
+config explainで実際に利用される値とその出どころを確認できます。
+
+設定ファイルの先頭にはapiVersionとkindを記述します。これらの無い以前の形式の設定ファイルは読み込む際にメモリ上で変換します。
+ファイル自体を変換する場合はconfig migrateを実行してください。
+設定ファイルの読み込みに失敗しても、警告を出力してサブコマンドを実行します。`,
+		"cmd.config.view.short":    "設定ファイルの内容を表示します",
+		"cmd.config.view.long":     "設定ファイルの内容をYAML形式で表示します。認証トークンは--rawを指定しない限り伏せて表示します。",
+		"cmd.config.view.flag.raw": "認証トークンを伏せずに表示します",
+		"cmd.config.set.short":     "設定ファイルのキーに値を設定します",
+		"cmd.config.set.long": `設定ファイルのキーに値を設定します。キーはretry.maxのように.で区切って指定します。
+値はキーの型(数値、真偽値、時間など)に合わせて変換し、合わない場合はエラーにします。
+設定ファイルの他の設定はそのまま残します。
+
+例:
+  todo config set protocol https
+  todo config set retry.base_delay 500ms
+  todo config set defaults.get.fingerprint true`,
+		"cmd.config.unset.short":   "設定ファイルからキーを削除します",
+		"cmd.config.unset.long":    "設定ファイルから指定したキーを削除します。設定ファイルの他の設定はそのまま残します。",
+		"cmd.config.explain.short": "実際に利用される設定の値とその出どころを表示します",
+		"cmd.config.explain.long": `各設定について実際に利用される値と、その出どころを表示します。
+出どころは次のいずれかです。
+  flag       コマンドラインオプション
+  env        環境変数
+  config     設定ファイル(パスとキー)
+  discovery  Kubernetesの環境から見つけた接続先(環境変数の名前やファイルのパス)
+  default    既定の値
+
+あわせて設定ファイルをスキーマと照合し、不明なキーや型の合わない値を警告します。
+問題がある場合は終了コード2で終了します。`,
+		"cmd.config.explain.flag.json": "JSON形式で出力します",
+		"cmd.config.migrate.short":     "設定ファイルを現在の形式に変換します",
+		"cmd.config.migrate.long": `以前の形式の設定ファイルを現在の形式(apiVersion, kind)に変換して保存します。
+変換前のファイルは名前に.bakを付けて残します。設定ファイルの他の設定はそのまま残します。
+--dry-runを指定した場合は変換後の設定ファイルを表示するだけで、ファイルは変更しません。`,
+		"cmd.config.migrate.current": "%sはすでに現在の形式(%s)です",
+		"cmd.config.migrate.done":    "%sを%sに変換しました(バックアップ: %s)",
+		"cmd.config.using":           "設定ファイル%sを利用します",
+		"cmd.config.legacy":          "%sは以前の形式です。todo config migrateで現在の形式に変換してください",
+		"cmd.config.saved":           "%sを%sに保存しました",
+		"cmd.config.unset":           "%sを%sから削除しました",
+		ConfigArgsInvalid:            "キーと値を指定してください(例: todo config set retry.max 5, todo config unset retry.max)",
+		ConfigKeyUnknown:             "不明な設定のキーです: %s",
+		ConfigKeyNotSettable:         "%sはconfig setで設定できません。設定ファイルを直接編集してください",
+		ConfigValueInvalid:           "%sの値が正しくありません(%s): %v",
+		ConfigKeyNotSet:              "%sは設定ファイル%sにありません",
+		ConfigFileInvalid:            "設定ファイル%sに%d件の問題があります",
+		ConfigFileNotFound:           "設定ファイル%sがありません",
+		ConfigHeaderNotSettable:      "%sは設定できません。設定ファイルを現在の形式に変換する場合はtodo config migrateを実行してください",
+		"cmd.template.short":         "タスクのテンプレートを表示します",
+		"cmd.template.long": `繰り返し作成するタスクを記述したテンプレートを表示します。
+テンプレートは$XDG_CONFIG_HOME/todo/templates(設定ファイルのtemplate_dirで変更可能)に<名前>.yamlとして置きます。
+タスクの名前と概要にはGoのtext/templateのプレースホルダを記述できます。
+  {{.Date}}          展開した日付(2006-01-02の形式)
+  {{.Now}}           展開した日時({{.Now.Format "01/02"}}のように書式を指定できます)
+  {{.Args.service}}  --set service=apiで指定したパラメータ
+paramsにパラメータを記述した場合は、それ以外のパラメータを受け付けません。既定値が空のパラメータは指定が必須です。
+
+例(handover.yaml):
+description: 週次の当番の引き継ぎ
+params:
+  service: ""
+  owner: oncall
+tasks:
+- title: "{{.Args.service}}のアラートを確認する({{.Date}})"
+- title: "{{.Args.service}}の引き継ぎメモを書く"
+  description: "担当: {{.Args.owner}}"
+
+todo create --template handover --set service=apiでテンプレートのタスクをまとめて作成します。
+タスクを作成する前にすべてのタスクを展開するため、テンプレートに誤りがあればタスクを1件も作成しません。`,
+		"cmd.template.list.short":       "テンプレートの一覧を表示します",
+		"cmd.template.list.long":        "テンプレートの名前、タスクの件数、説明を表示します。読み込めないテンプレートは警告を出力します。",
+		"cmd.template.show.short":       "テンプレートの内容を表示します",
+		"cmd.template.show.long":        "テンプレートのファイルの内容を表示します。--renderを指定した場合は、--setのパラメータと現在の日時で展開したタスクを表示します。",
+		"cmd.template.show.flag.render": "テンプレートを展開したタスクを表示します",
+		"cmd.template.flag.set":         "テンプレートのパラメータ(KEY=VALUEの形式、複数指定可)",
+		TemplateArgsInvalid:             "テンプレートの名前を指定してください(例: todo template show handover)",
+		TemplateSetInvalid:              "--setにはKEY=VALUEの形式で指定してください: %s",
+		"flag.task.tag":                 "タスクのタグ(,区切りまたは複数指定可。--tag=で削除)",
+		"flag.task.priority":            "タスクの優先度(low/medium/high。空の値で削除)",
+		"flag.task.due":                 "タスクの期限(2006-01-02またはRFC3339の日時。空の値で削除)",
+		"cmd.get.flag.tag":              "指定したすべてのタグを持つタスクに絞り込みます",
+		"cmd.get.flag.priority":         "指定した優先度のタスクに絞り込みます",
+		"cmd.get.flag.due-before":       "期限が指定した日付(または日時)以前のタスクに絞り込みます",
+		"flag.lang":                     "メッセージの言語(ja/en)。指定しない場合はLC_ALL, LC_MESSAGES, LANGから判断します",
+		"cmd.help_flag":                 "%sのヘルプを表示します",
+	})
+}
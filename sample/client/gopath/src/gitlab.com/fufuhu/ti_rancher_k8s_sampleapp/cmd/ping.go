@@ -15,21 +15,56 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
 
 	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
 
 	"github.com/spf13/cobra"
 )
 
+// PingCountInvalid は送信する回数に負の値が指定された場合のエラーメッセージです。
+const PingCountInvalid service.MessageID = "cmd.ping.count_invalid"
+
+// PingIntervalInvalid は送信の間隔や応答を待つ時間に負の値が指定された場合のエラーメッセージです。
+const PingIntervalInvalid service.MessageID = "cmd.ping.interval_invalid"
+
+// PingNoReply はToDoサーバから一度も応答が得られなかった場合のエラーメッセージです。
+const PingNoReply service.MessageID = "cmd.ping.no_reply"
+
+// PingReply はping-pong APIの呼び出し1回分の結果です。
+type PingReply struct {
+	Seq     int     `json:"seq"`               // 何回目の呼び出しか(1から数える)
+	OK      bool    `json:"ok"`                // 応答が得られたかどうか
+	Message string  `json:"message,omitempty"` // ToDoサーバが返したメッセージ
+	RTT     float64 `json:"rtt_ms"`            // 応答までの時間(ミリ秒)
+	Error   string  `json:"error,omitempty"`   // 失敗した場合のエラー
+}
+
+// PingReport はpingの結果の全体です。--jsonを指定した場合はこの内容を出力します。
+type PingReport struct {
+	Target      string      `json:"target"`
+	Replies     []PingReply `json:"replies"`
+	Transmitted int         `json:"transmitted"`  // 送信した回数
+	Received    int         `json:"received"`     // 応答が得られた回数
+	Loss        float64     `json:"loss_percent"` // 応答が得られなかった割合(%)
+	Min         float64     `json:"min_ms"`       // 応答までの時間の最小値(ミリ秒)
+	Avg         float64     `json:"avg_ms"`       // 平均値(ミリ秒)
+	Max         float64     `json:"max_ms"`       // 最大値(ミリ秒)
+	StdDev      float64     `json:"stddev_ms"`    // 標準偏差(ミリ秒)
+}
+
 // pingCmd represents the ping command
 var pingCmd = &cobra.Command{
 	Use:   "ping",
-	Short: "ping-poing APIを使ってToDoサーバの起動を確認します",
-	Long: `ToDoサーバに対してping-pong APIを使ったアクセス確認を行うことで
-ToDoサーバへの通信の疎通を確認します。`,
-	Run: ping,
+	Short: "cmd.ping.short",
+	Long:  "cmd.ping.long",
+	RunE:  ping,
 	// Run: func(cmd *cobra.Command, args []string) {
 	// 	fmt.Println("ping called")
 	// },
@@ -47,23 +82,146 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// pingCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	pingCmd.Flags().IntP("count", "c", 1, "cmd.ping.flag.count")
+	pingCmd.Flags().DurationP("interval", "i", time.Second, "cmd.ping.flag.interval")
+	pingCmd.Flags().Duration("timeout", 5*time.Second, "cmd.ping.flag.timeout")
+	pingCmd.Flags().Bool("until-ready", false, "cmd.ping.flag.until-ready")
+	pingCmd.Flags().Bool("json", false, "cmd.ping.flag.json")
 }
 
-func ping(cmd *cobra.Command, args []string) {
+func ping(cmd *cobra.Command, args []string) error {
 
 	protocol, err := clientSetting.Protocol()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	host, err := clientSetting.Host()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	port, err := clientSetting.Port()
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	count, _ := cmd.Flags().GetInt("count")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	untilReady, _ := cmd.Flags().GetBool("until-ready")
+	asJSON, _ := cmd.Flags().GetBool("json")
+	if count < 0 {
+		return usageError(PingCountInvalid)
+	}
+	if interval < 0 || timeout < 0 {
+		return usageError(PingIntervalInvalid)
+	}
+	// --until-readyの場合、回数の指定が無ければ応答が得られるまで繰り返します。
+	if untilReady && !cmd.Flags().Changed("count") {
+		count = 0
+	}
+
+	defer limitRequests(timeout)()
+
+	// Ctrl-Cで中断した場合も、それまでの結果の統計を出力します。
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+
+	report := PingReport{Target: protocol + "://" + net.JoinHostPort(host, strconv.Itoa(port)) + "/api/ping"}
+	if !asJSON {
+		fmt.Println(service.T("cmd.ping.header", report.Target))
+	}
+
+	var lastErr error
+	var samples []time.Duration
+loop:
+	for seq := 1; count == 0 || seq <= count; seq++ {
+		if seq > 1 {
+			select {
+			case <-time.After(interval):
+			case <-interrupted:
+				break loop
+			}
+		}
+
+		result, err := service.Ping(protocol, host, port)
+		reply := PingReply{Seq: seq, OK: err == nil, Message: result.Pong.Message, RTT: milliseconds(result.RTT)}
+		if err != nil {
+			reply.Error = err.Error()
+			lastErr = err
+		} else {
+			samples = append(samples, result.RTT)
+		}
+		report.Replies = append(report.Replies, reply)
+
+		if !asJSON {
+			if reply.OK {
+				fmt.Println(service.T("cmd.ping.reply", reply.Seq, reply.Message, roundDuration(result.RTT)))
+			} else {
+				fmt.Println(service.T("cmd.ping.reply_failure", reply.Seq, reply.Error))
+			}
+		}
+		if reply.OK && untilReady {
+			break
+		}
 	}
 
-	pongMessage := service.RequestPing(protocol, host, port)
-	fmt.Println(pongMessage.Message)
+	report.summarize(samples)
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printPingSummary(report)
+	}
+
+	// ping(8)と同様に、一度でも応答が得られれば正常に終了します。
+	if report.Received > 0 {
+		return nil
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+	return service.NewError(service.ErrorNetwork, PingNoReply)
+}
+
+// summarize は応答までの時間から統計を求めます。
+func (r *PingReport) summarize(samples []time.Duration) {
+	r.Transmitted = len(r.Replies)
+	r.Received = len(samples)
+	if r.Transmitted > 0 {
+		r.Loss = float64(r.Transmitted-r.Received) * 100 / float64(r.Transmitted)
+	}
+	summary := service.SummarizeLatency(samples)
+	r.Min, r.Avg, r.Max, r.StdDev = milliseconds(summary.Min), milliseconds(summary.Avg), milliseconds(summary.Max), milliseconds(summary.StdDev)
+}
+
+// printPingSummary はping(8)と同様の形式で統計を出力します。
+func printPingSummary(report PingReport) {
+	fmt.Println()
+	fmt.Println(service.T("cmd.ping.statistics", report.Target))
+	fmt.Println(service.T("cmd.ping.summary", report.Transmitted, report.Received, report.Loss))
+	if report.Received > 0 {
+		fmt.Printf("rtt min/avg/max/stddev = %.3f/%.3f/%.3f/%.3f ms\n", report.Min, report.Avg, report.Max, report.StdDev)
+	}
+}
+
+// milliseconds は時間をミリ秒の単位の値に変換します。
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// limitRequests は障害をそのまま報告するため、再試行を行わず、応答を待つ時間をtimeoutに制限します(0の場合は制限しません)。
+// 戻り値の関数を呼び出すと元の設定に戻します。
+func limitRequests(timeout time.Duration) func() {
+	retry, client := service.Retry, service.HTTPClient
+	service.Retry.MaxRetries = 0
+	limited := *client
+	limited.Timeout = timeout
+	service.HTTPClient = &limited
+	return func() {
+		service.Retry, service.HTTPClient = retry, client
+	}
 }
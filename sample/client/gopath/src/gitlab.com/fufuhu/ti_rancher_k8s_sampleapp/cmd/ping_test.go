@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestPingExitCode ではToDoサーバの状態に応じて、exec probeに利用できる終了コードで終了することを確認する。
+func TestPingExitCode(t *testing.T) {
+	requests := 0
+	ready := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"message": "pong"}`))
+	}))
+	defer server.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedArgs := serverArgs(closed)
+	closed.Close()
+
+	cases := []struct {
+		name     string
+		ready    int
+		args     []string
+		code     int
+		requests int
+	}{
+		{"ok", 0, append([]string{"ping", "-c", "3", "-i", "0"}, serverArgs(server)...), ExitOK, 3},
+		{"some replies", 2, append([]string{"ping", "-c", "3", "-i", "0"}, serverArgs(server)...), ExitOK, 3},
+		{"no reply", 3, append([]string{"ping", "-c", "3", "-i", "0"}, serverArgs(server)...), ExitServer, 3},
+		{"until ready", 4, append([]string{"ping", "--until-ready", "-i", "0"}, serverArgs(server)...), ExitOK, 5},
+		{"until ready with count", 4, append([]string{"ping", "--until-ready", "-c", "2", "-i", "0", "--json"}, serverArgs(server)...), ExitServer, 2},
+		{"network", 0, append([]string{"ping", "-i", "0"}, closedArgs...), ExitNetwork, 0},
+		{"invalid count", 0, append([]string{"ping", "-c", "-1"}, serverArgs(server)...), ExitUsage, 0},
+	}
+	for _, c := range cases {
+		requests, ready = 0, c.ready
+		if code := executeForTest(c.args...); code != c.code {
+			t.Errorf("%s: exit code = %d, want %d", c.name, code, c.code)
+		}
+		if requests != c.requests {
+			t.Errorf("%s: requests = %d, want %d", c.name, requests, c.requests)
+		}
+	}
+}
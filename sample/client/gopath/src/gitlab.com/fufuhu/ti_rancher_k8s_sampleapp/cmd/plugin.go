@@ -0,0 +1,319 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// PluginPrefix はプラグインとして扱う実行ファイルの名前の接頭辞です。
+// todo <名前>で組み込みのサブコマンドが見つからない場合は、PATHからtodo-<名前>を探して実行します。
+const PluginPrefix = "todo-"
+
+// PluginHandshakeVersion はプラグインに渡すハンドシェイクの形式のバージョンです。
+const PluginHandshakeVersion = 1
+
+// プラグインに解決済みの設定を渡す環境変数です。
+// 設定を読み込む環境変数と同じ名前のため、プラグインからtodoを実行した場合も同じ接続先と認証トークンを利用します。
+const (
+	EnvPluginName      = "TODO_PLUGIN_NAME"
+	EnvPluginServerURL = "TODO_SERVER_URL"
+	EnvPluginProtocol  = "TODO_PROTOCOL"
+	EnvPluginHost      = "TODO_HOST"
+	EnvPluginPort      = "TODO_PORT"
+	EnvPluginToken     = "TODO_TOKEN"
+	EnvPluginContext   = "TODO_CONTEXT"
+	EnvPluginConfig    = EnvConfig
+	EnvPluginLang      = "TODO_LANG"
+	EnvPluginDryRun    = "TODO_DRY_RUN"
+	// EnvPluginHandshake は標準入力の先頭でハンドシェイクを渡す場合に"stdin"が設定されます。
+	EnvPluginHandshake = "TODO_PLUGIN_HANDSHAKE"
+)
+
+// PluginExecFailure はプラグインを起動できなかった場合のエラーメッセージです。
+const PluginExecFailure service.MessageID = "cmd.plugin.exec_failure"
+
+// PluginHandshake はプラグインに渡す解決済みの接続先と認証情報です。
+// 設定(plugin.handshake)でプラグインの名前を指定した場合は、JSONにして標準入力の先頭の1行で渡します。
+type PluginHandshake struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	ServerURL string `json:"server_url"`
+	Protocol  string `json:"protocol"`
+	Host      string `json:"host"`
+	Port      int    `json:"port"`
+	Token     string `json:"token,omitempty"`
+	Context   string `json:"context"`
+	Config    string `json:"config"`
+	Lang      string `json:"lang"`
+	DryRun    bool   `json:"dry_run"`
+}
+
+// environ はハンドシェイクの内容を環境変数の形式で返します。
+// 認証トークンが無い場合は空の値で上書きしないよう、TODO_TOKENを含めません。
+func (h PluginHandshake) environ() []string {
+	env := []string{
+		EnvPluginName + "=" + h.Name,
+		EnvPluginServerURL + "=" + h.ServerURL,
+		EnvPluginProtocol + "=" + h.Protocol,
+		EnvPluginHost + "=" + h.Host,
+		EnvPluginPort + "=" + strconv.Itoa(h.Port),
+		EnvPluginContext + "=" + h.Context,
+		EnvPluginConfig + "=" + h.Config,
+		EnvPluginLang + "=" + h.Lang,
+		EnvPluginDryRun + "=" + strconv.FormatBool(h.DryRun),
+	}
+	if h.Token != "" {
+		env = append(env, EnvPluginToken+"="+h.Token)
+	}
+	return env
+}
+
+// Plugin はPATHから見つけたプラグインです。
+type Plugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// ShadowedBy はPATHで先に見つかった同じ名前のプラグインのパスです。
+	ShadowedBy string `json:"shadowed_by,omitempty"`
+	// Builtin は組み込みのサブコマンドと同じ名前のため実行されないかどうかです。
+	Builtin bool `json:"builtin,omitempty"`
+}
+
+// pluginCmd represents the plugin command
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "cmd.plugin.short",
+	Long:  "cmd.plugin.long",
+}
+
+// pluginListCmd represents the plugin list command
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "cmd.plugin.list.short",
+	Long:  "cmd.plugin.list.long",
+	RunE:  pluginList,
+}
+
+func init() {
+	rootCmd.AddCommand(pluginCmd)
+	pluginCmd.AddCommand(pluginListCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// pluginCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// pluginCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	pluginListCmd.Flags().Bool("json", false, "cmd.plugin.list.flag.json")
+}
+
+func pluginList(cmd *cobra.Command, args []string) error {
+	plugins := findPlugins(filepath.SplitList(os.Getenv("PATH")))
+	for _, plugin := range plugins {
+		if plugin.Builtin {
+			logger.Warn(service.T("cmd.plugin.shadows_builtin", plugin.Path, plugin.Name))
+		}
+		if plugin.ShadowedBy != "" {
+			logger.Warn(service.T("cmd.plugin.shadowed", plugin.Path, plugin.ShadowedBy))
+		}
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		if plugins == nil {
+			plugins = []Plugin{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plugins)
+	}
+
+	if len(plugins) == 0 {
+		logger.Info(service.T("cmd.plugin.none"))
+		return nil
+	}
+	for _, plugin := range plugins {
+		fmt.Println(plugin.Path)
+	}
+	return nil
+}
+
+// findPlugins はPATHのディレクトリの順にtodo-<名前>の実行ファイルを探します。
+// 同じ名前のプラグインは先に見つかったものだけが実行されるため、後のものにはShadowedByを設定します。
+func findPlugins(dirs []string) []Plugin {
+	builtins := builtinCommandNames()
+	found := map[string]string{}
+	var plugins []Plugin
+	for _, dir := range dirs {
+		if dir == "" {
+			dir = "."
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		// ReadDirは名前の順に返します。
+		for _, file := range files {
+			name := strings.TrimPrefix(file.Name(), PluginPrefix)
+			if name == file.Name() || name == "" || !isExecutable(filepath.Join(dir, file.Name())) {
+				continue
+			}
+			plugin := Plugin{Name: name, Path: filepath.Join(dir, file.Name()), ShadowedBy: found[name], Builtin: builtins[name]}
+			if plugin.ShadowedBy == "" {
+				found[name] = plugin.Path
+			}
+			plugins = append(plugins, plugin)
+		}
+	}
+	return plugins
+}
+
+// isExecutable はパスが実行可能な通常のファイル(シンボリックリンクの場合はリンク先)かどうかを返します。
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
+
+// builtinCommandNames は組み込みのサブコマンドの名前と別名を返します。
+// Cobraが実行時に追加するhelpとcompletionも含めます。
+func builtinCommandNames() map[string]bool {
+	names := map[string]bool{"help": true, "completion": true}
+	for _, c := range rootCmd.Commands() {
+		names[c.Name()] = true
+		for _, alias := range c.Aliases {
+			names[alias] = true
+		}
+	}
+	return names
+}
+
+// pluginFromArgs はコマンドライン引数のサブコマンドが組み込みのものでなく、
+// PATHにプラグインがある場合に、プラグインのパスとその前後の引数を返します。
+// サブコマンドより前の引数はtodoのオプションとして、後の引数はプラグインの引数として扱います。
+func pluginFromArgs(args []string) (string, string, []string, []string, bool) {
+	i := positionalIndex(args, rootCmd.PersistentFlags())
+	if i < 0 || builtinCommandNames()[args[i]] || strings.ContainsAny(args[i], `/\`) {
+		return "", "", nil, nil, false
+	}
+	path, err := exec.LookPath(PluginPrefix + args[i])
+	if err != nil {
+		return "", "", nil, nil, false
+	}
+	return args[i], path, args[:i], args[i+1:], true
+}
+
+// runPlugin はプラグインより前の引数をtodoのオプションとして解決した設定を渡してプラグインを実行し、その終了コードを返します。
+func runPlugin(name string, path string, globalArgs []string, pluginArgs []string) int {
+	commandStarted = true
+	handshake, err := resolvePluginHandshake(name, globalArgs)
+	if err != nil {
+		logger.Error(err.Error())
+		return exitCode(err)
+	}
+
+	plugin := exec.Command(path, pluginArgs...)
+	plugin.Env = append(os.Environ(), handshake.environ()...)
+	plugin.Stdin = os.Stdin
+	plugin.Stdout = os.Stdout
+	plugin.Stderr = os.Stderr
+	if pluginHandshakeEnabled(name) {
+		line, err := json.Marshal(handshake)
+		if err != nil {
+			logger.Error(err.Error())
+			return ExitFailure
+		}
+		plugin.Stdin = io.MultiReader(bytes.NewReader(append(line, '\n')), os.Stdin)
+		plugin.Env = append(plugin.Env, EnvPluginHandshake+"=stdin")
+	}
+
+	err = plugin.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.ExitStatus() > 0 {
+			return status.ExitStatus()
+		}
+		return ExitFailure
+	}
+	if err != nil {
+		err = service.WrapError(service.ErrorUnknown, PluginExecFailure, err, path)
+		logger.Error(err.Error())
+		return exitCode(err)
+	}
+	return ExitOK
+}
+
+// resolvePluginHandshake はtodoのオプションと設定ファイルからプラグインに渡す接続先と認証情報を解決します。
+// 複数のエンドポイントを利用する場合は、現在のエンドポイントの名前をコンテキストとして渡します。
+func resolvePluginHandshake(name string, globalArgs []string) (PluginHandshake, error) {
+	handshake := PluginHandshake{Version: PluginHandshakeVersion, Name: name, Context: "default"}
+	if err := rootCmd.PersistentFlags().Parse(globalArgs); err != nil {
+		return handshake, usageError(UsageInvalid, err)
+	}
+	initConfig()
+	if err := applyServiceSettings(); err != nil {
+		return handshake, err
+	}
+
+	protocol, err := clientSetting.Protocol()
+	if err != nil {
+		return handshake, err
+	}
+	host, err := clientSetting.Host()
+	if err != nil {
+		return handshake, err
+	}
+	port, err := clientSetting.Port()
+	if err != nil {
+		return handshake, err
+	}
+	// 認証トークンを必要としないプラグインもあるため、見つからなくてもエラーにしません。
+	token, _ := clientSetting.Token()
+	handshake.Protocol, handshake.Host, handshake.Port, handshake.Token = activeTarget(protocol, host, port, token)
+	if service.Endpoints != nil && service.Endpoints.Current().Name != "" {
+		handshake.Context = service.Endpoints.Current().Name
+	}
+
+	handshake.ServerURL = handshake.Protocol + "://" + net.JoinHostPort(handshake.Host, strconv.Itoa(handshake.Port))
+	handshake.Config = viper.ConfigFileUsed()
+	handshake.Lang = service.Language()
+	handshake.DryRun = service.DryRun
+	return handshake, nil
+}
+
+// pluginHandshakeEnabled は設定ファイル(plugin.handshake)で標準入力によるハンドシェイクを指定したプラグインかどうかを返します。
+func pluginHandshakeEnabled(name string) bool {
+	for _, handshake := range viper.GetStringSlice("plugin.handshake") {
+		if handshake == name {
+			return true
+		}
+	}
+	return false
+}
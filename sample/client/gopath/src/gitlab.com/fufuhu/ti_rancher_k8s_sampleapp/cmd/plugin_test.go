@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// writePluginForTest はディレクトリに実行ファイル(シェルスクリプト)を作成する。
+func writePluginForTest(t *testing.T, dir string, name string, script string, mode os.FileMode) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestFindPlugins ではPATHの順にプラグインを探し、実行されないプラグインを判別できることを確認する。
+func TestFindPlugins(t *testing.T) {
+	root, err := ioutil.TempDir("", "plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	first, second := filepath.Join(root, "first"), filepath.Join(root, "second")
+	os.Mkdir(first, 0755)
+	os.Mkdir(second, 0755)
+
+	report := writePluginForTest(t, first, "todo-report", "", 0755)
+	shadowed := writePluginForTest(t, second, "todo-report", "", 0755)
+	get := writePluginForTest(t, second, "todo-get", "", 0755)
+	writePluginForTest(t, second, "todo-notexec", "", 0644)
+	writePluginForTest(t, second, "kubectl-todo", "", 0755)
+	os.Mkdir(filepath.Join(second, "todo-dir"), 0755)
+
+	got := findPlugins([]string{first, filepath.Join(root, "missing"), second})
+	want := []Plugin{
+		{Name: "report", Path: report},
+		{Name: "get", Path: get, Builtin: true},
+		{Name: "report", Path: shadowed, ShadowedBy: report},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findPlugins() = %+v, want %+v", got, want)
+	}
+}
+
+// TestRunPlugin では未知のサブコマンドでプラグインが実行され、解決済みの設定と引数、終了コードが引き渡されることを確認する。
+func TestRunPlugin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "output")
+	writePluginForTest(t, dir, "todo-report", `
+handshake=""
+if [ "$TODO_PLUGIN_HANDSHAKE" = "stdin" ]; then
+  read -r handshake
+fi
+echo "$TODO_SERVER_URL $TODO_TOKEN $TODO_CONTEXT $TODO_CONFIG $*" > "`+output+`"
+echo "$handshake" >> "`+output+`"
+exit $1
+`, 0755)
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	run := func(args ...string) (int, []string) {
+		defer resetCommandsForTest()
+		os.Remove(output)
+		code := execute(append([]string{"--config", DefaultTestFilename + ".yaml", "--quiet", "--lang", "ja", "--host", "todo-server"}, args...))
+		content, _ := ioutil.ReadFile(output)
+		return code, strings.Split(strings.TrimSpace(string(content)), "\n")
+	}
+
+	// プラグインより後の引数はtodoのオプションと同じ名前でもプラグインに渡す
+	code, lines := run("report", "3", "--host", "other")
+	if code != 3 || lines[0] != "https://todo-server:1000 test_token default test_config.yaml 3 --host other" {
+		t.Errorf("exit code = %d, output = %q", code, lines)
+	}
+
+	viper.Set("plugin.handshake", []string{"report"})
+	code, lines = run("report", "0")
+	want := `{"version":1,"name":"report","server_url":"https://todo-server:1000","protocol":"https","host":"todo-server","port":1000,"token":"test_token","context":"default","config":"test_config.yaml","lang":"ja","dry_run":false}`
+	if code != ExitOK || len(lines) != 2 || lines[1] != want {
+		t.Errorf("exit code = %d, output = %q", code, lines)
+	}
+
+	// プラグインが無い場合は未知のサブコマンドとして扱う
+	if code := executeForTest("missing-plugin"); code != ExitUsage {
+		t.Errorf("missing plugin: exit code = %d", code)
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// SettingSource は設定の値がどこから得られたかを表します。
+type SettingSource struct {
+	// Kind は値の出どころの種類(flag/env/config/discovery/default)です。
+	Kind string `json:"kind"`
+	// Detail はオプションの名前、環境変数の名前、設定ファイルのパスとキーなどです。
+	Detail string `json:"detail,omitempty"`
+}
+
+// 設定の値の出どころの種類です。
+const (
+	SourceFlag      = "flag"
+	SourceEnv       = "env"
+	SourceConfig    = "config"
+	SourceDiscovery = "discovery"
+	SourceDefault   = "default"
+)
+
+// String は出どころを表示用の文字列で返します。
+func (s SettingSource) String() string {
+	if s.Detail == "" {
+		return s.Kind
+	}
+	return s.Kind + " (" + s.Detail + ")"
+}
+
+// settingSources はClientSettingの各設定を最後に解決した際の値の出どころです。
+// config explainで表示するため、各設定の読み込み時にnoteSourceで記録します。
+var settingSources = struct {
+	sync.Mutex
+	m map[string]SettingSource
+}{m: map[string]SettingSource{}}
+
+// noteSource は設定の値の出どころを記録します。
+func noteSource(key string, source SettingSource) {
+	settingSources.Lock()
+	defer settingSources.Unlock()
+	settingSources.m[key] = source
+}
+
+// sourceOf は記録された設定の値の出どころを返します。
+func sourceOf(key string) (SettingSource, bool) {
+	settingSources.Lock()
+	defer settingSources.Unlock()
+	source, ok := settingSources.m[key]
+	return source, ok
+}
+
+// flagSource はコマンドラインオプションを出どころとして返します。
+func flagSource(name string) SettingSource {
+	return SettingSource{Kind: SourceFlag, Detail: "--" + name}
+}
+
+// envSource は環境変数を出どころとして返します。
+func envSource(name string) SettingSource {
+	return SettingSource{Kind: SourceEnv, Detail: name}
+}
+
+// configSource は設定ファイルのキーを出どころとして返します。
+// viperは対応する環境変数(bindEnvを参照)を設定ファイルより優先するため、環境変数がある場合はそちらを返します。
+func configSource(key string) SettingSource {
+	if name := configEnvName(key); os.Getenv(name) != "" {
+		return envSource(name)
+	}
+	return SettingSource{Kind: SourceConfig, Detail: viper.ConfigFileUsed() + ": " + key}
+}
+
+// configEnvName は設定ファイルのキーに対応する環境変数の名前を返します。
+// 大文字にして.を_に置き換え、TODO_を付けます(retry.maxの場合はTODO_RETRY_MAX)。
+func configEnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+// envName はコンテキストやサービスの名前を環境変数の名前に使える形(大文字、英数字以外は_)に変換します。
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// discoverySource は環境から見つけた接続先を出どころとして返します。
+func discoverySource(discovery ServerDiscovery) SettingSource {
+	return SettingSource{Kind: SourceDiscovery, Detail: discovery.Source}
+}
+
+// defaultSource は既定の値を出どころとして返します。
+func defaultSource() SettingSource {
+	return SettingSource{Kind: SourceDefault}
+}
@@ -0,0 +1,308 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// RemindNotifyFailure はデスクトップ通知のコマンドが失敗した場合のエラーメッセージです。
+// コマンドの名前とコマンドの出力を埋め込みます。
+const RemindNotifyFailure service.MessageID = "cmd.remind.notify_failure"
+
+// RemindWebhookStatusUnexpected はWebhookのURLが2xx以外のステータスコードを返した場合のエラーメッセージです。
+const RemindWebhookStatusUnexpected service.MessageID = "cmd.remind.webhook_status_unexpected"
+
+// remindWebhookTimeout はWebhookのURLへのPOSTの応答を待つ時間です。
+var remindWebhookTimeout = 10 * time.Second
+
+// remindCmd represents the remind command
+var remindCmd = &cobra.Command{
+	Use:   "remind",
+	Short: "cmd.remind.short",
+	Long:  "cmd.remind.long",
+}
+
+// remindDaemonCmd represents the remind daemon command
+var remindDaemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "cmd.remind.daemon.short",
+	Long:  "cmd.remind.daemon.long",
+	RunE:  remindDaemon,
+}
+
+func init() {
+	rootCmd.AddCommand(remindCmd)
+	remindCmd.AddCommand(remindDaemonCmd)
+
+	remindDaemonCmd.Flags().Duration("interval", 5*time.Minute, "cmd.remind.daemon.flag.interval")
+	remindDaemonCmd.Flags().Duration("before", 24*time.Hour, "cmd.remind.daemon.flag.before")
+	remindDaemonCmd.Flags().StringSlice("sink", []string{ReminderSinkStdout}, "cmd.remind.daemon.flag.sink")
+	remindDaemonCmd.Flags().String("notify-command", DefaultNotifyCommand, "cmd.remind.daemon.flag.notify-command")
+	remindDaemonCmd.Flags().String("webhook-url", "", "cmd.remind.daemon.flag.webhook-url")
+	remindDaemonCmd.Flags().Bool("once", false, "cmd.remind.daemon.flag.once")
+}
+
+func remindDaemon(cmd *cobra.Command, args []string) error {
+	interval, err := remindSetting.Interval()
+	if err != nil {
+		return err
+	}
+	before, err := remindSetting.Before()
+	if err != nil {
+		return err
+	}
+	contexts, err := remindSetting.Contexts()
+	if err != nil {
+		return err
+	}
+	sinks, err := newReminderSinks()
+	if err != nil {
+		return err
+	}
+	path, err := remindSetting.StateFile()
+	if err != nil {
+		return err
+	}
+	log, err := service.LoadReminderLog(path)
+	if err != nil {
+		return err
+	}
+
+	// コンテキストごとにToDoサーバを明示するため振り分けは行わず、
+	// 次の確認までに終わるよう応答を待つ時間を確認の間隔に制限します。
+	failover := service.Endpoints
+	service.Endpoints = nil
+	defer func() {
+		service.Endpoints = failover
+	}()
+	defer limitRequests(interval)()
+
+	daemon := &reminderDaemon{contexts: contexts, before: before, sinks: sinks, log: log, now: time.Now}
+	if once, _ := cmd.Flags().GetBool("once"); once {
+		return daemon.check()
+	}
+	logger.Info(service.T("cmd.remind.started"), "interval", interval, "before", before, "contexts", len(contexts), "state_file", path)
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		daemon.run(interval, done)
+		close(finished)
+	}()
+
+	// Ctrl-CやSIGTERMで、確認中の通知の送信と記録を終えてから終了します。
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	sig := <-stop
+	logger.Info(service.T("cmd.remind.stopping"), "signal", sig)
+	close(done)
+	<-finished
+	return nil
+}
+
+// newReminderSinks は設定された送信先の一覧から通知の送信先を作成します。
+func newReminderSinks() ([]reminderSink, error) {
+	names, err := remindSetting.Sinks()
+	if err != nil {
+		return nil, err
+	}
+	var sinks []reminderSink
+	for _, name := range names {
+		switch name {
+		case ReminderSinkStdout:
+			sinks = append(sinks, writerSink{w: os.Stdout})
+		case ReminderSinkNotify:
+			command, err := remindSetting.NotifyCommand()
+			if err != nil {
+				return nil, err
+			}
+			if len(command) == 0 {
+				command = []string{DefaultNotifyCommand}
+			}
+			sinks = append(sinks, commandSink{command: command})
+		case ReminderSinkWebhook:
+			webhook, err := remindSetting.WebhookURL()
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, webhookSink{url: webhook, client: &http.Client{Timeout: remindWebhookTimeout}})
+		}
+	}
+	return sinks, nil
+}
+
+// reminderDaemon はToDoサーバのタスクを定期的に取得し、期限が近いタスクと期限を過ぎたタスクを通知します。
+type reminderDaemon struct {
+	contexts []ServerContext
+	before   time.Duration // 期限のどれだけ前から通知するか
+	sinks    []reminderSink
+	log      *service.ReminderLog
+	now      func() time.Time // 現在の時刻(テストでは差し替えます)
+}
+
+// run はdoneが閉じられるまでintervalごとに期限を確認します。最初の確認はすぐに行います。
+func (d *reminderDaemon) run(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// 失敗はcheckContextで警告として出力し、次の確認で改めて取得します。
+		d.check()
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// check はすべてのコンテキストのタスクの期限を確認し、送信済みの通知の記録を保存します。
+// タスクを取得できなかったコンテキストがあった場合は、最後のエラーを返します。
+func (d *reminderDaemon) check() error {
+	now := d.now()
+	var lastErr error
+	for _, c := range d.contexts {
+		if err := d.checkContext(c, now); err != nil {
+			lastErr = err
+		}
+	}
+	if err := d.log.Save(); err != nil {
+		logger.Warn(service.T("cmd.remind.save_failure"), "state_file", d.log.Path, "error", err)
+	}
+	return lastErr
+}
+
+// checkContext は実際のgetコマンドと同じservice.GetTasksでタスクを取得し、まだ送信していない通知を送信します。
+// 送信に失敗した通知先は記録しないため、次の確認で送信しなおします。
+func (d *reminderDaemon) checkContext(c ServerContext, now time.Time) error {
+	var tasks []service.Task
+	protocol, host, port, err := c.Target()
+	if err == nil {
+		tasks, err = service.GetTasks(protocol, host, port, c.Token)
+	}
+	if err != nil {
+		logger.Warn(service.T("cmd.remind.check_failure"), "context", c.Name, "error", err)
+		return err
+	}
+
+	reminders := service.FindReminders(c.Name, tasks, now, d.before)
+	for _, reminder := range reminders {
+		for _, sink := range d.sinks {
+			if d.log.Sent(reminder, sink.name()) {
+				continue
+			}
+			if err := sink.send(reminder); err != nil {
+				logger.Warn(service.T("cmd.remind.send_failure"), "sink", sink.name(), "context", c.Name, "id", reminder.TaskID, "error", err)
+				continue
+			}
+			d.log.Record(reminder, sink.name(), now)
+		}
+	}
+	d.log.Prune(c.Name, reminders)
+	return nil
+}
+
+// reminderSink は通知の送信先です。
+type reminderSink interface {
+	name() string
+	send(reminder service.Reminder) error
+}
+
+// writerSink は通知の文面を1行ずつ出力します。
+type writerSink struct {
+	w io.Writer
+}
+
+func (s writerSink) name() string {
+	return ReminderSinkStdout
+}
+
+func (s writerSink) send(reminder service.Reminder) error {
+	_, err := fmt.Fprintln(s.w, reminder.Message())
+	return err
+}
+
+// commandSink は通知の文面を最後の引数としてコマンド(notify-sendなど)を実行します。
+// 独自のスクリプトで扱えるよう、通知の内容を環境変数(TODO_REMINDER_*)でも渡します。
+type commandSink struct {
+	command []string
+}
+
+func (s commandSink) name() string {
+	return ReminderSinkNotify
+}
+
+func (s commandSink) send(reminder service.Reminder) error {
+	notify := exec.Command(s.command[0], append(s.command[1:], reminder.Message())...)
+	notify.Env = append(os.Environ(),
+		"TODO_REMINDER_CONTEXT="+reminder.Context,
+		"TODO_REMINDER_KIND="+reminder.Kind,
+		"TODO_REMINDER_TASK_ID="+strconv.Itoa(reminder.TaskID),
+		"TODO_REMINDER_TITLE="+reminder.Title,
+		"TODO_REMINDER_DUE="+reminder.Due,
+	)
+	if output, err := notify.CombinedOutput(); err != nil {
+		return service.WrapError(service.ErrorUnknown, RemindNotifyFailure, err, s.command[0], strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// webhookSink は通知をJSONでWebhookのURLにPOSTします。
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// reminderPayload はWebhookに送信する通知です。
+type reminderPayload struct {
+	service.Reminder
+	Message string `json:"message"` // 通知の文面
+}
+
+func (s webhookSink) name() string {
+	return ReminderSinkWebhook
+}
+
+func (s webhookSink) send(reminder service.Reminder) error {
+	body, err := json.Marshal(reminderPayload{Reminder: reminder, Message: reminder.Message()})
+	if err != nil {
+		return err
+	}
+	res, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return service.NewError(service.ErrorUnknown, RemindWebhookStatusUnexpected, res.StatusCode)
+	}
+	return nil
+}
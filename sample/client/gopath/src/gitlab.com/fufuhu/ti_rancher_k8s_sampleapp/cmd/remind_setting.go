@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// RemindSetting はremind daemonの動作に関する設定を格納します。
+// コマンドラインオプション、環境変数、設定ファイルの順に値を探します。
+type RemindSetting struct {
+	// Interval タスクの期限を確認する間隔
+	Interval func() (time.Duration, error)
+	// Before 期限のどれだけ前から通知するか
+	Before func() (time.Duration, error)
+	// Sinks 通知の送信先(stdout/notify/webhook)
+	Sinks func() ([]string, error)
+	// NotifyCommand デスクトップ通知に利用するコマンドと引数
+	NotifyCommand func() ([]string, error)
+	// WebhookURL 通知をPOSTするURL
+	WebhookURL func() (string, error)
+	// StateFile 送信済みの通知を記録するファイル
+	StateFile func() (string, error)
+	// Contexts 期限を確認するToDoサーバと認証トークンの一覧
+	Contexts func() ([]ServerContext, error)
+}
+
+// 通知の送信先です。
+const (
+	ReminderSinkStdout  = "stdout"  // 標準出力
+	ReminderSinkNotify  = "notify"  // デスクトップ通知のコマンド
+	ReminderSinkWebhook = "webhook" // WebhookのURLへのPOST
+)
+
+// DefaultNotifyCommand はデスクトップ通知に利用するコマンドの既定値です。
+const DefaultNotifyCommand = "notify-send"
+
+// SettingRemindIntervalInvalid は確認の間隔に正でない値が指定された場合のエラーメッセージです。
+const SettingRemindIntervalInvalid service.MessageID = "settings.remind_interval_invalid"
+
+// SettingRemindBeforeInvalid は期限の前に通知する時間に負の値が指定された場合のエラーメッセージです。
+const SettingRemindBeforeInvalid service.MessageID = "settings.remind_before_invalid"
+
+// SettingRemindSinkInvalid は通知の送信先にstdout/notify/webhook以外が指定された場合のエラーメッセージです。
+const SettingRemindSinkInvalid service.MessageID = "settings.remind_sink_invalid"
+
+// SettingRemindWebhookInvalid はwebhookに送信するのにURLが指定されていない、または不正な場合のエラーメッセージです。
+const SettingRemindWebhookInvalid service.MessageID = "settings.remind_webhook_invalid"
+
+// SettingRemindContextInvalid はremindのコンテキストの指定が不正な場合のエラーメッセージです。
+const SettingRemindContextInvalid service.MessageID = "settings.remind_context_invalid"
+
+// remindの設定を読み込む環境変数です。間隔などは設定ファイルのキーに対応する環境変数(TODO_REMIND_INTERVALなど)で指定します。
+const (
+	// EnvRemindContexts は"名前=URL"をカンマで区切って並べたコンテキストの一覧です。
+	EnvRemindContexts = "TODO_REMIND_CONTEXTS"
+	// EnvRemindToken はEnvRemindContextsで指定したコンテキストに共通する認証トークンです。
+	// コンテキストごとの認証トークンはTODO_REMIND_TOKEN_<名前を大文字にしたもの>で指定します。
+	EnvRemindToken = "TODO_REMIND_TOKEN"
+)
+
+var remindSetting RemindSetting
+
+// remindDuration はコマンドラインオプション、環境変数、設定ファイルの順に時間の設定を読み込みます。
+func remindDuration(flag string, key string) (time.Duration, error) {
+	flags := remindDaemonCmd.Flags()
+	if flags.Changed(flag) {
+		return flags.GetDuration(flag)
+	}
+	if value := os.Getenv(configEnvName(key)); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, service.NewError(service.ErrorUsage, SettingEnvInvalid, configEnvName(key), value)
+		}
+		return d, nil
+	}
+	if viper.IsSet(key) {
+		return viper.GetDuration(key), nil
+	}
+	return flags.GetDuration(flag)
+}
+
+func init() {
+
+	// Interval コマンドラインオプション(--interval)、環境変数(TODO_REMIND_INTERVAL)、
+	// 設定ファイル(remind.interval)の順にタスクの期限を確認する間隔を読み込む。
+	// いずれも指定が無い場合は5分ごとに確認します。
+	remindSetting.Interval = func() (time.Duration, error) {
+		interval, err := remindDuration("interval", "remind.interval")
+		if err != nil {
+			return 0, err
+		}
+		if interval <= 0 {
+			return 0, service.NewError(service.ErrorUsage, SettingRemindIntervalInvalid)
+		}
+		return interval, nil
+	}
+
+	// Before コマンドラインオプション(--before)、環境変数(TODO_REMIND_BEFORE)、
+	// 設定ファイル(remind.before)の順に期限のどれだけ前から通知するかを読み込む。
+	// いずれも指定が無い場合は期限の24時間前から通知します。0の場合は期限を過ぎたタスクだけを通知します。
+	remindSetting.Before = func() (time.Duration, error) {
+		before, err := remindDuration("before", "remind.before")
+		if err != nil {
+			return 0, err
+		}
+		if before < 0 {
+			return 0, service.NewError(service.ErrorUsage, SettingRemindBeforeInvalid)
+		}
+		return before, nil
+	}
+
+	// Sinks コマンドラインオプション(--sink)、設定ファイル(remind.sinks)の順に通知の送信先を読み込む。
+	// いずれも指定が無い場合は標準出力に出力します。
+	remindSetting.Sinks = func() ([]string, error) {
+		sinks, err := remindDaemonCmd.Flags().GetStringSlice("sink")
+		if err != nil {
+			return nil, err
+		}
+		if !remindDaemonCmd.Flags().Changed("sink") && viper.IsSet("remind.sinks") {
+			sinks = viper.GetStringSlice("remind.sinks")
+		}
+		for _, sink := range sinks {
+			switch sink {
+			case ReminderSinkStdout, ReminderSinkNotify, ReminderSinkWebhook:
+			default:
+				return nil, service.NewError(service.ErrorUsage, SettingRemindSinkInvalid, sink)
+			}
+		}
+		return sinks, nil
+	}
+
+	// NotifyCommand コマンドラインオプション(--notify-command)、環境変数(TODO_REMIND_NOTIFY_COMMAND)、
+	// 設定ファイル(remind.notify_command)の順にデスクトップ通知に利用するコマンドを読み込む。
+	// 空白で区切った文字列、または設定ファイルでは引数の一覧で指定します。指定が無い場合はnotify-sendを利用します。
+	remindSetting.NotifyCommand = func() ([]string, error) {
+		command, err := remindDaemonCmd.Flags().GetString("notify-command")
+		if err != nil {
+			return nil, err
+		}
+		if !remindDaemonCmd.Flags().Changed("notify-command") && viper.IsSet("remind.notify_command") {
+			return viper.GetStringSlice("remind.notify_command"), nil
+		}
+		return strings.Fields(command), nil
+	}
+
+	// WebhookURL コマンドラインオプション(--webhook-url)、環境変数(TODO_REMIND_WEBHOOK_URL)、
+	// 設定ファイル(remind.webhook_url)の順に通知をPOSTするURLを読み込む。
+	remindSetting.WebhookURL = func() (string, error) {
+		webhook, err := remindDaemonCmd.Flags().GetString("webhook-url")
+		if err != nil {
+			return "", err
+		}
+		if !remindDaemonCmd.Flags().Changed("webhook-url") {
+			webhook = viper.GetString("remind.webhook_url")
+		}
+		u, err := url.Parse(webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", service.NewError(service.ErrorUsage, SettingRemindWebhookInvalid, webhook)
+		}
+		return webhook, nil
+	}
+
+	// StateFile 設定ファイル(remind.state_file)から送信済みの通知を記録するファイルを読み込む
+	// 指定が無い場合は$HOME/.todo/remind.jsonを利用します。
+	remindSetting.StateFile = func() (string, error) {
+		if path := viper.GetString("remind.state_file"); path != "" {
+			return path, nil
+		}
+		home, err := homedir.Dir()
+		if err != nil {
+			logger.Warn(err.Error())
+			return "", err
+		}
+		return filepath.Join(home, ".todo", "remind.json"), nil
+	}
+
+	// Contexts 環境変数(TODO_REMIND_CONTEXTS)、設定ファイル(remind.contexts)の順に
+	// 期限を確認するコンテキストを読み込む。いずれも指定が無い場合は、
+	// 他のサブコマンドと同じ設定(--protocol, --host, --portと認証トークン)をdefaultという名前で利用します。
+	remindSetting.Contexts = func() ([]ServerContext, error) {
+		return loadContexts(EnvRemindContexts, EnvRemindToken, "remind.contexts", SettingRemindContextInvalid)
+	}
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// TestReminderDaemon では期限が近いタスクと期限を過ぎたタスクを一度だけ通知し、
+// 送信に失敗した通知先と、再起動後に記録を読み込んだ場合の振る舞いを確認する。
+func TestReminderDaemon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "remind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "remind.json")
+
+	todo := &smoketestServer{tasks: map[int]service.Task{
+		1: {ID: 1, Title: "deploy", Status: "TODO", Description: "---\ntodo: {due: \"2019-04-01T12:00:00Z\"}\n---\n"},
+		2: {ID: 2, Title: "report", Status: "RUNNING", Description: "---\ntodo: {due: \"2019-03-31T00:00:00Z\"}\n---\n"},
+		3: {ID: 3, Title: "plan", Status: "TODO", Description: "---\ntodo: {due: \"2019-05-01T00:00:00Z\"}\n---\n"},
+	}, nextID: 4}
+	server := httptest.NewServer(todo)
+	defer server.Close()
+
+	// Webhookは最初の1回だけ失敗する
+	var mu sync.Mutex
+	var posted []reminderPayload
+	failures := 1
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var payload reminderPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		posted = append(posted, payload)
+	}))
+	defer webhook.Close()
+
+	now := time.Date(2019, 4, 1, 9, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	newDaemon := func() *reminderDaemon {
+		log, err := service.LoadReminderLog(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &reminderDaemon{
+			contexts: []ServerContext{{Name: "prod", URL: server.URL, Token: "token"}, {Name: "down", URL: "http://127.0.0.1:1"}},
+			before:   6 * time.Hour,
+			sinks:    []reminderSink{writerSink{w: &out}, webhookSink{url: webhook.URL, client: http.DefaultClient}},
+			log:      log,
+			now:      func() time.Time { return now },
+		}
+	}
+	defer limitRequests(time.Second)()
+
+	daemon := newDaemon()
+	if err := daemon.check(); err == nil {
+		t.Error("check() must report the context that could not be checked")
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "report") || !strings.Contains(lines[1], "deploy") {
+		t.Errorf("output = %q", out.String())
+	}
+	if len(posted) != 1 || posted[0].TaskID != 1 || posted[0].Kind != service.ReminderDueSoon || posted[0].Message == "" {
+		t.Errorf("posted = %+v", posted)
+	}
+
+	// 送信済みの通知は繰り返さず、失敗したWebhookにだけ送信しなおす
+	out.Reset()
+	daemon.check()
+	if out.Len() != 0 || len(posted) != 2 || posted[1].TaskID != 2 || posted[1].Kind != service.ReminderOverdue {
+		t.Errorf("output = %q, posted = %+v", out.String(), posted)
+	}
+
+	// 再起動しても記録を引き継ぐ。期限を過ぎたタスクは改めて通知し、完了したタスクは通知しない
+	now = now.Add(4 * time.Hour)
+	task := todo.tasks[2]
+	task.Status = service.TaskStatusFinished
+	todo.tasks[2] = task
+	daemon = newDaemon()
+	daemon.check()
+	if got := out.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, "deploy") || strings.Contains(got, "report") {
+		t.Errorf("output after restart = %q", got)
+	}
+	// 期限が近いという通知の記録は、期限を過ぎた時点で取り除く
+	if len(daemon.log.Contexts["prod"]) != 2 {
+		t.Errorf("log = %+v", daemon.log.Contexts)
+	}
+}
+
+// TestReminderCommandSink では通知の文面を最後の引数として、通知の内容を環境変数で渡してコマンドを実行することを確認する。
+func TestReminderCommandSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "remind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "notified")
+	script := filepath.Join(dir, "notify")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$1 $TODO_REMINDER_TASK_ID $TODO_REMINDER_KIND $2\" > "+output+"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	reminder := service.Reminder{Context: "prod", Kind: service.ReminderOverdue, TaskID: 7, Title: "report", Due: "2019-03-31"}
+	if err := (commandSink{command: []string{script, "-u"}}).send(reminder); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "-u 7 overdue " + reminder.Message() + "\n"; string(got) != want {
+		t.Errorf("notified = %q, want %q", got, want)
+	}
+
+	if err := (commandSink{command: []string{"false"}}).send(reminder); !service.IsMessage(err, RemindNotifyFailure) {
+		t.Errorf("send() = %v, want %s", err, RemindNotifyFailure)
+	}
+}
+
+// TestRemindDaemonSettings では通知の送信先などの指定の誤りを終了コード2とすることを確認する。
+func TestRemindDaemonSettings(t *testing.T) {
+	for _, args := range [][]string{
+		{"remind", "daemon", "--once", "--sink", "mail"},
+		{"remind", "daemon", "--once", "--sink", "webhook"},
+		{"remind", "daemon", "--once", "--sink", "webhook", "--webhook-url", "todo.example.com/hook"},
+		{"remind", "daemon", "--once", "--interval", "0s"},
+		{"remind", "daemon", "--once", "--before", "-1h"},
+	} {
+		if code := executeForTest(args...); code != ExitUsage {
+			t.Errorf("%v: exit code = %d, want %d", args, code, ExitUsage)
+		}
+	}
+}
@@ -15,39 +15,91 @@
 package cmd
 
 import (
-	"fmt"
-	"log"
+	"bytes"
 	"os"
+	"path/filepath"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
 )
 
+// UsageInvalid はサブコマンドやコマンドラインオプションを解釈できなかった場合のエラーメッセージです。
+const UsageInvalid service.MessageID = "cmd.usage_invalid"
+
 var cfgFile string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "todo-client",
-	Short: "ToDoアプリケーションのクライアント用アプリケーションです",
-	Long: `マルチテナント型ToDoアプリケーション用クライアントアプリケーションのサンプル実装です。
-Cobraを用いてCLIの実装を行っています。`,
+	Short: "cmd.root.short",
+	Long:  "cmd.root.long",
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		commandStarted = true
+		return applyServiceSettings()
+	},
+	// エラーはExecuteでLoggerを使って出力します。
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
+// commandStarted はサブコマンドの実行まで進んだかどうかを表します。
+// 未知のサブコマンドなど、Cobraが検出したエラーを指定の誤りとして扱うために利用します。
+var commandStarted bool
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	os.Exit(execute(os.Args[1:]))
+}
+
+// execute は引数を指定してコマンドを実行し、終了コードを返します。
+// サブコマンドが返したエラーはここでまとめて出力し、原因の種類に応じた終了コードに変換します。
+func execute(args []string) int {
+	// ヘルプはサブコマンドの実行前に出力されるため、--lang(TODO_LANG)の指定だけは先に反映します。
+	lang := flagFromArgs(args, "lang")
+	if lang == "" {
+		lang = os.Getenv(configEnvName("lang"))
+	}
+	if lang != "" {
+		service.SetLanguage(lang)
 	}
+	localizeCommands(rootCmd)
+
+	// 設定ファイルのエイリアスと既定のオプションはCobraが引数を解釈する前に反映します。
+	args, err := resolveArgs(args)
+	if err != nil {
+		logger.Error(err.Error())
+		return exitCode(err)
+	}
+
+	// 組み込みのサブコマンドが無い場合はPATHのプラグイン(todo-<名前>)を実行します。
+	if name, path, globalArgs, pluginArgs, ok := pluginFromArgs(args); ok {
+		return runPlugin(name, path, globalArgs, pluginArgs)
+	}
+
+	commandStarted = false
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+	if err == nil {
+		return ExitOK
+	}
+	if !commandStarted && service.KindOf(err) == service.ErrorUnknown {
+		err = usageError(UsageInvalid, err)
+	}
+	logger.Error(err.Error())
+	return exitCode(err)
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError(UsageInvalid, err)
+	})
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
@@ -57,43 +109,112 @@ func init() {
 	// この部分の取扱をsettings.goに寄せるかはよく考えること
 	homeDir, err := homedir.Dir()
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
-	defaultConfigPath := homeDir + "/.todo_config.yaml"
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", defaultConfigPath, "config file (default is $HOME/.todo_config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", defaultConfigPath(homeDir), "config file (default is $XDG_CONFIG_HOME/todo/config.yaml, or $HOME/.todo_config.yaml if only that exists)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
-	rootCmd.PersistentFlags().String("protocol", "", "ToDoサーバにアクセスする際のプロトコル")
-	rootCmd.PersistentFlags().String("host", "", "ToDoサーバのホスト名/IPアドレス")
-	rootCmd.PersistentFlags().Int("port", 0, "ToDoサーバのポート番号")
+	rootCmd.PersistentFlags().String("protocol", "", "flag.protocol")
+	rootCmd.PersistentFlags().String("host", "", "flag.host")
+	rootCmd.PersistentFlags().Int("port", 0, "flag.port")
+	rootCmd.PersistentFlags().String("log-level", "info", "flag.log-level")
+	rootCmd.PersistentFlags().String("log-format", service.LogFormatText, "flag.log-format")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "flag.quiet")
+	rootCmd.PersistentFlags().IntP("verbosity", "v", 0, "flag.verbosity")
+	rootCmd.PersistentFlags().String("trace-file", "", "flag.trace-file")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "flag.dry-run")
+	rootCmd.PersistentFlags().String("lang", "", "flag.lang")
+
+	// 一時的な障害に対する再試行の設定(設定ファイルのretry.*より優先されます)
+	rootCmd.PersistentFlags().Int("retry-max", service.DefaultRetryPolicy.MaxRetries, "flag.retry-max")
+	rootCmd.PersistentFlags().Duration("retry-base-delay", service.DefaultRetryPolicy.BaseDelay, "flag.retry-base-delay")
+	rootCmd.PersistentFlags().Duration("retry-max-delay", service.DefaultRetryPolicy.MaxDelay, "flag.retry-max-delay")
+	rootCmd.PersistentFlags().Bool("retry-post", false, "flag.retry-post")
+}
+
+// EnvXDGConfigHome はXDG Base Directoryの設定ファイルを置くディレクトリを指定する環境変数です。
+const EnvXDGConfigHome = "XDG_CONFIG_HOME"
+
+// LegacyConfigName は以前の既定の設定ファイルの名前です。ホームディレクトリに置きます。
+const LegacyConfigName = ".todo_config.yaml"
+
+// configDir はXDG Base Directoryの設定ファイルなどを置くディレクトリ($XDG_CONFIG_HOME/todo)を返します。
+// $XDG_CONFIG_HOMEが無い場合は$HOME/.config/todoを返します。
+func configDir(home string) string {
+	configHome := os.Getenv(EnvXDGConfigHome)
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "todo")
+}
+
+// defaultConfigPath は既定の設定ファイルのパスを返します。
+// 環境変数TODO_CONFIGが無い場合は$XDG_CONFIG_HOME/todo/config.yaml($XDG_CONFIG_HOMEが無い場合は$HOME/.config/todo/config.yaml)を使い、
+// それが無く以前の場所の$HOME/.todo_config.yamlだけがある場合はそちらを使います。
+func defaultConfigPath(home string) string {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path
+	}
+	path := filepath.Join(configDir(home), "config.yaml")
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	legacy := filepath.Join(home, LegacyConfigName)
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy
+	}
+	return path
+}
+
+// configFileState はinitConfigで読み込んだ設定ファイルの状態です。
+// Loggerの設定前に読み込むため、メッセージやエラーはapplyServiceSettingsで出力します。
+var configFileState struct {
+	Found  bool  // 設定ファイルがあるかどうか
+	Legacy bool  // 以前の形式のため、メモリ上で現在の形式に変換したかどうか
+	Err    error // 設定ファイルを解釈できなかった場合のエラー
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := homedir.Dir()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+	path := cfgFile
+	if path == "" {
+		path = rootCmd.PersistentFlags().Lookup("config").DefValue
+	}
+	viper.SetConfigFile(path)
+	viper.SetConfigType("yaml")
+
+	// read in environment variables that match (TODO_HOST, TODO_RETRY_MAX, ...)
+	bindEnv()
+
+	readConfigFile(path)
+}
 
-		// Search config in home directory with name ".ti_rancher_k8s_sampleapp" (without extension).
-		viper.AddConfigPath(home)
-		// viper.SetConfigName(".ti_rancher_k8s_sampleapp")
-		viper.SetConfigName(".todo_config")
+// readConfigFile は設定ファイルを読み込み、以前の形式の場合は現在の形式に変換してからviperに渡します。
+// 不明なキーや型の合わない値は、設定ファイルの行番号を含むエラーとしてconfigFileStateに記録します。
+func readConfigFile(path string) {
+	configFileState.Found, configFileState.Legacy, configFileState.Err = false, false, nil
+	if _, err := os.Stat(path); err != nil {
+		return
 	}
+	configFileState.Found = true
 
-	viper.AutomaticEnv() // read in environment variables that match
+	file, err := service.LoadConfigFile(path)
+	if err != nil {
+		configFileState.Err = service.WrapError(service.ErrorUsage, service.ConfigDecodeFailure, err, path)
+		return
+	}
+	configFileState.Legacy, err = file.Migrate()
+	if err == nil {
+		_, err = file.Decode()
+	}
+	configFileState.Err = err
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	content, err := file.Marshal()
+	if err != nil {
+		return
 	}
+	viper.ReadConfig(bytes.NewReader(content))
 }
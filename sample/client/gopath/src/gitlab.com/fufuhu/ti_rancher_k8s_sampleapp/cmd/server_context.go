@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// ServerContext はexporterやremindが接続するToDoサーバと利用者の組み合わせです。
+type ServerContext struct {
+	Name  string `yaml:"name" json:"name"`   // メトリクスのcontextラベルや通知に利用する名前
+	URL   string `yaml:"url" json:"url"`     // ToDoサーバのURL(例: http://todo-server:8000)
+	Token string `yaml:"token" json:"token"` // 認証トークン
+}
+
+// Target はToDoサーバのURLをプロトコル・ホスト・ポート番号に分解して返します。
+func (c ServerContext) Target() (string, string, int, error) {
+	return service.Endpoint{Name: c.Name, URL: c.URL}.Target()
+}
+
+// parseContexts は"名前=URL"をカンマで区切って並べたコンテキストの一覧を解釈します。
+// 認証トークンは<tokenEnv>_<名前を大文字にしたもの>、または<tokenEnv>から読み込みます。
+func parseContexts(value string, tokenEnv string, invalid service.MessageID, getenv func(string) string) ([]ServerContext, error) {
+	var contexts []ServerContext
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.Index(item, "=")
+		if i <= 0 {
+			return nil, service.NewError(service.ErrorUsage, invalid, item)
+		}
+		context := ServerContext{Name: item[:i], URL: item[i+1:]}
+		context.Token = getenv(tokenEnv + "_" + envName(context.Name))
+		if context.Token == "" {
+			context.Token = getenv(tokenEnv)
+		}
+		contexts = append(contexts, context)
+	}
+	return contexts, nil
+}
+
+// loadContexts は環境変数(contextsEnv)、設定ファイル(key)の順にコンテキストの一覧を読み込みます。
+// いずれも指定が無い場合は、他のサブコマンドと同じ設定(--protocol, --host, --portと認証トークン)をdefaultという名前で利用します。
+func loadContexts(contextsEnv string, tokenEnv string, key string, invalid service.MessageID) ([]ServerContext, error) {
+	var contexts []ServerContext
+	if value := os.Getenv(contextsEnv); value != "" {
+		var err error
+		if contexts, err = parseContexts(value, tokenEnv, invalid, os.Getenv); err != nil {
+			return nil, err
+		}
+	} else if viper.IsSet(key) {
+		if err := viper.UnmarshalKey(key, &contexts); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(contexts) == 0 {
+		protocol, err := clientSetting.Protocol()
+		if err != nil {
+			return nil, err
+		}
+		host, err := clientSetting.Host()
+		if err != nil {
+			return nil, err
+		}
+		port, err := clientSetting.Port()
+		if err != nil {
+			return nil, err
+		}
+		token, _ := clientSetting.Token()
+		contexts = append(contexts, ServerContext{Name: "default", URL: protocol + "://" + net.JoinHostPort(host, strconv.Itoa(port)), Token: token})
+	}
+
+	names := map[string]bool{}
+	for _, context := range contexts {
+		if _, _, _, err := context.Target(); err != nil || context.Name == "" || names[context.Name] {
+			return nil, service.NewError(service.ErrorUsage, invalid, context.Name)
+		}
+		names[context.Name] = true
+	}
+	return contexts, nil
+}
@@ -1,10 +1,13 @@
 package cmd
 
 import (
-	"errors"
-	"log"
+	"os"
+	"path/filepath"
+	"strconv"
 
+	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
 )
 
 // ClientSetting はToDoクライアントが
@@ -22,156 +25,734 @@ type ClientSetting struct {
 	Password func() (string, error)
 	// クライアントがサーバにアクセスする際の認証トークン(JWT)
 	Token func() (string, error)
+	// 更新系のリクエストを送信せずに内容の表示だけを行うかどうか
+	DryRun func() (bool, error)
+	// 操作ジャーナルを保管するディレクトリ
+	JournalDir func() (string, error)
+	// タスクのテンプレートを置くディレクトリ
+	TemplateDir func() (string, error)
+	// 一時的な障害でリクエストが失敗した場合の再試行の方針
+	RetryPolicy func() (service.RetryPolicy, error)
+	// KubernetesのPod内で実行された場合に環境から見つけた接続先と認証情報
+	Discovery func() (ServerDiscovery, error)
+	// 複数のエンドポイントを優先度の順に利用する場合の振り分けの設定(利用しない場合はnil)
+	Failover func() (*service.Failover, error)
+	// 設定ファイルのendpointsのうち利用するエンドポイントの名前(defaultの場合はすべてを利用する)
+	Context func() (string, error)
+	// 接続先のエンドポイントやHTTP通信の内容を出力する詳細さ(0の場合は出力しない)
+	Verbosity func() (int, error)
+	// HTTP通信の内容をHAR形式で記録するファイルのパス(空の場合は記録しない)
+	TraceFile func() (string, error)
+	// ログの出力レベル(--quietが指定された場合はエラーのみ)
+	LogLevel func() (service.LogLevel, error)
+	// ログの出力形式(text/json)
+	LogFormat func() (string, error)
+	// メッセージの言語(ja/en)
+	Language func() (string, error)
 }
 
 // SettingErrorMessageUsernameNotFound はユーザ名がusernameオプションで定義
 // されていない場合に発生するエラーに含まれるエラーメッセージです。
-const SettingErrorMessageUsernameNotFound = "ユーザ名情報が設定されていません。"
+const SettingErrorMessageUsernameNotFound service.MessageID = "settings.username_not_found"
 
 // SettingErrorMessagePasswordNotFound はパスワードがpassswordオプションで定義
 // されていない場合に発生するエラーに含まれるエラーメッセージです。
-const SettingErrorMessagePasswordNotFound = "パスワード情報が指定されていません。"
+const SettingErrorMessagePasswordNotFound service.MessageID = "settings.password_not_found"
 
 // SettingErrorMessageTokenNotFound はJWTによる認証トークンが設定ファイルから
 // 取得できない場合に発生するエラーに含まれるエラーメッセージです。
-const SettingErrorMessageTokenNotFound = "トークン情報が見つかりません。"
+const SettingErrorMessageTokenNotFound service.MessageID = "settings.token_not_found"
+
+// SettingErrorMessageTokenInvalid は認証トークンが利用できない場合に
+// 再取得を促すエラーメッセージです。
+const SettingErrorMessageTokenInvalid service.MessageID = "settings.token_invalid"
+
+// SettingRetryMaxInvalid は再試行の最大回数に負の値が指定された場合の
+// エラーに含まれるエラーメッセージです。
+const SettingRetryMaxInvalid service.MessageID = "settings.retry_max_invalid"
+
+// SettingFailureThresholdInvalid はサーキットブレーカーを開くまでの失敗の回数に
+// 1未満の値が指定された場合のエラーに含まれるエラーメッセージです。
+const SettingFailureThresholdInvalid service.MessageID = "settings.failure_threshold_invalid"
+
+// SettingServerInvalid は環境変数(TODO_SERVER)にToDoサーバのURLとして解釈できない値が指定された場合の
+// エラーに含まれるエラーメッセージです。
+const SettingServerInvalid service.MessageID = "settings.server_invalid"
+
+// SettingContextNotFound は設定ファイルのendpointsに無いコンテキストが指定された場合の
+// エラーに含まれるエラーメッセージです。
+const SettingContextNotFound service.MessageID = "settings.context_not_found"
+
+// SettingEnvInvalid は環境変数の値を設定の型に変換できない場合のエラーに含まれるエラーメッセージです。
+const SettingEnvInvalid service.MessageID = "settings.env_invalid"
+
+// EnvPrefix は設定を読み込む環境変数の名前の接頭辞です。
+// コンテナの環境にあるHOSTやPORTなど、無関係な環境変数で接続先が変わらないよう、この接頭辞の付いたものだけを読み込みます。
+const EnvPrefix = "TODO_"
+
+// 設定ファイルのキーに対応しない設定を読み込む環境変数です。
+const (
+	// EnvServer はToDoサーバのURLです。protocol, host, portをまとめて指定します。
+	EnvServer = "TODO_SERVER"
+	// EnvConfig は--configを指定しない場合に読み込む設定ファイルのパスです。
+	EnvConfig = "TODO_CONFIG"
+)
+
+// DefaultContext は設定ファイルのendpointsをすべて利用する場合のコンテキストの名前です。
+const DefaultContext = "default"
+
+// DryRunMessage はドライランのため更新系の処理を行わなかったことを知らせるメッセージです。
+const DryRunMessage service.MessageID = "settings.dry_run"
 
 var clientSetting ClientSetting
 
+// applyServiceSettings はコマンドラインオプションや設定ファイルから得られた
+// serviceパッケージの振る舞いに関する設定を反映します。
+// 各サブコマンドの実行前にルートコマンドのPersistentPreRunEから呼び出されます。
+func applyServiceSettings() error {
+	// 以降の設定の読み込みで発生したエラーも指定された言語と形式で出力できるよう、最初に言語とLoggerを設定します。
+	lang, err := clientSetting.Language()
+	if err != nil {
+		return err
+	}
+	service.SetLanguage(lang)
+
+	level, err := clientSetting.LogLevel()
+	if err != nil {
+		return err
+	}
+	format, err := clientSetting.LogFormat()
+	if err != nil {
+		return err
+	}
+	streamLogger, err := service.NewStreamLogger(os.Stderr, level, format)
+	if err != nil {
+		return err
+	}
+	logger = streamLogger
+	service.SetLogger(streamLogger)
+
+	if configFileState.Found {
+		logger.Debug(service.T("cmd.config.using", viper.ConfigFileUsed()))
+	}
+	if configFileState.Err != nil {
+		return configFileState.Err
+	}
+	if configFileState.Legacy {
+		logger.Info(service.T("cmd.config.legacy", viper.ConfigFileUsed()))
+	}
+
+	dryRun, err := clientSetting.DryRun()
+	if err != nil {
+		return err
+	}
+	service.DryRun = dryRun
+
+	retry, err := clientSetting.RetryPolicy()
+	if err != nil {
+		return err
+	}
+	service.Retry = retry
+
+	verbosity, err := clientSetting.Verbosity()
+	if err != nil {
+		return err
+	}
+	traceFile, err := clientSetting.TraceFile()
+	if err != nil {
+		return err
+	}
+	var har *service.HARRecorder
+	if traceFile != "" {
+		har = service.NewHARRecorder(traceFile)
+	}
+	service.EnableTracing(verbosity, os.Stderr, har)
+
+	failover, err := clientSetting.Failover()
+	if err != nil {
+		return err
+	}
+	if failover != nil {
+		if verbosity >= service.VerbosityEndpoint {
+			failover.Verbose = os.Stderr
+		}
+		if err := failover.LoadState(); err != nil {
+			logger.Warn(service.T("cmd.settings.state_load_failure"), "error", err)
+		}
+	}
+	service.Endpoints = failover
+	return nil
+}
+
+// bindEnv は設定ファイルの各キーを、TODO_を付けた環境変数(retry.maxの場合はTODO_RETRY_MAX)に対応付けます。
+// 一覧を値に持つキー(endpointsなど)と設定ファイルのヘッダ(apiVersion, kind)は環境変数では指定できません。
+func bindEnv() {
+	for _, k := range configSchema {
+		if k.Type != configList && k.Type != configHeader {
+			viper.BindEnv(k.Key, configEnvName(k.Key))
+		}
+	}
+}
+
+// endpointsFromConfig は設定ファイル(endpoints)から複数のエンドポイントの設定を読み込みます。
+// コンテキストが指定されている場合は、その名前のエンドポイントだけを返します。
+func endpointsFromConfig() ([]service.Endpoint, error) {
+	var endpoints []service.Endpoint
+	if !viper.IsSet("endpoints") {
+		return endpoints, nil
+	}
+	if err := viper.UnmarshalKey("endpoints", &endpoints); err != nil {
+		return nil, err
+	}
+
+	context, err := clientSetting.Context()
+	if err != nil || context == DefaultContext {
+		return endpoints, err
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.Name == context {
+			return []service.Endpoint{endpoint}, nil
+		}
+	}
+	return nil, service.NewError(service.ErrorUsage, SettingContextNotFound, context)
+}
+
+// serverTarget は環境変数(TODO_SERVER)で指定されたToDoサーバのURLをプロトコル・ホスト・ポート番号に分解して返します。
+// 指定が無い場合は空のプロトコルを返します。
+func serverTarget() (string, string, int, error) {
+	server := os.Getenv(EnvServer)
+	if server == "" {
+		return "", "", 0, nil
+	}
+	protocol, host, port, err := service.Endpoint{URL: server}.Target()
+	if err != nil {
+		return "", "", 0, service.NewError(service.ErrorUsage, SettingServerInvalid, EnvServer, server)
+	}
+	return protocol, host, port, nil
+}
+
+// flagOrEnv はコマンドラインオプションだけで指定する設定について、オプションが指定されていない場合は
+// 環境変数の値を返し、値の出どころを記録します。いずれも指定が無い場合はokにfalseを返します。
+func flagOrEnv(key string, flag string) (string, bool) {
+	if rootCmd.PersistentFlags().Changed(flag) {
+		noteSource(key, flagSource(flag))
+		return "", false
+	}
+	name := configEnvName(key)
+	if value := os.Getenv(name); value != "" {
+		noteSource(key, envSource(name))
+		return value, true
+	}
+	noteSource(key, defaultSource())
+	return "", false
+}
+
+// primaryEndpointTarget は設定ファイルに複数のエンドポイントが定義されている場合に、
+// 最も優先度の高いエンドポイントのプロトコル・ホスト・ポート番号を返します。
+func primaryEndpointTarget() (string, string, int, bool) {
+	endpoints, err := endpointsFromConfig()
+	if err != nil || len(endpoints) == 0 {
+		return "", "", 0, false
+	}
+	failover, err := service.NewFailover(endpoints)
+	if err != nil {
+		return "", "", 0, false
+	}
+	protocol, host, port, err := failover.Endpoints[0].Target()
+	return protocol, host, port, err == nil
+}
+
 func init() {
 
-	// clientSetting.Protocol 設定ファイルおよびコマンドラインオプション(--protocol)
-	// からToDoサーバにアクセスする際のプロトコル(http or https)でアクセスします。
-	// 指定が何も無い場合はhttpでアクセスします。
+	// clientSetting.Protocol コマンドラインオプション(--protocol)、環境変数(TODO_SERVER, TODO_PROTOCOL)、
+	// 設定ファイルの順にToDoサーバにアクセスする際のプロトコル(http or https)を読み込みます。
+	// 指定が何も無い場合は環境から見つけた接続先、それも無ければhttpでアクセスします。
 	clientSetting.Protocol = func() (string, error) {
 		var protocol string
 
-		// 設定ファイルからの読み込み
-		if protocolFromConfig := viper.GetString("protocol"); protocolFromConfig != "" {
+		serverProtocol, _, _, err := serverTarget()
+		if err != nil {
+			return "", err
+		}
+
+		// 環境変数(TODO_SERVER)および設定ファイルからの読み込み
+		if serverProtocol != "" {
+			protocol = serverProtocol
+			noteSource("protocol", envSource(EnvServer))
+		} else if protocolFromConfig := viper.GetString("protocol"); protocolFromConfig != "" {
 			protocol = protocolFromConfig
+			noteSource("protocol", configSource("protocol"))
+		} else if protocolFromEndpoint, _, _, ok := primaryEndpointTarget(); ok {
+			protocol = protocolFromEndpoint
+			noteSource("protocol", configSource("endpoints"))
+		} else {
+			discovery, err := discoveredTarget()
+			if err != nil {
+				return "", err
+			}
+			protocol = discovery.Protocol
+			noteSource("protocol", discoverySource(discovery))
 		}
 
 		// コマンドオプションからの読み込み
 		protocolFromOption, err := rootCmd.PersistentFlags().GetString("protocol")
 		if err != nil {
-			log.Println(err)
+			logger.Warn(err.Error())
 		}
 
 		if protocolFromOption != "" {
 			protocol = protocolFromOption
+			noteSource("protocol", flagSource("protocol"))
 		}
 
 		// いずれの場合も値が得られなければデフォルトの値(http)を設定する。
 		if protocol == "" {
 			protocol = "http"
+			noteSource("protocol", defaultSource())
 		}
 		return protocol, err
 	}
 
-	// clientSetting.Host 設定ファイルおよびコマンドラインオプション(--host)から
-	// ToDoサーバのFQDNを取得します。指定が何も無い場合は"127.0.0.1"を利用します。
+	// clientSetting.Host コマンドラインオプション(--host)、環境変数(TODO_SERVER, TODO_HOST)、
+	// 設定ファイルの順にToDoサーバのFQDNを取得します。指定が何も無い場合は環境から見つけた接続先、
+	// それも無ければ"127.0.0.1"を利用します。
 	clientSetting.Host = func() (string, error) {
 
 		var host string
 
-		// 設定ファイルからの読み込み
-		if hostFromConfig := viper.GetString("host"); hostFromConfig != "" {
+		_, serverHost, _, err := serverTarget()
+		if err != nil {
+			return "", err
+		}
+
+		// 環境変数(TODO_SERVER)および設定ファイルからの読み込み
+		if serverHost != "" {
+			host = serverHost
+			noteSource("host", envSource(EnvServer))
+		} else if hostFromConfig := viper.GetString("host"); hostFromConfig != "" {
 			host = hostFromConfig
+			noteSource("host", configSource("host"))
+		} else if _, hostFromEndpoint, _, ok := primaryEndpointTarget(); ok {
+			host = hostFromEndpoint
+			noteSource("host", configSource("endpoints"))
+		} else {
+			discovery, err := discoveredTarget()
+			if err != nil {
+				return "", err
+			}
+			host = discovery.Host
+			noteSource("host", discoverySource(discovery))
 		}
 
 		// コマンドオプションからの読み込み
 		hostFromOption, err := rootCmd.PersistentFlags().GetString("host")
 		if hostFromOption != "" {
 			host = hostFromOption
+			noteSource("host", flagSource("host"))
 		}
 
 		if err != nil {
-			log.Println(err)
+			logger.Warn(err.Error())
 		}
 
 		// いずれの場合も値が得られなければデフォルトの値(127.0.0.1)を設定する。
 		if host == "" {
 			host = "127.0.0.1"
+			noteSource("host", defaultSource())
 		}
 		return host, nil
 	}
 
-	// clientSetting.Port 設定ファイルおよびコマンドラインオプション(--port)から
-	// ToDoサーバにアクセスする際の宛先TCPポート番号を指定します。
-	// いずれも値が得られない場合は80番ポートを利用します。
+	// clientSetting.Port コマンドラインオプション(--port)、環境変数(TODO_SERVER, TODO_PORT)、
+	// 設定ファイルの順にToDoサーバにアクセスする際の宛先TCPポート番号を指定します。
+	// いずれも値が得られない場合は環境から見つけた接続先、それも無ければ80番ポートを利用します。
 	clientSetting.Port = func() (int, error) {
 		port := 0
 
-		// 設定ファイルからの読み込み
-		if portFromConfig := viper.GetInt("port"); portFromConfig != 0 {
+		_, _, serverPort, err := serverTarget()
+		if err != nil {
+			return 0, err
+		}
+
+		// 環境変数(TODO_SERVER)および設定ファイルからの読み込み
+		if serverPort != 0 {
+			port = serverPort
+			noteSource("port", envSource(EnvServer))
+		} else if portFromConfig := viper.GetInt("port"); portFromConfig != 0 {
 			port = portFromConfig
+			noteSource("port", configSource("port"))
+		} else if _, _, portFromEndpoint, ok := primaryEndpointTarget(); ok {
+			port = portFromEndpoint
+			noteSource("port", configSource("endpoints"))
+		} else {
+			discovery, err := discoveredTarget()
+			if err != nil {
+				return 0, err
+			}
+			port = discovery.Port
+			noteSource("port", discoverySource(discovery))
 		}
 
 		// コマンドオプションからの読み込み
 		portFromConfig, err := rootCmd.PersistentFlags().GetInt("port")
 
 		if err != nil {
-			log.Println(err)
+			logger.Warn(err.Error())
 		}
 
 		if portFromConfig != 0 {
 			port = portFromConfig
+			noteSource("port", flagSource("port"))
 		}
 
 		// いずれも値が得られなければデフォルトの値(80)を設定する。
 		if port == 0 {
 			port = 80
+			noteSource("port", defaultSource())
 		}
 		return port, err
 	}
 
 	// clientSetting.Username コマンドラインオプション(--username)からユーザ名情報を読み込む
+	// 指定が無い場合はマウントされたディレクトリのユーザ名を利用します。
 	clientSetting.Username = func() (string, error) {
 		var username string
 		//コマンドオプションからの読み込み
 		usernameFromConfig, err := loginCmd.Flags().GetString("username")
 		if err != nil {
-			log.Fatal(err)
+			return "", err
 		}
 
 		if usernameFromConfig != "" {
 			username = usernameFromConfig
+		} else if discovery, _ := clientSetting.Discovery(); discovery.Username != "" {
+			username = discovery.Username
 		} else {
-			err = errors.New(SettingErrorMessageUsernameNotFound)
+			err = service.NewError(service.ErrorUsage, SettingErrorMessageUsernameNotFound)
 		}
 
 		return username, err
 	}
 
 	// clientSetting.Password コマンドラインオプション(--password)からパスワード情報を読み込む
+	// 指定が無い場合はマウントされたディレクトリのパスワードを利用します。
 	clientSetting.Password = func() (string, error) {
 		var password string
 
 		//コマンドオプションからの読み込み
 		passwordFromConfig, err := loginCmd.Flags().GetString("password")
 		if err != nil {
-			log.Fatal(err)
+			return "", err
 		}
 
 		if passwordFromConfig != "" {
 			password = passwordFromConfig
+		} else if discovery, _ := clientSetting.Discovery(); discovery.Password != "" {
+			password = discovery.Password
 		} else {
-			err = errors.New(SettingErrorMessagePasswordNotFound)
+			err = service.NewError(service.ErrorUsage, SettingErrorMessagePasswordNotFound)
 		}
 
 		return password, err
 	}
 
-	// Token JWTの認証トークン情報を設定ファイルから取得する
+	// Token JWTの認証トークン情報を環境変数(TODO_TOKEN)、設定ファイルの順に取得する
+	// 設定ファイルに無い場合はマウントされたディレクトリの認証トークンを利用します。
 	clientSetting.Token = func() (string, error) {
 
 		var token string
 		var err error
 		if tokenFromConfig := viper.GetString("token"); tokenFromConfig != "" {
 			token = tokenFromConfig
+			noteSource("token", configSource("token"))
+		} else if discovery, _ := clientSetting.Discovery(); discovery.Token != "" {
+			token = discovery.Token
+			noteSource("token", SettingSource{Kind: SourceDiscovery, Detail: discovery.TokenSource})
 		} else {
-			err = errors.New(SettingErrorMessageTokenNotFound)
+			err = service.NewError(service.ErrorAuth, SettingErrorMessageTokenNotFound)
 		}
 		return token, err
 	}
+
+	// DryRun コマンドラインオプション(--dry-run)、環境変数(TODO_DRY_RUN)の順にドライランの指定を読み込む
+	clientSetting.DryRun = func() (bool, error) {
+		dryRun, err := rootCmd.PersistentFlags().GetBool("dry-run")
+		if err != nil {
+			logger.Warn(err.Error())
+		}
+		if value, ok := flagOrEnv("dry_run", "dry-run"); ok {
+			if dryRun, err = strconv.ParseBool(value); err != nil {
+				return false, service.NewError(service.ErrorUsage, SettingEnvInvalid, configEnvName("dry_run"), value)
+			}
+		}
+		return dryRun, err
+	}
+
+	// JournalDir 設定ファイル(journal_dir)から操作ジャーナルの保管先を読み込む
+	// 指定が無い場合は$HOME/.todo/journalを利用します。
+	clientSetting.JournalDir = func() (string, error) {
+		if dir := viper.GetString("journal_dir"); dir != "" {
+			noteSource("journal_dir", configSource("journal_dir"))
+			return dir, nil
+		}
+		noteSource("journal_dir", defaultSource())
+
+		home, err := homedir.Dir()
+		if err != nil {
+			logger.Warn(err.Error())
+			return "", err
+		}
+		return filepath.Join(home, ".todo", "journal"), nil
+	}
+
+	// TemplateDir 設定ファイル(template_dir)からタスクのテンプレートを置くディレクトリを読み込む
+	// 指定が無い場合は設定ファイルの既定の場所のtemplates($XDG_CONFIG_HOME/todo/templates)を利用します。
+	clientSetting.TemplateDir = func() (string, error) {
+		if dir := viper.GetString("template_dir"); dir != "" {
+			noteSource("template_dir", configSource("template_dir"))
+			return dir, nil
+		}
+		noteSource("template_dir", defaultSource())
+
+		home, err := homedir.Dir()
+		if err != nil {
+			logger.Warn(err.Error())
+			return "", err
+		}
+		return filepath.Join(configDir(home), "templates"), nil
+	}
+
+	// RetryPolicy 設定ファイル(retry.max, retry.base_delay, retry.max_delay, retry.post)および
+	// コマンドラインオプション(--retry-max, --retry-base-delay, --retry-max-delay, --retry-post)から
+	// 再試行の方針を読み込む。いずれも指定が無い場合はservice.DefaultRetryPolicyを利用します。
+	clientSetting.RetryPolicy = func() (service.RetryPolicy, error) {
+		policy := service.DefaultRetryPolicy
+
+		// 設定ファイルからの読み込み
+		noteSource("retry.max", defaultSource())
+		if viper.IsSet("retry.max") {
+			policy.MaxRetries = viper.GetInt("retry.max")
+			noteSource("retry.max", configSource("retry.max"))
+		}
+		noteSource("retry.base_delay", defaultSource())
+		if viper.IsSet("retry.base_delay") {
+			policy.BaseDelay = viper.GetDuration("retry.base_delay")
+			noteSource("retry.base_delay", configSource("retry.base_delay"))
+		}
+		noteSource("retry.max_delay", defaultSource())
+		if viper.IsSet("retry.max_delay") {
+			policy.MaxDelay = viper.GetDuration("retry.max_delay")
+			noteSource("retry.max_delay", configSource("retry.max_delay"))
+		}
+		noteSource("retry.post", defaultSource())
+		if viper.IsSet("retry.post") {
+			policy.RetryPost = viper.GetBool("retry.post")
+			noteSource("retry.post", configSource("retry.post"))
+		}
+
+		// コマンドオプションからの読み込み(明示的に指定された場合のみ上書きする)
+		flags := rootCmd.PersistentFlags()
+		var err error
+		if flags.Changed("retry-max") {
+			if policy.MaxRetries, err = flags.GetInt("retry-max"); err != nil {
+				return policy, err
+			}
+			noteSource("retry.max", flagSource("retry-max"))
+		}
+		if flags.Changed("retry-base-delay") {
+			if policy.BaseDelay, err = flags.GetDuration("retry-base-delay"); err != nil {
+				return policy, err
+			}
+			noteSource("retry.base_delay", flagSource("retry-base-delay"))
+		}
+		if flags.Changed("retry-max-delay") {
+			if policy.MaxDelay, err = flags.GetDuration("retry-max-delay"); err != nil {
+				return policy, err
+			}
+			noteSource("retry.max_delay", flagSource("retry-max-delay"))
+		}
+		if flags.Changed("retry-post") {
+			if policy.RetryPost, err = flags.GetBool("retry-post"); err != nil {
+				return policy, err
+			}
+			noteSource("retry.post", flagSource("retry-post"))
+		}
+
+		if policy.MaxRetries < 0 {
+			return policy, service.NewError(service.ErrorUsage, SettingRetryMaxInvalid)
+		}
+		return policy, nil
+	}
+
+	// Discovery 環境変数(TODO_DISCOVERY_SERVICE, TODO_DISCOVERY_DIR)および設定ファイル(discovery.service, discovery.dir)の順に
+	// 探索するServiceの名前とマウントされたディレクトリを読み込み、ToDoサーバの接続先と認証情報を探す。
+	// ディレクトリの指定が無い場合は/etc/todoを利用します。
+	clientSetting.Discovery = func() (ServerDiscovery, error) {
+		serviceName := viper.GetString("discovery.service")
+		noteSource("discovery.service", configSource("discovery.service"))
+		if serviceName == "" {
+			noteSource("discovery.service", defaultSource())
+		}
+		dir := viper.GetString("discovery.dir")
+		noteSource("discovery.dir", configSource("discovery.dir"))
+		if dir == "" {
+			dir = DefaultDiscoveryDir
+			noteSource("discovery.dir", defaultSource())
+		}
+		return discoverServer(discoveryEnviron(), serviceName, dir)
+	}
+
+	// Failover 設定ファイル(endpoints, failover.failure_threshold, failover.open_timeout, failover.state_file)から
+	// 複数のエンドポイントの振り分けの設定を読み込む。
+	// エンドポイントが定義されていない場合や、コマンドラインオプション(--protocol, --host, --port)または
+	// 環境変数(TODO_SERVER)でアクセス先が明示された場合は振り分けを行わないためnilを返します。
+	clientSetting.Failover = func() (*service.Failover, error) {
+		flags := rootCmd.PersistentFlags()
+		if flags.Changed("protocol") || flags.Changed("host") || flags.Changed("port") || os.Getenv(EnvServer) != "" {
+			return nil, nil
+		}
+
+		endpoints, err := endpointsFromConfig()
+		if err != nil || len(endpoints) == 0 {
+			return nil, err
+		}
+
+		failover, err := service.NewFailover(endpoints)
+		if err != nil {
+			return nil, err
+		}
+
+		noteSource("failover.failure_threshold", defaultSource())
+		if viper.IsSet("failover.failure_threshold") {
+			failover.FailureThreshold = viper.GetInt("failover.failure_threshold")
+			noteSource("failover.failure_threshold", configSource("failover.failure_threshold"))
+		}
+		if failover.FailureThreshold < 1 {
+			return nil, service.NewError(service.ErrorUsage, SettingFailureThresholdInvalid)
+		}
+		noteSource("failover.open_timeout", defaultSource())
+		if viper.IsSet("failover.open_timeout") {
+			failover.OpenTimeout = viper.GetDuration("failover.open_timeout")
+			noteSource("failover.open_timeout", configSource("failover.open_timeout"))
+		}
+
+		// サーキットブレーカーの状態はコマンドの実行をまたいで引き継ぐため、ファイルに保存します。
+		// 指定が無い場合は$HOME/.todo/circuit.jsonを利用します。
+		failover.StateFile = viper.GetString("failover.state_file")
+		noteSource("failover.state_file", configSource("failover.state_file"))
+		if failover.StateFile == "" {
+			noteSource("failover.state_file", defaultSource())
+			home, err := homedir.Dir()
+			if err != nil {
+				return nil, err
+			}
+			failover.StateFile = filepath.Join(home, ".todo", "circuit.json")
+		}
+		return failover, nil
+	}
+
+	// Context 環境変数(TODO_CONTEXT)、設定ファイル(context)の順に利用するエンドポイントの名前を読み込む
+	// 指定が無い場合はdefaultとし、設定ファイルのendpointsをすべて利用します。
+	clientSetting.Context = func() (string, error) {
+		if context := viper.GetString("context"); context != "" {
+			noteSource("context", configSource("context"))
+			return context, nil
+		}
+		noteSource("context", defaultSource())
+		return DefaultContext, nil
+	}
+
+	// Verbosity コマンドラインオプション(-v, --verbosity)、環境変数(TODO_VERBOSITY)の順に出力する情報の詳細さを読み込む
+	clientSetting.Verbosity = func() (int, error) {
+		verbosity, err := rootCmd.PersistentFlags().GetInt("verbosity")
+		if err != nil {
+			logger.Warn(err.Error())
+		}
+		if value, ok := flagOrEnv("verbosity", "verbosity"); ok {
+			if verbosity, err = strconv.Atoi(value); err != nil {
+				return 0, service.NewError(service.ErrorUsage, SettingEnvInvalid, configEnvName("verbosity"), value)
+			}
+		}
+		return verbosity, err
+	}
+
+	// TraceFile コマンドラインオプション(--trace-file)、環境変数(TODO_TRACE_FILE)の順にHAR形式で記録するファイルのパスを読み込む
+	clientSetting.TraceFile = func() (string, error) {
+		traceFile, err := rootCmd.PersistentFlags().GetString("trace-file")
+		if err != nil {
+			logger.Warn(err.Error())
+		}
+		if value, ok := flagOrEnv("trace_file", "trace-file"); ok {
+			traceFile = value
+		}
+		return traceFile, err
+	}
+
+	// LogLevel 設定ファイル(log.level)およびコマンドラインオプション(--log-level, --quiet)から
+	// ログの出力レベルを読み込む。--quietが指定された場合はエラーのみを出力します。
+	// いずれも指定が無い場合はinfoを利用します。
+	clientSetting.LogLevel = func() (service.LogLevel, error) {
+		flags := rootCmd.PersistentFlags()
+		if quiet, _ := flags.GetBool("quiet"); quiet {
+			noteSource("log.level", flagSource("quiet"))
+			return service.LogLevelError, nil
+		}
+
+		name := "info"
+		noteSource("log.level", defaultSource())
+		if levelFromConfig := viper.GetString("log.level"); levelFromConfig != "" {
+			name = levelFromConfig
+			noteSource("log.level", configSource("log.level"))
+		}
+		if flags.Changed("log-level") {
+			name, _ = flags.GetString("log-level")
+			noteSource("log.level", flagSource("log-level"))
+		}
+		return service.ParseLogLevel(name)
+	}
+
+	// LogFormat 設定ファイル(log.format)およびコマンドラインオプション(--log-format)から
+	// ログの出力形式を読み込む。いずれも指定が無い場合はtextを利用します。
+	clientSetting.LogFormat = func() (string, error) {
+		format := service.LogFormatText
+		noteSource("log.format", defaultSource())
+		if formatFromConfig := viper.GetString("log.format"); formatFromConfig != "" {
+			format = formatFromConfig
+			noteSource("log.format", configSource("log.format"))
+		}
+		flags := rootCmd.PersistentFlags()
+		if flags.Changed("log-format") {
+			format, _ = flags.GetString("log-format")
+			noteSource("log.format", flagSource("log-format"))
+		}
+		if format != service.LogFormatText && format != service.LogFormatJSON {
+			return format, service.NewError(service.ErrorUsage, service.LogFormatInvalid)
+		}
+		return format, nil
+	}
+
+	// Language コマンドラインオプション(--lang)、環境変数(TODO_LANG)、設定ファイル(lang)、
+	// 環境変数(LC_ALL, LC_MESSAGES, LANG)の順にメッセージの言語を読み込む
+	clientSetting.Language = func() (string, error) {
+		lang, err := rootCmd.PersistentFlags().GetString("lang")
+		if err != nil {
+			logger.Warn(err.Error())
+		}
+		noteSource("lang", flagSource("lang"))
+		if lang == "" {
+			lang = viper.GetString("lang")
+			noteSource("lang", configSource("lang"))
+		}
+		if lang == "" {
+			noteSource("lang", defaultSource())
+			for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+				if os.Getenv(name) != "" {
+					noteSource("lang", envSource(name))
+					break
+				}
+			}
+			return service.DetectLanguage(), nil
+		}
+		return lang, nil
+	}
 }
@@ -4,8 +4,10 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
 )
 
 const DefaultTestFilename = "test_config"
@@ -309,7 +311,7 @@ func TestUsernameWithoutCommandLineOption(t *testing.T) {
 	}
 
 	log.Println(err)
-	if err.Error() != SettingErrorMessageUsernameNotFound {
+	if !service.IsMessage(err, SettingErrorMessageUsernameNotFound) {
 		t.Fail()
 	}
 }
@@ -354,7 +356,7 @@ func TestPasswordWithoutCommandLineOption(t *testing.T) {
 	}
 
 	log.Println(err)
-	if err.Error() != SettingErrorMessagePasswordNotFound {
+	if !service.IsMessage(err, SettingErrorMessagePasswordNotFound) {
 		t.Fail()
 	}
 }
@@ -395,8 +397,182 @@ func TestTokenWithoutConfigFile(t *testing.T) {
 	}
 
 	log.Println(err)
-	if err.Error() != SettingErrorMessageTokenNotFound {
+	if !service.IsMessage(err, SettingErrorMessageTokenNotFound) {
 		t.Fail()
 	}
 
 }
+
+// TestDryRunWithOptionOverride は--dry-runオプションで
+// DryRunの返り値が上書きされることを確認する
+func TestDryRunWithOptionOverride(t *testing.T) {
+	flags := rootCmd.PersistentFlags()
+	if err := flags.Set("dry-run", "true"); err != nil {
+		log.Fatal(err)
+		t.Fail()
+	}
+	defer flags.Set("dry-run", "false")
+
+	dryRun, err := clientSetting.DryRun()
+	if err != nil {
+		t.Fail()
+	}
+	if !dryRun {
+		t.Fail()
+	}
+}
+
+// TestFailoverWithConfigFile は設定ファイルに複数のエンドポイントが定義されている場合に、
+// 優先度の順に並べたエンドポイントの振り分けの設定が得られることを確認する。
+func TestFailoverWithConfigFile(t *testing.T) {
+	flags := rootCmd.PersistentFlags()
+	for _, name := range []string{"protocol", "host", "port"} {
+		flag := flags.Lookup(name)
+		changed, value := flag.Changed, flag.Value.String()
+		flags.Set(name, flag.DefValue)
+		flag.Changed = false
+		defer func(name string) {
+			flags.Set(name, value)
+			flags.Lookup(name).Changed = changed
+		}(name)
+	}
+	viper.Reset()
+	defer viper.Reset()
+	loadConfigForConfigFileOveride("test_config_with_endpoints")
+
+	failover, err := clientSetting.Failover()
+	if err != nil || failover == nil {
+		log.Fatal(err)
+	}
+	if len(failover.Endpoints) != 2 || failover.Endpoints[0].Name != "cluster-a" || failover.Endpoints[0].Token != "test_token_a" {
+		t.Fail()
+	}
+	if failover.FailureThreshold != 3 || failover.OpenTimeout != time.Minute {
+		t.Fail()
+	}
+
+	// アクセス先の指定が無い場合は最も優先度の高いエンドポイントを利用すること
+	protocol, _ := clientSetting.Protocol()
+	host, _ := clientSetting.Host()
+	port, _ := clientSetting.Port()
+	if protocol != "https" || host != "todo-a.example.com" || port != 443 {
+		t.Fail()
+	}
+
+	// --hostオプションでアクセス先が明示された場合は振り分けを行わないこと
+	flags.Set("host", "example.com")
+	if failover, err := clientSetting.Failover(); err != nil || failover != nil {
+		t.Fail()
+	}
+}
+
+// TestLogLevelWithQuiet は--quietオプションが--log-levelオプションより優先され、
+// エラーのみを出力するレベルになることを確認する。
+func TestLogLevelWithQuiet(t *testing.T) {
+	flags := rootCmd.PersistentFlags()
+	flags.Set("log-level", "debug")
+	defer flags.Set("log-level", "info")
+
+	level, err := clientSetting.LogLevel()
+	if err != nil || level != service.LogLevelDebug {
+		t.Fail()
+	}
+
+	flags.Set("quiet", "true")
+	defer flags.Set("quiet", "false")
+
+	level, err = clientSetting.LogLevel()
+	if err != nil || level != service.LogLevelError {
+		t.Fail()
+	}
+}
+
+// TestLogFormatWithInvalidOption は--log-formatオプションにtext/json以外を指定した場合に
+// エラーとなることを確認する。
+func TestLogFormatWithInvalidOption(t *testing.T) {
+	flags := rootCmd.PersistentFlags()
+	flags.Set("log-format", "xml")
+	defer flags.Set("log-format", service.LogFormatText)
+
+	if _, err := clientSetting.LogFormat(); err == nil || !service.IsMessage(err, service.LogFormatInvalid) {
+		t.Fail()
+	}
+}
+
+// TestSettingsWithEnv はTODO_を付けた環境変数だけが設定として読み込まれ、
+// コンテナの環境にあるHOSTやPORTなどの無関係な環境変数は無視されることを確認する。
+func TestSettingsWithEnv(t *testing.T) {
+	resetCommandsForTest()
+	defer resetCommandsForTest()
+	bindEnv()
+	for name, value := range map[string]string{"HOST": "10.43.0.1", "PORT": "8080", "PROTOCOL": "https", "TOKEN": "stray_token", "DRY_RUN": "true"} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	protocol, _ := clientSetting.Protocol()
+	host, _ := clientSetting.Host()
+	port, _ := clientSetting.Port()
+	dryRun, _ := clientSetting.DryRun()
+	if protocol != "http" || host != "127.0.0.1" || port != 80 || dryRun {
+		t.Errorf("unrelated env = %s://%s:%d dry-run=%v", protocol, host, port, dryRun)
+	}
+	if _, err := clientSetting.Token(); err == nil {
+		t.Errorf("TOKEN was read")
+	}
+
+	for name, value := range map[string]string{"TODO_HOST": "todo.example.com", "TODO_PORT": "8000", "TODO_TOKEN": "env_token", "TODO_DRY_RUN": "true", "TODO_RETRY_MAX": "7"} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+	host, _ = clientSetting.Host()
+	port, _ = clientSetting.Port()
+	token, _ := clientSetting.Token()
+	dryRun, _ = clientSetting.DryRun()
+	retry, _ := clientSetting.RetryPolicy()
+	if host != "todo.example.com" || port != 8000 || token != "env_token" || !dryRun || retry.MaxRetries != 7 {
+		t.Errorf("TODO_ env = %s:%d %s dry-run=%v retry=%d", host, port, token, dryRun, retry.MaxRetries)
+	}
+
+	// TODO_SERVERはTODO_HOSTなどより優先し、コマンドラインオプションはさらに優先する
+	os.Setenv(EnvServer, "https://todo-server.example.com")
+	defer os.Unsetenv(EnvServer)
+	protocol, _ = clientSetting.Protocol()
+	host, _ = clientSetting.Host()
+	port, _ = clientSetting.Port()
+	if protocol != "https" || host != "todo-server.example.com" || port != 443 {
+		t.Errorf("TODO_SERVER = %s://%s:%d", protocol, host, port)
+	}
+	rootCmd.PersistentFlags().Set("port", "9000")
+	if port, _ := clientSetting.Port(); port != 9000 {
+		t.Errorf("--port with TODO_SERVER = %d", port)
+	}
+
+	os.Setenv(EnvServer, "todo-server:8000")
+	if _, err := clientSetting.Host(); service.KindOf(err) != service.ErrorUsage {
+		t.Errorf("invalid TODO_SERVER: err = %v", err)
+	}
+}
+
+// TestFailoverWithContext はTODO_CONTEXTで指定した名前のエンドポイントだけを利用することを確認する。
+func TestFailoverWithContext(t *testing.T) {
+	resetCommandsForTest()
+	defer resetCommandsForTest()
+	bindEnv()
+	loadConfigForConfigFileOveride("test_config_with_endpoints")
+	os.Setenv("TODO_CONTEXT", "cluster-b")
+	defer os.Unsetenv("TODO_CONTEXT")
+
+	failover, err := clientSetting.Failover()
+	if err != nil || failover == nil || len(failover.Endpoints) != 1 || failover.Endpoints[0].Name != "cluster-b" {
+		t.Errorf("failover = %+v, %v", failover, err)
+	}
+	if host, _ := clientSetting.Host(); host != "todo-b.example.com" {
+		t.Errorf("host = %s", host)
+	}
+
+	os.Setenv("TODO_CONTEXT", "cluster-c")
+	if _, err := clientSetting.Failover(); service.KindOf(err) != service.ErrorUsage {
+		t.Errorf("unknown context: err = %v", err)
+	}
+}
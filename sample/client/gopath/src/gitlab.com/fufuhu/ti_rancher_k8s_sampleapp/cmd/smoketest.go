@@ -0,0 +1,409 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// SmoketestFailed はシナリオのいずれかの手順が失敗した場合のエラーメッセージです。
+const SmoketestFailed service.MessageID = "cmd.smoketest.failed"
+
+// SmoketestFormatInvalid は結果の出力形式にtap/junit以外が指定された場合のエラーメッセージです。
+const SmoketestFormatInvalid service.MessageID = "cmd.smoketest.format_invalid"
+
+// SmoketestMismatch はToDoサーバの応答が期待した値と異なる場合のエラーメッセージです。
+const SmoketestMismatch service.MessageID = "cmd.smoketest.mismatch"
+
+// SmoketestDryRunUnsupported はドライランでsmoketestを実行しようとした場合のエラーメッセージです。
+const SmoketestDryRunUnsupported service.MessageID = "cmd.smoketest.dry_run_unsupported"
+
+// smoketestの結果の出力形式です。
+const (
+	SmoketestFormatTAP   = "tap"
+	SmoketestFormatJUnit = "junit"
+)
+
+// SmoketestStep はシナリオの手順ひとつの結果です。
+type SmoketestStep struct {
+	Name    string        // 手順の名前
+	Err     error         // 失敗した場合の原因
+	Skipped string        // 実行しなかった場合の理由
+	Elapsed time.Duration // 手順にかかった時間
+}
+
+// smoketestScenario はシナリオの実行中の状態です。
+// 手順が失敗した場合、その結果を必要とする以降の手順は実行しません。
+type smoketestScenario struct {
+	protocol string
+	host     string
+	port     int
+	token    string
+	steps    []SmoketestStep
+	failed   string // 最初に失敗した手順の名前
+}
+
+// run は手順を実行して結果を記録します。前の手順が失敗している場合は実行しません。
+func (s *smoketestScenario) run(name string, step func() error) bool {
+	if s.failed != "" {
+		s.skip(name, service.T("cmd.smoketest.skipped", s.failed))
+		return false
+	}
+	return s.record(name, step)
+}
+
+// record は前の手順の結果に関わらず手順を実行して結果を記録します。
+func (s *smoketestScenario) record(name string, step func() error) bool {
+	start := time.Now()
+	err := step()
+	s.steps = append(s.steps, SmoketestStep{Name: name, Err: err, Elapsed: time.Since(start)})
+	if err != nil {
+		if s.failed == "" {
+			s.failed = name
+		}
+		return false
+	}
+	return true
+}
+
+// skip は手順を実行しなかったことを記録します。
+func (s *smoketestScenario) skip(name string, reason string) {
+	s.steps = append(s.steps, SmoketestStep{Name: name, Skipped: reason})
+}
+
+// smoketestCmd represents the smoketest command
+var smoketestCmd = &cobra.Command{
+	Use:   "smoketest",
+	Short: "cmd.smoketest.short",
+	Long:  "cmd.smoketest.long",
+	RunE:  smoketest,
+}
+
+func init() {
+	rootCmd.AddCommand(smoketestCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// smoketestCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// smoketestCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	smoketestCmd.Flags().String("username", "", "cmd.smoketest.flag.username")
+	smoketestCmd.Flags().String("password", "", "cmd.smoketest.flag.password")
+	smoketestCmd.Flags().Duration("wait", 60*time.Second, "cmd.smoketest.flag.wait")
+	smoketestCmd.Flags().Duration("timeout", 10*time.Second, "cmd.smoketest.flag.timeout")
+	smoketestCmd.Flags().String("format", SmoketestFormatTAP, "cmd.smoketest.flag.format")
+	smoketestCmd.Flags().StringP("output", "o", "", "cmd.smoketest.flag.output")
+}
+
+func smoketest(cmd *cobra.Command, args []string) error {
+	protocol, err := clientSetting.Protocol()
+	if err != nil {
+		return err
+	}
+	host, err := clientSetting.Host()
+	if err != nil {
+		return err
+	}
+	port, err := clientSetting.Port()
+	if err != nil {
+		return err
+	}
+
+	username, _ := cmd.Flags().GetString("username")
+	password, _ := cmd.Flags().GetString("password")
+	if username == "" && password == "" {
+		// Pod内で実行された場合はマウントされたSecretの認証情報を利用します。
+		discovery, _ := clientSetting.Discovery()
+		username, password = discovery.Username, discovery.Password
+	}
+	wait, _ := cmd.Flags().GetDuration("wait")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	if format != SmoketestFormatTAP && format != SmoketestFormatJUnit {
+		return usageError(SmoketestFormatInvalid)
+	}
+	if service.DryRun {
+		return usageError(SmoketestDryRunUnsupported)
+	}
+
+	// ログインしない場合は設定ファイルの認証トークンを利用します。
+	token, _ := clientSetting.Token()
+	protocol, host, port, token = pinEndpoint(protocol, host, port, token)
+	defer limitRequests(timeout)()
+
+	scenario := &smoketestScenario{protocol: protocol, host: host, port: port, token: token}
+	start := time.Now()
+	runSmoketest(scenario, username, password, wait)
+	elapsed := time.Since(start)
+
+	w := io.Writer(os.Stdout)
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if format == SmoketestFormatJUnit {
+		err = writeJUnit(w, scenario.steps, elapsed)
+	} else {
+		err = writeTAP(w, scenario.steps)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, step := range scenario.steps {
+		if step.Err != nil {
+			return service.WrapError(service.ErrorUnknown, SmoketestFailed, step.Err, step.Name)
+		}
+	}
+	return nil
+}
+
+// runSmoketest は起動の待ち合わせ、ログイン、タスクの作成・取得・一覧・更新・削除を順に行い、
+// 応答を検証します。途中で失敗した場合も、作成したタスクは最後に削除します。
+func runSmoketest(s *smoketestScenario, username string, password string, wait time.Duration) {
+	title := fmt.Sprintf("smoketest %d", time.Now().UnixNano())
+	description := "created by todo smoketest"
+	var created service.CreatedTask
+
+	s.run("ready", func() error {
+		deadline := time.Now().Add(wait)
+		for {
+			_, err := service.Ping(s.protocol, s.host, s.port)
+			if err == nil || !time.Now().Before(deadline) {
+				return err
+			}
+			logger.Info(service.T("cmd.smoketest.waiting"), "error", err)
+			time.Sleep(smoketestPollInterval)
+		}
+	})
+
+	if username != "" || password != "" {
+		s.run("login", func() error {
+			config, err := service.Login(s.protocol, s.host, s.port, username, password)
+			if err != nil {
+				return err
+			}
+			if _, err := service.ParseTokenClaims(config.Token); err != nil {
+				return err
+			}
+			s.token = config.Token
+			return nil
+		})
+	} else if s.token == "" {
+		s.run("login", func() error {
+			return service.NewError(service.ErrorAuth, SettingErrorMessageTokenNotFound)
+		})
+	} else {
+		s.skip("login", service.T("cmd.smoketest.login_skipped"))
+	}
+
+	s.run("create", func() error {
+		var err error
+		if created, err = service.CreateTask(s.protocol, s.host, s.port, s.token, title, description); err != nil {
+			return err
+		}
+		if created.ID <= 0 {
+			return smoketestMismatch("id", "> 0", created.ID)
+		}
+		return expectTask(service.Task{ID: created.ID, Title: created.Title, Description: created.Description, Status: "TODO"},
+			created.ID, title, description, "TODO")
+	})
+
+	s.run("get", func() error {
+		tasks, err := service.GetTask(s.protocol, s.host, s.port, s.token, created.ID)
+		if err != nil {
+			return err
+		}
+		if len(tasks) != 1 {
+			return smoketestMismatch("tasks", 1, len(tasks))
+		}
+		return expectTask(tasks[0], created.ID, title, description, "TODO")
+	})
+
+	s.run("list", func() error {
+		tasks, err := service.GetTasks(s.protocol, s.host, s.port, s.token)
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			if task.ID == created.ID {
+				return expectTask(task, created.ID, title, description, "TODO")
+			}
+		}
+		return smoketestMismatch("list", created.ID, "-")
+	})
+
+	s.run("update", func() error {
+		updatedTitle, updatedDescription := title+" (updated)", description+" (updated)"
+		updated, err := service.UpdateTask(s.protocol, s.host, s.port, s.token, created.ID, updatedTitle, updatedDescription, "RUNNING")
+		if err != nil {
+			return err
+		}
+		if err := expectTask(updated, created.ID, updatedTitle, updatedDescription, "RUNNING"); err != nil {
+			return err
+		}
+		// 更新の結果が保存されていることを取得しなおして確認します。
+		tasks, err := service.GetTask(s.protocol, s.host, s.port, s.token, created.ID)
+		if err != nil {
+			return err
+		}
+		if len(tasks) != 1 {
+			return smoketestMismatch("tasks", 1, len(tasks))
+		}
+		return expectTask(tasks[0], created.ID, updatedTitle, updatedDescription, "RUNNING")
+	})
+
+	deleted := s.run("delete", func() error {
+		if _, err := service.DeleteTask(s.protocol, s.host, s.port, s.token, created.ID); err != nil {
+			return err
+		}
+		// 削除したタスクは取得できないことを確認します。
+		_, err := service.GetTask(s.protocol, s.host, s.port, s.token, created.ID)
+		if service.KindOf(err) != service.ErrorNotFound {
+			return smoketestMismatch("get after delete", "404", err)
+		}
+		return nil
+	})
+
+	// 後片付けは前の手順の結果に関わらず行います。
+	s.record("cleanup", func() error {
+		if created.ID <= 0 || deleted {
+			return nil
+		}
+		_, err := service.DeleteTask(s.protocol, s.host, s.port, s.token, created.ID)
+		if service.KindOf(err) == service.ErrorNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+// smoketestPollInterval はToDoサーバの起動を待つ間に/api/pingを呼び出す間隔です。
+var smoketestPollInterval = 2 * time.Second
+
+// expectTask はタスクの内容が期待した値と一致することを確認します。
+func expectTask(task service.Task, id int, title string, description string, status string) error {
+	switch {
+	case task.ID != id:
+		return smoketestMismatch("id", id, task.ID)
+	case task.Title != title:
+		return smoketestMismatch("title", title, task.Title)
+	case task.Description != description:
+		return smoketestMismatch("description", description, task.Description)
+	case task.Status != status:
+		return smoketestMismatch("status", status, task.Status)
+	}
+	return nil
+}
+
+func smoketestMismatch(field string, want interface{}, got interface{}) error {
+	return service.NewError(service.ErrorServer, SmoketestMismatch, field, want, got)
+}
+
+// writeTAP はTAP version 13の形式で結果を出力します。
+func writeTAP(w io.Writer, steps []SmoketestStep) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", len(steps))
+	for i, step := range steps {
+		switch {
+		case step.Skipped != "":
+			fmt.Fprintf(&b, "ok %d - %s # SKIP %s\n", i+1, step.Name, step.Skipped)
+		case step.Err != nil:
+			fmt.Fprintf(&b, "not ok %d - %s\n", i+1, step.Name)
+			fmt.Fprintf(&b, "  ---\n  message: %q\n  duration_ms: %.3f\n  ...\n", step.Err.Error(), milliseconds(step.Elapsed))
+		default:
+			fmt.Fprintf(&b, "ok %d - %s\n", i+1, step.Name)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// junitTestSuites はGitLab CIが読み込めるJUnit XMLのルート要素です。
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnit はJUnit XMLの形式で結果を出力します。
+func writeJUnit(w io.Writer, steps []SmoketestStep, elapsed time.Duration) error {
+	suite := junitTestSuite{Name: "todo smoketest", Tests: len(steps), Time: fmt.Sprintf("%.3f", elapsed.Seconds())}
+	for _, step := range steps {
+		c := junitTestCase{Name: step.Name, ClassName: "smoketest", Time: fmt.Sprintf("%.3f", step.Elapsed.Seconds())}
+		switch {
+		case step.Skipped != "":
+			c.Skipped = &junitSkipped{Message: step.Skipped}
+			suite.Skipped++
+		case step.Err != nil:
+			c.Failure = &junitFailure{Message: step.Err.Error(), Text: step.Err.Error()}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// smoketestServer はsmoketestのシナリオに必要なAPIだけを持つToDoサーバです。
+// ignoreTitleOnPatchがtrueの場合は更新時に名前を反映しない不具合を模擬します。
+type smoketestServer struct {
+	mu                 sync.Mutex
+	tasks              map[int]service.Task
+	nextID             int
+	ignoreTitleOnPatch bool
+}
+
+func (s *smoketestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fields := map[string]string{}
+	json.NewDecoder(r.Body).Decode(&fields)
+	writeJSON := func(status int, v interface{}) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	switch {
+	case r.URL.Path == "/api/ping":
+		writeJSON(http.StatusOK, service.PongMessage{Message: "pong"})
+	case r.URL.Path == "/api/auth":
+		if fields["password"] != "test_password" {
+			writeJSON(http.StatusBadRequest, nil)
+			return
+		}
+		writeJSON(http.StatusOK, service.JWTAuthMessage{Token: testToken(time.Now().Add(time.Hour).Unix())})
+	case r.URL.Path == "/api/task" && r.Method == "GET":
+		tasks := []service.Task{}
+		for id := 1; id < s.nextID; id++ {
+			if task, ok := s.tasks[id]; ok {
+				tasks = append(tasks, task)
+			}
+		}
+		writeJSON(http.StatusOK, tasks)
+	case r.URL.Path == "/api/task" && r.Method == "POST":
+		task := service.Task{ID: s.nextID, Title: fields["title"], Description: fields["description"], Status: "TODO"}
+		s.tasks[task.ID] = task
+		s.nextID++
+		writeJSON(http.StatusCreated, service.CreatedTask{ID: task.ID, Title: task.Title, Description: task.Description})
+	case strings.HasPrefix(r.URL.Path, "/api/task/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/task/"))
+		task, ok := s.tasks[id]
+		if !ok {
+			writeJSON(http.StatusNotFound, []service.Task{})
+			return
+		}
+		switch r.Method {
+		case "GET":
+			writeJSON(http.StatusOK, []service.Task{task})
+		case "DELETE":
+			// cmdパッケージではdeleteがコマンド変数で隠されているため、マップを作り直す
+			tasks := map[int]service.Task{}
+			for k, v := range s.tasks {
+				if k != id {
+					tasks[k] = v
+				}
+			}
+			s.tasks = tasks
+			writeJSON(http.StatusOK, task)
+		case "PATCH":
+			if title, ok := fields["title"]; ok && !s.ignoreTitleOnPatch {
+				task.Title = title
+			}
+			if description, ok := fields["description"]; ok {
+				task.Description = description
+			}
+			task.Status = fields["status"]
+			s.tasks[id] = task
+			writeJSON(http.StatusOK, task)
+		}
+	}
+}
+
+// runSmoketestForTest はsmoketestを実行し、終了コードと出力されたファイルの内容を返す。
+func runSmoketestForTest(t *testing.T, server *httptest.Server, args ...string) (int, string) {
+	file, err := ioutil.TempFile("", "smoketest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	args = append(append([]string{"smoketest", "--output", file.Name()}, args...), serverArgs(server)...)
+	code := executeForTest(args...)
+	output, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, string(output)
+}
+
+// TestSmoketest ではシナリオのすべての手順が成功した場合にTAPで結果を出力し、タスクが残らないことを確認する。
+func TestSmoketest(t *testing.T) {
+	todo := &smoketestServer{tasks: map[int]service.Task{}, nextID: 1}
+	server := httptest.NewServer(todo)
+	defer server.Close()
+
+	code, output := runSmoketestForTest(t, server, "--username", "test_user", "--password", "test_password")
+	if code != ExitOK {
+		t.Errorf("exit code = %d\n%s", code, output)
+	}
+	want := "TAP version 13\n1..8\nok 1 - ready\nok 2 - login\nok 3 - create\nok 4 - get\nok 5 - list\nok 6 - update\nok 7 - delete\nok 8 - cleanup\n"
+	if output != want {
+		t.Errorf("output = %q", output)
+	}
+	if len(todo.tasks) != 0 {
+		t.Errorf("tasks remain: %v", todo.tasks)
+	}
+
+	// ログインに失敗した場合は以降の手順を実行しない
+	code, output = runSmoketestForTest(t, server, "--username", "test_user", "--password", "wrong")
+	if code != ExitValidation || !strings.Contains(output, "not ok 2 - login\n") || !strings.Contains(output, "ok 3 - create # SKIP") {
+		t.Errorf("exit code = %d\n%s", code, output)
+	}
+}
+
+// TestSmoketestJUnit では応答が期待と異なる場合に失敗として報告し、作成したタスクを削除することを確認する。
+func TestSmoketestJUnit(t *testing.T) {
+	todo := &smoketestServer{tasks: map[int]service.Task{}, nextID: 1, ignoreTitleOnPatch: true}
+	server := httptest.NewServer(todo)
+	defer server.Close()
+
+	code, output := runSmoketestForTest(t, server, "--username", "test_user", "--password", "test_password", "--format", "junit")
+	if code != ExitServer {
+		t.Errorf("exit code = %d\n%s", code, output)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal([]byte(output), &suites); err != nil {
+		t.Fatal(err)
+	}
+	suite := suites.Suites[0]
+	if suite.Tests != 8 || suite.Failures != 1 || suite.Skipped != 1 {
+		t.Errorf("suite = %+v", suite)
+	}
+	if c := suite.Cases[5]; c.Name != "update" || c.Failure == nil || !strings.Contains(c.Failure.Message, "title") {
+		t.Errorf("update = %+v", c)
+	}
+	if c := suite.Cases[7]; c.Name != "cleanup" || c.Failure != nil || c.Skipped != nil {
+		t.Errorf("cleanup = %+v", c)
+	}
+	if len(todo.tasks) != 0 {
+		t.Errorf("tasks remain: %v", todo.tasks)
+	}
+}
@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"errors"
-	"log"
+	"github.com/spf13/pflag"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
 )
 
 // TaskRequestSetting はToDoクライアントが
@@ -16,15 +16,25 @@ type TaskRequestSetting struct {
 	ID func() (int, error)
 	// Status 取得したいタスクのステータス
 	Status func() (string, error)
+	// UpdateOptions タスク更新時の楽観的排他制御の条件
+	UpdateOptions func() (service.UpdateOptions, error)
+	// Metadata タスクの作成・更新時に概要に記述するメタデータ(--tag, --priority, --due)
+	Metadata func(flags *pflag.FlagSet) (service.MetadataUpdate, error)
+	// MetadataFilter タスクの取得時にメタデータで絞り込む条件(--tag, --priority, --due-before)
+	MetadataFilter func() (service.MetadataFilter, error)
 }
 
 // SettingTaskTitleNotFound はタスクの名前がtitleオプションで設定
 // されていない場合に発生するエラーに含まれるエラーメッセージです。
-const SettingTaskTitleNotFound = "タスクの名前が指定されていません"
+const SettingTaskTitleNotFound service.MessageID = "settings.task_title_not_found"
 
 // SettingTaskDescriptionNotFound はタスクの名前がdesriptionオプションで設定
 // されていない場合に発生するエラーに含まれるエラーメッセージです。
-const SettingTaskDescriptionNotFound = "タスクの概要が指定されていません"
+const SettingTaskDescriptionNotFound service.MessageID = "settings.task_description_not_found"
+
+// SettingTaskConflictPolicyInvalid は--on-conflictオプションにretry/abort以外が
+// 指定された場合に発生するエラーに含まれるエラーメッセージです。
+const SettingTaskConflictPolicyInvalid service.MessageID = "settings.task_conflict_policy_invalid"
 
 var taskRequestSetting TaskRequestSetting
 
@@ -32,7 +42,7 @@ func init() {
 	taskRequestSetting.Title = func() (string, error) {
 		title, err := updateCmd.Flags().GetString("title")
 		if err != nil {
-			log.Println(err)
+			logger.Warn(err.Error())
 		}
 		if title != "" {
 			return title, err
@@ -40,14 +50,14 @@ func init() {
 
 		title, err = createCmd.Flags().GetString("title")
 		if err != nil {
-			log.Println(err)
+			logger.Warn(err.Error())
 		}
 		if title != "" {
 			return title, err
 		}
 
 		if title == "" {
-			err = errors.New(SettingTaskTitleNotFound)
+			err = service.NewError(service.ErrorUsage, SettingTaskTitleNotFound)
 		}
 
 		return title, err
@@ -56,7 +66,7 @@ func init() {
 	taskRequestSetting.Description = func() (string, error) {
 		description, err := createCmd.Flags().GetString("description")
 		if err != nil {
-			log.Println(err)
+			logger.Warn(err.Error())
 		} else if description != "" {
 			return description, err
 		}
@@ -64,11 +74,11 @@ func init() {
 		// タスクの更新時の--descriptionを取得
 		description, err = updateCmd.Flags().GetString("description")
 		if err != nil {
-			log.Println(err)
+			logger.Warn(err.Error())
 		}
 
 		if description == "" {
-			err = errors.New(SettingTaskDescriptionNotFound)
+			err = service.NewError(service.ErrorUsage, SettingTaskDescriptionNotFound)
 		}
 		return description, err
 	}
@@ -77,15 +87,15 @@ func init() {
 		// デフォルトでIDの値が0で入ることを逆手に取る
 		getID, err := getCmd.Flags().GetInt("id")
 		if err != nil {
-			log.Println(err)
+			logger.Warn(err.Error())
 		}
 		deleteID, err := deleteCmd.Flags().GetInt("id")
 		if err != nil {
-			log.Println(err)
+			logger.Warn(err.Error())
 		}
 		updateID, err := updateCmd.Flags().GetInt("id")
 		if err != nil {
-			log.Println(err)
+			logger.Warn(err.Error())
 		}
 
 		id := getID + deleteID + updateID
@@ -98,10 +108,84 @@ func init() {
 		status, err := updateCmd.Flags().GetString("status")
 
 		if err != nil {
-			log.Println(err)
+			logger.Warn(err.Error())
 			return "", err
 		}
 
 		return status, err
 	}
+
+	taskRequestSetting.UpdateOptions = func() (service.UpdateOptions, error) {
+		options := service.DefaultUpdateOptions
+		flags := updateCmd.Flags()
+
+		var err error
+		if options.IfMatch, err = flags.GetString("if-match"); err != nil {
+			logger.Warn(err.Error())
+			return options, err
+		}
+		if options.ExpectStatus, err = flags.GetString("expect-status"); err != nil {
+			logger.Warn(err.Error())
+			return options, err
+		}
+		if options.MaxRetries, err = flags.GetInt("max-retries"); err != nil {
+			logger.Warn(err.Error())
+			return options, err
+		}
+
+		policy, err := flags.GetString("on-conflict")
+		if err != nil {
+			logger.Warn(err.Error())
+			return options, err
+		}
+		switch service.ConflictPolicy(policy) {
+		case service.ConflictRetry, service.ConflictAbort:
+			options.OnConflict = service.ConflictPolicy(policy)
+		default:
+			return options, service.NewError(service.ErrorUsage, SettingTaskConflictPolicyInvalid)
+		}
+
+		return options, nil
+	}
+
+	taskRequestSetting.Metadata = func(flags *pflag.FlagSet) (service.MetadataUpdate, error) {
+		// 指定されたオプションだけを変更します。空の値を指定した場合はその項目を削除します。
+		var update service.MetadataUpdate
+		var metadata service.TaskMetadata
+		if flags.Changed("tag") {
+			tags, err := flags.GetStringSlice("tag")
+			if err != nil {
+				return update, err
+			}
+			// --tag=のように空の値だけを指定した場合はタグを削除します。
+			if len(tags) == 1 && tags[0] == "" {
+				tags = []string{}
+			}
+			update.Tags, metadata.Tags = &tags, tags
+		}
+		if flags.Changed("priority") {
+			priority, _ := flags.GetString("priority")
+			update.Priority, metadata.Priority = &priority, priority
+		}
+		if flags.Changed("due") {
+			due, _ := flags.GetString("due")
+			update.Due, metadata.Due = &due, due
+		}
+		return update, metadata.Validate()
+	}
+
+	taskRequestSetting.MetadataFilter = func() (service.MetadataFilter, error) {
+		var filter service.MetadataFilter
+		flags := getCmd.Flags()
+		filter.Tags, _ = flags.GetStringSlice("tag")
+		filter.Priority, _ = flags.GetString("priority")
+		if before, _ := flags.GetString("due-before"); before != "" {
+			due, err := service.ParseDue(before)
+			if err != nil {
+				return filter, err
+			}
+			filter.DueBefore = due
+		}
+		return filter, service.TaskMetadata{Tags: filter.Tags, Priority: filter.Priority}.Validate()
+	}
 }
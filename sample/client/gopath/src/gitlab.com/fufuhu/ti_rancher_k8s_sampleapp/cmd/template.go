@@ -0,0 +1,138 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// TemplateArgsInvalid はtemplate showにテンプレートの名前が指定されていない場合のエラーメッセージです。
+const TemplateArgsInvalid service.MessageID = "cmd.template.args_invalid"
+
+// TemplateSetInvalid は--setの指定がKEY=VALUEの形式でない場合のエラーメッセージです。
+const TemplateSetInvalid service.MessageID = "cmd.template.set_invalid"
+
+// templateCmd represents the template command
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "cmd.template.short",
+	Long:  "cmd.template.long",
+}
+
+// templateListCmd represents the template list command
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "cmd.template.list.short",
+	Long:  "cmd.template.list.long",
+	RunE:  templateList,
+}
+
+// templateShowCmd represents the template show command
+var templateShowCmd = &cobra.Command{
+	Use:   "show NAME",
+	Short: "cmd.template.show.short",
+	Long:  "cmd.template.show.long",
+	RunE:  templateShow,
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateListCmd)
+	templateCmd.AddCommand(templateShowCmd)
+
+	templateShowCmd.Flags().Bool("render", false, "cmd.template.show.flag.render")
+	templateShowCmd.Flags().StringArray("set", nil, "cmd.template.flag.set")
+}
+
+func templateList(cmd *cobra.Command, args []string) error {
+	dir, err := clientSetting.TemplateDir()
+	if err != nil {
+		return err
+	}
+	templates, errs := service.ListTaskTemplates(dir)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTASKS\tDESCRIPTION")
+	for _, tmpl := range templates {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", tmpl.Name, len(tmpl.Tasks), tmpl.Description)
+	}
+	w.Flush()
+	// 読み込めないテンプレートがあっても、他のテンプレートは表示します。
+	for _, err := range errs {
+		logger.Warn(err.Error())
+	}
+	return nil
+}
+
+func templateShow(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return usageError(TemplateArgsInvalid)
+	}
+	dir, err := clientSetting.TemplateDir()
+	if err != nil {
+		return err
+	}
+	tmpl, err := service.LoadTaskTemplate(dir, args[0])
+	if err != nil {
+		return err
+	}
+
+	if render, _ := cmd.Flags().GetBool("render"); !render {
+		content, err := ioutil.ReadFile(tmpl.Path)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(content))
+		return nil
+	}
+	// 作成されるタスクを、テンプレートのtasksと同じ形式で表示します。
+	sets, _ := cmd.Flags().GetStringArray("set")
+	tasks, err := renderTaskTemplate(tmpl, sets)
+	if err != nil {
+		return err
+	}
+	entries := make([]service.TaskTemplateEntry, len(tasks))
+	for i, task := range tasks {
+		entries[i] = service.TaskTemplateEntry{Title: task.Title, Description: task.Description}
+	}
+	out, err := yaml.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+// renderTaskTemplate は--setで指定されたパラメータと現在の日時でテンプレートを展開します。
+func renderTaskTemplate(tmpl service.TaskTemplate, sets []string) ([]service.Task, error) {
+	params := make(map[string]string, len(sets))
+	for _, set := range sets {
+		i := strings.Index(set, "=")
+		if i <= 0 {
+			return nil, usageError(TemplateSetInvalid, set)
+		}
+		params[set[:i]] = set[i+1:]
+	}
+	return tmpl.Render(time.Now(), params)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// TestCreateFromTemplate ではcreate --templateでテンプレートのタスクをまとめて作成し、
+// テンプレートやパラメータに誤りがある場合はタスクを1件も作成しないことを確認する。
+func TestCreateFromTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TODO_TEMPLATE_DIR", dir)
+	defer os.Unsetenv("TODO_TEMPLATE_DIR")
+	os.Setenv("TODO_JOURNAL_DIR", filepath.Join(dir, "journal"))
+	defer os.Unsetenv("TODO_JOURNAL_DIR")
+
+	templates := map[string]string{
+		"handover": "params:\n  service: \"\"\n  owner: oncall\ntasks:\n- title: \"check {{.Args.service}}\"\n- title: \"notes {{.Args.service}}\"\n  description: \"owner: {{.Args.owner}}\"\n",
+		"broken":   "tasks:\n- title: ok\n- title: \"{{.Args.missing}}\"\n",
+	}
+	for name, content := range templates {
+		if err := ioutil.WriteFile(filepath.Join(dir, name+service.TemplateExt), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	todo := &smoketestServer{tasks: map[int]service.Task{}, nextID: 1}
+	server := httptest.NewServer(todo)
+	defer server.Close()
+	create := func(args ...string) int {
+		return executeForTest(append(append([]string{"create"}, args...), serverArgs(server)...)...)
+	}
+
+	for _, args := range [][]string{
+		{"--template", "handover"},
+		{"--template", "handover", "--set", "service=api", "--set", "team=sre"},
+		{"--template", "handover", "--set", "service"},
+		{"--template", "handover", "--set", "service=api", "--title", "x"},
+		{"--template", "broken"},
+		{"--template", "missing"},
+		{"--template", "../handover"},
+	} {
+		if code := create(args...); code != ExitUsage {
+			t.Errorf("%v: exit code = %d", args, code)
+		}
+	}
+	if len(todo.tasks) != 0 {
+		t.Fatalf("tasks created from an invalid template: %v", todo.tasks)
+	}
+
+	if code := create("--template", "handover", "--set", "service=api", "--set", "owner=alice"); code != ExitOK {
+		t.Errorf("exit code = %d", code)
+	}
+	want := []service.Task{
+		{ID: 1, Title: "check api", Status: "TODO"},
+		{ID: 2, Title: "notes api", Description: "owner: alice", Status: "TODO"},
+	}
+	for _, task := range want {
+		if todo.tasks[task.ID] != task {
+			t.Errorf("task %d = %+v, want %+v", task.ID, todo.tasks[task.ID], task)
+		}
+	}
+
+	// 途中で作成に失敗した場合は、失敗の原因に応じた終了コードで終了する
+	posts := 0
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" && r.URL.Path == "/api/task" {
+			if posts++; posts > 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		todo.ServeHTTP(w, r)
+	}))
+	defer failing.Close()
+	args := append([]string{"create", "--template", "handover", "--set", "service=web"}, serverArgs(failing)...)
+	if code := executeForTest(args...); code != ExitServer || len(todo.tasks) != 3 {
+		t.Errorf("partial: exit code = %d, want %d, tasks = %v", code, ExitServer, todo.tasks)
+	}
+
+	if code := executeForTest("template", "list"); code != ExitOK {
+		t.Errorf("list: exit code = %d", code)
+	}
+	if code := executeForTest("template", "show", "handover", "--render", "--set", "service=api"); code != ExitOK {
+		t.Errorf("show: exit code = %d", code)
+	}
+}
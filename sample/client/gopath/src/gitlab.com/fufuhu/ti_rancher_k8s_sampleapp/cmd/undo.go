@@ -0,0 +1,133 @@
+// Copyright © 2019 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// UndoCountInvalid は取り消す操作の数に1未満が指定された場合のエラーメッセージです。
+const UndoCountInvalid service.MessageID = "cmd.undo.count_invalid"
+
+// UndoFailure は操作の取り消しに失敗した場合のエラーメッセージです。取り消そうとした操作を埋め込みます。
+const UndoFailure service.MessageID = "cmd.undo.failure"
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "cmd.undo.short",
+	Long:  "cmd.undo.long",
+	RunE:  undo,
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// undoCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// undoCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	undoCmd.Flags().IntP("count", "n", 1, "cmd.undo.flag.count")
+}
+
+func undo(cmd *cobra.Command, args []string) error {
+	protocol, err := clientSetting.Protocol()
+	if err != nil {
+		return err
+	}
+	host, err := clientSetting.Host()
+	if err != nil {
+		return err
+	}
+	port, err := clientSetting.Port()
+	if err != nil {
+		return err
+	}
+
+	token, err := clientSetting.Token()
+	if err != nil {
+		return service.WrapError(service.ErrorAuth, SettingErrorMessageTokenInvalid, err)
+	}
+
+	count, err := cmd.Flags().GetInt("count")
+	if err != nil || count < 1 {
+		return usageError(UndoCountInvalid)
+	}
+
+	// 取り消しは操作を記録したエンドポイントに対して行う必要があるため、接続先を固定します。
+	protocol, host, port, token = pinEndpoint(protocol, host, port, token)
+
+	journal, err := openJournal(protocol, host, port, token)
+	if err != nil {
+		return err
+	}
+
+	dryRun, _ := clientSetting.DryRun()
+	if dryRun {
+		// ドライラン時はジャーナルを更新しないため、対象の操作をまとめて取り出します。
+		entries, err := journal.Undoable(count)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			_, err := service.UndoJournalEntry(protocol, host, port, token, entry)
+			if err == nil {
+				// 書き込みを伴わない取り消しはnilを返すため、その旨を出力して次の操作に進みます。
+				logger.Info(service.T("cmd.undo.nothing_to_send", entry.Seq, entry.Operation, entry.TaskID))
+				continue
+			}
+			if err != service.ErrDryRun {
+				return service.WrapError(service.KindOf(err), UndoFailure, err, entry.Seq, entry.Operation, entry.TaskID)
+			}
+		}
+		logger.Info(service.T(DryRunMessage))
+		return nil
+	}
+
+	for i := 0; i < count; i++ {
+		// 削除の取り消しでタスクのIDが変わることがあるため、1件ずつ取り出します。
+		entries, err := journal.Undoable(1)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			logger.Info(service.T("cmd.undo.nothing"))
+			return nil
+		}
+		entry := entries[0]
+
+		undone, err := service.UndoJournalEntry(protocol, host, port, token, entry)
+		if err != nil {
+			return service.WrapError(service.KindOf(err), UndoFailure, err, entry.Seq, entry.Operation, entry.TaskID)
+		}
+
+		if _, err := journal.Append(undone); err != nil {
+			logger.Warn(service.T("cmd.undo.record_failure"), "error", err)
+		}
+		fmt.Println(service.T("cmd.undo.done", entry.Seq, entry.Operation, entry.TaskID))
+		if undone.Current != nil && undone.Current.ID != entry.TaskID {
+			fmt.Println(service.T("cmd.undo.restored", undone.Current.ID))
+		}
+	}
+	return nil
+}
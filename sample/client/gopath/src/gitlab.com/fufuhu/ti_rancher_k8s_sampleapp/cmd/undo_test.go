@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
+)
+
+// TestUndoDryRun ではドライラン時に指定した数の操作をすべて確認し、タスクを変更しないことと、
+// 取り消せない操作があれば失敗することを確認する。
+func TestUndoDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "undo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("TODO_JOURNAL_DIR", dir)
+	defer os.Unsetenv("TODO_JOURNAL_DIR")
+
+	todo := &smoketestServer{tasks: map[int]service.Task{}, nextID: 1}
+	server := httptest.NewServer(todo)
+	defer server.Close()
+	run := func(args ...string) int {
+		return executeForTest(append(args, serverArgs(server)...)...)
+	}
+
+	for _, args := range [][]string{
+		{"create", "--title", "deploy", "--description", "release"},
+		{"create", "--title", "review", "--description", "code"},
+		{"update", "--id", "1", "--title", "deploy v2"},
+		{"update", "--id", "2", "--status", "RUNNING"},
+	} {
+		if code := run(args...); code != ExitOK {
+			t.Fatalf("%v: exit code = %d", args, code)
+		}
+	}
+	want := map[int]service.Task{1: todo.tasks[1], 2: todo.tasks[2]}
+
+	if code := run("undo", "--dry-run", "--count", "4"); code != ExitOK {
+		t.Errorf("undo --dry-run: exit code = %d", code)
+	}
+	for id, task := range want {
+		if todo.tasks[id] != task {
+			t.Errorf("task %d = %+v, want %+v", id, todo.tasks[id], task)
+		}
+	}
+
+	// 古い操作の対象のタスクが後から変更されていれば、ドライランでも取り消せないことを報告する
+	changed := want[1]
+	changed.Description = "edited"
+	todo.tasks[1] = changed
+	if code := run("undo", "--dry-run", "--count", "4"); code != ExitConflict {
+		t.Errorf("undo --dry-run after another change: exit code = %d, want %d", code, ExitConflict)
+	}
+}
@@ -15,20 +15,19 @@
 package cmd
 
 import (
-	"fmt"
-	"log"
-
 	"github.com/spf13/cobra"
 	"gitlab.com/fufuhu/ti_rancher_k8s_sampleapp/service"
 )
 
+// UpdateIDRequired は更新対象のタスクのIDが指定されていない場合のエラーメッセージです。
+const UpdateIDRequired service.MessageID = "cmd.update.id_required"
+
 // updateCmd represents the update command
 var updateCmd = &cobra.Command{
 	Use:   "update",
-	Short: "ユーザに紐づくTODOタスクを更新します。",
-	Long: `ユーザに紐づくTODOタスクを更新します。
-		--id指定なしの場合は、エラーを返します。`,
-	Run: update,
+	Short: "cmd.update.short",
+	Long:  "cmd.update.long",
+	RunE:  update,
 }
 
 func init() {
@@ -43,30 +42,36 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// updateCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	updateCmd.Flags().Int("id", 0, "更新したいタスクのID")
-	updateCmd.Flags().String("title", "", "更新後のタスクの名前")
-	updateCmd.Flags().String("description", "", "更新後のタスクの説明")
-	updateCmd.Flags().String("status", "", "更新後のタスクのステータス")
+	updateCmd.Flags().Int("id", 0, "cmd.update.flag.id")
+	updateCmd.Flags().String("title", "", "cmd.update.flag.title")
+	updateCmd.Flags().String("description", "", "cmd.update.flag.description")
+	updateCmd.Flags().String("status", "", "cmd.update.flag.status")
+	updateCmd.Flags().String("if-match", "", "cmd.update.flag.if-match")
+	updateCmd.Flags().String("expect-status", "", "cmd.update.flag.expect-status")
+	updateCmd.Flags().String("on-conflict", string(service.ConflictRetry), "cmd.update.flag.on-conflict")
+	updateCmd.Flags().Int("max-retries", service.DefaultUpdateOptions.MaxRetries, "cmd.update.flag.max-retries")
+	updateCmd.Flags().StringSlice("tag", nil, "flag.task.tag")
+	updateCmd.Flags().String("priority", "", "flag.task.priority")
+	updateCmd.Flags().String("due", "", "flag.task.due")
 }
 
-func update(cmd *cobra.Command, args []string) {
+func update(cmd *cobra.Command, args []string) error {
 	protocol, err := clientSetting.Protocol()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	host, err := clientSetting.Host()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	port, err := clientSetting.Port()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	token, err := clientSetting.Token()
 	if err != nil {
-		log.Println("JWTトークンの設定が異常です。loginサブコマンドで再取得してください。")
-		log.Fatal(err)
+		return service.WrapError(service.ErrorAuth, SettingErrorMessageTokenInvalid, err)
 	}
 
 	id, _ := taskRequestSetting.ID()
@@ -74,17 +79,42 @@ func update(cmd *cobra.Command, args []string) {
 	description, _ := taskRequestSetting.Description()
 	status, _ := taskRequestSetting.Status()
 
+	options, err := taskRequestSetting.UpdateOptions()
+	if err != nil {
+		return err
+	}
+	// --descriptionで本文だけを置き換える場合も、概要のメタデータは残します。
+	metadata, err := taskRequestSetting.Metadata(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	options.Metadata = &metadata
+
 	if id != 0 {
-		task, err := service.UpdateTask(protocol, host, port, token, id, title, description, status)
+		result, err := service.UpdateTaskWithOptions(protocol, host, port, token, id, title, description, status, options)
 
+		if err == service.ErrDryRun {
+			logger.Info(service.T(DryRunMessage))
+			return nil
+		}
 		if err != nil {
-			log.Println(err)
+			return err
+		}
+
+		task := result.Task
+		if result.Attempts > 0 {
+			recordJournal(protocol, host, port, token, service.JournalEntry{
+				Operation: service.JournalUpdate,
+				TaskID:    task.ID,
+				Previous:  &result.Previous,
+				Current:   &result.Task,
+			})
 		}
 
-		log.Printf("Task(ID=%d) is updated.\n", task.ID)
-		fmt.Printf("ID\tTitle\tStatus\tDescription\n")
-		fmt.Printf("%d\t%s\t%s\t%s\n", task.ID, task.Title, task.Status, task.Description)
+		logger.Info(service.T("cmd.update.done"), "id", task.ID, "fingerprint", service.TaskFingerprint(task))
+		printTasks([]service.Task{task}, false)
 	} else {
-		log.Fatal("更新対象のタスクのIDが正しく指定されていません(--id)")
+		return usageError(UpdateIDRequired)
 	}
+	return nil
 }
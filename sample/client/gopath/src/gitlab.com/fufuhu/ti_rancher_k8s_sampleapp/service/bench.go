@@ -0,0 +1,289 @@
+package service
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// benchで実行する操作の種類です。
+const (
+	BenchCreate = "create" // タスクの作成
+	BenchGet    = "get"    // IDを指定したタスクの取得
+	BenchList   = "list"   // タスクの一覧の取得
+	BenchUpdate = "update" // タスクの更新
+	BenchDelete = "delete" // タスクの削除
+)
+
+// BenchOperations はbenchで実行できる操作の一覧です。
+var BenchOperations = []string{BenchCreate, BenchGet, BenchList, BenchUpdate, BenchDelete}
+
+// BenchMixInvalid は操作の配分の指定が不正な場合のエラーメッセージです。
+const BenchMixInvalid MessageID = "bench.mix_invalid"
+
+// BenchErrorNetwork はToDoサーバから応答が得られなかった失敗をステータスコードの代わりに集計する際の名前です。
+const BenchErrorNetwork = "network"
+
+// BenchMix は操作の種類ごとの実行する割合(重み)です。
+type BenchMix map[string]int
+
+// ParseBenchMix は"create=1,get=4"のような形式で指定された操作の配分を解釈します。
+func ParseBenchMix(value string) (BenchMix, error) {
+	mix := BenchMix{}
+	total := 0
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.Index(item, "=")
+		if i < 0 {
+			return nil, NewError(ErrorUsage, BenchMixInvalid, item)
+		}
+		operation := item[:i]
+		weight, err := strconv.Atoi(item[i+1:])
+		if err != nil || weight < 0 || !isBenchOperation(operation) {
+			return nil, NewError(ErrorUsage, BenchMixInvalid, item)
+		}
+		mix[operation] = weight
+		total += weight
+	}
+	if total == 0 {
+		return nil, NewError(ErrorUsage, BenchMixInvalid, value)
+	}
+	return mix, nil
+}
+
+func isBenchOperation(operation string) bool {
+	for _, o := range BenchOperations {
+		if o == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// pick は重みに従って操作の種類をひとつ選びます。
+func (m BenchMix) pick(r *rand.Rand) string {
+	total := 0
+	for _, operation := range BenchOperations {
+		total += m[operation]
+	}
+	n := r.Intn(total)
+	for _, operation := range BenchOperations {
+		if n < m[operation] {
+			return operation
+		}
+		n -= m[operation]
+	}
+	return BenchOperations[0]
+}
+
+// BenchOptions は負荷をかける条件を表します。
+type BenchOptions struct {
+	Mix         BenchMix      // 操作の配分
+	Concurrency int           // 同時に実行する操作の数(1未満の場合は1)
+	Duration    time.Duration // 負荷をかける時間
+	Rate        float64       // 1秒あたりに開始する操作の目標値(0以下の場合は制限なし)
+}
+
+// BenchStats は操作の種類ひとつ分の集計結果です。
+type BenchStats struct {
+	Count   int64             // 実行した操作の数
+	Errors  int64             // 失敗した操作の数
+	Latency *LatencyHistogram // 操作にかかった時間(失敗した操作を含む)
+}
+
+func newBenchStats() *BenchStats {
+	return &BenchStats{Latency: NewLatencyHistogram()}
+}
+
+// ErrorRate は失敗した操作の割合(0〜1)を返します。
+func (s *BenchStats) ErrorRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Count)
+}
+
+// BenchReport は負荷をかけた結果です。
+type BenchReport struct {
+	Elapsed    time.Duration          // 負荷をかけた時間
+	Operations map[string]*BenchStats // 操作の種類ごとの集計結果
+	Total      *BenchStats            // すべての操作の集計結果
+	// ErrorsByStatus は失敗した操作の数をステータスコード(応答が無い場合はBenchErrorNetwork)ごとに数えたものです。
+	ErrorsByStatus map[string]int64
+	// Created はbenchで作成し、削除していないタスクのIDです。後片付けに利用します。
+	Created []int
+}
+
+// Throughput は1秒あたりに完了した操作の数を返します。
+func (r BenchReport) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Total.Count) / r.Elapsed.Seconds()
+}
+
+// benchPool はbenchで作成したタスクのIDを保持します。
+// 更新や削除は他の利用者のタスクを変更しないよう、benchで作成したタスクだけを対象とします。
+// 削除中のタスクを取得したり、同じタスクを同時に更新して競合したりしないよう、操作中のタスクは取り出しておきます。
+type benchPool struct {
+	mu  sync.Mutex
+	ids []int
+}
+
+func (p *benchPool) put(id int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ids = append(p.ids, id)
+}
+
+// take はタスクのIDをひとつ取り出します。タスクが無い場合はfalseを返します。
+func (p *benchPool) take(r *rand.Rand) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.ids) == 0 {
+		return 0, false
+	}
+	i := r.Intn(len(p.ids))
+	id := p.ids[i]
+	p.ids[i] = p.ids[len(p.ids)-1]
+	p.ids = p.ids[:len(p.ids)-1]
+	return id, true
+}
+
+// RunBench はoptions.Durationの間、指定された配分で操作を繰り返してToDoサーバに負荷をかけます。
+// get/update/deleteの対象となるタスクが無い場合は、代わりにタスクを作成します。
+func RunBench(protocol string, host string, port int, token string, options BenchOptions) BenchReport {
+	concurrency := options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var limiter <-chan time.Time
+	if options.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / options.Rate))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	report := BenchReport{
+		Operations:     map[string]*BenchStats{},
+		Total:          newBenchStats(),
+		ErrorsByStatus: map[string]int64{},
+	}
+	for _, operation := range BenchOperations {
+		report.Operations[operation] = newBenchStats()
+	}
+
+	var mu sync.Mutex
+	var pool benchPool
+	var sequence int64
+	start := time.Now()
+	deadline := start.Add(options.Duration)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for {
+				if limiter != nil {
+					select {
+					case <-limiter:
+					case <-time.After(time.Until(deadline)):
+					}
+				}
+				if !time.Now().Before(deadline) {
+					return
+				}
+
+				mu.Lock()
+				sequence++
+				n := sequence
+				mu.Unlock()
+
+				operation, elapsed, err := runBenchOperation(protocol, host, port, token, options.Mix.pick(r), n, &pool, r)
+
+				mu.Lock()
+				for _, stats := range []*BenchStats{report.Operations[operation], report.Total} {
+					stats.Count++
+					stats.Latency.Record(elapsed)
+					if err != nil {
+						stats.Errors++
+					}
+				}
+				if err != nil {
+					report.ErrorsByStatus[benchErrorStatus(err)]++
+				}
+				mu.Unlock()
+			}
+		}(time.Now().UnixNano() + int64(i))
+	}
+	wg.Wait()
+
+	report.Elapsed = time.Since(start)
+	report.Created = pool.ids
+	sort.Ints(report.Created)
+	return report
+}
+
+// runBenchOperation は操作をひとつ実行し、実際に実行した操作の種類とかかった時間を返します。
+func runBenchOperation(protocol string, host string, port int, token string, operation string, n int64, pool *benchPool, r *rand.Rand) (string, time.Duration, error) {
+	var id int
+	ok := true
+	switch operation {
+	case BenchGet, BenchUpdate, BenchDelete:
+		id, ok = pool.take(r)
+	}
+	if !ok {
+		operation = BenchCreate
+	}
+
+	start := time.Now()
+	var err error
+	switch operation {
+	case BenchCreate:
+		var created CreatedTask
+		created, err = CreateTask(protocol, host, port, token, "bench "+strconv.FormatInt(n, 10), "created by todo bench")
+		if err == nil {
+			pool.put(created.ID)
+		}
+	case BenchGet:
+		_, err = GetTask(protocol, host, port, token, id)
+		if KindOf(err) != ErrorNotFound {
+			pool.put(id)
+		}
+	case BenchList:
+		_, err = GetTasks(protocol, host, port, token)
+	case BenchUpdate:
+		// UpdateTaskは更新前後にタスクを取得するため、PATCHリクエストだけを送信して1回の操作とします。
+		// サーバはstatusを必須とするので、benchで作成したタスクのステータス(TODO)をそのまま送ります。
+		_, err = patchTask(protocol, host, port, token, id, map[string]string{"title": "bench " + strconv.FormatInt(n, 10), "status": "TODO"})
+		if KindOf(err) != ErrorNotFound {
+			pool.put(id)
+		}
+	case BenchDelete:
+		// 削除できなかったタスクは後片付けの対象として残します。
+		if _, err = DeleteTask(protocol, host, port, token, id); err != nil && KindOf(err) != ErrorNotFound {
+			pool.put(id)
+		}
+	}
+	return operation, time.Since(start), err
+}
+
+// benchErrorStatus は失敗をステータスコードごとに集計するための名前を返します。
+func benchErrorStatus(err error) string {
+	if e, ok := err.(*Error); ok && e.StatusCode != 0 {
+		return strconv.Itoa(e.StatusCode)
+	}
+	if KindOf(err) == ErrorNetwork {
+		return BenchErrorNetwork
+	}
+	return KindOf(err).String()
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestParseBenchMix では操作の配分を解釈でき、不正な指定を拒否することを確認する。
+func TestParseBenchMix(t *testing.T) {
+	mix, err := ParseBenchMix("create=1, get=4,list=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mix[BenchCreate] != 1 || mix[BenchGet] != 4 || mix[BenchList] != 0 || mix[BenchUpdate] != 0 {
+		t.Errorf("mix = %v", mix)
+	}
+
+	for _, value := range []string{"", "create=0", "create", "create=-1", "fetch=1", "get=x"} {
+		if _, err := ParseBenchMix(value); !IsMessage(err, BenchMixInvalid) {
+			t.Errorf("ParseBenchMix(%q) = %v", value, err)
+		}
+	}
+}
+
+// TestRunBench では指定した配分で操作を繰り返し、benchで作成したタスクだけを更新・削除することを確認する。
+func TestRunBench(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "user task", Description: "", Status: "TODO"})
+
+	protocol, host, port := server.target()
+	mix, _ := ParseBenchMix("create=1,get=1,list=1,update=1,delete=1")
+	report := RunBench(protocol, host, port, "token", BenchOptions{Mix: mix, Concurrency: 4, Duration: 200 * time.Millisecond})
+
+	if report.Total.Count == 0 || report.Total.Errors != 0 || len(report.ErrorsByStatus) != 0 {
+		t.Fatalf("total = %+v errors = %v", report.Total, report.ErrorsByStatus)
+	}
+	var sum int64
+	for _, operation := range BenchOperations {
+		sum += report.Operations[operation].Count
+		if report.Operations[operation].Count == 0 {
+			t.Errorf("%s was not run", operation)
+		}
+	}
+	if sum != report.Total.Count || report.Total.Latency.Count() != sum || report.Throughput() <= 0 {
+		t.Errorf("sum = %d total = %d", sum, report.Total.Count)
+	}
+
+	if task, ok := server.get(1); !ok || task.Title != "user task" {
+		t.Errorf("the user's task was changed: %+v", task)
+	}
+	for _, id := range report.Created {
+		if _, ok := server.get(id); !ok {
+			t.Errorf("task %d is reported as remaining but was deleted", id)
+		}
+	}
+}
+
+// TestRunBenchUpdateSendsSinglePatch ではupdateの操作ごとにPATCHリクエストを1回だけ送信することを確認する。
+func TestRunBenchUpdateSendsSinglePatch(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+
+	protocol, host, port := server.target()
+	mix, _ := ParseBenchMix("update=1")
+	report := RunBench(protocol, host, port, "token", BenchOptions{Mix: mix, Concurrency: 2, Duration: 100 * time.Millisecond})
+
+	methods := map[string]int64{}
+	for _, r := range server.requests {
+		methods[r.Method]++
+	}
+	if report.Operations[BenchUpdate].Count == 0 || methods["PATCH"] != report.Operations[BenchUpdate].Count || methods["GET"] != 0 {
+		t.Errorf("update = %d, requests = %v", report.Operations[BenchUpdate].Count, methods)
+	}
+}
+
+// TestRunBenchCountsErrorsByStatus では失敗をステータスコードごとに数え、目標の頻度を超えないことを確認する。
+func TestRunBenchCountsErrorsByStatus(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.beforeHandle = func(w http.ResponseWriter, r *http.Request, n int) bool {
+		w.WriteHeader(http.StatusBadGateway)
+		return true
+	}
+
+	protocol, host, port := server.target()
+	mix, _ := ParseBenchMix("list=1")
+	report := RunBench(protocol, host, port, "token", BenchOptions{Mix: mix, Concurrency: 4, Duration: 300 * time.Millisecond, Rate: 20})
+
+	if report.Total.Count == 0 || report.Total.Count > 7 {
+		t.Errorf("count = %d", report.Total.Count)
+	}
+	if report.Total.Errors != report.Total.Count || report.ErrorsByStatus["502"] != report.Total.Count {
+		t.Errorf("errors = %d by status = %v", report.Total.Errors, report.ErrorsByStatus)
+	}
+	if report.Total.ErrorRate() != 1 {
+		t.Errorf("error rate = %v", report.Total.ErrorRate())
+	}
+}
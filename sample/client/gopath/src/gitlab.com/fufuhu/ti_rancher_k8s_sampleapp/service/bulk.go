@@ -0,0 +1,203 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// BulkOperationUnknown はbulkで指定された操作の種類がcreate/update/delete以外の場合のエラーメッセージです。
+const BulkOperationUnknown MessageID = "bulk.operation_unknown"
+
+// BulkOperationIDRequired はupdate/deleteの操作でIDが指定されていない場合のエラーメッセージです。
+const BulkOperationIDRequired MessageID = "bulk.id_required"
+
+// BulkProgress はbulkの進捗として成功と失敗の件数を出力する際のメッセージです。
+const BulkProgress MessageID = "bulk.progress"
+
+// BulkOperation はまとめて実行する操作の1件分を表します。
+type BulkOperation struct {
+	Operation   string `yaml:"operation" json:"operation"`     // 操作の種類(create/update/delete)
+	ID          int    `yaml:"id" json:"id"`                   // 対象のタスクのID(update/deleteのみ)
+	Title       string `yaml:"title" json:"title"`             // タスクの名前(create/updateのみ)
+	Description string `yaml:"description" json:"description"` // タスクの概要(create/updateのみ)
+	Status      string `yaml:"status" json:"status"`           // タスクのステータス(updateのみ)
+}
+
+// Validate は操作の内容がリクエストを送信できる状態であるかを確認します。
+func (o BulkOperation) Validate() error {
+	switch o.Operation {
+	case JournalCreate:
+		return nil
+	case JournalUpdate, JournalDelete:
+		if o.ID <= 0 {
+			return NewError(ErrorValidation, BulkOperationIDRequired)
+		}
+		return nil
+	}
+	return NewError(ErrorValidation, BulkOperationUnknown)
+}
+
+// BulkResult はまとめて実行した操作の1件分の結果を表します。
+type BulkResult struct {
+	Index     int           // 操作の並び順(0始まり)
+	Operation BulkOperation // 実行した操作
+	Task      Task          // 操作後のタスク(削除の場合は削除したタスク)
+	Previous  *Task         // 操作前のタスク(作成の場合はなし)
+	Err       error         // 操作に失敗した場合のエラー
+	Elapsed   time.Duration // 操作にかかった時間
+}
+
+// BulkOptions はまとめて実行する際の並行数などの条件を表します。
+type BulkOptions struct {
+	Parallel int       // 同時に実行する操作の数(1未満の場合は1)
+	Rate     float64   // 1秒あたりに開始する操作の最大数(0以下の場合は制限なし)
+	Progress io.Writer // 進捗の出力先(nilの場合は出力しない)
+	// OnResult は操作が完了するたびに、操作の並び順どおりに呼ばれます。
+	OnResult func(BulkResult)
+}
+
+// BulkSummary はまとめて実行した操作の集計結果を表します。
+type BulkSummary struct {
+	Succeeded int // 成功した操作の数
+	Failed    int // 失敗した操作の数
+	Skipped   int // ドライランなどで実行しなかった操作の数
+}
+
+// Add は結果を成功・失敗・スキップに分けて集計に加えます。
+func (s *BulkSummary) Add(result BulkResult) {
+	switch {
+	case result.Err == ErrDryRun:
+		s.Skipped++
+	case result.Err != nil:
+		s.Failed++
+	default:
+		s.Succeeded++
+	}
+}
+
+// Total は集計した操作の総数を返します。
+func (s BulkSummary) Total() int {
+	return s.Succeeded + s.Failed + s.Skipped
+}
+
+// SummarizeBulkResults は結果を成功・失敗・スキップに分けて集計します。
+func SummarizeBulkResults(results []BulkResult) BulkSummary {
+	var summary BulkSummary
+	for _, result := range results {
+		summary.Add(result)
+	}
+	return summary
+}
+
+// RunBulk は複数の操作を並行して実行し、操作の並び順どおりに結果を返します。
+// 同時に実行する数はoptions.Parallelに、開始する頻度はoptions.Rateに制限されます。
+func RunBulk(protocol string, host string, port int, token string, operations []BulkOperation, options BulkOptions) []BulkResult {
+	parallel := options.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var limiter <-chan time.Time
+	if options.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / options.Rate))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	indexes := make(chan int)
+	done := make(chan BulkResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				if limiter != nil {
+					<-limiter
+				}
+				done <- runBulkOperation(protocol, host, port, token, index, operations[index])
+			}
+		}()
+	}
+
+	go func() {
+		for index := range operations {
+			indexes <- index
+		}
+		close(indexes)
+		wg.Wait()
+		close(done)
+	}()
+
+	// 完了した順に受け取った結果を並び替えながら通知します。
+	results := make([]BulkResult, len(operations))
+	completed := make([]bool, len(operations))
+	next := 0
+	var summary BulkSummary
+	for result := range done {
+		results[result.Index] = result
+		completed[result.Index] = true
+
+		summary.Add(result)
+		if options.Progress != nil {
+			fmt.Fprintf(options.Progress, "\r[%d/%d] %s", summary.Total(), len(operations), T(BulkProgress, summary.Succeeded, summary.Failed))
+		}
+
+		for next < len(operations) && completed[next] {
+			if options.OnResult != nil {
+				options.OnResult(results[next])
+			}
+			next++
+		}
+	}
+	if options.Progress != nil && len(operations) > 0 {
+		// 続けて出力する集計が進捗と同じ行に続かないよう、最後の進捗の後で改行します。
+		fmt.Fprintln(options.Progress)
+	}
+
+	return results
+}
+
+// runBulkOperation は1件分の操作を実行します。
+func runBulkOperation(protocol string, host string, port int, token string, index int, operation BulkOperation) BulkResult {
+	result := BulkResult{Index: index, Operation: operation}
+	start := time.Now()
+
+	if err := operation.Validate(); err != nil {
+		result.Err = err
+		result.Elapsed = time.Since(start)
+		return result
+	}
+
+	switch operation.Operation {
+	case JournalCreate:
+		created, err := CreateTask(protocol, host, port, token, operation.Title, operation.Description)
+		result.Task = Task{ID: created.ID, Title: created.Title, Description: created.Description, Status: "TODO"}
+		result.Err = err
+
+	case JournalUpdate:
+		updated, err := UpdateTaskWithOptions(protocol, host, port, token, operation.ID,
+			operation.Title, operation.Description, operation.Status, DefaultUpdateOptions)
+		result.Task = updated.Task
+		result.Previous = &updated.Previous
+		result.Err = err
+
+	case JournalDelete:
+		// 削除APIのレスポンスにはステータスが含まれないため、削除前のタスクを取得しておきます。
+		if tasks, err := GetTask(protocol, host, port, token, operation.ID); err == nil && len(tasks) > 0 {
+			result.Previous = &tasks[0]
+		}
+		deleted, err := DeleteTask(protocol, host, port, token, operation.ID)
+		result.Task = deleted
+		result.Err = err
+		if result.Previous == nil {
+			result.Previous = &deleted
+		}
+	}
+
+	result.Elapsed = time.Since(start)
+	return result
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRunBulkPreservesOrder では操作の完了順に関わらず、
+// 結果が操作の並び順どおりに通知されることを確認する。
+func TestRunBulkPreservesOrder(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	// 先に送られた作成リクエストほど応答を遅らせる
+	server.beforeHandle = func(w http.ResponseWriter, r *http.Request, n int) bool {
+		if r.Method == "POST" {
+			time.Sleep(time.Duration(20-n) * time.Millisecond)
+		}
+		return false
+	}
+
+	var operations []BulkOperation
+	for i := 0; i < 10; i++ {
+		operations = append(operations, BulkOperation{Operation: JournalCreate, Title: string('A' + rune(i)), Description: "D"})
+	}
+
+	protocol, host, port := server.target()
+	var notified []int
+	results := RunBulk(protocol, host, port, "token", operations, BulkOptions{
+		Parallel: 5,
+		OnResult: func(result BulkResult) { notified = append(notified, result.Index) },
+	})
+
+	if len(results) != len(operations) || len(notified) != len(operations) {
+		t.Fatalf("results: %d notified: %d", len(results), len(notified))
+	}
+	for i, result := range results {
+		if notified[i] != i || result.Index != i || result.Err != nil {
+			t.Fail()
+		}
+		if result.Task.Title != operations[i].Title {
+			t.Fail()
+		}
+	}
+}
+
+// TestRunBulkLimitsParallelism では同時に実行される操作の数がParallelを超えないことを確認する。
+func TestRunBulkLimitsParallelism(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	server.beforeHandle = func(w http.ResponseWriter, r *http.Request, n int) bool {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return false
+	}
+
+	var operations []BulkOperation
+	for i := 0; i < 12; i++ {
+		operations = append(operations, BulkOperation{Operation: JournalCreate, Title: "T", Description: "D"})
+	}
+
+	protocol, host, port := server.target()
+	RunBulk(protocol, host, port, "token", operations, BulkOptions{Parallel: 3})
+
+	if peak > 3 || peak < 2 {
+		t.Fatalf("peak: %d", peak)
+	}
+}
+
+// TestRunBulkWithFailures では失敗した操作があっても残りの操作が実行され、
+// 集計と進捗の出力に反映されることを確認する。
+func TestRunBulkWithFailures(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "D", Status: "TODO"})
+	server.put(Task{ID: 2, Title: "TODO", Description: "D", Status: "TODO"})
+
+	operations := []BulkOperation{
+		{Operation: JournalDelete, ID: 1},
+		{Operation: JournalDelete, ID: 100},
+		{Operation: JournalUpdate, ID: 2, Status: "RUNNING"},
+		{Operation: "rename", ID: 2},
+	}
+
+	protocol, host, port := server.target()
+	var progress bytes.Buffer
+	results := RunBulk(protocol, host, port, "token", operations, BulkOptions{Parallel: 2, Progress: &progress})
+
+	if results[0].Err != nil || results[0].Previous == nil || results[0].Previous.Status != "TODO" {
+		t.Fail()
+	}
+	if results[1].Err == nil || !IsMessage(results[1].Err, TaskDeleteReturnedNotFoundStatusCode) {
+		t.Fail()
+	}
+	if results[2].Err != nil || results[2].Task.Status != "RUNNING" {
+		t.Fail()
+	}
+	if results[3].Err == nil || !IsMessage(results[3].Err, BulkOperationUnknown) {
+		t.Fail()
+	}
+
+	summary := SummarizeBulkResults(results)
+	if summary.Succeeded != 2 || summary.Failed != 2 {
+		t.Fail()
+	}
+	if !strings.Contains(progress.String(), "[4/4] "+T(BulkProgress, 2, 2)) || !strings.HasSuffix(progress.String(), "\n") {
+		t.Log(progress.String())
+		t.Fail()
+	}
+}
+
+// TestRunBulkWithRate では開始する頻度がRateで制限されることを確認する。
+func TestRunBulkWithRate(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+
+	var operations []BulkOperation
+	for i := 0; i < 5; i++ {
+		operations = append(operations, BulkOperation{Operation: JournalCreate, Title: "T", Description: "D"})
+	}
+
+	protocol, host, port := server.target()
+	start := time.Now()
+	RunBulk(protocol, host, port, "token", operations, BulkOptions{Parallel: 5, Rate: 50})
+
+	// 50回/秒であれば5件の開始に少なくとも80ms程度かかる
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Fatalf("elapsed: %s", elapsed)
+	}
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ConfigAPIVersion は現在の設定ファイルの形式のバージョンです。設定ファイルの先頭のapiVersionに記述します。
+const ConfigAPIVersion = "todo.fufuhu.gitlab.com/v1"
+
+// ConfigKind は設定ファイルの種類です。設定ファイルの先頭のkindに記述します。
+const ConfigKind = "ClientConfig"
+
+// ConfigBackupSuffix は以前の形式の設定ファイルを変換する際に、変換前のファイルを残すファイル名の接尾辞です。
+const ConfigBackupSuffix = ".bak"
+
+// ConfigVersionUnsupported は設定ファイルのapiVersionがこのクライアントで扱えない場合のエラーメッセージです。
+const ConfigVersionUnsupported MessageID = "config.version_unsupported"
+
+// ConfigKindInvalid は設定ファイルのkindがClientConfigでない場合のエラーメッセージです。
+const ConfigKindInvalid MessageID = "config.kind_invalid"
+
+// ConfigDecodeFailure は設定ファイルに不明なキーや型の合わない値がある場合のエラーメッセージです。
+const ConfigDecodeFailure MessageID = "config.decode_failure"
+
+// Config はyamlファイルとして保管されているToDoクライアントの設定ファイルを表します。
+// 設定ファイルを厳密に解釈するため、利用できるすべてのキーを持ちます。
+type Config struct {
+	APIVersion  string `yaml:"apiVersion,omitempty"` // 設定ファイルの形式のバージョン
+	Kind        string `yaml:"kind,omitempty"`       // 設定ファイルの種類(ClientConfig)
+	Protocol    string `yaml:"protocol,omitempty"`   // ToDoサーバにアクセスする際のプロトコル
+	Host        string `yaml:"host,omitempty"`       // ToDoサーバのFQDN
+	Port        int    `yaml:"port,omitempty"`       // ToDoサーバにアクセスする際の宛先TCPポート番号
+	Token       string `yaml:"token,omitempty"`      // ToDoサーバから取得したトークン
+	Context     string `yaml:"context,omitempty"`    // endpointsのうち利用するエンドポイントの名前
+	Lang        string `yaml:"lang,omitempty"`       // メッセージの言語
+	JournalDir  string `yaml:"journal_dir,omitempty"`
+	TemplateDir string `yaml:"template_dir,omitempty"`
+	// Endpoints は複数のエンドポイントを優先度の順に利用する場合のアクセス先です。
+	Endpoints []Endpoint       `yaml:"endpoints,omitempty"`
+	Retry     *RetryConfig     `yaml:"retry,omitempty"`
+	Failover  *FailoverConfig  `yaml:"failover,omitempty"`
+	Log       *LogConfig       `yaml:"log,omitempty"`
+	Exporter  *ExporterConfig  `yaml:"exporter,omitempty"`
+	Remind    *RemindConfig    `yaml:"remind,omitempty"`
+	Discovery *DiscoveryConfig `yaml:"discovery,omitempty"`
+	Plugin    *PluginConfig    `yaml:"plugin,omitempty"`
+	// Aliases はエイリアスの名前と展開後の引数(一覧または空白で区切った文字列)です。
+	Aliases map[string]interface{} `yaml:"aliases,omitempty"`
+	// Defaults はサブコマンドごとの既定のオプションです。
+	Defaults map[string]map[string]interface{} `yaml:"defaults,omitempty"`
+}
+
+// RetryConfig は設定ファイルの再試行の方針(retry)です。
+type RetryConfig struct {
+	Max       *int   `yaml:"max,omitempty"`
+	BaseDelay string `yaml:"base_delay,omitempty"`
+	MaxDelay  string `yaml:"max_delay,omitempty"`
+	Post      *bool  `yaml:"post,omitempty"`
+}
+
+// FailoverConfig は設定ファイルの複数のエンドポイントの振り分けの設定(failover)です。
+type FailoverConfig struct {
+	FailureThreshold *int   `yaml:"failure_threshold,omitempty"`
+	OpenTimeout      string `yaml:"open_timeout,omitempty"`
+	StateFile        string `yaml:"state_file,omitempty"`
+}
+
+// LogConfig は設定ファイルのログの設定(log)です。
+type LogConfig struct {
+	Level  string `yaml:"level,omitempty"`
+	Format string `yaml:"format,omitempty"`
+}
+
+// ExporterConfig は設定ファイルのexporterの設定(exporter)です。
+type ExporterConfig struct {
+	Listen   string `yaml:"listen,omitempty"`
+	Interval string `yaml:"interval,omitempty"`
+	Contexts []struct {
+		Name  string `yaml:"name"`
+		URL   string `yaml:"url"`
+		Token string `yaml:"token,omitempty"`
+	} `yaml:"contexts,omitempty"`
+}
+
+// RemindConfig は設定ファイルのremind daemonの設定(remind)です。
+type RemindConfig struct {
+	Interval string   `yaml:"interval,omitempty"`
+	Before   string   `yaml:"before,omitempty"`
+	Sinks    []string `yaml:"sinks,omitempty"`
+	// NotifyCommand は空白で区切った文字列、または引数の一覧です。
+	NotifyCommand interface{} `yaml:"notify_command,omitempty"`
+	WebhookURL    string      `yaml:"webhook_url,omitempty"`
+	StateFile     string      `yaml:"state_file,omitempty"`
+	Contexts      []struct {
+		Name  string `yaml:"name"`
+		URL   string `yaml:"url"`
+		Token string `yaml:"token,omitempty"`
+	} `yaml:"contexts,omitempty"`
+}
+
+// DiscoveryConfig は設定ファイルの接続先の探索の設定(discovery)です。
+type DiscoveryConfig struct {
+	Service string `yaml:"service,omitempty"`
+	Dir     string `yaml:"dir,omitempty"`
+}
+
+// PluginConfig は設定ファイルのプラグインの設定(plugin)です。
+type PluginConfig struct {
+	Handshake []string `yaml:"handshake,omitempty"`
+}
+
+// configMigration は設定ファイルの形式を1つ新しいバージョンに変換します。
+type configMigration struct {
+	From    string // 変換前のバージョン(ヘッダの無い以前の形式は空)
+	To      string // 変換後のバージョン
+	Migrate func(root yaml.MapSlice) yaml.MapSlice
+}
+
+// configMigrations は設定ファイルの形式の変換を古い順に並べたものです。
+// 形式を変更する場合は、ConfigAPIVersionを更新してここに変換を追加します。
+var configMigrations = []configMigration{
+	// ヘッダの無い以前の形式(protocol, host, port, tokenなど)は、キーはそのままでヘッダを加えます。
+	{From: "", To: ConfigAPIVersion, Migrate: func(root yaml.MapSlice) yaml.MapSlice {
+		return root
+	}},
+}
+
+// ConfigFile はYAML形式の設定ファイルを、編集しないキーの値や記述の順序を保ったまま編集します。
+// キーは"aliases.running"のように.で区切って入れ子のマップを指定します。
+type ConfigFile struct {
+	Path    string
+	root    yaml.MapSlice
+	content []byte
+}
+
+// LoadConfigFile は設定ファイルを読み込みます。
+// ファイルが無い場合は、現在の形式のヘッダ(apiVersion, kind)だけを持つ設定として扱います。
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	config := &ConfigFile{Path: path}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		config.root = configHeader(ConfigAPIVersion)
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, &config.root); err != nil {
+		return nil, err
+	}
+	config.content = content
+	return config, nil
+}
+
+// configHeader は設定ファイルの先頭に記述するapiVersionとkindです。
+func configHeader(version string) yaml.MapSlice {
+	return yaml.MapSlice{{Key: "apiVersion", Value: version}, {Key: "kind", Value: ConfigKind}}
+}
+
+// Version は設定ファイルの形式のバージョン(apiVersion)を返します。ヘッダの無い以前の形式の場合は空を返します。
+func (c *ConfigFile) Version() string {
+	version, _ := c.Get("apiVersion")
+	s, _ := version.(string)
+	return s
+}
+
+// Migrate は以前の形式の設定ファイルを現在の形式に変換し、変換したかどうかを返します。
+// 変換はメモリ上で行うため、ファイルに反映する場合はBackupで変換前のファイルを残してからSaveを呼び出します。
+// このクライアントより新しい形式の場合や、kindがClientConfigでない場合はエラーを返します。
+func (c *ConfigFile) Migrate() (bool, error) {
+	if kind, ok := c.Get("kind"); ok && kind != ConfigKind {
+		return false, NewError(ErrorUsage, ConfigKindInvalid, c.Path, kind, ConfigKind)
+	}
+	version := c.Version()
+	migrated := false
+	for _, migration := range configMigrations {
+		if migration.From != version {
+			continue
+		}
+		root := migration.Migrate(c.root)
+		// ヘッダは他の設定より前に記述します。
+		header := configHeader(migration.To)
+		for _, item := range root {
+			if key, _ := item.Key.(string); key != "apiVersion" && key != "kind" {
+				header = append(header, item)
+			}
+		}
+		c.root, version, migrated = header, migration.To, true
+	}
+	if version != ConfigAPIVersion {
+		return migrated, NewError(ErrorUsage, ConfigVersionUnsupported, c.Path, version, ConfigAPIVersion)
+	}
+	return migrated, nil
+}
+
+// Backup は変換前の設定ファイルを、名前に.bakを付けたファイルにコピーしてそのパスを返します。
+func (c *ConfigFile) Backup() (string, error) {
+	path := c.Path + ConfigBackupSuffix
+	content, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		return "", err
+	}
+	return path, ioutil.WriteFile(path, content, 0600)
+}
+
+// yamlTypeInfo はyamlパッケージのエラーに含まれるGoの型の名前です。利用者には意味が無いため取り除きます。
+var yamlTypeInfo = regexp.MustCompile(` in type [\w.]+`)
+
+// Decode は読み込んだ設定ファイルの内容を厳密に解釈します。
+// 不明なキーや型の合わない値がある場合は、ファイルの行番号を含むエラーを返します。
+// これまでの形式の変換はヘッダを加えるだけのため、変換前のファイルの内容を解釈して行番号をファイルの行と一致させます。
+func (c *ConfigFile) Decode() (Config, error) {
+	var config Config
+	err := yaml.UnmarshalStrict(c.content, &config)
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		err = errors.New(yamlTypeInfo.ReplaceAllString(strings.Join(typeErr.Errors, "; "), ""))
+	}
+	if err != nil {
+		return config, WrapError(ErrorUsage, ConfigDecodeFailure, err, c.Path)
+	}
+	return config, nil
+}
+
+// Get はキーの値を返します。入れ子のマップはyaml.MapSliceで返します。
+func (c *ConfigFile) Get(key string) (interface{}, bool) {
+	var value interface{} = c.root
+	for _, name := range strings.Split(key, ".") {
+		m, ok := value.(yaml.MapSlice)
+		if !ok {
+			return nil, false
+		}
+		i := configIndex(m, name)
+		if i < 0 {
+			return nil, false
+		}
+		value = m[i].Value
+	}
+	return value, true
+}
+
+// Set はキーに値を設定します。途中のマップが無い場合は作成し、マップでない値は置き換えます。
+func (c *ConfigFile) Set(key string, value interface{}) {
+	c.root = configSet(c.root, strings.Split(key, "."), value)
+}
+
+// configSet はマップのnamesの位置に値を設定したマップを返します。
+func configSet(m yaml.MapSlice, names []string, value interface{}) yaml.MapSlice {
+	i := configIndex(m, names[0])
+	if i < 0 {
+		m = append(m, yaml.MapItem{Key: names[0]})
+		i = len(m) - 1
+	}
+	if len(names) == 1 {
+		m[i].Value = value
+		return m
+	}
+	child, _ := m[i].Value.(yaml.MapSlice)
+	m[i].Value = configSet(child, names[1:], value)
+	return m
+}
+
+// Delete はキーを削除し、削除したかどうかを返します。
+func (c *ConfigFile) Delete(key string) bool {
+	names := strings.Split(key, ".")
+	parent := c.root
+	if len(names) > 1 {
+		value, ok := c.Get(strings.Join(names[:len(names)-1], "."))
+		if parent, ok = value.(yaml.MapSlice); !ok {
+			return false
+		}
+	}
+	i := configIndex(parent, names[len(names)-1])
+	if i < 0 {
+		return false
+	}
+	parent = append(parent[:i:i], parent[i+1:]...)
+	if len(names) == 1 {
+		c.root = parent
+	} else {
+		c.Set(strings.Join(names[:len(names)-1], "."), parent)
+	}
+	return true
+}
+
+// Marshal は設定をYAML形式で返します。
+func (c *ConfigFile) Marshal() ([]byte, error) {
+	return yaml.Marshal(c.root)
+}
+
+// Save は設定ファイルを保存します。
+// 書き込みの途中で失敗しても元のファイルが壊れないよう、同じディレクトリの一時ファイルに書き込んでから置き換えます。
+// 既存のファイルのパーミッションを引き継ぎ、新しく作成する場合は認証トークンを含むため所有者だけが読み書きできるようにします。
+func (c *ConfigFile) Save() error {
+	out, err := c.Marshal()
+	if err != nil {
+		return err
+	}
+
+	// シンボリックリンクの場合はリンク先のファイルを置き換えます。
+	path := c.Path
+	mode := os.FileMode(0600)
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	// XDG Base Directoryの既定の場所($HOME/.config/todo)などはディレクトリから作成します。
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(out); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// configIndex はマップのキーの位置を返します。キーが無い場合は-1を返します。
+func configIndex(m yaml.MapSlice, name string) int {
+	for i, item := range m {
+		if key, ok := item.Key.(string); ok && key == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestConfigFile では編集しない設定の値と順序を保ったまま設定ファイルを編集できることを確認する。
+func TestConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte("token: abc\nretry:\n  max: 5\naliases:\n  running: [get, --status, RUNNING]\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := config.Get("retry.max"); !ok || value != 5 {
+		t.Errorf("Get(retry.max) = %v, %v", value, ok)
+	}
+	if _, ok := config.Get("token.max"); ok {
+		t.Errorf("Get(token.max) found")
+	}
+	config.Set("aliases.done", []string{"update", "--status", "DONE"})
+	config.Set("defaults.get.output", "wide")
+	if !config.Delete("aliases.running") || config.Delete("aliases.missing") || config.Delete("token.missing") {
+		t.Errorf("Delete() returned an unexpected result")
+	}
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := ioutil.ReadFile(path)
+	want := "token: abc\nretry:\n  max: 5\naliases:\n  done:\n  - update\n  - --status\n  - DONE\ndefaults:\n  get:\n    output: wide\n"
+	if string(content) != want {
+		t.Errorf("saved = %q, want %q", content, want)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v", info.Mode())
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("temporary files remain: %d", len(files))
+	}
+
+	// ファイルが無い場合は所有者だけが読み書きできるファイルを作成する
+	config, err = LoadConfigFile(filepath.Join(dir, "new.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Set("token", "abc")
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(config.Path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v", info.Mode())
+	}
+}
+
+// TestConfigFileMigrate では以前の形式の設定ファイルに他の設定を保ったままヘッダを加え、
+// 変換前のファイルをバックアップとして残せることを確認する。
+func TestConfigFileMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	legacy := "protocol: https\nhost: example.com\nport: 1000\ntoken: abc\n"
+	if err := ioutil.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated, err := config.Migrate(); !migrated || err != nil {
+		t.Fatalf("Migrate() = %v, %v", migrated, err)
+	}
+	backup, err := config.Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadFile(path)
+	want := "apiVersion: " + ConfigAPIVersion + "\nkind: " + ConfigKind + "\n" + legacy
+	if string(content) != want {
+		t.Errorf("migrated = %q, want %q", content, want)
+	}
+	if content, _ := ioutil.ReadFile(backup); string(content) != legacy {
+		t.Errorf("backup = %q, want %q", content, legacy)
+	}
+
+	// 現在の形式の設定ファイルは変換しない
+	config, err = LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated, err := config.Migrate(); migrated || err != nil {
+		t.Errorf("Migrate() = %v, %v", migrated, err)
+	}
+
+	// このクライアントより新しい形式や、種類の異なるファイルは扱わない
+	for content, id := range map[string]MessageID{
+		"apiVersion: todo.fufuhu.gitlab.com/v2\nkind: ClientConfig\n": ConfigVersionUnsupported,
+		"apiVersion: todo.fufuhu.gitlab.com/v1\nkind: ServerConfig\n": ConfigKindInvalid,
+	} {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		config, err := LoadConfigFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := config.Migrate(); !IsMessage(err, id) || KindOf(err) != ErrorUsage {
+			t.Errorf("%q: Migrate() = %v, want %s", content, err, id)
+		}
+	}
+
+	// 新しく作成する設定ファイルには現在の形式のヘッダを記述する
+	config, err = LoadConfigFile(filepath.Join(dir, "todo", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Save(); err != nil {
+		t.Fatal(err)
+	}
+	content, _ = ioutil.ReadFile(config.Path)
+	if want := "apiVersion: " + ConfigAPIVersion + "\nkind: " + ConfigKind + "\n"; string(content) != want {
+		t.Errorf("new = %q, want %q", content, want)
+	}
+}
+
+// TestConfigFileDecode では設定ファイルの不明なキーや型の合わない値を、行番号を含むエラーとして報告することを確認する。
+func TestConfigFileDecode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte("host: example.com\nport: 1000\nretry:\n  max: 3\n  post: true\nendpoints:\n- name: a\n  url: https://a.example.com\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := config.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Host != "example.com" || decoded.Port != 1000 || *decoded.Retry.Max != 3 || !*decoded.Retry.Post || decoded.Endpoints[0].Name != "a" {
+		t.Errorf("Decode() = %+v", decoded)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("host: example.com\nprot: https\nport: many\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if config, err = LoadConfigFile(path); err != nil {
+		t.Fatal(err)
+	}
+	_, err = config.Decode()
+	if !IsMessage(err, ConfigDecodeFailure) || KindOf(err) != ErrorUsage {
+		t.Fatalf("Decode() = %v", err)
+	}
+	want := T(ConfigDecodeFailure, path) + ": line 2: field prot not found; line 3: cannot unmarshal !!str `many` into int"
+	if err.Error() != want {
+		t.Errorf("Decode() = %q, want %q", err, want)
+	}
+}
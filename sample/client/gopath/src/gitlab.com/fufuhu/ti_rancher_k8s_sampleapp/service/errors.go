@@ -0,0 +1,132 @@
+package service
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// ErrorKind はエラーの原因の種類を表します。
+// コマンドの終了コードなど、エラーの原因によって振る舞いを変える場合に利用します。
+type ErrorKind int
+
+// エラーの原因の種類です。
+const (
+	ErrorUnknown    ErrorKind = iota // 分類できないエラー
+	ErrorUsage                       // コマンドラインオプションなどの指定の誤り
+	ErrorAuth                        // 認証トークンが無い、または期限切れなどで認証が必要
+	ErrorNotFound                    // 指定したタスクが見つからない
+	ErrorValidation                  // ToDoサーバが受け付けない入力
+	ErrorConflict                    // 楽観的排他制御による更新の中止
+	ErrorNetwork                     // ToDoサーバに接続できない
+	ErrorServer                      // ToDoサーバのエラー、または想定外の応答
+)
+
+var errorKindNames = map[ErrorKind]string{
+	ErrorUnknown:    "unknown",
+	ErrorUsage:      "usage",
+	ErrorAuth:       "auth",
+	ErrorNotFound:   "not_found",
+	ErrorValidation: "validation",
+	ErrorConflict:   "conflict",
+	ErrorNetwork:    "network",
+	ErrorServer:     "server",
+}
+
+// String は原因の種類の名前を返します。メトリクスのラベルなどに利用します。
+func (k ErrorKind) String() string {
+	if name, ok := errorKindNames[k]; ok {
+		return name
+	}
+	return errorKindNames[ErrorUnknown]
+}
+
+// Error は原因の種類を持ったエラーです。
+// メッセージはError()を呼び出した時点の言語で組み立てるため、
+// エラーの判別にはIsMessageなどでメッセージのIDを比較してください。
+type Error struct {
+	Kind       ErrorKind     // エラーの原因の種類
+	ID         MessageID     // エラーメッセージのID
+	Args       []interface{} // エラーメッセージの書式に埋め込む値
+	Cause      error         // 原因となったエラー(メッセージの後ろに続けて出力します)
+	StatusCode int           // ToDoサーバが返したステータスコード(応答を受け取っていない場合は0)
+}
+
+// Error はエラーメッセージを返します。
+func (e *Error) Error() string {
+	message := T(e.ID, e.Args...)
+	if e.Cause != nil {
+		message += ": " + e.Cause.Error()
+	}
+	return message
+}
+
+// NewError は原因の種類とメッセージのIDを指定してエラーを作成します。
+func NewError(kind ErrorKind, id MessageID, args ...interface{}) *Error {
+	return &Error{Kind: kind, ID: id, Args: args}
+}
+
+// WrapError は原因となったエラーの前にメッセージを加えたエラーを作成します。
+// 原因の種類が分かる場合はそれを引き継ぎ、分からない場合はkindとして扱います。
+func WrapError(kind ErrorKind, id MessageID, cause error, args ...interface{}) *Error {
+	wrapped := &Error{Kind: kind, ID: id, Args: args, Cause: cause}
+	if k := KindOf(cause); k != ErrorUnknown {
+		wrapped.Kind = k
+	}
+	if e, ok := cause.(*Error); ok {
+		wrapped.StatusCode = e.StatusCode
+	}
+	return wrapped
+}
+
+// newStatusError はToDoサーバが返したステータスコードから原因の種類を判断してエラーを作成します。
+func newStatusError(id MessageID, statusCode int) *Error {
+	kind := ErrorServer
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		kind = ErrorAuth
+	case statusCode == http.StatusNotFound:
+		kind = ErrorNotFound
+	case statusCode == http.StatusBadRequest:
+		kind = ErrorValidation
+	case statusCode == http.StatusConflict || statusCode == http.StatusPreconditionFailed:
+		kind = ErrorConflict
+	}
+	return &Error{Kind: kind, ID: id, StatusCode: statusCode}
+}
+
+// MessageOf はエラーメッセージのIDを返します。Errorでない場合は空文字列を返します。
+func MessageOf(err error) MessageID {
+	if e, ok := err.(*Error); ok {
+		return e.ID
+	}
+	return ""
+}
+
+// IsMessage はエラーが指定したIDのメッセージを持つかを返します。
+func IsMessage(err error, id MessageID) bool {
+	return MessageOf(err) == id
+}
+
+// KindOf はエラーの原因の種類を返します。
+// ToDoサーバへの接続に失敗した場合のエラーはErrorNetworkとして扱います。
+// 種類が不明なErrorは、包んでいる元のエラーの種類を返します。
+func KindOf(err error) ErrorKind {
+	switch e := err.(type) {
+	case nil:
+		return ErrorUnknown
+	case *Error:
+		if e.Kind == ErrorUnknown && e.Cause != nil {
+			return KindOf(e.Cause)
+		}
+		return e.Kind
+	case *url.Error:
+		return ErrorNetwork
+	case net.Error:
+		return ErrorNetwork
+	}
+	if err == ErrEndpointUnavailable {
+		return ErrorNetwork
+	}
+	return ErrorUnknown
+}
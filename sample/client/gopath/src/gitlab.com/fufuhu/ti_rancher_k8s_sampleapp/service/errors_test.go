@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// TestNewStatusError はステータスコードから原因の種類が判断されることを確認する。
+func TestNewStatusError(t *testing.T) {
+	cases := map[int]ErrorKind{
+		http.StatusBadRequest:          ErrorValidation,
+		http.StatusUnauthorized:        ErrorAuth,
+		http.StatusForbidden:           ErrorAuth,
+		http.StatusNotFound:            ErrorNotFound,
+		http.StatusConflict:            ErrorConflict,
+		http.StatusPreconditionFailed:  ErrorConflict,
+		http.StatusInternalServerError: ErrorServer,
+		http.StatusOK:                  ErrorServer,
+	}
+	for status, kind := range cases {
+		err := newStatusError(TaskGetReturnedStatusCodeUnexpected, status)
+		if err.Kind != kind || err.StatusCode != status || !IsMessage(err, TaskGetReturnedStatusCodeUnexpected) {
+			t.Errorf("newStatusError(%d) = %+v, want kind %d", status, err, kind)
+		}
+	}
+}
+
+// TestKindOf はエラーの種類を判断できることを確認する。
+func TestKindOf(t *testing.T) {
+	if KindOf(NewError(ErrorConflict, TaskUpdateConflict)) != ErrorConflict {
+		t.Fail()
+	}
+	if KindOf(&url.Error{Op: "Get", URL: "http://localhost", Err: errors.New("connection refused")}) != ErrorNetwork {
+		t.Fail()
+	}
+	if KindOf(ErrEndpointUnavailable) != ErrorNetwork {
+		t.Fail()
+	}
+	if KindOf(errors.New("unknown")) != ErrorUnknown || KindOf(nil) != ErrorUnknown {
+		t.Fail()
+	}
+	// 種類が不明なErrorは包んでいるエラーの種類を引き継ぐ
+	if KindOf(&Error{Kind: ErrorUnknown, Cause: NewError(ErrorConflict, TaskUpdateConflict)}) != ErrorConflict {
+		t.Fail()
+	}
+}
+
+// TestErrorKindString ではメトリクスのラベルに使う原因の種類の名前が得られることを確認する。
+func TestErrorKindString(t *testing.T) {
+	if ErrorNetwork.String() != "network" || ErrorNotFound.String() != "not_found" || ErrorKind(100).String() != "unknown" {
+		t.Fail()
+	}
+}
@@ -0,0 +1,433 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointURLInvalid はエンドポイントのURLを解釈できない場合のエラーメッセージです。
+const EndpointURLInvalid MessageID = "endpoint.url_invalid"
+
+// EndpointUnavailable はすべてのエンドポイントへのリクエストに失敗した場合のエラーメッセージです。
+const EndpointUnavailable MessageID = "endpoint.unavailable"
+
+// ErrEndpointUnavailable はすべてのエンドポイントへのリクエストに失敗した場合に返されるエラーです。
+var ErrEndpointUnavailable error = NewError(ErrorNetwork, EndpointUnavailable)
+
+// エンドポイントの切り替えの経過として出力するメッセージです。
+const (
+	FailoverEndpointSelected    MessageID = "failover.endpoint_selected"
+	FailoverCircuitStateChanged MessageID = "failover.circuit_state_changed"
+	FailoverHealthCheckFailure  MessageID = "failover.health_check_failure"
+	FailoverRequestFailure      MessageID = "failover.request_failure"
+	FailoverStateSaveFailure    MessageID = "failover.state_save_failure"
+)
+
+// DefaultFailureThreshold はサーキットブレーカーを開くまでに許容する連続した失敗の回数の既定値です。
+const DefaultFailureThreshold = 2
+
+// DefaultOpenTimeout はサーキットブレーカーを開いてから、再びエンドポイントの状態を確認するまでの時間の既定値です。
+const DefaultOpenTimeout = 30 * time.Second
+
+// HealthCheckTimeout は/api/pingによるエンドポイントの状態確認を待つ時間です。
+var HealthCheckTimeout = 2 * time.Second
+
+// Endpoint はToDoサーバのアクセス先のひとつを表します。
+// 複数のクラスタにデプロイされたToDoサーバを、優先度(値が小さいほど優先)の順に利用します。
+type Endpoint struct {
+	Name     string `yaml:"name" json:"name"`                       // エンドポイントの名前(表示用)
+	URL      string `yaml:"url" json:"url"`                         // エンドポイントのURL(例: https://todo.example.com:443)
+	Priority int    `yaml:"priority" json:"priority"`               // 優先度
+	Token    string `yaml:"token,omitempty" json:"token,omitempty"` // このエンドポイントで取得した認証トークン
+}
+
+// Target はエンドポイントのURLをプロトコル・ホスト・ポート番号に分解して返します。
+// ポート番号が省略されている場合はプロトコルの既定のポート番号を返します。
+func (e Endpoint) Target() (string, string, int, error) {
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return "", "", 0, NewError(ErrorValidation, EndpointURLInvalid)
+	}
+
+	port := 80
+	if u.Scheme == "https" {
+		port = 443
+	}
+	if u.Port() != "" {
+		if port, err = strconv.Atoi(u.Port()); err != nil {
+			return "", "", 0, NewError(ErrorValidation, EndpointURLInvalid)
+		}
+	}
+	return u.Scheme, u.Hostname(), port, nil
+}
+
+// String はエンドポイントを表示用の文字列で返します。
+func (e Endpoint) String() string {
+	if e.Name == "" {
+		return e.URL
+	}
+	return e.Name + " (" + e.URL + ")"
+}
+
+// CircuitState はサーキットブレーカーの状態を表します。
+type CircuitState string
+
+// サーキットブレーカーの状態です。
+const (
+	CircuitClosed   CircuitState = "closed"    // 正常(リクエストを送信する)
+	CircuitOpen     CircuitState = "open"      // 異常(リクエストを送信しない)
+	CircuitHalfOpen CircuitState = "half-open" // 回復の確認中(/api/pingで確認してから送信する)
+)
+
+// CircuitBreaker はエンドポイントごとの障害の状況を表します。
+type CircuitBreaker struct {
+	State    CircuitState `json:"state"`     // 現在の状態
+	Failures int          `json:"failures"`  // 連続した失敗の回数
+	OpenedAt time.Time    `json:"opened_at"` // 最後に開いた日時
+}
+
+// allow はリクエストを送信してよいかと、送信前に状態を確認する必要があるかを返します。
+func (b *CircuitBreaker) allow(now time.Time, timeout time.Duration) (bool, bool) {
+	switch b.State {
+	case CircuitOpen:
+		if now.Sub(b.OpenedAt) < timeout {
+			return false, false
+		}
+		b.State = CircuitHalfOpen
+		return true, true
+	case CircuitHalfOpen:
+		return true, true
+	}
+	return true, false
+}
+
+// success は成功を記録し、状態が変わった場合にtrueを返します。
+func (b *CircuitBreaker) success() bool {
+	changed := b.State != CircuitClosed && b.State != ""
+	b.State = CircuitClosed
+	b.Failures = 0
+	return changed
+}
+
+// failure は失敗を記録し、状態が変わった場合にtrueを返します。
+func (b *CircuitBreaker) failure(now time.Time, threshold int) bool {
+	b.Failures++
+	if b.State == CircuitOpen || (b.State != CircuitHalfOpen && b.Failures < threshold) {
+		return false
+	}
+	b.State = CircuitOpen
+	b.OpenedAt = now
+	return true
+}
+
+// Failover は複数のエンドポイントを優先度の順に利用し、
+// 障害が発生したエンドポイントをサーキットブレーカーで切り離して他のエンドポイントに切り替えます。
+type Failover struct {
+	Endpoints        []Endpoint    // 優先度の順に並べたエンドポイント
+	FailureThreshold int           // サーキットブレーカーを開くまでの連続した失敗の回数
+	OpenTimeout      time.Duration // サーキットブレーカーを開いてから状態を確認しなおすまでの時間
+	StateFile        string        // サーキットブレーカーの状態を保存するファイル(空の場合は保存しない)
+	Verbose          io.Writer     // 接続先の切り替えなどを出力する先(nilの場合は出力しない)
+
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+	current  int
+}
+
+// Endpoints が設定されている場合、ToDoサーバへのリクエストは
+// リクエストのURLに関わらずこれらのエンドポイントに振り分けられます。
+var Endpoints *Failover
+
+// unhealthyStatusCodes はエンドポイントの障害とみなすステータスコードです。
+var unhealthyStatusCodes = map[int]bool{
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// NewFailover はエンドポイントを優先度の順に並べたFailoverを返します。
+func NewFailover(endpoints []Endpoint) (*Failover, error) {
+	sorted := make([]Endpoint, len(endpoints))
+	copy(sorted, endpoints)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+	for _, endpoint := range sorted {
+		if _, _, _, err := endpoint.Target(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Failover{
+		Endpoints:        sorted,
+		FailureThreshold: DefaultFailureThreshold,
+		OpenTimeout:      DefaultOpenTimeout,
+		breakers:         map[string]*CircuitBreaker{},
+		current:          -1,
+	}, nil
+}
+
+// LoadState はStateFileに保存されたサーキットブレーカーの状態を読み込みます。
+// 前回のコマンドの実行で障害を検知したエンドポイントを、以降のコマンドでも避けるために利用します。
+func (f *Failover) LoadState() error {
+	if f.StateFile == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(f.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	breakers := map[string]*CircuitBreaker{}
+	if err := json.Unmarshal(data, &breakers); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.breakers = breakers
+	return nil
+}
+
+// saveState はサーキットブレーカーの状態をStateFileに保存します。呼び出し時にはf.muを取得しておく必要があります。
+func (f *Failover) saveState() {
+	if f.StateFile == "" {
+		return
+	}
+	data, err := json.Marshal(f.breakers)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(f.StateFile), 0700)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(f.StateFile, data, 0600)
+	}
+	if err != nil {
+		f.printf(FailoverStateSaveFailure, err)
+	}
+}
+
+// Breaker はエンドポイントのサーキットブレーカーの状態を返します。
+func (f *Failover) Breaker(endpoint Endpoint) CircuitBreaker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.breaker(endpoint)
+}
+
+func (f *Failover) breaker(endpoint Endpoint) *CircuitBreaker {
+	b, ok := f.breakers[endpoint.URL]
+	if !ok {
+		b = &CircuitBreaker{State: CircuitClosed}
+		f.breakers[endpoint.URL] = b
+	}
+	return b
+}
+
+// Current は直近のリクエストを処理したエンドポイントを返します。
+// まだリクエストを送信していない場合は、サーキットブレーカーが開いていない最も優先度の高いエンドポイントを返します。
+func (f *Failover) Current() Endpoint {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.current >= 0 {
+		return f.Endpoints[f.current]
+	}
+	for _, endpoint := range f.Endpoints {
+		if f.breaker(endpoint).State != CircuitOpen {
+			return endpoint
+		}
+	}
+	return f.Endpoints[0]
+}
+
+// candidate はリクエストの送信先の候補です。
+type candidate struct {
+	index int
+	probe bool
+}
+
+// candidates はリクエストを送信してよいエンドポイントを優先度の順に返します。
+// すべてのエンドポイントのサーキットブレーカーが開いている場合は、最後の手段としてすべてを状態確認の対象にします。
+func (f *Failover) candidates(now time.Time) []candidate {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var candidates []candidate
+	for i, endpoint := range f.Endpoints {
+		if ok, probe := f.breaker(endpoint).allow(now, f.OpenTimeout); ok {
+			candidates = append(candidates, candidate{index: i, probe: probe})
+		}
+	}
+	if len(candidates) == 0 {
+		for i := range f.Endpoints {
+			candidates = append(candidates, candidate{index: i, probe: true})
+		}
+	}
+	return candidates
+}
+
+// record はリクエストの結果をサーキットブレーカーに記録します。
+func (f *Failover) record(index int, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	endpoint := f.Endpoints[index]
+	b := f.breaker(endpoint)
+	var changed bool
+	if ok {
+		changed = b.success()
+		if f.current != index {
+			f.printf(FailoverEndpointSelected, endpoint)
+			f.current = index
+		}
+	} else {
+		changed = b.failure(time.Now(), f.FailureThreshold)
+	}
+
+	if changed {
+		f.printf(FailoverCircuitStateChanged, endpoint, b.State)
+	}
+	// 連続した失敗の回数もコマンドの実行をまたいで数えるため、失敗した場合は状態が変わらなくても保存します。
+	if changed || !ok {
+		f.saveState()
+	}
+}
+
+// printf はIDに対応するメッセージを1行としてVerboseに出力します。
+func (f *Failover) printf(id MessageID, args ...interface{}) {
+	if f.Verbose != nil {
+		fmt.Fprintln(f.Verbose, T(id, args...))
+	}
+}
+
+// probe は/api/pingでエンドポイントが応答するかを確認します。
+func (f *Failover) probe(client *http.Client, endpoint Endpoint) bool {
+	protocol, host, port, _ := endpoint.Target()
+	probeClient := &http.Client{Transport: client.Transport, Timeout: HealthCheckTimeout}
+	res, err := probeClient.Get(protocol + "://" + host + ":" + strconv.Itoa(port) + "/api/ping")
+	if err != nil {
+		f.printf(FailoverHealthCheckFailure, endpoint, err)
+		return false
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		f.printf(FailoverHealthCheckFailure, endpoint, res.Status)
+		return false
+	}
+	return true
+}
+
+// do はリクエストをエンドポイントに振り分けて送信します。
+// 障害を検知した場合は、リクエストの種類が許す限り次の優先度のエンドポイントに切り替えて送信しなおします。
+// 最後の候補以外のエンドポイントでは、切り替えを早めるため再試行を行いません。
+func (f *Failover) do(client *http.Client, req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	candidates := f.candidates(time.Now())
+	lastErr := ErrEndpointUnavailable
+
+	for i, c := range candidates {
+		endpoint := f.Endpoints[c.index]
+		last := i == len(candidates)-1
+
+		if c.probe && !f.probe(client, endpoint) {
+			f.record(c.index, false)
+			continue
+		}
+
+		attempt, err := rewriteRequest(req, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		attemptPolicy := policy
+		if !last {
+			attemptPolicy.MaxRetries = 0
+		}
+
+		res, err := doWithRetry(client, attempt, attemptPolicy)
+		if err == nil && !unhealthyStatusCodes[res.StatusCode] {
+			f.record(c.index, true)
+			return res, nil
+		}
+		f.record(c.index, false)
+
+		if last || !canFailover(req.Method, policy, err) {
+			return res, err
+		}
+		if err != nil {
+			f.printf(FailoverRequestFailure, endpoint, err)
+			lastErr = err
+		} else {
+			f.printf(FailoverRequestFailure, endpoint, res.Status)
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+	}
+	return nil, lastErr
+}
+
+// canFailover はリクエストを他のエンドポイントに送信しなおしてよいかを返します。
+// 接続できなかった場合はリクエストがToDoサーバに届いていないため、メソッドに関わらず切り替えます。
+func canFailover(method string, policy RetryPolicy, err error) bool {
+	return isDialError(err) || policy.Retryable(method)
+}
+
+// isDialError はエラーがToDoサーバへの接続の確立に失敗したことを表すかを返します。
+func isDialError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "dial"
+}
+
+// rewriteRequest はリクエストの宛先をエンドポイントに書き換えた複製を返します。
+// 認証ヘッダはエンドポイントの認証トークンに置き換えます。他のエンドポイントの認証トークンを送信しないよう、
+// 認証トークンが無いエンドポイント(ログインに失敗したものなど)には認証ヘッダを付けず、認証エラーとします。
+func rewriteRequest(req *http.Request, endpoint Endpoint) (*http.Request, error) {
+	base, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return nil, NewError(ErrorValidation, EndpointURLInvalid)
+	}
+
+	rewritten := req.WithContext(req.Context())
+	u := *req.URL
+	u.Scheme = base.Scheme
+	u.Host = base.Host
+	u.Path = strings.TrimRight(base.Path, "/") + req.URL.Path
+	rewritten.URL = &u
+	rewritten.Host = ""
+
+	rewritten.Header = make(http.Header, len(req.Header))
+	for name, values := range req.Header {
+		rewritten.Header[name] = append([]string(nil), values...)
+	}
+	if rewritten.Header.Get("Authorization") != "" {
+		if endpoint.Token != "" {
+			rewritten.Header.Set("Authorization", "JWT "+endpoint.Token)
+		} else {
+			rewritten.Header.Del("Authorization")
+		}
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		rewritten.Body = body
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// withFailover はエンドポイントの振り分けを有効にしてfを実行します。
+func withFailover(failover *Failover, f func()) {
+	original := Endpoints
+	Endpoints = failover
+	defer func() {
+		Endpoints = original
+	}()
+	withRetry(fastRetry, f)
+}
+
+// newTestFailover はテスト用のエンドポイントを優先度の順に持つFailoverを作成します。
+func newTestFailover(t *testing.T, urls ...string) *Failover {
+	var endpoints []Endpoint
+	for i, u := range urls {
+		endpoints = append(endpoints, Endpoint{Name: string(rune('a' + i)), URL: u, Priority: i + 1})
+	}
+	failover, err := NewFailover(endpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return failover
+}
+
+// closedServerURL は接続できないエンドポイントのURLを返します。
+func closedServerURL() string {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+// TestEndpointTarget ではエンドポイントのURLを分解できることを確認する。
+func TestEndpointTarget(t *testing.T) {
+	protocol, host, port, err := Endpoint{URL: "https://todo.example.com"}.Target()
+	if err != nil || protocol != "https" || host != "todo.example.com" || port != 443 {
+		t.Fail()
+	}
+
+	protocol, host, port, err = Endpoint{URL: "http://10.0.0.1:30080/"}.Target()
+	if err != nil || protocol != "http" || host != "10.0.0.1" || port != 30080 {
+		t.Fail()
+	}
+
+	if _, _, _, err := (Endpoint{URL: "todo.example.com"}).Target(); err == nil || !IsMessage(err, EndpointURLInvalid) {
+		t.Fail()
+	}
+}
+
+// TestNewFailoverSortsByPriority ではエンドポイントが優先度の順に並べられることを確認する。
+func TestNewFailoverSortsByPriority(t *testing.T) {
+	failover, err := NewFailover([]Endpoint{
+		{Name: "b", URL: "http://b.example.com", Priority: 2},
+		{Name: "a", URL: "http://a.example.com", Priority: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failover.Endpoints[0].Name != "a" || failover.Current().Name != "a" {
+		t.Fail()
+	}
+}
+
+// TestFailoverOnConnectionError では接続できないエンドポイントを避けて次のエンドポイントに切り替えることを確認する。
+func TestFailoverOnConnectionError(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "D", Status: "TODO"})
+
+	failover := newTestFailover(t, closedServerURL(), server.URL)
+	failover.FailureThreshold = 1
+
+	var tasks []Task
+	var err error
+	withFailover(failover, func() {
+		// 宛先には振り分けの対象ではないホストを指定しても、エンドポイントに振り分けられること
+		tasks, err = GetTasks("http", "unused.example.com", 80, "token")
+	})
+
+	if err != nil || len(tasks) != 1 {
+		t.Fatal(err)
+	}
+	if failover.Current().Name != "b" {
+		t.Fail()
+	}
+	if failover.Breaker(failover.Endpoints[0]).State != CircuitOpen {
+		t.Fail()
+	}
+}
+
+// TestFailoverSkipsOpenCircuit ではサーキットブレーカーが開いたエンドポイントにリクエストを送信しないことを確認する。
+func TestFailoverSkipsOpenCircuit(t *testing.T) {
+	primary := newFlakyServer(100, http.StatusServiceUnavailable, nil)
+	defer primary.Close()
+	secondary := newFakeTodoServer()
+	defer secondary.Close()
+
+	failover := newTestFailover(t, primary.URL, secondary.URL)
+	failover.FailureThreshold = 2
+
+	withFailover(failover, func() {
+		for i := 0; i < 4; i++ {
+			if _, err := GetTasks("http", "unused.example.com", 80, "token"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	if primary.attemptsOf("GET") != 2 {
+		t.Fatalf("attempts: %d", primary.attemptsOf("GET"))
+	}
+	if len(secondary.requestsOf("GET")) != 4 {
+		t.Fail()
+	}
+}
+
+// TestFailoverRecoversAfterHealthCheck ではサーキットブレーカーを開いてから一定時間が経過した後に、
+// /api/pingで回復を確認できたエンドポイントに戻ることを確認する。
+func TestFailoverRecoversAfterHealthCheck(t *testing.T) {
+	primary := newFlakyServer(1, http.StatusServiceUnavailable, nil)
+	defer primary.Close()
+	secondary := newFakeTodoServer()
+	defer secondary.Close()
+
+	failover := newTestFailover(t, primary.URL, secondary.URL)
+	failover.FailureThreshold = 1
+	failover.OpenTimeout = 0
+
+	withFailover(failover, func() {
+		if _, err := GetTasks("http", "unused.example.com", 80, "token"); err != nil {
+			t.Fatal(err)
+		}
+		if failover.Current().Name != "b" {
+			t.Fail()
+		}
+
+		if _, err := GetTasks("http", "unused.example.com", 80, "token"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if failover.Current().Name != "a" || failover.Breaker(failover.Endpoints[0]).State != CircuitClosed {
+		t.Fail()
+	}
+	pings := 0
+	for _, req := range primary.requests {
+		if req.Path == "/api/ping" {
+			pings++
+		}
+	}
+	if pings != 1 {
+		t.Fail()
+	}
+}
+
+// TestFailoverUsesEndpointToken ではエンドポイントごとの認証トークンが利用されることを確認する。
+func TestFailoverUsesEndpointToken(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	var authorization string
+	server.beforeHandle = func(w http.ResponseWriter, r *http.Request, n int) bool {
+		authorization = r.Header.Get("Authorization")
+		return false
+	}
+
+	failover, err := NewFailover([]Endpoint{{Name: "a", URL: server.URL, Token: "token-a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withFailover(failover, func() {
+		_, err = GetTasks("http", "unused.example.com", 80, "token")
+	})
+
+	if err != nil || authorization != "JWT token-a" {
+		t.Fail()
+	}
+}
+
+// TestFailoverDoesNotForwardOtherToken では認証トークンの無いエンドポイントに切り替えた場合に、
+// 他のエンドポイントの認証トークンを送信せず、認証エラーとすることを確認する。
+func TestFailoverDoesNotForwardOtherToken(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	authorization := "not sent"
+	server.beforeHandle = func(w http.ResponseWriter, r *http.Request, n int) bool {
+		authorization = r.Header.Get("Authorization")
+		if authorization == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return true
+		}
+		return false
+	}
+
+	// aでログインした認証トークンが、コマンドの認証トークンとして渡される
+	failover, err := NewFailover([]Endpoint{
+		{Name: "a", URL: closedServerURL(), Priority: 1, Token: "token-a"},
+		{Name: "b", URL: server.URL, Priority: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withFailover(failover, func() {
+		_, err = GetTasks("http", "unused.example.com", 80, "token-a")
+	})
+
+	if authorization != "" || KindOf(err) != ErrorAuth {
+		t.Errorf("authorization = %q, err = %v", authorization, err)
+	}
+}
+
+// TestFailoverDoesNotRepeatPost ではエンドポイントに届いた可能性のあるPOSTリクエストを
+// 他のエンドポイントに送信しなおさないことを確認する。
+func TestFailoverDoesNotRepeatPost(t *testing.T) {
+	primary := newFlakyServer(1, http.StatusServiceUnavailable, nil)
+	defer primary.Close()
+	secondary := newFakeTodoServer()
+	defer secondary.Close()
+
+	failover := newTestFailover(t, primary.URL, secondary.URL)
+
+	var err error
+	withFailover(failover, func() {
+		_, err = CreateTask("http", "unused.example.com", 80, "token", "TODO", "D")
+	})
+
+	if err == nil || len(secondary.requestsOf("POST")) != 0 {
+		t.Fail()
+	}
+
+	// 接続できなかった場合はリクエストが届いていないため切り替えること
+	failover = newTestFailover(t, closedServerURL(), secondary.URL)
+	withFailover(failover, func() {
+		_, err = CreateTask("http", "unused.example.com", 80, "token", "TODO", "D")
+	})
+
+	if err != nil || len(secondary.requestsOf("POST")) != 1 {
+		t.Fail()
+	}
+}
+
+// TestFailoverStateFile ではサーキットブレーカーの状態がファイルに保存され、読み込めることを確認する。
+func TestFailoverStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "failover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := newFakeTodoServer()
+	defer server.Close()
+	unreachable := closedServerURL()
+
+	failover := newTestFailover(t, unreachable, server.URL)
+	failover.FailureThreshold = 1
+	failover.StateFile = filepath.Join(dir, "circuit.json")
+	withFailover(failover, func() {
+		if _, err := GetTasks("http", "unused.example.com", 80, "token"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	loaded := newTestFailover(t, unreachable, server.URL)
+	loaded.StateFile = failover.StateFile
+	if err := loaded.LoadState(); err != nil {
+		t.Fatal(err)
+	}
+
+	breaker := loaded.Breaker(loaded.Endpoints[0])
+	if breaker.State != CircuitOpen || time.Since(breaker.OpenedAt) > time.Minute {
+		t.Fail()
+	}
+	// まだリクエストを送信していなくても、開いているエンドポイントは現在の接続先にしないこと
+	if loaded.Current().Name != "b" {
+		t.Fail()
+	}
+}
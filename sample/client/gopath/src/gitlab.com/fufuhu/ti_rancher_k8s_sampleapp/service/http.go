@@ -0,0 +1,146 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RequestSkippedByDryRun はドライランのためにリクエストの送信を
+// 行わなかった場合のエラーメッセージです。
+const RequestSkippedByDryRun MessageID = "request.skipped_by_dry_run"
+
+// RequestSendFailure はToDoサーバへのリクエストの送信に失敗した場合のログのメッセージです。
+const RequestSendFailure MessageID = "request.send_failure"
+
+// ErrDryRun はドライラン中に更新系のリクエストを送信しようとした場合に返されるエラーです。
+var ErrDryRun error = NewError(ErrorUnknown, RequestSkippedByDryRun)
+
+// DryRun がtrueの場合、GET以外のリクエストはToDoサーバに送信せず、
+// 送信されるはずだったリクエストの内容をDryRunOutputに出力します。
+var DryRun bool
+
+// DryRunOutput はドライラン時にリクエストの内容を出力する先です。
+var DryRunOutput io.Writer = os.Stdout
+
+// RedactedValue は認証情報を伏せる際に代わりに出力する文字列です。
+const RedactedValue = "<redacted>"
+
+// redactedHeaders は値を伏せて出力するヘッダの一覧です。
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// credentialPattern はJSONのボディに含まれるパスワードと認証トークンを表します。
+var credentialPattern = regexp.MustCompile(`("(?:password|token)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// HTTPClient はToDoサーバへのリクエストに共通して利用するHTTPクライアントです。
+// 接続を使いまわすため、リクエストのたびに生成せずにこのクライアントを利用します。
+var HTTPClient = &http.Client{Transport: NewTransport()}
+
+// dryRunOutputMutex は並行して出力されるドライランの内容が混ざらないようにします。
+var dryRunOutputMutex sync.Mutex
+
+// NewTransport はToDoサーバへの並行したリクエストに向けて調整したTransportを返します。
+func NewTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// send はToDoサーバへリクエストを送信します。
+// 一時的な障害で失敗した場合はRetryの方針に従って再試行します。
+// Endpointsが設定されている場合は、障害の発生していないエンドポイントに振り分けます。
+// ドライラン中はGET以外のリクエストを送信せずにErrDryRunを返します。
+func send(req *http.Request) (*http.Response, error) {
+	if DryRun && req.Method != "GET" {
+		var buf bytes.Buffer
+		if err := writeRequest(&buf, req); err != nil {
+			return nil, err
+		}
+		dryRunOutputMutex.Lock()
+		defer dryRunOutputMutex.Unlock()
+		if _, err := buf.WriteTo(DryRunOutput); err != nil {
+			return nil, err
+		}
+		return nil, ErrDryRun
+	}
+
+	if Endpoints != nil {
+		return Endpoints.do(HTTPClient, req, Retry)
+	}
+	return doWithRetry(HTTPClient, req, Retry)
+}
+
+// RedactHeader はヘッダの値のうち認証情報を含むものを伏せて返します。
+func RedactHeader(name string, value string) string {
+	if redactedHeaders[http.CanonicalHeaderKey(name)] {
+		return RedactedValue
+	}
+	return value
+}
+
+// RedactBody はボディに含まれるパスワードと認証トークンを伏せて返します。
+func RedactBody(body []byte) []byte {
+	return credentialPattern.ReplaceAll(body, []byte(`${1}"`+RedactedValue+`"`))
+}
+
+// readRequestBody はリクエストボディを読み出し、再度送信できるように元に戻します。
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.GetBody == nil {
+		return nil, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+// writeRequest はリクエストの内容を認証情報を伏せた上でHTTPのメッセージ形式で出力します。
+func writeRequest(w io.Writer, req *http.Request) error {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%s %s %s\n", req.Method, req.URL.String(), req.Proto)
+	fmt.Fprintf(w, "Host: %s\n", req.URL.Host)
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			fmt.Fprintf(w, "%s: %s\n", name, RedactHeader(name, value))
+		}
+	}
+
+	fmt.Fprintln(w)
+	if len(body) > 0 {
+		fmt.Fprintf(w, "%s\n", RedactBody(body))
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+// withDryRun はドライランを有効にしてfを実行し、出力された内容を返します。
+func withDryRun(f func()) string {
+	var out bytes.Buffer
+	DryRun = true
+	DryRunOutput = &out
+	defer func() {
+		DryRun = false
+	}()
+	f()
+	return out.String()
+}
+
+// TestRedactBody ではJSONのボディに含まれるパスワードが伏せられることを確認する。
+func TestRedactBody(t *testing.T) {
+	body := []byte(`{"password":"p\"ass","username":"test_user"}`)
+	redacted := string(RedactBody(body))
+
+	if redacted != `{"password":"<redacted>","username":"test_user"}` {
+		log.Println(redacted)
+		t.Fail()
+	}
+}
+
+// TestRedactHeader ではAuthorizationヘッダの値が伏せられることを確認する。
+func TestRedactHeader(t *testing.T) {
+	if RedactHeader("authorization", "JWT token") != RedactedValue {
+		t.Fail()
+	}
+	if RedactHeader("Content-Type", "application/json") != "application/json" {
+		t.Fail()
+	}
+}
+
+// TestCreateTaskWithDryRun ではドライラン時にタスク作成のリクエストが送信されず、
+// 認証情報を伏せたリクエストの内容が出力されることを確認する。
+func TestCreateTaskWithDryRun(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+
+	protocol, host, port := server.target()
+	var err error
+	out := withDryRun(func() {
+		_, err = CreateTask(protocol, host, port, "secret-token", "TODO", "TODO_DESCRIPTION")
+	})
+
+	if err != ErrDryRun {
+		t.Fail()
+	}
+	if len(server.requests) != 0 {
+		t.Fail()
+	}
+	if !strings.HasPrefix(out, "POST "+server.URL+"/api/task ") {
+		log.Println(out)
+		t.Fail()
+	}
+	if strings.Contains(out, "secret-token") || !strings.Contains(out, "Authorization: "+RedactedValue) {
+		log.Println(out)
+		t.Fail()
+	}
+	if !strings.Contains(out, `{"description":"TODO_DESCRIPTION","title":"TODO"}`) {
+		log.Println(out)
+		t.Fail()
+	}
+}
+
+// TestLoginWithDryRun ではドライラン時にパスワードが出力に含まれないことを確認する。
+func TestLoginWithDryRun(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+
+	protocol, host, port := server.target()
+	var err error
+	out := withDryRun(func() {
+		_, err = Login(protocol, host, port, "test_user", "test_password")
+	})
+
+	if err != ErrDryRun {
+		t.Fail()
+	}
+	if strings.Contains(out, "test_password") || !strings.Contains(out, `"username":"test_user"`) {
+		log.Println(out)
+		t.Fail()
+	}
+}
+
+// TestUpdateTaskWithDryRun ではドライラン時も更新前のタスクの取得は行われ、
+// PATCHリクエストは送信されないことを確認する。
+func TestUpdateTaskWithDryRun(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "TODO_DESCRIPTION", Status: "TODO"})
+
+	protocol, host, port := server.target()
+	var result UpdateResult
+	var err error
+	out := withDryRun(func() {
+		result, err = UpdateTaskWithOptions(protocol, host, port, "token", 1, "", "", "RUNNING", DefaultUpdateOptions)
+	})
+
+	if err != ErrDryRun {
+		t.Fail()
+	}
+	if len(server.requestsOf("GET")) == 0 || len(server.requestsOf("PATCH")) != 0 {
+		t.Fail()
+	}
+	if result.Task.Status != "RUNNING" || result.Task.Title != "TODO" {
+		t.Fail()
+	}
+	if !strings.HasPrefix(out, "PATCH ") || !strings.Contains(out, `{"status":"RUNNING"}`) {
+		log.Println(out)
+		t.Fail()
+	}
+	if task, _ := server.get(1); task.Status != "TODO" {
+		t.Fail()
+	}
+}
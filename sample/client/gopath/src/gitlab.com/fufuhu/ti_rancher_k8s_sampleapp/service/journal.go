@@ -0,0 +1,314 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// 操作ジャーナルに記録する操作の種類です。
+const (
+	JournalCreate = "create" // タスクの作成
+	JournalUpdate = "update" // タスクの更新
+	JournalDelete = "delete" // タスクの削除
+	JournalUndo   = "undo"   // 記録済みの操作の取り消し
+)
+
+// DefaultJournalMaxSize はジャーナルファイルをローテートする大きさ(バイト)の既定値です。
+const DefaultJournalMaxSize = 1024 * 1024
+
+// DefaultJournalMaxBackups はローテートしたジャーナルファイルを残す世代数の既定値です。
+const DefaultJournalMaxBackups = 3
+
+// JournalUndoUnsupported は取り消せない操作を取り消そうとした場合のエラーメッセージです。
+const JournalUndoUnsupported MessageID = "journal.undo_unsupported"
+
+// JournalEntry は操作ジャーナルに記録される1件の操作を表します。
+type JournalEntry struct {
+	Seq       int       `json:"seq"`                // ジャーナル内での通し番号
+	Time      time.Time `json:"time"`               // 操作を行った日時
+	Operation string    `json:"operation"`          // 操作の種類
+	TaskID    int       `json:"task_id"`            // 操作の対象となったタスクのID
+	Previous  *Task     `json:"previous,omitempty"` // 操作前のタスク(作成時はなし)
+	Current   *Task     `json:"current,omitempty"`  // 操作後のタスク(削除時はなし)
+	Undoes    int       `json:"undoes,omitempty"`   // 取り消した操作の通し番号(undoの場合のみ)
+}
+
+// Journal はToDoサーバおよびユーザごとに保管される追記専用の操作ジャーナルです。
+type Journal struct {
+	Path       string // ジャーナルファイルのパス
+	MaxSize    int64  // ローテートする大きさ(バイト)
+	MaxBackups int    // ローテートしたファイルを残す世代数
+}
+
+// unsafePathCharacters はファイル名に使わない文字を表します。
+var unsafePathCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// JournalPath はToDoサーバとユーザの組み合わせに対応するジャーナルファイルのパスを返します。
+func JournalPath(dir string, protocol string, host string, port int, username string) string {
+	server := unsafePathCharacters.ReplaceAllString(protocol+"_"+host+"_"+strconv.Itoa(port), "_")
+	user := unsafePathCharacters.ReplaceAllString(username, "_")
+	return filepath.Join(dir, server, user+".jsonl")
+}
+
+// NewJournal は既定のローテート設定を持ったJournalを返します。
+func NewJournal(path string) *Journal {
+	return &Journal{
+		Path:       path,
+		MaxSize:    DefaultJournalMaxSize,
+		MaxBackups: DefaultJournalMaxBackups,
+	}
+}
+
+// Append はジャーナルに操作を追記します。通し番号と日時は自動的に設定されます。
+func (j *Journal) Append(entry JournalEntry) (JournalEntry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return entry, err
+	}
+	entry.Seq = 1
+	if len(entries) > 0 {
+		entry.Seq = entries[len(entries)-1].Seq + 1
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+	line = append(line, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(j.Path), 0700); err != nil {
+		return entry, err
+	}
+	if err := j.rotate(int64(len(line))); err != nil {
+		return entry, err
+	}
+
+	f, err := os.OpenFile(j.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return entry, err
+	}
+	defer f.Close()
+
+	_, err = f.Write(line)
+	return entry, err
+}
+
+// rotate は追記によってMaxSizeを超える場合にジャーナルファイルをローテートします。
+func (j *Journal) rotate(incoming int64) error {
+	info, err := os.Stat(j.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if j.MaxSize <= 0 || info.Size()+incoming <= j.MaxSize {
+		return nil
+	}
+
+	// 最も古い世代を削除し、残りの世代をひとつずつずらします。
+	os.Remove(j.backupPath(j.MaxBackups))
+	for i := j.MaxBackups - 1; i >= 1; i-- {
+		if _, err := os.Stat(j.backupPath(i)); err == nil {
+			if err := os.Rename(j.backupPath(i), j.backupPath(i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	if j.MaxBackups <= 0 {
+		return os.Remove(j.Path)
+	}
+	return os.Rename(j.Path, j.backupPath(1))
+}
+
+func (j *Journal) backupPath(generation int) string {
+	return fmt.Sprintf("%s.%d", j.Path, generation)
+}
+
+// Entries はローテート済みのものも含めて、ジャーナルに記録された操作を古い順に返します。
+func (j *Journal) Entries() ([]JournalEntry, error) {
+	var entries []JournalEntry
+
+	paths := []string{}
+	for i := j.MaxBackups; i >= 1; i-- {
+		paths = append(paths, j.backupPath(i))
+	}
+	paths = append(paths, j.Path)
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return entries, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var entry JournalEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				f.Close()
+				return entries, err
+			}
+			entries = append(entries, entry)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return entries, err
+		}
+	}
+	return entries, nil
+}
+
+// Undoable はまだ取り消されていない操作を新しい順に最大n件返します。
+func (j *Journal) Undoable(n int) ([]JournalEntry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	undone := map[int]bool{}
+	restored := map[int]int{}
+	deleted := map[int]int{}
+	for _, entry := range entries {
+		if entry.Operation == JournalDelete {
+			deleted[entry.Seq] = entry.TaskID
+		}
+		if entry.Operation == JournalUndo {
+			undone[entry.Undoes] = true
+			// 削除の取り消しで作成しなおしたタスクは別のIDを持つため、対応を記録します。
+			if oldID, ok := deleted[entry.Undoes]; ok && oldID != entry.TaskID {
+				restored[oldID] = entry.TaskID
+			}
+		}
+	}
+
+	var undoable []JournalEntry
+	for i := len(entries) - 1; i >= 0 && len(undoable) < n; i-- {
+		entry := entries[i]
+		if entry.Operation == JournalUndo || undone[entry.Seq] {
+			continue
+		}
+		undoable = append(undoable, entry.withTaskID(resolveTaskID(restored, entry.TaskID)))
+	}
+	return undoable, nil
+}
+
+// resolveTaskID は削除の取り消しによって作成しなおされたタスクの現在のIDを返します。
+func resolveTaskID(restored map[int]int, id int) int {
+	for i := 0; i < len(restored); i++ {
+		next, ok := restored[id]
+		if !ok {
+			break
+		}
+		id = next
+	}
+	return id
+}
+
+// withTaskID は対象のタスクのIDを置き換えたJournalEntryを返します。
+func (e JournalEntry) withTaskID(id int) JournalEntry {
+	if e.TaskID == id {
+		return e
+	}
+	e.TaskID = id
+	if e.Previous != nil {
+		previous := *e.Previous
+		previous.ID = id
+		e.Previous = &previous
+	}
+	if e.Current != nil {
+		current := *e.Current
+		current.ID = id
+		e.Current = &current
+	}
+	return e
+}
+
+// UndoJournalEntry は記録された操作を取り消し、取り消しを表すJournalEntryを返します。
+// 作成したタスクは削除し、削除したタスクは作成しなおしてステータスを復元し、
+// 更新したタスクは更新前の内容に戻します。
+// 更新の取り消しは、記録後に他の変更が行われていない場合に限り行います。
+func UndoJournalEntry(protocol string, host string, port int, token string, entry JournalEntry) (JournalEntry, error) {
+	undo := JournalEntry{Operation: JournalUndo, Undoes: entry.Seq, TaskID: entry.TaskID}
+
+	switch entry.Operation {
+	case JournalCreate:
+		task, err := DeleteTask(protocol, host, port, token, entry.TaskID)
+		if err != nil {
+			return undo, err
+		}
+		undo.Previous = &task
+		return undo, nil
+
+	case JournalDelete:
+		if entry.Previous == nil {
+			return undo, NewError(ErrorValidation, JournalUndoUnsupported)
+		}
+		created, err := CreateTask(protocol, host, port, token, entry.Previous.Title, entry.Previous.Description)
+		if err != nil {
+			return undo, err
+		}
+		restored := Task{ID: created.ID, Title: created.Title, Description: created.Description, Status: "TODO"}
+		if entry.Previous.Status != "" && entry.Previous.Status != restored.Status {
+			restored, err = UpdateTask(protocol, host, port, token, created.ID, "", "", entry.Previous.Status)
+			if err != nil {
+				return undo, err
+			}
+		}
+		undo.TaskID = restored.ID
+		undo.Current = &restored
+		return undo, nil
+
+	case JournalUpdate:
+		if entry.Previous == nil || entry.Current == nil {
+			return undo, NewError(ErrorValidation, JournalUndoUnsupported)
+		}
+		current, err := getTaskForUpdate(protocol, host, port, token, entry.TaskID)
+		if err != nil {
+			return undo, err
+		}
+		if TaskFingerprint(current) != TaskFingerprint(*entry.Current) {
+			Log.Debug(T(TaskUpdatePreconditionFailed), "id", entry.TaskID, "fingerprint", TaskFingerprint(current))
+			return undo, NewError(ErrorConflict, TaskUpdatePreconditionFailed)
+		}
+		undo.Previous = &current
+
+		// 更新で空の項目に値を設定した場合も元に戻せるよう、空の項目も含めて更新前の内容をすべて送ります。
+		// UpdateTaskWithOptionsは空の項目を変更しないものとして扱うため利用しません。
+		body := map[string]string{
+			"title":       entry.Previous.Title,
+			"description": entry.Previous.Description,
+			"status":      entry.Previous.Status,
+		}
+		if body["status"] == "" {
+			body["status"] = current.Status
+		}
+		task, err := patchTask(protocol, host, port, token, entry.TaskID, body)
+		if err == ErrDryRun {
+			// ドライラン時は書き戻した場合のタスクを組み立てて返します。
+			restored := applyTaskFields(current, body)
+			undo.Current = &restored
+			return undo, err
+		}
+		if err != nil {
+			return undo, err
+		}
+		undo.Current = &task
+		return undo, nil
+	}
+
+	return undo, NewError(ErrorValidation, JournalUndoUnsupported)
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestJournal は一時ディレクトリにジャーナルを作成します。
+func newTestJournal() (*Journal, func()) {
+	dir, err := ioutil.TempDir("", "todo-journal")
+	if err != nil {
+		log.Fatal(err)
+	}
+	journal := NewJournal(JournalPath(dir, "http", "127.0.0.1", 8000, "test_user"))
+	return journal, func() { os.RemoveAll(dir) }
+}
+
+// TestJournalPath ではサーバとユーザごとに異なるパスが得られることを確認する。
+func TestJournalPath(t *testing.T) {
+	a := JournalPath("/tmp", "http", "todo.example.com", 80, "alice")
+	b := JournalPath("/tmp", "http", "todo.example.com", 80, "bob")
+	c := JournalPath("/tmp", "https", "todo.example.com", 443, "alice")
+
+	if a == b || a == c {
+		t.Fail()
+	}
+	if filepath.Dir(JournalPath("/tmp", "http", "../../etc", 80, "../alice")) != "/tmp/http_.._.._etc_80" {
+		log.Println(JournalPath("/tmp", "http", "../../etc", 80, "../alice"))
+		t.Fail()
+	}
+}
+
+// TestJournalAppend では追記した操作に通し番号が振られ、古い順に読み出せることを確認する。
+func TestJournalAppend(t *testing.T) {
+	journal, cleanup := newTestJournal()
+	defer cleanup()
+
+	for id := 1; id <= 3; id++ {
+		if _, err := journal.Append(JournalEntry{Operation: JournalCreate, TaskID: id}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	entries, err := journal.Entries()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("entries: %d", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Seq != i+1 || entry.TaskID != i+1 || entry.Time.IsZero() {
+			t.Fail()
+		}
+	}
+}
+
+// TestJournalRotate では大きさの上限を超えた場合にファイルがローテートされ、
+// 残した世代の範囲で操作を読み出せることを確認する。
+func TestJournalRotate(t *testing.T) {
+	journal, cleanup := newTestJournal()
+	defer cleanup()
+	journal.MaxSize = 300
+	journal.MaxBackups = 2
+
+	for id := 1; id <= 20; id++ {
+		task := Task{ID: id, Title: strings.Repeat("x", 50)}
+		if _, err := journal.Append(JournalEntry{Operation: JournalCreate, TaskID: id, Current: &task}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if _, err := os.Stat(journal.Path + ".2"); err != nil {
+		t.Fail()
+	}
+	if _, err := os.Stat(journal.Path + ".3"); !os.IsNotExist(err) {
+		t.Fail()
+	}
+
+	entries, err := journal.Entries()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(entries) == 0 || len(entries) == 20 {
+		t.Fatalf("entries: %d", len(entries))
+	}
+	// ローテートしても通し番号は連続している
+	last := entries[len(entries)-1]
+	if last.Seq != 20 {
+		t.Fail()
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Seq != entries[i-1].Seq+1 {
+			t.Fail()
+		}
+	}
+}
+
+// TestJournalUndoable では取り消し済みの操作を除いて新しい順に返されることを確認する。
+func TestJournalUndoable(t *testing.T) {
+	journal, cleanup := newTestJournal()
+	defer cleanup()
+
+	journal.Append(JournalEntry{Operation: JournalCreate, TaskID: 1})
+	journal.Append(JournalEntry{Operation: JournalCreate, TaskID: 2})
+	journal.Append(JournalEntry{Operation: JournalDelete, TaskID: 3})
+	journal.Append(JournalEntry{Operation: JournalUndo, Undoes: 3})
+
+	entries, err := journal.Undoable(5)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].TaskID != 2 || entries[1].TaskID != 1 {
+		t.Fail()
+	}
+}
+
+// TestUndoJournalEntry では作成・更新・削除のそれぞれの操作を取り消せることを確認する。
+func TestUndoJournalEntry(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	protocol, host, port := server.target()
+
+	// 作成の取り消しはタスクの削除
+	server.put(Task{ID: 1, Title: "CREATED", Description: "D", Status: "TODO"})
+	_, err := UndoJournalEntry(protocol, host, port, "token", JournalEntry{Seq: 1, Operation: JournalCreate, TaskID: 1})
+	if err != nil {
+		log.Println(err)
+		t.Fail()
+	}
+	if _, ok := server.get(1); ok {
+		t.Fail()
+	}
+
+	// 削除の取り消しはタスクの再作成とステータスの復元
+	deleted := Task{ID: 1, Title: "CREATED", Description: "D", Status: "RUNNING"}
+	undo, err := UndoJournalEntry(protocol, host, port, "token", JournalEntry{Seq: 2, Operation: JournalDelete, TaskID: 1, Previous: &deleted})
+	if err != nil {
+		log.Println(err)
+		t.Fail()
+	}
+	restored, ok := server.get(undo.TaskID)
+	if !ok || restored.Title != "CREATED" || restored.Status != "RUNNING" || undo.Undoes != 2 {
+		t.Fail()
+	}
+
+	// 更新の取り消しは更新前の内容への書き戻し
+	previous := restored
+	current := restored
+	current.Title = "UPDATED"
+	current.Status = "FINISHED"
+	server.put(current)
+	_, err = UndoJournalEntry(protocol, host, port, "token", JournalEntry{Seq: 3, Operation: JournalUpdate, TaskID: current.ID, Previous: &previous, Current: &current})
+	if err != nil {
+		log.Println(err)
+		t.Fail()
+	}
+	if task, _ := server.get(current.ID); task.Title != "CREATED" || task.Status != "RUNNING" {
+		t.Fail()
+	}
+}
+
+// TestUndoJournalEntryAfterOtherChange では更新後に他の変更が行われていた場合は
+// 取り消しを行わないことを確認する。
+func TestUndoJournalEntryAfterOtherChange(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	protocol, host, port := server.target()
+
+	previous := Task{ID: 1, Title: "TODO", Description: "D", Status: "TODO"}
+	current := Task{ID: 1, Title: "UPDATED", Description: "D", Status: "TODO"}
+	server.put(Task{ID: 1, Title: "UPDATED", Description: "D", Status: "FINISHED"})
+
+	_, err := UndoJournalEntry(protocol, host, port, "token", JournalEntry{Seq: 1, Operation: JournalUpdate, TaskID: 1, Previous: &previous, Current: &current})
+	if err == nil || !IsMessage(err, TaskUpdatePreconditionFailed) {
+		t.Fail()
+	}
+	if task, _ := server.get(1); task.Title != "UPDATED" {
+		t.Fail()
+	}
+}
+
+// TestUndoJournalEntryRestoresEmptyFields では更新で空の概要に値を設定した場合に、
+// 取り消しで空の概要を送って元に戻すことを確認する。
+func TestUndoJournalEntryRestoresEmptyFields(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	protocol, host, port := server.target()
+
+	previous := Task{ID: 1, Title: "TODO", Description: "", Status: "TODO"}
+	current := Task{ID: 1, Title: "TODO", Description: "added", Status: "TODO"}
+	server.put(current)
+
+	undo, err := UndoJournalEntry(protocol, host, port, "token", JournalEntry{Seq: 1, Operation: JournalUpdate, TaskID: 1, Previous: &previous, Current: &current})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var patch *fakeRequest
+	for i, r := range server.requests {
+		if r.Method == "PATCH" {
+			patch = &server.requests[i]
+		}
+	}
+	if patch == nil {
+		t.Fatal("no PATCH request was sent")
+	}
+	if description, ok := patch.Body["description"]; !ok || description != "" {
+		t.Errorf("PATCH = %+v, want an empty description", patch.Body)
+	}
+	if task, _ := server.get(1); task != previous || *undo.Current != previous {
+		t.Errorf("task = %+v, undo = %+v, want %+v", task, undo.Current, previous)
+	}
+}
+
+// TestJournalUndoableAfterRestore では削除の取り消しで作成しなおしたタスクについて、
+// それ以前の操作が新しいIDで返されることを確認する。
+func TestJournalUndoableAfterRestore(t *testing.T) {
+	journal, cleanup := newTestJournal()
+	defer cleanup()
+
+	task := Task{ID: 1, Title: "TODO", Status: "RUNNING"}
+	journal.Append(JournalEntry{Operation: JournalUpdate, TaskID: 1, Previous: &task, Current: &task})
+	journal.Append(JournalEntry{Operation: JournalDelete, TaskID: 1, Previous: &task})
+	journal.Append(JournalEntry{Operation: JournalUndo, Undoes: 2, TaskID: 5})
+
+	entries, err := journal.Undoable(1)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Seq != 1 || entries[0].TaskID != 5 || entries[0].Current.ID != 5 {
+		t.Fail()
+	}
+}
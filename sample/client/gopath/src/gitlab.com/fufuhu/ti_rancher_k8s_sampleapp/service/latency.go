@@ -0,0 +1,173 @@
+package service
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// LatencySummary は応答時間の統計です。
+type LatencySummary struct {
+	Count  int           // 応答時間の数
+	Min    time.Duration // 最小値
+	Avg    time.Duration // 平均値
+	Max    time.Duration // 最大値
+	StdDev time.Duration // 標準偏差
+}
+
+// SummarizeLatency は応答時間の最小値・平均値・最大値・標準偏差を求めます。
+// samplesが空の場合はゼロ値を返します。
+func SummarizeLatency(samples []time.Duration) LatencySummary {
+	summary := LatencySummary{Count: len(samples)}
+	if len(samples) == 0 {
+		return summary
+	}
+
+	summary.Min, summary.Max = samples[0], samples[0]
+	var sum, squares float64
+	for _, sample := range samples {
+		if sample < summary.Min {
+			summary.Min = sample
+		}
+		if sample > summary.Max {
+			summary.Max = sample
+		}
+		sum += float64(sample)
+		squares += float64(sample) * float64(sample)
+	}
+
+	avg := sum / float64(len(samples))
+	summary.Avg = time.Duration(avg)
+	summary.StdDev = time.Duration(math.Sqrt(math.Max(squares/float64(len(samples))-avg*avg, 0)))
+	return summary
+}
+
+// histogramSubBuckets は2のべき乗ごとの区間をいくつに分けるかを表します。
+// 64分割のため、記録した値との誤差は1.6%以内に収まります(有効数字2桁)。
+const histogramSubBuckets = 64
+
+// LatencyHistogram はHDR Histogramと同様に、値の大きさに応じて幅を広げた区間で応答時間を数えます。
+// 大量の応答時間を一定のメモリで記録し、パーセンタイルを求めるために利用します。
+// 複数のgoroutineから同時に利用する場合は呼び出し側で排他制御を行ってください。
+type LatencyHistogram struct {
+	counts  []int64
+	count   int64
+	min     time.Duration
+	max     time.Duration
+	sum     float64
+	squares float64
+}
+
+// NewLatencyHistogram は空のヒストグラムを作成します。
+func NewLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram{counts: make([]int64, histogramBucket(math.MaxInt64)+1)}
+}
+
+// histogramBucket は値を数える区間の番号を返します。
+// 2*histogramSubBuckets未満の値は1ナノ秒単位で、それ以上の値は上位7ビットで区間を分けます。
+func histogramBucket(value int64) int {
+	if value < 2*histogramSubBuckets {
+		return int(value)
+	}
+	shift := uint(bits.Len64(uint64(value)) - 7)
+	return int(shift)*histogramSubBuckets + int(value>>shift)
+}
+
+// histogramBucketRange は区間に含まれる値の最小値と最大値を返します。
+func histogramBucketRange(bucket int) (int64, int64) {
+	if bucket < 2*histogramSubBuckets {
+		return int64(bucket), int64(bucket)
+	}
+	shift := uint(bucket/histogramSubBuckets - 1)
+	sub := int64(bucket - int(shift)*histogramSubBuckets)
+	return sub << shift, (sub+1)<<shift - 1
+}
+
+// Record は応答時間をひとつ記録します。負の値は0として扱います。
+func (h *LatencyHistogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.counts[histogramBucket(int64(d))]++
+	h.count++
+	h.sum += float64(d)
+	h.squares += float64(d) * float64(d)
+}
+
+// Merge は他のヒストグラムに記録した応答時間をまとめて記録します。
+func (h *LatencyHistogram) Merge(other *LatencyHistogram) {
+	if other.count == 0 {
+		return
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.count += other.count
+	h.sum += other.sum
+	h.squares += other.squares
+}
+
+// Count は記録した応答時間の数を返します。
+func (h *LatencyHistogram) Count() int64 {
+	return h.count
+}
+
+// Percentile は記録した応答時間のうち、pパーセント(0〜100)がその値以下となる応答時間を返します。
+// 返す値は区間の中央の値のため、記録した値とは最大で1.6%程度の誤差があります。
+func (h *LatencyHistogram) Percentile(p float64) time.Duration {
+	switch {
+	case h.count == 0:
+		return 0
+	case p <= 0:
+		return h.min
+	case p >= 100:
+		return h.max
+	}
+	rank := int64(math.Ceil(p / 100 * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int64
+	for bucket, c := range h.counts {
+		seen += c
+		if seen < rank {
+			continue
+		}
+		low, high := histogramBucketRange(bucket)
+		value := time.Duration(low + (high-low)/2)
+		if value < h.min {
+			value = h.min
+		}
+		if value > h.max {
+			value = h.max
+		}
+		return value
+	}
+	return h.max
+}
+
+// Summary は記録した応答時間の最小値・平均値・最大値・標準偏差を返します。
+// 区間ではなく記録した値そのものから求めるため、誤差はありません。
+func (h *LatencyHistogram) Summary() LatencySummary {
+	summary := LatencySummary{Count: int(h.count)}
+	if h.count == 0 {
+		return summary
+	}
+	avg := h.sum / float64(h.count)
+	summary.Min, summary.Max, summary.Avg = h.min, h.max, time.Duration(avg)
+	summary.StdDev = time.Duration(math.Sqrt(math.Max(h.squares/float64(h.count)-avg*avg, 0)))
+	return summary
+}
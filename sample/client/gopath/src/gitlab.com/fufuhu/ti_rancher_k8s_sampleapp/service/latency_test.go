@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSummarizeLatency では応答時間の最小値・平均値・最大値・標準偏差が求められることを確認する。
+func TestSummarizeLatency(t *testing.T) {
+	summary := SummarizeLatency([]time.Duration{2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond, 5 * time.Millisecond, 5 * time.Millisecond, 7 * time.Millisecond, 9 * time.Millisecond})
+	if summary.Count != 8 || summary.Min != 2*time.Millisecond || summary.Max != 9*time.Millisecond {
+		t.Errorf("summary = %+v", summary)
+	}
+	if summary.Avg != 5*time.Millisecond || summary.StdDev != 2*time.Millisecond {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+// TestSummarizeLatencyWithoutSamples では応答時間が無い場合にゼロ値を返すことを確認する。
+func TestSummarizeLatencyWithoutSamples(t *testing.T) {
+	if summary := SummarizeLatency(nil); summary != (LatencySummary{}) {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+// TestHistogramBucketRange では区間が隙間なく並び、値が自身の区間に含まれることを確認する。
+func TestHistogramBucketRange(t *testing.T) {
+	var next int64
+	for bucket := 0; bucket <= histogramBucket(1<<40); bucket++ {
+		low, high := histogramBucketRange(bucket)
+		if low != next || high < low {
+			t.Fatalf("bucket %d = [%d, %d], want low %d", bucket, low, high, next)
+		}
+		if histogramBucket(low) != bucket || histogramBucket(high) != bucket {
+			t.Fatalf("bucket %d = [%d, %d]", bucket, low, high)
+		}
+		next = high + 1
+	}
+}
+
+// TestLatencyHistogramPercentile ではパーセンタイルが有効数字2桁の精度で求められることを確認する。
+func TestLatencyHistogramPercentile(t *testing.T) {
+	h := NewLatencyHistogram()
+	other := NewLatencyHistogram()
+	for i := 1; i <= 10000; i++ {
+		if i%2 == 0 {
+			h.Record(time.Duration(i) * time.Microsecond)
+		} else {
+			other.Record(time.Duration(i) * time.Microsecond)
+		}
+	}
+	h.Merge(other)
+
+	if h.Count() != 10000 {
+		t.Fatalf("count = %d", h.Count())
+	}
+	for _, p := range []float64{50, 90, 99, 99.9} {
+		want := float64(p * 100 * float64(time.Microsecond))
+		got := float64(h.Percentile(p))
+		if got < want*0.984 || got > want*1.016 {
+			t.Errorf("p%g = %v, want about %v", p, time.Duration(got), time.Duration(want))
+		}
+	}
+	if h.Percentile(100) != 10*time.Millisecond || h.Percentile(0) != time.Microsecond {
+		t.Errorf("p100 = %v, p0 = %v", h.Percentile(100), h.Percentile(0))
+	}
+
+	summary := h.Summary()
+	if summary.Min != time.Microsecond || summary.Max != 10*time.Millisecond || summary.Avg != 5000500*time.Nanosecond {
+		t.Errorf("summary = %+v", summary)
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevelInvalid はログの出力レベルにdebug/info/warn/error以外が指定された場合のエラーメッセージです。
+const LogLevelInvalid MessageID = "log.level_invalid"
+
+// LogFormatInvalid はログの出力形式にtext/json以外が指定された場合のエラーメッセージです。
+const LogFormatInvalid MessageID = "log.format_invalid"
+
+// Logger はserviceパッケージが診断情報を出力する先です。
+// メソッドはlog/slogのLoggerと同じ形をしているため、*slog.Loggerをそのまま設定することもできます。
+// keysAndValuesには項目名と値を交互に指定します。
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// Log はserviceパッケージが利用するLoggerです。
+// ライブラリとして利用された場合に余計な出力をしないよう、既定では何も出力しません。
+var Log Logger = NopLogger{}
+
+// SetLogger はserviceパッケージが利用するLoggerを設定します。nilを指定した場合は何も出力しません。
+func SetLogger(logger Logger) {
+	if logger == nil {
+		logger = NopLogger{}
+	}
+	Log = logger
+}
+
+// NopLogger は何も出力しないLoggerです。
+type NopLogger struct{}
+
+// Debug は何も出力しません。
+func (NopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+// Info は何も出力しません。
+func (NopLogger) Info(msg string, keysAndValues ...interface{}) {}
+
+// Warn は何も出力しません。
+func (NopLogger) Warn(msg string, keysAndValues ...interface{}) {}
+
+// Error は何も出力しません。
+func (NopLogger) Error(msg string, keysAndValues ...interface{}) {}
+
+// LogLevel はログの出力レベルを表します。
+type LogLevel int
+
+// ログの出力レベルです。値はlog/slogのLevelに合わせています。
+const (
+	LogLevelDebug LogLevel = -4
+	LogLevelInfo  LogLevel = 0
+	LogLevelWarn  LogLevel = 4
+	LogLevelError LogLevel = 8
+)
+
+// String は出力レベルを大文字の名前で返します。
+func (l LogLevel) String() string {
+	switch {
+	case l <= LogLevelDebug:
+		return "DEBUG"
+	case l <= LogLevelInfo:
+		return "INFO"
+	case l <= LogLevelWarn:
+		return "WARN"
+	}
+	return "ERROR"
+}
+
+// ParseLogLevel は名前(debug/info/warn/error)から出力レベルを返します。
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LogLevelDebug, nil
+	case "info":
+		return LogLevelInfo, nil
+	case "warn", "warning":
+		return LogLevelWarn, nil
+	case "error":
+		return LogLevelError, nil
+	}
+	return LogLevelInfo, NewError(ErrorUsage, LogLevelInvalid)
+}
+
+// ログの出力形式です。
+const (
+	LogFormatText = "text" // 日時、レベル、メッセージ、項目名=値を1行に並べた形式
+	LogFormatJSON = "json" // 1行に1つのJSONオブジェクトを出力する形式(time/level/msgはslogと同じ項目名)
+)
+
+// StreamLogger は出力レベル以上のログを指定した形式でOutputに書き出すLoggerです。
+type StreamLogger struct {
+	Output io.Writer // ログの出力先
+	Level  LogLevel  // 出力する最低のレベル
+	Format string    // 出力形式(LogFormatText/LogFormatJSON)
+
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewStreamLogger は出力先、レベル、形式を指定してStreamLoggerを返します。
+func NewStreamLogger(output io.Writer, level LogLevel, format string) (*StreamLogger, error) {
+	if format != LogFormatText && format != LogFormatJSON {
+		return nil, NewError(ErrorUsage, LogFormatInvalid)
+	}
+	return &StreamLogger{Output: output, Level: level, Format: format, now: time.Now}, nil
+}
+
+// Debug はデバッグ用の詳細な情報を出力します。
+func (l *StreamLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelDebug, msg, keysAndValues)
+}
+
+// Info は処理の経過などの情報を出力します。
+func (l *StreamLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelInfo, msg, keysAndValues)
+}
+
+// Warn は処理は継続できるものの注意が必要な事象を出力します。
+func (l *StreamLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelWarn, msg, keysAndValues)
+}
+
+// Error は処理を継続できないエラーを出力します。
+func (l *StreamLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelError, msg, keysAndValues)
+}
+
+// Enabled は指定したレベルのログが出力されるかを返します。
+func (l *StreamLogger) Enabled(level LogLevel) bool {
+	return level >= l.Level
+}
+
+func (l *StreamLogger) log(level LogLevel, msg string, keysAndValues []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	now := time.Now
+	if l.now != nil {
+		now = l.now
+	}
+
+	var buf bytes.Buffer
+	if l.Format == LogFormatJSON {
+		writeJSONLog(&buf, now(), level, msg, keysAndValues)
+	} else {
+		writeTextLog(&buf, now(), level, msg, keysAndValues)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	buf.WriteTo(l.Output)
+}
+
+// logAttributes は項目名と値の組を順に返します。
+// 項目名が文字列でない場合や値が足りない場合は、slogと同じく!BADKEYという項目名で出力します。
+func logAttributes(keysAndValues []interface{}, f func(key string, value interface{})) {
+	for i := 0; i < len(keysAndValues); i++ {
+		key, ok := keysAndValues[i].(string)
+		if !ok || i+1 >= len(keysAndValues) {
+			f("!BADKEY", keysAndValues[i])
+			continue
+		}
+		f(key, keysAndValues[i+1])
+		i++
+	}
+}
+
+// logValue はエラーなどの値を出力用の値に変換します。
+func logValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+func writeTextLog(buf *bytes.Buffer, t time.Time, level LogLevel, msg string, keysAndValues []interface{}) {
+	fmt.Fprintf(buf, "%s %s %s", t.Format("2006/01/02 15:04:05"), level, msg)
+	logAttributes(keysAndValues, func(key string, value interface{}) {
+		text := fmt.Sprint(logValue(value))
+		if text == "" || strings.ContainsAny(text, " \t\n\"=") {
+			text = fmt.Sprintf("%q", text)
+		}
+		fmt.Fprintf(buf, " %s=%s", key, text)
+	})
+	buf.WriteByte('\n')
+}
+
+func writeJSONLog(buf *bytes.Buffer, t time.Time, level LogLevel, msg string, keysAndValues []interface{}) {
+	// 項目の並び順を保つため、mapを使わずに組み立てます。
+	writeField := func(key string, value interface{}) {
+		name, _ := json.Marshal(key)
+		data, err := json.Marshal(logValue(value))
+		if err != nil {
+			data, _ = json.Marshal(fmt.Sprint(value))
+		}
+		buf.WriteByte(',')
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(data)
+	}
+
+	buf.WriteByte('{')
+	data, _ := json.Marshal(t.Format(time.RFC3339Nano))
+	buf.WriteString(`"time":`)
+	buf.Write(data)
+	writeField("level", level.String())
+	writeField("msg", msg)
+	logAttributes(keysAndValues, writeField)
+	buf.WriteString("}\n")
+}
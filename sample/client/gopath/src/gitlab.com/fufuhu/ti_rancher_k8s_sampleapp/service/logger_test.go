@@ -0,0 +1,115 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestLogger は日時を固定したStreamLoggerを作成します。
+func newTestLogger(level LogLevel, format string) (*StreamLogger, *bytes.Buffer) {
+	var out bytes.Buffer
+	l, err := NewStreamLogger(&out, level, format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	l.now = func() time.Time {
+		return time.Date(2019, 6, 1, 12, 34, 56, 0, time.UTC)
+	}
+	return l, &out
+}
+
+// TestStreamLoggerWithTextFormat ではtext形式で項目名=値が出力されることを確認する。
+func TestStreamLoggerWithTextFormat(t *testing.T) {
+	l, out := newTestLogger(LogLevelInfo, LogFormatText)
+	l.Info("タスクを削除しました。", "id", 1, "title", "two words", "error", errors.New("失敗"))
+
+	expected := "2019/06/01 12:34:56 INFO タスクを削除しました。 id=1 title=\"two words\" error=失敗\n"
+	if out.String() != expected {
+		log.Println(out.String())
+		t.Fail()
+	}
+}
+
+// TestStreamLoggerWithJSONFormat ではjson形式で1行に1つのオブジェクトが出力されることを確認する。
+func TestStreamLoggerWithJSONFormat(t *testing.T) {
+	l, out := newTestLogger(LogLevelDebug, LogFormatJSON)
+	l.Debug("更新しました。", "id", 1, "error", errors.New("失敗"), "odd")
+
+	expected := `{"time":"2019-06-01T12:34:56Z","level":"DEBUG","msg":"更新しました。","id":1,"error":"失敗","!BADKEY":"odd"}` + "\n"
+	if out.String() != expected {
+		log.Println(out.String())
+		t.Fail()
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fail()
+	}
+}
+
+// TestStreamLoggerFiltersByLevel では出力レベル未満のログが出力されないことを確認する。
+func TestStreamLoggerFiltersByLevel(t *testing.T) {
+	l, out := newTestLogger(LogLevelWarn, LogFormatText)
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+
+	if strings.Count(out.String(), "\n") != 2 || !strings.Contains(out.String(), " WARN warn") || !strings.Contains(out.String(), " ERROR error") {
+		log.Println(out.String())
+		t.Fail()
+	}
+}
+
+// TestParseLogLevel では名前から出力レベルを得られることを確認する。
+func TestParseLogLevel(t *testing.T) {
+	for name, expected := range map[string]LogLevel{"debug": LogLevelDebug, "INFO": LogLevelInfo, "warn": LogLevelWarn, "error": LogLevelError} {
+		if level, err := ParseLogLevel(name); err != nil || level != expected {
+			t.Errorf("%s: %v", name, level)
+		}
+	}
+	if _, err := ParseLogLevel("verbose"); err == nil || !IsMessage(err, LogLevelInvalid) {
+		t.Fail()
+	}
+	if _, err := NewStreamLogger(&bytes.Buffer{}, LogLevelInfo, "xml"); err == nil || !IsMessage(err, LogFormatInvalid) {
+		t.Fail()
+	}
+}
+
+// recordingLogger は出力されたメッセージを記録するテスト用のLoggerです。
+type recordingLogger struct {
+	NopLogger
+	messages []string
+}
+
+func (l *recordingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.messages = append(l.messages, msg)
+}
+
+// TestSetLogger では設定したLoggerにserviceパッケージの診断情報が出力されることを確認する。
+func TestSetLogger(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+
+	recorder := &recordingLogger{}
+	SetLogger(recorder)
+	defer SetLogger(nil)
+
+	protocol, host, port := server.target()
+	if _, err := GetTask(protocol, host, port, "token", 1); err == nil {
+		t.Fail()
+	}
+
+	if len(recorder.messages) != 1 || recorder.messages[0] != T(TaskGetReturnedStatusCodeUnexpected) {
+		log.Println(recorder.messages)
+		t.Fail()
+	}
+	if _, ok := Log.(*recordingLogger); !ok {
+		t.Fail()
+	}
+}
@@ -1,16 +1,11 @@
 package service
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
-	"os"
 	"strconv"
-	"strings"
-
-	yaml "gopkg.in/yaml.v2"
 )
 
 // JWTAuthMessage は認証の結果として返ってくるJSONメッセージを
@@ -19,6 +14,10 @@ type JWTAuthMessage struct {
 	Token string `json:"token"` // 受け取ったトークン本体
 }
 
+// LoginReturnedStatusCodeUnexpected は認証のリクエストを行った際に、
+// 200 OK 以外のレスポンスコードが返ってきた場合のエラーメッセージです。
+const LoginReturnedStatusCodeUnexpected MessageID = "login.status_unexpected"
+
 // LoginConfig はToDoクライアントがToDoサーバに対して認証処理および、
 // その結果を受け取る際に必要となる情報をまとめた構造体です
 type LoginConfig struct {
@@ -29,6 +28,8 @@ type LoginConfig struct {
 	Username string // 認証に利用するユーザ名
 	Password string // 認証に利用するパスワード
 	Token    string // ToDoサーバから取得したトークン
+	// Endpoints は複数のエンドポイントを利用する場合の、エンドポイントごとのトークンです。
+	Endpoints []Endpoint
 }
 
 // Login はToDoクライアントからToDoサーバへの認証処理を行い、
@@ -39,40 +40,50 @@ func Login(protocol string, host string, port int, username string, password str
 	path := "/api/auth"
 	url := protocol + "://" + host + ":" + strconv.Itoa(port) + path
 
-	client := &http.Client{}
-
-	authInfo := fmt.Sprintf(`{ "username": "%s","password": "%s"}`, username, password)
-
-	req, err := http.NewRequest("POST", url, strings.NewReader(authInfo))
-	req.Header.Set("Content-Type", "application/json") //ボディに含まれるコンテンツがJSONであることを明示する
+	authInfo, err := json.Marshal(map[string]string{
+		"username": username,
+		"password": password,
+	})
+	if err != nil {
+		return LoginConfig{}, err
+	}
 
+	req, err := http.NewRequest("POST", url, bytes.NewReader(authInfo))
 	if err != nil {
-		log.Fatal(err)
+		return LoginConfig{}, err
 	}
+	req.Header.Set("Content-Type", "application/json") //ボディに含まれるコンテンツがJSONであることを明示する
 
 	// ログインリクエストを発行します。
-	res, err := client.Do(req)
+	res, err := send(req)
 
+	if err == ErrDryRun {
+		return LoginConfig{Protocol: protocol, Host: host, Port: port, Username: username, Password: password}, err
+	}
 	if err != nil {
-		log.Fatal(err)
+		Log.Debug(T(RequestSendFailure), "url", url, "error", err)
+		return LoginConfig{}, err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
 		// 200 OK以外のステータスが返ってきた場合は異常です。
-		log.Fatal(res)
+		Log.Debug(T(LoginReturnedStatusCodeUnexpected), "status", res.Status)
+		return LoginConfig{}, newStatusError(LoginReturnedStatusCodeUnexpected, res.StatusCode)
 	}
 
 	// レスポンスメッセージのボディの中からJWTを取得します。
 	body, err := ioutil.ReadAll(res.Body)
 
 	if err != nil {
-		log.Fatal(err)
+		Log.Debug(T(ResponseBodyReadFailure), "error", err)
+		return LoginConfig{}, NewError(ErrorServer, ResponseBodyReadFailure)
 	}
 
 	var authMessage JWTAuthMessage
 	if err := json.Unmarshal(body, &authMessage); err != nil {
-		log.Fatal(err)
+		Log.Debug(T(ResponseBodyParseFailure), "error", err)
+		return LoginConfig{}, NewError(ErrorServer, ResponseBodyParseFailure)
 	}
 
 	// LoginConfigに詰めなおして返却します。
@@ -88,14 +99,6 @@ func Login(protocol string, host string, port int, username string, password str
 	return loginConfig, err
 }
 
-// Config はyamlファイルとして保管されているToDoクライアントの設定ファイルを表します。
-type Config struct {
-	Protocol string `yaml:"protocol"` // ToDoサーバにアクセスする際のプロトコル
-	Host     string `yaml:"host"`     // ToDoサーバのFQDN
-	Port     int    `yaml:"port"`     // ToDoサーバにアクセスする際の宛先TCPポート番号
-	Token    string `yaml:"token"`    // ToDoサーバから取得したトークン
-}
-
 // CreateConfigFile はLoginConfigの情報を受け取ってYAML形式でToDoクライアントの設定ファイルを作成します。
 func CreateConfigFile(loginConfig LoginConfig) (Config, error) {
 
@@ -106,21 +109,26 @@ func CreateConfigFile(loginConfig LoginConfig) (Config, error) {
 		Host:     loginConfig.Host,
 		Port:     loginConfig.Port,
 		Token:    loginConfig.Token,
-	}
 
-	// 構造体からYAML形式への変換処理を行います。
-	out, err := yaml.Marshal(config)
-
-	if err != nil {
-		log.Fatal(err)
+		Endpoints: loginConfig.Endpoints,
 	}
 
-	// ファイルへの出力
-	err = ioutil.WriteFile(loginConfig.Filepath, out, os.ModePerm)
-
+	// 既存の設定ファイルのエイリアスなど、ログインと関係の無い設定は残したまま更新します。
+	file, err := LoadConfigFile(loginConfig.Filepath)
 	if err != nil {
-		log.Fatal(err)
+		return config, err
+	}
+	file.Set("protocol", config.Protocol)
+	file.Set("host", config.Host)
+	file.Set("port", config.Port)
+	file.Set("token", config.Token)
+	if len(config.Endpoints) > 0 {
+		file.Set("endpoints", config.Endpoints)
+	} else {
+		file.Delete("endpoints")
 	}
 
+	// ファイルへの出力
+	err = file.Save()
 	return config, err
 }
@@ -82,6 +82,32 @@ func TestCreateConfigFile(t *testing.T) {
 	}
 }
 
+// TestCreateConfigFileKeepsOtherSettings はログインと関係の無い設定が
+// 設定ファイルの更新後も残ることを確認する。
+func TestCreateConfigFileKeepsOtherSettings(t *testing.T) {
+	file, err := ioutil.TempFile("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("token: old\naliases:\n  running: [get, --status, RUNNING]\nendpoints:\n- url: http://a\n")
+	file.Close()
+
+	if _, err := CreateConfigFile(LoginConfig{Filepath: file.Name(), Protocol: "http", Host: "localhost", Port: 80, Token: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfigFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := config.Get("token")
+	_, hasAliases := config.Get("aliases.running")
+	_, hasEndpoints := config.Get("endpoints")
+	if token != "new" || !hasAliases || hasEndpoints {
+		t.Errorf("token = %v, aliases = %v, endpoints = %v", token, hasAliases, hasEndpoints)
+	}
+}
+
 // TestLogin は正常系のテストです。
 // テスト用ユーザ(test_user)を使って
 // JWTトークンを取得できるかを確認します。
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// MessageID はメッセージカタログからメッセージを引くためのIDです。
+// エラーの判別にはメッセージの文言ではなくIDを利用します。
+type MessageID string
+
+// 対応している言語です。
+const (
+	LanguageJapanese = "ja"
+	LanguageEnglish  = "en"
+)
+
+// DefaultLanguage は言語の指定が無い場合や、対応していない言語が指定された場合に利用する言語です。
+const DefaultLanguage = LanguageJapanese
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = map[string]map[MessageID]string{}
+	language  = DetectLanguage()
+)
+
+func init() {
+	RegisterMessages(LanguageJapanese, messagesJa)
+	RegisterMessages(LanguageEnglish, messagesEn)
+}
+
+// RegisterMessages は言語ごとのメッセージをメッセージカタログに追加します。
+// 同じIDのメッセージが登録済みの場合は上書きします。
+func RegisterMessages(lang string, messages map[MessageID]string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	if catalog[lang] == nil {
+		catalog[lang] = map[MessageID]string{}
+	}
+	for id, message := range messages {
+		catalog[lang][id] = message
+	}
+}
+
+// SetLanguage はメッセージの出力に利用する言語を設定します。
+// ja_JP.UTF-8のようなロケール名も指定できます。対応していない言語の場合はDefaultLanguageを利用します。
+func SetLanguage(lang string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	language = normalizeLanguage(lang)
+}
+
+// Language はメッセージの出力に利用している言語を返します。
+func Language() string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	return language
+}
+
+// DetectLanguage は環境変数(LC_ALL, LC_MESSAGES, LANGの順)から言語を判断します。
+func DetectLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return normalizeLanguage(value)
+		}
+	}
+	return DefaultLanguage
+}
+
+// normalizeLanguage はja_JP.UTF-8のようなロケール名から言語の部分を取り出します。
+func normalizeLanguage(lang string) string {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	switch lang {
+	case LanguageJapanese, LanguageEnglish:
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// T はIDに対応するメッセージを現在の言語で返します。
+// argsを指定した場合はメッセージを書式としてfmt.Sprintfで組み立てます。
+// 現在の言語に翻訳が無い場合はDefaultLanguageのメッセージを、それも無い場合はIDをそのまま返します。
+func T(id MessageID, args ...interface{}) string {
+	catalogMu.RLock()
+	message, ok := catalog[language][id]
+	if !ok {
+		message, ok = catalog[DefaultLanguage][id]
+	}
+	catalogMu.RUnlock()
+
+	if !ok {
+		message = string(id)
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"os"
+	"testing"
+)
+
+// withLanguage は言語を切り替えてfを実行し、終了後に元の言語に戻す。
+func withLanguage(lang string, f func()) {
+	previous := Language()
+	SetLanguage(lang)
+	defer SetLanguage(previous)
+	f()
+}
+
+// TestSetLanguage はロケール名から言語を判断し、対応していない言語は既定の言語として扱うことを確認する。
+func TestSetLanguage(t *testing.T) {
+	cases := map[string]string{
+		"ja":          LanguageJapanese,
+		"ja_JP.UTF-8": LanguageJapanese,
+		"en":          LanguageEnglish,
+		"en_US.UTF-8": LanguageEnglish,
+		"EN-us":       LanguageEnglish,
+		"fr_FR":       DefaultLanguage,
+		"C":           DefaultLanguage,
+		"":            DefaultLanguage,
+	}
+	for lang, expected := range cases {
+		withLanguage(lang, func() {
+			if Language() != expected {
+				t.Errorf("SetLanguage(%q): %s, want %s", lang, Language(), expected)
+			}
+		})
+	}
+}
+
+// TestDetectLanguage はLC_ALL, LC_MESSAGES, LANGの順に言語を判断することを確認する。
+func TestDetectLanguage(t *testing.T) {
+	names := []string{"LC_ALL", "LC_MESSAGES", "LANG"}
+	for _, name := range names {
+		value, ok := os.LookupEnv(name)
+		os.Unsetenv(name)
+		if ok {
+			defer os.Setenv(name, value)
+		} else {
+			defer os.Unsetenv(name)
+		}
+	}
+
+	if DetectLanguage() != DefaultLanguage {
+		t.Fail()
+	}
+	os.Setenv("LANG", "en_US.UTF-8")
+	if DetectLanguage() != LanguageEnglish {
+		t.Fail()
+	}
+	os.Setenv("LC_MESSAGES", "ja_JP.UTF-8")
+	if DetectLanguage() != LanguageJapanese {
+		t.Fail()
+	}
+	os.Setenv("LC_ALL", "en_US.UTF-8")
+	if DetectLanguage() != LanguageEnglish {
+		t.Fail()
+	}
+}
+
+// TestTranslate は言語ごとのメッセージが得られ、翻訳が無い場合は既定の言語やIDで代用することを確認する。
+func TestTranslate(t *testing.T) {
+	RegisterMessages(LanguageJapanese, map[MessageID]string{"test.only_ja": "日本語のみ: %d"})
+
+	withLanguage(LanguageEnglish, func() {
+		if T(TaskGetReturnedNotFoundStatusCode) != messagesEn[TaskGetReturnedNotFoundStatusCode] {
+			t.Fail()
+		}
+		if T("test.only_ja", 1) != "日本語のみ: 1" {
+			t.Fail()
+		}
+		if T("test.unknown") != "test.unknown" {
+			t.Fail()
+		}
+	})
+	withLanguage(LanguageJapanese, func() {
+		if T(TaskGetReturnedNotFoundStatusCode) != messagesJa[TaskGetReturnedNotFoundStatusCode] {
+			t.Fail()
+		}
+	})
+}
+
+// TestCatalogsHaveSameMessages は日本語と英語のメッセージカタログに同じIDが揃っていることを確認する。
+func TestCatalogsHaveSameMessages(t *testing.T) {
+	for id := range messagesJa {
+		if _, ok := messagesEn[id]; !ok {
+			t.Errorf("%s: 英語のメッセージがありません", id)
+		}
+	}
+	for id := range messagesEn {
+		if _, ok := messagesJa[id]; !ok {
+			t.Errorf("%s: 日本語のメッセージがありません", id)
+		}
+	}
+}
+
+// TestErrorIdentityDoesNotDependOnLanguage はエラーの判別が言語に依存しないことを確認する。
+func TestErrorIdentityDoesNotDependOnLanguage(t *testing.T) {
+	err := WrapError(ErrorUnknown, TaskUpdateConflict, newStatusError(TaskGetReturnedNotFoundStatusCode, 404))
+
+	withLanguage(LanguageEnglish, func() {
+		if err.Error() != messagesEn[TaskUpdateConflict]+": "+messagesEn[TaskGetReturnedNotFoundStatusCode] {
+			t.Error(err.Error())
+		}
+	})
+	withLanguage(LanguageJapanese, func() {
+		if err.Error() != messagesJa[TaskUpdateConflict]+": "+messagesJa[TaskGetReturnedNotFoundStatusCode] {
+			t.Error(err.Error())
+		}
+	})
+	if !IsMessage(err, TaskUpdateConflict) || KindOf(err) != ErrorNotFound || err.StatusCode != 404 {
+		t.Fail()
+	}
+}
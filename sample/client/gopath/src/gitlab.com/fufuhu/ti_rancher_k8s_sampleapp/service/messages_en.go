@@ -0,0 +1,76 @@
+package service
+
+// messagesEn はserviceパッケージの英語のメッセージです。
+var messagesEn = map[MessageID]string{
+	BenchMixInvalid: "The operation mix (--mix) %q is invalid. Give weights of 0 or more to create/get/list/update/delete in the form \"create=1,get=4\".",
+
+	BulkOperationIDRequired: "update/delete operations require the ID of the target task (id).",
+	BulkOperationUnknown:    "The operation must be one of create/update/delete.",
+	BulkProgress:            "succeeded: %d failed: %d",
+
+	ConfigDecodeFailure:      "Cannot decode config file %s",
+	ConfigKindInvalid:        "Config file %s has kind %v, which this client does not support. Use %s.",
+	ConfigVersionUnsupported: "Config file %s has apiVersion %q, which this client does not support. This client supports %s.",
+
+	EndpointUnavailable:         "No ToDo server endpoint is available.",
+	EndpointURLInvalid:          "The endpoint URL (url) must start with http:// or https://.",
+	FailoverEndpointSelected:    "Endpoint: %s",
+	FailoverCircuitStateChanged: "The circuit breaker of endpoint %s is now %s.",
+	FailoverHealthCheckFailure:  "Health check of endpoint %s failed: %v",
+	FailoverRequestFailure:      "Request to endpoint %s failed: %v",
+	FailoverStateSaveFailure:    "Failed to save the circuit breaker state: %v",
+
+	JournalUndoUnsupported: "This operation cannot be undone.",
+
+	LogFormatInvalid: "The log format must be one of text/json.",
+	LogLevelInvalid:  "The log level must be one of debug/info/warn/error.",
+
+	LoginReturnedStatusCodeUnexpected: "Authentication failed. Check your username and password.",
+	PingReturnedStatusCodeUnexpected:  "The ping-pong API of the ToDo server did not return the expected status code (200 OK).",
+
+	MetadataDueInvalid:      "Cannot read the due date %q. Give a date in the form 2006-01-02 or a time in RFC3339.",
+	MetadataPriorityInvalid: "The priority %q is invalid. Give one of low/medium/high.",
+	MetadataTagInvalid:      "The tag %q is invalid. Tags must not be empty or contain spaces or commas.",
+
+	ReminderMessageDueSoon:   "[%s] Task %d \"%s\" is due soon (%s).",
+	ReminderMessageOverdue:   "[%s] Task %d \"%s\" is overdue (due %s).",
+	RequestSendFailure:       "Failed to send the request.",
+	RequestSkippedByDryRun:   "The request was not sent because of dry run.",
+	ResponseBodyParseFailure: "Failed to parse the response body",
+	ResponseBodyReadFailure:  "Failed to read the response body",
+
+	TaskCreated:                              "Created the task.",
+	TaskCreationReturnedStatusCodeUnexpected: "The response status code is not the expected one (201 Created).",
+	TaskDeleteRetriedNotFound:                "The retried delete request found no task, so the earlier request is considered to have deleted it.",
+	TaskDeleteReturnedNotFoundStatusCode:     "The task to delete was not found for the given ID.",
+	TaskDeleteReturnedStatusCodeUnexpected:   "Deleting the task with the given ID returned an unexpected status code.",
+	TaskGetReturnedNotFoundStatusCode:        "No task was found for the given ID.",
+	TaskGetReturnedStatusCodeUnexpected:      "The response status code is not the expected one (200 OK).",
+	TaskUpdated:                              "Updated the task.",
+	TaskUpdateRetrying:                       "Detected a conflict; retrying the update based on the latest task.",
+	TaskUpdateConflict:                       "The update was aborted because the task was changed by another operation after it was fetched.",
+	TaskUpdatePreconditionFailed:             "The task does not match the given conditions (--if-match/--expect-status).",
+	TaskUpdateReturnedBadRequestStatusCode:   "The status given for the update is invalid",
+	TaskUpdateReturnedNotFoundStatusCode:     "The task to update was not found for the given ID.",
+	TaskUpdateReturnedStatusCodeUnexpected:   "Updating the task with the given ID returned an unexpected status code.",
+
+	TemplateInvalid:       "Cannot read the template %s",
+	TemplateNameInvalid:   "The template name %q may only contain lowercase letters, digits, - and _.",
+	TemplateNoTasks:       "The template %s has no tasks to create (tasks).",
+	TemplateNotFound:      "The template %s is not in %s.",
+	TemplateParamMissing:  "Give the parameter %s with --set (template: %s).",
+	TemplateParamUnknown:  "The parameter %s is not in the params of the template %s.",
+	TemplateRenderFailure: "Cannot render task %[2]d of the template %[1]s",
+	TemplateTitleEmpty:    "The title of task %[2]d of the template %[1]s is empty.",
+
+	TokenMalformed: "The authentication token (JWT) is malformed.",
+
+	TraceBodyTruncated:    "(truncated)",
+	TraceHARRecordFailure: "Failed to record the HAR log: %v",
+	TraceNoResponse:       "No response was received.",
+	TraceRequestBody:      "Request body",
+	TraceRequestFailed:    "failed",
+	TraceRequestHeaders:   "Request headers",
+	TraceResponseBody:     "Response body",
+	TraceResponseHeaders:  "Response headers",
+}
@@ -0,0 +1,76 @@
+package service
+
+// messagesJa はserviceパッケージの日本語のメッセージです。
+var messagesJa = map[MessageID]string{
+	BenchMixInvalid: "操作の配分(--mix)の指定%qが不正です。create/get/list/update/deleteに0以上の重みを\"create=1,get=4\"の形式で指定してください。",
+
+	BulkOperationIDRequired: "update/deleteの操作には対象のタスクのID(id)を指定してください。",
+	BulkOperationUnknown:    "操作の種類(operation)にはcreate/update/deleteのいずれかを指定してください。",
+	BulkProgress:            "成功: %d 失敗: %d",
+
+	ConfigDecodeFailure:      "設定ファイル%sを解釈できません",
+	ConfigKindInvalid:        "設定ファイル%sの種類(kind)%vはこのクライアントでは扱えません。%sを指定してください。",
+	ConfigVersionUnsupported: "設定ファイル%sの形式(apiVersion)%qはこのクライアントでは扱えません。このクライアントが扱える形式は%sです。",
+
+	EndpointUnavailable:         "利用可能なToDoサーバのエンドポイントがありません。",
+	EndpointURLInvalid:          "エンドポイントのURL(url)にはhttp://またはhttps://から始まるURLを指定してください。",
+	FailoverEndpointSelected:    "接続先: %s",
+	FailoverCircuitStateChanged: "エンドポイント %s のサーキットブレーカーが%sになりました。",
+	FailoverHealthCheckFailure:  "エンドポイント %s の状態確認に失敗しました: %v",
+	FailoverRequestFailure:      "エンドポイント %s へのリクエストに失敗しました: %v",
+	FailoverStateSaveFailure:    "サーキットブレーカーの状態の保存に失敗しました: %v",
+
+	JournalUndoUnsupported: "この操作は取り消すことができません。",
+
+	LogFormatInvalid: "ログの出力形式にはtext/jsonのいずれかを指定してください。",
+	LogLevelInvalid:  "ログの出力レベルにはdebug/info/warn/errorのいずれかを指定してください。",
+
+	LoginReturnedStatusCodeUnexpected: "認証に失敗しました。ユーザ名とパスワードを確認してください。",
+	PingReturnedStatusCodeUnexpected:  "ToDoサーバのping-pong APIが期待したレスポンスステータスコード(200 OK)を返しませんでした。",
+
+	MetadataDueInvalid:      "期限%qを解釈できません。2006-01-02の形式の日付か、RFC3339の形式の日時を指定してください。",
+	MetadataPriorityInvalid: "優先度%qは指定できません。low/medium/highのいずれかを指定してください。",
+	MetadataTagInvalid:      "タグ%qは指定できません。空白や,を含まないタグを指定してください。",
+
+	ReminderMessageDueSoon:   "[%s] タスク%d「%s」の期限(%s)が近づいています。",
+	ReminderMessageOverdue:   "[%s] タスク%d「%s」の期限(%s)を過ぎています。",
+	RequestSendFailure:       "リクエストの送信に失敗しました。",
+	RequestSkippedByDryRun:   "ドライランのためリクエストを送信しませんでした。",
+	ResponseBodyParseFailure: "レスポンスボディのパースに失敗しました",
+	ResponseBodyReadFailure:  "レスポンスボディの読み込みに失敗しました",
+
+	TaskCreated:                              "タスクを作成しました。",
+	TaskCreationReturnedStatusCodeUnexpected: "期待したレスポンスステータスコード(201 Created)ではありません。",
+	TaskDeleteRetriedNotFound:                "再送した削除のリクエストでタスクが見つからないため、以前のリクエストで削除できたとみなします。",
+	TaskDeleteReturnedNotFoundStatusCode:     "削除の為に指定したIDに対応するTaskが見つかりませんでした。",
+	TaskDeleteReturnedStatusCodeUnexpected:   "指定されたIDに対応するTaskを削除しようとしましたが、想定外のステータスコードが返されました。",
+	TaskGetReturnedNotFoundStatusCode:        "指定したタスクのIDに対応するタスクが見つかりません。",
+	TaskGetReturnedStatusCodeUnexpected:      "期待したレスポンスステータスコード(200 OK)ではありません。",
+	TaskUpdated:                              "タスクを更新しました。",
+	TaskUpdateRetrying:                       "競合を検知したため、最新のタスクを元に更新を再試行します。",
+	TaskUpdateConflict:                       "更新対象のタスクが取得後に他の操作によって変更されたため、更新を中止しました。",
+	TaskUpdatePreconditionFailed:             "更新対象のタスクが指定された条件(--if-match/--expect-status)と一致しません。",
+	TaskUpdateReturnedBadRequestStatusCode:   "更新の為に指定したステータス情報が不正です",
+	TaskUpdateReturnedNotFoundStatusCode:     "更新の為に指定したIDに対応するTaskが見つかりませんでした。",
+	TaskUpdateReturnedStatusCodeUnexpected:   "指定されたIDに対応するTaskを更新しようとしましたが、想定外のステータスコードが返されました。",
+
+	TemplateInvalid:       "テンプレート%sを解釈できません",
+	TemplateNameInvalid:   "テンプレートの名前%qには英小文字、数字、-、_のみを使用できます。",
+	TemplateNoTasks:       "テンプレート%sに作成するタスク(tasks)が記述されていません。",
+	TemplateNotFound:      "テンプレート%sが%sにありません。",
+	TemplateParamMissing:  "パラメータ%sを--setで指定してください(テンプレート: %s)。",
+	TemplateParamUnknown:  "パラメータ%sはテンプレート%sのparamsにありません。",
+	TemplateRenderFailure: "テンプレート%sの%d件目のタスクを展開できません",
+	TemplateTitleEmpty:    "テンプレート%sの%d件目のタスクの名前が空です。",
+
+	TokenMalformed: "認証トークン(JWT)の形式が不正です。",
+
+	TraceBodyTruncated:    "(省略)",
+	TraceHARRecordFailure: "HAR形式の記録に失敗しました: %v",
+	TraceNoResponse:       "レスポンスを受信できませんでした。",
+	TraceRequestBody:      "リクエストボディ",
+	TraceRequestFailed:    "失敗",
+	TraceRequestHeaders:   "リクエストヘッダ",
+	TraceResponseBody:     "レスポンスボディ",
+	TraceResponseHeaders:  "レスポンスヘッダ",
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// MetadataKey はタスクの概要の先頭のフロントマターのうち、このクライアントのメタデータを記述するキーです。
+const MetadataKey = "todo"
+
+// frontMatterDelimiter はフロントマターの始まりと終わりの行です。
+const frontMatterDelimiter = "---"
+
+// タスクの優先度です。
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+)
+
+// Priorities は指定できる優先度を低い順に並べたものです。
+var Priorities = []string{PriorityLow, PriorityMedium, PriorityHigh}
+
+// MetadataTagInvalid はタグが空の場合や、空白や,を含む場合のエラーメッセージです。
+const MetadataTagInvalid MessageID = "metadata.tag_invalid"
+
+// MetadataPriorityInvalid は優先度がlow/medium/high以外の場合のエラーメッセージです。
+const MetadataPriorityInvalid MessageID = "metadata.priority_invalid"
+
+// MetadataDueInvalid は期限を日付や日時として解釈できない場合のエラーメッセージです。
+const MetadataDueInvalid MessageID = "metadata.due_invalid"
+
+// TaskMetadata はToDoサーバのタスクには無い、クライアントで管理するタスクの情報です。
+// タスクの概要の先頭にYAMLのフロントマターとして記述します。
+//
+//	---
+//	todo:
+//	  tags: [ops, api]
+//	  priority: high
+//	  due: "2019-04-01"
+//	---
+//	概要の本文
+type TaskMetadata struct {
+	Tags     []string `yaml:"tags,omitempty,flow" json:"tags,omitempty"`    // タグ
+	Priority string   `yaml:"priority,omitempty" json:"priority,omitempty"` // 優先度(low/medium/high)
+	Due      string   `yaml:"due,omitempty" json:"due,omitempty"`           // 期限(2006-01-02またはRFC3339の日時)
+}
+
+// IsZero はメタデータが何も指定されていないかどうかを返します。
+func (m TaskMetadata) IsZero() bool {
+	return len(m.Tags) == 0 && m.Priority == "" && m.Due == ""
+}
+
+// HasTag はタグを持っているかどうかを返します。
+func (m TaskMetadata) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// DueTime は期限を日時として返します。期限が無い場合はfalseを返します。
+func (m TaskMetadata) DueTime() (time.Time, bool) {
+	if m.Due == "" {
+		return time.Time{}, false
+	}
+	due, err := ParseDue(m.Due)
+	return due, err == nil
+}
+
+// Validate はメタデータの値が正しいかどうかを確認します。
+func (m TaskMetadata) Validate() error {
+	for _, tag := range m.Tags {
+		if tag == "" || strings.ContainsAny(tag, ", \t\r\n") {
+			return NewError(ErrorUsage, MetadataTagInvalid, tag)
+		}
+	}
+	if m.Priority != "" && PriorityRank(m.Priority) < 0 {
+		return NewError(ErrorUsage, MetadataPriorityInvalid, m.Priority)
+	}
+	if m.Due != "" {
+		if _, err := ParseDue(m.Due); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PriorityRank は優先度の高さ(lowが0)を返します。優先度として正しくない場合は-1を返します。
+func PriorityRank(priority string) int {
+	for i, p := range Priorities {
+		if p == priority {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseDue は期限を解釈します。日付(2006-01-02)だけの場合は、その日の終わり(翌日の0時、ローカル時刻)とします。
+func ParseDue(due string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, due); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", due, time.Local); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	return time.Time{}, NewError(ErrorUsage, MetadataDueInvalid, due)
+}
+
+// TaskDescription はタスクの概要を、メタデータと本文に分けたものです。
+// ParseDescriptionで分けた概要は、メタデータと本文を変更しなければStringで元の文字列に戻ります。
+type TaskDescription struct {
+	Metadata TaskMetadata
+	Body     string
+
+	// 他のツールがフロントマターに記述したキーは、そのまま残します。
+	extra yaml.MapSlice
+	// 解析した元の概要と、その時点のメタデータと本文です。
+	original         string
+	originalMetadata TaskMetadata
+	originalBody     string
+	parsed           bool
+}
+
+// ParseDescription はタスクの概要の先頭のフロントマターからメタデータを読み取ります。
+// フロントマターが無い場合や、todoのキーを持たないなど解釈できない場合は、概要全体を本文として扱います。
+// 他のクライアントが作成したタスクもそのまま表示できるよう、エラーは返しません。
+func ParseDescription(description string) TaskDescription {
+	d := TaskDescription{Body: description, original: description, originalBody: description, parsed: true}
+	front, body, ok := splitFrontMatter(description)
+	if !ok {
+		return d
+	}
+	var root yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(front), &root); err != nil {
+		return d
+	}
+	var metadata TaskMetadata
+	found := false
+	var extra yaml.MapSlice
+	for _, item := range root {
+		if key, _ := item.Key.(string); key != MetadataKey {
+			extra = append(extra, item)
+			continue
+		}
+		value, err := yaml.Marshal(item.Value)
+		if err != nil || yaml.UnmarshalStrict(value, &metadata) != nil {
+			return d
+		}
+		found = true
+	}
+	if !found {
+		return d
+	}
+	d.Metadata, d.Body, d.extra = metadata, body, extra
+	d.originalMetadata, d.originalBody = metadata, body
+	return d
+}
+
+// splitFrontMatter は---の行で囲まれた先頭のフロントマターと、それに続く本文を返します。
+func splitFrontMatter(description string) (string, string, bool) {
+	if !strings.HasPrefix(description, frontMatterDelimiter+"\n") {
+		return "", "", false
+	}
+	rest := description[len(frontMatterDelimiter)+1:]
+	if strings.HasPrefix(rest, frontMatterDelimiter+"\n") || rest == frontMatterDelimiter {
+		return "", strings.TrimPrefix(rest[len(frontMatterDelimiter):], "\n"), true
+	}
+	end := strings.Index(rest, "\n"+frontMatterDelimiter+"\n")
+	if end < 0 {
+		if !strings.HasSuffix(rest, "\n"+frontMatterDelimiter) {
+			return "", "", false
+		}
+		return rest[:len(rest)-len(frontMatterDelimiter)-1], "", true
+	}
+	return rest[:end], rest[end+len(frontMatterDelimiter)+2:], true
+}
+
+// String はメタデータをフロントマターとして本文の前に記述した概要を返します。
+// メタデータが無い場合は本文だけを返します。ただし、本文がメタデータを持つ概要と区別できない場合は、
+// 空のメタデータを記述して本文がそのまま残るようにします。
+func (d TaskDescription) String() string {
+	if d.parsed && d.Body == d.originalBody && reflect.DeepEqual(d.Metadata, d.originalMetadata) {
+		return d.original
+	}
+	if d.Metadata.IsZero() && len(d.extra) == 0 && !ParseDescription(d.Body).hasMetadata() {
+		return d.Body
+	}
+
+	front := append(yaml.MapSlice{}, d.extra...)
+	front = append(front, yaml.MapItem{Key: MetadataKey, Value: d.Metadata})
+	out, err := yaml.Marshal(front)
+	if err != nil {
+		// TaskMetadataは常にYAMLに変換できます。
+		panic(err)
+	}
+	return frontMatterDelimiter + "\n" + string(out) + frontMatterDelimiter + "\n" + d.Body
+}
+
+// hasMetadata は概要がこのクライアントのメタデータを持つかどうかを返します。
+func (d TaskDescription) hasMetadata() bool {
+	return d.Body != d.original
+}
+
+// MetadataUpdate はタスクの更新時に変更するメタデータです。nilの項目は変更しません。
+type MetadataUpdate struct {
+	Tags     *[]string
+	Priority *string
+	Due      *string
+}
+
+// Apply は現在の概要にメタデータの変更を適用した概要を返します。
+// bodyが空でない場合は本文をbodyに置き換えます。メタデータは残したまま本文だけを置き換える場合にも利用します。
+func (u MetadataUpdate) Apply(current string, body string) string {
+	d := ParseDescription(current)
+	if body != "" {
+		d.Body = body
+	}
+	if u.Tags != nil {
+		d.Metadata.Tags = nil
+		if len(*u.Tags) > 0 {
+			d.Metadata.Tags = *u.Tags
+		}
+	}
+	if u.Priority != nil {
+		d.Metadata.Priority = *u.Priority
+	}
+	if u.Due != nil {
+		d.Metadata.Due = *u.Due
+	}
+	return d.String()
+}
+
+// MetadataFilter はメタデータでタスクを絞り込む条件です。空の項目では絞り込みません。
+type MetadataFilter struct {
+	Tags      []string  // すべてのタグを持つタスクに絞り込みます
+	Priority  string    // 優先度が一致するタスクに絞り込みます
+	DueBefore time.Time // 期限がこの日時以前のタスクに絞り込みます
+}
+
+// Match はタスクが条件に合うかどうかを返します。
+func (f MetadataFilter) Match(task Task) bool {
+	metadata := ParseDescription(task.Description).Metadata
+	for _, tag := range f.Tags {
+		if !metadata.HasTag(tag) {
+			return false
+		}
+	}
+	if f.Priority != "" && metadata.Priority != f.Priority {
+		return false
+	}
+	if !f.DueBefore.IsZero() {
+		due, ok := metadata.DueTime()
+		if !ok || due.After(f.DueBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterTasks は条件に合うタスクだけを返します。
+func FilterTasks(tasks []Task, filter MetadataFilter) []Task {
+	var filtered []Task
+	for _, task := range tasks {
+		if filter.Match(task) {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered
+}
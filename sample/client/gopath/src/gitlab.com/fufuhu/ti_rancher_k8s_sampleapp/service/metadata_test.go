@@ -0,0 +1,137 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseDescription ではフロントマターからメタデータを読み取り、変更しなければ元の概要に戻ることと、
+// 他のツールのフロントマターや区別できない本文をそのまま残すことを確認する。
+func TestParseDescription(t *testing.T) {
+	for _, c := range []struct {
+		description string
+		metadata    TaskMetadata
+		body        string
+	}{
+		{"plain body", TaskMetadata{}, "plain body"},
+		{"---\ntodo:\n  tags: [ops, api]\n  priority: high\n---\nbody\n", TaskMetadata{Tags: []string{"ops", "api"}, Priority: "high"}, "body\n"},
+		{"---\ntodo: {due: \"2019-04-01\"}\n---\n", TaskMetadata{Due: "2019-04-01"}, ""},
+		{"---\ntodo: {}\n---\n---\ntodo: {priority: low}\n---\n", TaskMetadata{}, "---\ntodo: {priority: low}\n---\n"},
+		// todoのキーが無い、または解釈できないフロントマターは本文として扱う
+		{"---\ntitle: post\n---\nbody", TaskMetadata{}, "---\ntitle: post\n---\nbody"},
+		{"---\ntodo: {color: red}\n---\nbody", TaskMetadata{}, "---\ntodo: {color: red}\n---\nbody"},
+		{"---\ntodo: [\n---\nbody", TaskMetadata{}, "---\ntodo: [\n---\nbody"},
+	} {
+		d := ParseDescription(c.description)
+		if d.Body != c.body || d.Metadata.Priority != c.metadata.Priority || d.Metadata.Due != c.metadata.Due || len(d.Metadata.Tags) != len(c.metadata.Tags) {
+			t.Errorf("ParseDescription(%q) = %+v, %q, want %+v, %q", c.description, d.Metadata, d.Body, c.metadata, c.body)
+		}
+		if got := d.String(); got != c.description {
+			t.Errorf("ParseDescription(%q).String() = %q", c.description, got)
+		}
+	}
+
+	// 本文がメタデータを持つ概要と区別できない場合は、空のメタデータを記述して本文を残す
+	ambiguous := "---\ntodo: {priority: low}\n---\nbody"
+	d := TaskDescription{Body: ambiguous}
+	if got := ParseDescription(d.String()); got.Body != ambiguous || !got.Metadata.IsZero() {
+		t.Errorf("String() = %q, parsed as %+v, %q", d.String(), got.Metadata, got.Body)
+	}
+
+	// 他のツールのキーは残したまま、メタデータを追加する
+	got := ParseDescription("---\ntitle: post\ntodo: {}\n---\nbody")
+	got.Metadata.Priority = PriorityHigh
+	if want := "---\ntitle: post\ntodo:\n  priority: high\n---\nbody"; got.String() != want {
+		t.Errorf("String() = %q, want %q", got.String(), want)
+	}
+}
+
+// TestMetadataUpdateApply では指定した項目だけを変更し、本文を置き換えてもメタデータが残ることを確認する。
+func TestMetadataUpdateApply(t *testing.T) {
+	tags := []string{"ops"}
+	none := []string{}
+	high, empty, due := PriorityHigh, "", "2019-04-01"
+
+	current := MetadataUpdate{Tags: &tags, Priority: &high}.Apply("", "body")
+	if want := "---\ntodo:\n  tags: [ops]\n  priority: high\n---\nbody"; current != want {
+		t.Fatalf("Apply() = %q, want %q", current, want)
+	}
+	for _, c := range []struct {
+		update MetadataUpdate
+		body   string
+		want   string
+	}{
+		{MetadataUpdate{}, "new body", "---\ntodo:\n  tags: [ops]\n  priority: high\n---\nnew body"},
+		{MetadataUpdate{Due: &due}, "", "---\ntodo:\n  tags: [ops]\n  priority: high\n  due: \"2019-04-01\"\n---\nbody"},
+		{MetadataUpdate{Tags: &none, Priority: &empty}, "", "body"},
+	} {
+		if got := c.update.Apply(current, c.body); got != c.want {
+			t.Errorf("Apply(%+v, %q) = %q, want %q", c.update, c.body, got, c.want)
+		}
+	}
+	// 何も変更しない場合は元の概要の書式を保つ
+	original := "---\ntodo: {priority: low}   # keep\n---\nbody"
+	if got := (MetadataUpdate{}).Apply(original, ""); got != original {
+		t.Errorf("Apply() = %q, want %q", got, original)
+	}
+}
+
+// TestTaskMetadataValidate ではタグ、優先度、期限の値の確認と、期限の解釈を確認する。
+func TestTaskMetadataValidate(t *testing.T) {
+	for _, c := range []struct {
+		metadata TaskMetadata
+		id       MessageID
+	}{
+		{TaskMetadata{Tags: []string{"ops", "api"}, Priority: PriorityLow, Due: "2019-04-01T09:00:00+09:00"}, ""},
+		{TaskMetadata{Tags: []string{"a b"}}, MetadataTagInvalid},
+		{TaskMetadata{Tags: []string{""}}, MetadataTagInvalid},
+		{TaskMetadata{Priority: "urgent"}, MetadataPriorityInvalid},
+		{TaskMetadata{Due: "tomorrow"}, MetadataDueInvalid},
+	} {
+		err := c.metadata.Validate()
+		if c.id == "" && err != nil || c.id != "" && (!IsMessage(err, c.id) || KindOf(err) != ErrorUsage) {
+			t.Errorf("Validate(%+v) = %v, want %q", c.metadata, err, c.id)
+		}
+	}
+
+	// 日付だけの期限はその日の終わりとする
+	due, err := ParseDue("2019-04-01")
+	if want := time.Date(2019, 4, 2, 0, 0, 0, 0, time.Local); err != nil || !due.Equal(want) {
+		t.Errorf("ParseDue() = %v, %v, want %v", due, err, want)
+	}
+}
+
+// TestFilterTasks ではタグ、優先度、期限でタスクを絞り込むことを確認する。
+func TestFilterTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Description: "---\ntodo: {tags: [ops, api], priority: high, due: \"2019-04-01\"}\n---\n"},
+		{ID: 2, Description: "---\ntodo: {tags: [ops], priority: low, due: \"2019-05-01\"}\n---\n"},
+		{ID: 3, Description: "no metadata"},
+	}
+	before, _ := ParseDue("2019-04-15")
+	for _, c := range []struct {
+		filter MetadataFilter
+		want   []int
+	}{
+		{MetadataFilter{}, []int{1, 2, 3}},
+		{MetadataFilter{Tags: []string{"ops"}}, []int{1, 2}},
+		{MetadataFilter{Tags: []string{"ops", "api"}}, []int{1}},
+		{MetadataFilter{Priority: PriorityLow}, []int{2}},
+		{MetadataFilter{DueBefore: before}, []int{1}},
+	} {
+		var got []int
+		for _, task := range FilterTasks(tasks, c.filter) {
+			got = append(got, task.ID)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("FilterTasks(%+v) = %v, want %v", c.filter, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("FilterTasks(%+v) = %v, want %v", c.filter, got, c.want)
+				break
+			}
+		}
+	}
+}
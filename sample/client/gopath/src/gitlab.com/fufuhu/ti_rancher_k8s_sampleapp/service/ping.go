@@ -3,56 +3,91 @@ package service
 import (
 	"encoding/json"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type PongMessage struct {
-	Message string `json:message`
+	Message string `json:"message"`
 }
 
+// PingReturnedStatusCodeUnexpected はping-pong APIの呼び出しで
+// 200 OK以外のステータスコードが返ってきた場合のエラーメッセージです。
+const PingReturnedStatusCodeUnexpected MessageID = "ping.status_unexpected"
+
 /*
 	RequestPingはToDoサーバに対してのPingRequestを行い、PongMessageを返します。
 	protocol: プロトコル(http/https)を指定
 	host:
 */
-func RequestPing(protocol string, host string, port int) PongMessage {
+func RequestPing(protocol string, host string, port int) (PongMessage, error) {
+	result, err := Ping(protocol, host, port)
+	return result.Pong, err
+}
+
+// PingResult はping-pong APIの呼び出し結果です。
+type PingResult struct {
+	Pong       PongMessage   // ToDoサーバが返したメッセージ
+	RTT        time.Duration // リクエストの送信からレスポンスの受信までの時間
+	ServerTime time.Time     // レスポンスのDateヘッダが示すToDoサーバの時刻(ヘッダが無い場合はゼロ値)
+	Sent       time.Time     // リクエストを送信した時刻
+}
+
+// ClockSkew はToDoサーバの時刻と手元の時刻の差を返します。
+// 通信にかかった時間の半分をレスポンスの生成までの時間とみなして補正します。
+// Dateヘッダの精度は1秒のため、1秒未満の差には意味がありません。
+func (r PingResult) ClockSkew() time.Duration {
+	if r.ServerTime.IsZero() {
+		return 0
+	}
+	return r.ServerTime.Sub(r.Sent.Add(r.RTT / 2))
+}
 
-	var pong PongMessage
+// Ping はToDoサーバのping-pong APIを呼び出し、応答までの時間やToDoサーバの時刻とともに返します。
+func Ping(protocol string, host string, port int) (PingResult, error) {
+	var result PingResult
 
 	path := "/api/ping"
 	url := protocol + "://" + host + ":" + strconv.Itoa(port) + path
 
-	client := &http.Client{}
-
 	req, err := http.NewRequest("GET", url, nil)
 
 	if err != nil {
-		log.Fatal(err)
+		return result, err
 	}
 
-	res, err := client.Do(req)
+	result.Sent = time.Now()
+	res, err := send(req)
+	result.RTT = time.Since(result.Sent)
 
 	if err != nil {
-		log.Fatal(err)
+		Log.Debug(T(RequestSendFailure), "url", url, "error", err)
+		return result, err
 	}
 
 	defer res.Body.Close()
 
+	if date, err := http.ParseTime(res.Header.Get("Date")); err == nil {
+		result.ServerTime = date
+	}
+
 	if res.StatusCode != http.StatusOK {
-		log.Fatal(res)
+		Log.Debug(T(PingReturnedStatusCodeUnexpected), "status", res.Status)
+		return result, newStatusError(PingReturnedStatusCodeUnexpected, res.StatusCode)
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
 
 	if err != nil {
-		log.Fatal(res)
+		Log.Debug(T(ResponseBodyReadFailure), "error", err)
+		return result, NewError(ErrorServer, ResponseBodyReadFailure)
 	}
 
-	if err := json.Unmarshal(body, &pong); err != nil {
-		log.Fatal(err)
+	if err := json.Unmarshal(body, &result.Pong); err != nil {
+		Log.Debug(T(ResponseBodyParseFailure), "error", err)
+		return result, NewError(ErrorServer, ResponseBodyParseFailure)
 	}
 
-	return pong
+	return result, nil
 }
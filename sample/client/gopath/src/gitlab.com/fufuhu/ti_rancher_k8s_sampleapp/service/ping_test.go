@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"testing"
 )
@@ -15,7 +16,10 @@ func TestRequestPing(t *testing.T) {
 
 	fmt.Println(testTarget)
 
-	pongMessage := RequestPing("http", testTarget, 8000)
+	pongMessage, err := RequestPing("http", testTarget, 8000)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	if pongMessage.Message != "pong" {
 		t.Fail()
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 通知の種類です。
+const (
+	ReminderDueSoon = "due_soon" // 期限が近づいている
+	ReminderOverdue = "overdue"  // 期限を過ぎている
+)
+
+// TaskStatusFinished は完了したタスクのステータスです。完了したタスクは通知しません。
+const TaskStatusFinished = "FINISHED"
+
+// ReminderMessageDueSoon は期限が近づいているタスクの通知の文面です。
+// コンテキスト、タスクのID、名前、期限を埋め込みます。
+const ReminderMessageDueSoon MessageID = "remind.due_soon"
+
+// ReminderMessageOverdue は期限を過ぎたタスクの通知の文面です。
+// コンテキスト、タスクのID、名前、期限を埋め込みます。
+const ReminderMessageOverdue MessageID = "remind.overdue"
+
+// Reminder はタスクひとつについての通知です。
+type Reminder struct {
+	Context  string    `json:"context"`            // タスクを取得したコンテキストの名前
+	Kind     string    `json:"kind"`               // 通知の種類(due_soon/overdue)
+	TaskID   int       `json:"task_id"`            // タスクのID
+	Title    string    `json:"title"`              // タスクの名前
+	Status   string    `json:"status"`             // タスクのステータス
+	Priority string    `json:"priority,omitempty"` // タスクの優先度
+	Tags     []string  `json:"tags,omitempty"`     // タスクのタグ
+	Due      string    `json:"due"`                // 概要に記述された期限
+	DueTime  time.Time `json:"due_time"`           // 期限を日時として解釈したもの
+}
+
+// Message は通知の文面を返します。
+func (r Reminder) Message() string {
+	id := ReminderMessageDueSoon
+	if r.Kind == ReminderOverdue {
+		id = ReminderMessageOverdue
+	}
+	return T(id, r.Context, r.TaskID, r.Title, r.Due)
+}
+
+// key は通知を送信済みかどうかを記録するためのキーです。
+// 期限を変更した場合は改めて通知するよう、期限を含めます。
+func (r Reminder) key() string {
+	return strconv.Itoa(r.TaskID) + "/" + r.Kind + "/" + r.Due
+}
+
+// FindReminders は完了していないタスクのうち、期限を過ぎたタスクと期限までlead以内のタスクの通知を期限の順に返します。
+// 期限を持たないタスクや、期限を解釈できないタスクは通知しません。
+func FindReminders(context string, tasks []Task, now time.Time, lead time.Duration) []Reminder {
+	var reminders []Reminder
+	for _, task := range tasks {
+		if task.Status == TaskStatusFinished {
+			continue
+		}
+		metadata := ParseDescription(task.Description).Metadata
+		due, ok := metadata.DueTime()
+		if !ok {
+			continue
+		}
+
+		var kind string
+		switch {
+		case !now.Before(due):
+			kind = ReminderOverdue
+		case due.Sub(now) <= lead:
+			kind = ReminderDueSoon
+		default:
+			continue
+		}
+		reminders = append(reminders, Reminder{
+			Context:  context,
+			Kind:     kind,
+			TaskID:   task.ID,
+			Title:    task.Title,
+			Status:   task.Status,
+			Priority: metadata.Priority,
+			Tags:     metadata.Tags,
+			Due:      metadata.Due,
+			DueTime:  due,
+		})
+	}
+	sort.SliceStable(reminders, func(i, j int) bool {
+		return reminders[i].DueTime.Before(reminders[j].DueTime)
+	})
+	return reminders
+}
+
+// ReminderLog は送信済みの通知を、デーモンの再起動をまたいで同じ通知を繰り返さないようファイルに記録します。
+// 通知先ごとに記録するため、送信に失敗した通知先にだけ次の確認で送信しなおします。
+type ReminderLog struct {
+	Path string `json:"-"`
+	// Contexts はコンテキストごとの、通知のキーと通知先の組み合わせと送信した日時です。
+	Contexts map[string]map[string]time.Time `json:"contexts"`
+}
+
+// LoadReminderLog は送信済みの通知の記録を読み込みます。ファイルが無い場合は空の記録を返します。
+func LoadReminderLog(path string) (*ReminderLog, error) {
+	log := &ReminderLog{Path: path, Contexts: map[string]map[string]time.Time{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return log, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, log); err != nil {
+		return nil, err
+	}
+	if log.Contexts == nil {
+		log.Contexts = map[string]map[string]time.Time{}
+	}
+	return log, nil
+}
+
+// Sent は通知を通知先に送信済みかどうかを返します。
+func (l *ReminderLog) Sent(r Reminder, sink string) bool {
+	_, ok := l.Contexts[r.Context][r.key()+"/"+sink]
+	return ok
+}
+
+// Record は通知を通知先に送信したことを記録します。
+func (l *ReminderLog) Record(r Reminder, sink string, at time.Time) {
+	if l.Contexts[r.Context] == nil {
+		l.Contexts[r.Context] = map[string]time.Time{}
+	}
+	l.Contexts[r.Context][r.key()+"/"+sink] = at
+}
+
+// Prune はコンテキストの記録から、現在の通知に含まれないもの(完了、削除、期限を変更したタスクなど)を取り除きます。
+// タスクを取得できたコンテキストについてだけ呼び出します。
+func (l *ReminderLog) Prune(context string, current []Reminder) {
+	keys := map[string]bool{}
+	for _, r := range current {
+		keys[r.key()] = true
+	}
+	for key := range l.Contexts[context] {
+		if !keys[key[:strings.LastIndex(key, "/")]] {
+			delete(l.Contexts[context], key)
+		}
+	}
+	if len(l.Contexts[context]) == 0 {
+		delete(l.Contexts, context)
+	}
+}
+
+// Save は記録をファイルに保存します。書き込みの途中で終了しても記録が壊れないよう、一時ファイルを置き換えます。
+func (l *ReminderLog) Save() error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0700); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(filepath.Dir(l.Path), "."+filepath.Base(l.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), l.Path)
+}
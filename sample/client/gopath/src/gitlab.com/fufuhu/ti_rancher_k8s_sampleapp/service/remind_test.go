@@ -0,0 +1,93 @@
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestFindReminders では完了していないタスクのうち、期限を過ぎたタスクと期限が近いタスクだけを期限の順に通知することを確認する。
+func TestFindReminders(t *testing.T) {
+	now := time.Date(2019, 4, 1, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: 1, Title: "later", Status: "TODO", Description: "---\ntodo: {due: \"2019-04-01T15:00:00Z\"}\n---\n"},
+		{ID: 2, Title: "overdue", Status: "RUNNING", Description: "---\ntodo: {due: \"2019-03-31T00:00:00Z\", priority: high}\n---\n"},
+		{ID: 3, Title: "far", Status: "TODO", Description: "---\ntodo: {due: \"2019-05-01T00:00:00Z\"}\n---\n"},
+		{ID: 4, Title: "finished", Status: TaskStatusFinished, Description: "---\ntodo: {due: \"2019-03-31T00:00:00Z\"}\n---\n"},
+		{ID: 5, Title: "no due", Status: "TODO", Description: "body"},
+		{ID: 6, Title: "now", Status: "TODO", Description: "---\ntodo: {due: \"2019-04-01T09:00:00Z\"}\n---\n"},
+	}
+
+	reminders := FindReminders("prod", tasks, now, 12*time.Hour)
+	want := []struct {
+		id   int
+		kind string
+	}{{2, ReminderOverdue}, {6, ReminderOverdue}, {1, ReminderDueSoon}}
+	if len(reminders) != len(want) {
+		t.Fatalf("FindReminders() = %+v", reminders)
+	}
+	for i, w := range want {
+		if r := reminders[i]; r.TaskID != w.id || r.Kind != w.kind || r.Context != "prod" {
+			t.Errorf("reminders[%d] = %+v, want %d %s", i, r, w.id, w.kind)
+		}
+	}
+	if reminders[0].Priority != PriorityHigh || reminders[0].Due != "2019-03-31T00:00:00Z" {
+		t.Errorf("reminders[0] = %+v", reminders[0])
+	}
+
+	// 期限の前の通知をしない場合は、期限を過ぎたタスクだけを通知する
+	if reminders := FindReminders("prod", tasks, now, 0); len(reminders) != 2 {
+		t.Errorf("FindReminders() without lead = %+v", reminders)
+	}
+}
+
+// TestReminderLog では通知先ごとに送信済みの通知を記録して保存し、現在の通知に無い記録を取り除くことを確認する。
+func TestReminderLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "remind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state", "remind.json")
+
+	log, err := LoadReminderLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2019, 4, 1, 9, 0, 0, 0, time.UTC)
+	overdue := Reminder{Context: "prod", Kind: ReminderOverdue, TaskID: 1, Due: "2019-03-31"}
+	soon := Reminder{Context: "prod", Kind: ReminderDueSoon, TaskID: 2, Due: "2019-04-02"}
+	staging := Reminder{Context: "staging", Kind: ReminderOverdue, TaskID: 1, Due: "2019-03-31"}
+	log.Record(overdue, "stdout", now)
+	log.Record(overdue, "webhook", now)
+	log.Record(soon, "stdout", now)
+	log.Record(staging, "stdout", now)
+	if err := log.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err = LoadReminderLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !log.Sent(overdue, "webhook") || !log.Sent(soon, "stdout") || log.Sent(soon, "webhook") {
+		t.Errorf("log = %+v", log.Contexts)
+	}
+	// 期限を変更したタスクは送信済みとしない
+	changed := overdue
+	changed.Due = "2019-04-03"
+	if log.Sent(changed, "stdout") {
+		t.Error("reminder with a changed due date must not be treated as sent")
+	}
+
+	log.Prune("prod", []Reminder{soon})
+	if log.Sent(overdue, "stdout") || log.Sent(overdue, "webhook") || !log.Sent(soon, "stdout") || !log.Sent(staging, "stdout") {
+		t.Errorf("log after Prune() = %+v", log.Contexts)
+	}
+	log.Prune("staging", nil)
+	if _, ok := log.Contexts["staging"]; ok {
+		t.Errorf("log after Prune() = %+v", log.Contexts)
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy は一時的な障害でリクエストが失敗した場合の再試行の方針を表します。
+// 再試行の間隔は指数的に伸ばし、複数のクライアントが同時に再試行しないように揺らぎを加えます。
+type RetryPolicy struct {
+	MaxRetries int           // 再試行の最大回数(0の場合は再試行しない)
+	BaseDelay  time.Duration // 初回の再試行までの待ち時間
+	MaxDelay   time.Duration // 再試行までの待ち時間の上限
+	RetryPost  bool          // POSTリクエストも再試行の対象とするかどうか
+}
+
+// DefaultRetryPolicy は既定の再試行の方針です。
+// Rancherのノードのドレインなどでpodが再起動する数秒間を乗り切ることを想定しています。
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  200 * time.Millisecond,
+	MaxDelay:   5 * time.Second,
+}
+
+// Retry はToDoサーバへのリクエストに適用する再試行の方針です。
+var Retry = DefaultRetryPolicy
+
+// retryableStatusCodes はIngressやpodの再起動中に返される、再試行してよいステータスコードです。
+var retryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+var (
+	jitterMutex sync.Mutex
+	jitter      = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// attemptCounterKey はリクエストを送信した回数を数えるカウンタを、リクエストのコンテキストに格納するキーです。
+type attemptCounterKey struct{}
+
+// withAttemptCounter は再試行や他のエンドポイントへの切り替えを含めて、リクエストを送信した回数を数えるカウンタを付けます。
+// 再送したリクエストへのレスポンスかどうかで振る舞いを変える場合に利用します。
+func withAttemptCounter(req *http.Request) (*http.Request, *int) {
+	attempts := new(int)
+	return req.WithContext(context.WithValue(req.Context(), attemptCounterKey{}, attempts)), attempts
+}
+
+// countAttempt はリクエストにカウンタが付いている場合に、送信した回数を数えます。
+func countAttempt(req *http.Request) {
+	if attempts, ok := req.Context().Value(attemptCounterKey{}).(*int); ok {
+		*attempts++
+	}
+}
+
+// Retryable はメソッドに応じてリクエストを再試行してよいかを返します。
+// GETは冪等であるため、DELETEは同じタスクを繰り返し削除しても404 Not Foundが返るだけで
+// 他のタスクに影響しないため、既定で再試行します。
+// 最初のDELETEで削除できたもののレスポンスを受け取れなかった場合、再試行は404 Not Foundになるため、
+// DeleteTaskは再送したDELETEの404 Not Foundを削除の成功として扱います。
+// POSTは重複してタスクが作成されるおそれがあるため、RetryPostを指定した場合のみ再試行します。
+func (p RetryPolicy) Retryable(method string) bool {
+	switch method {
+	case "GET", "HEAD", "DELETE":
+		return true
+	case "POST":
+		return p.RetryPost
+	}
+	return false
+}
+
+// Backoff はattempt回目(0始まり)の再試行までの待ち時間を返します。
+// 待ち時間はBaseDelay×2^attemptをMaxDelayで頭打ちにした値の半分から全体の間でばらつきます。
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < attempt && i < 30; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	jitterMutex.Lock()
+	defer jitterMutex.Unlock()
+	return delay/2 + time.Duration(jitter.Int63n(int64(delay/2)+1))
+}
+
+// retryAfter はRetry-Afterヘッダで指定された待ち時間を返します。
+// 秒数とHTTP日付の両方の形式に対応します。
+func retryAfter(res *http.Response, now time.Time) (time.Duration, bool) {
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// doWithRetry は再試行の方針に従ってリクエストを送信します。
+// 通信エラーと再試行してよいステータスコードの場合に限り、待ち時間を置いて再送します。
+// Retry-Afterヘッダで指定された待ち時間がMaxDelayを超える場合は再試行せずにそのレスポンスを返します。
+func doWithRetry(client *http.Client, req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	retryable := policy.Retryable(req.Method)
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		countAttempt(req)
+		res, err := client.Do(req)
+		if !retryable || attempt >= policy.MaxRetries {
+			return res, err
+		}
+
+		wait := policy.Backoff(attempt)
+		if err == nil {
+			if !retryableStatusCodes[res.StatusCode] {
+				return res, nil
+			}
+			if after, ok := retryAfter(res, time.Now()); ok {
+				if policy.MaxDelay > 0 && after > policy.MaxDelay {
+					return res, nil
+				}
+				if after > wait {
+					wait = after
+				}
+			}
+			// 接続を再利用できるようにボディを読み捨ててから閉じます。
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyServer は最初のfailures回のリクエストに対してstatusを返し、
+// 以降はToDoサーバと同じように振る舞うテスト用のサーバです。
+type flakyServer struct {
+	*fakeTodoServer
+
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+// newFlakyServer はfailures回だけstatusを返すサーバを作成します。
+// statusに0を指定した場合は接続を切断して通信エラーを模擬します。
+func newFlakyServer(failures int, status int, header http.Header) *flakyServer {
+	s := &flakyServer{fakeTodoServer: newFakeTodoServer(), attempts: map[string]int{}}
+	s.beforeHandle = func(w http.ResponseWriter, r *http.Request, n int) bool {
+		s.mu.Lock()
+		s.attempts[r.Method]++
+		attempt := s.attempts[r.Method]
+		s.mu.Unlock()
+
+		if attempt > failures {
+			return false
+		}
+		if status == 0 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return true
+		}
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(status)
+		return true
+	}
+	return s
+}
+
+func (s *flakyServer) attemptsOf(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[method]
+}
+
+// withRetry は再試行の方針を差し替えてfを実行します。
+func withRetry(policy RetryPolicy, f func()) {
+	original := Retry
+	Retry = policy
+	defer func() {
+		Retry = original
+	}()
+	f()
+}
+
+var fastRetry = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+// TestRetryOnServiceUnavailable ではGETリクエストが503 Service Unavailableの間、再試行されることを確認する。
+func TestRetryOnServiceUnavailable(t *testing.T) {
+	server := newFlakyServer(2, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "D", Status: "TODO"})
+
+	protocol, host, port := server.target()
+	var tasks []Task
+	var err error
+	withRetry(fastRetry, func() {
+		tasks, err = GetTasks(protocol, host, port, "token")
+	})
+
+	if err != nil || len(tasks) != 1 {
+		t.Fail()
+	}
+	if server.attemptsOf("GET") != 3 {
+		t.Fatalf("attempts: %d", server.attemptsOf("GET"))
+	}
+}
+
+// TestRetryOnConnectionError では通信エラーの場合もDELETEリクエストが再試行されることを確認する。
+func TestRetryOnConnectionError(t *testing.T) {
+	server := newFlakyServer(1, 0, nil)
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "D", Status: "TODO"})
+
+	protocol, host, port := server.target()
+	var err error
+	withRetry(fastRetry, func() {
+		_, err = DeleteTask(protocol, host, port, "token", 1)
+	})
+
+	if err != nil {
+		t.Fail()
+	}
+	if server.attemptsOf("DELETE") != 2 {
+		t.Fatalf("attempts: %d", server.attemptsOf("DELETE"))
+	}
+}
+
+// TestRetryGivesUp では最大回数まで再試行しても回復しない場合に失敗することを確認する。
+func TestRetryGivesUp(t *testing.T) {
+	server := newFlakyServer(100, http.StatusBadGateway, nil)
+	defer server.Close()
+
+	protocol, host, port := server.target()
+	var err error
+	withRetry(fastRetry, func() {
+		_, err = GetTasks(protocol, host, port, "token")
+	})
+
+	if err == nil || !IsMessage(err, TaskGetReturnedStatusCodeUnexpected) {
+		t.Fail()
+	}
+	if server.attemptsOf("GET") != fastRetry.MaxRetries+1 {
+		t.Fatalf("attempts: %d", server.attemptsOf("GET"))
+	}
+}
+
+// TestRetryDoesNotRepeatPostByDefault では既定ではPOSTリクエストを再試行せず、
+// RetryPostを指定した場合に再試行することを確認する。
+func TestRetryDoesNotRepeatPostByDefault(t *testing.T) {
+	server := newFlakyServer(1, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+
+	protocol, host, port := server.target()
+	var err error
+	withRetry(fastRetry, func() {
+		_, err = CreateTask(protocol, host, port, "token", "TODO", "D")
+	})
+	if err == nil || server.attemptsOf("POST") != 1 {
+		t.Fail()
+	}
+
+	server.attempts = map[string]int{}
+	policy := fastRetry
+	policy.RetryPost = true
+	withRetry(policy, func() {
+		_, err = CreateTask(protocol, host, port, "token", "TODO", "D")
+	})
+	if err != nil || server.attemptsOf("POST") != 2 {
+		t.Fail()
+	}
+	// 再送時にもボディが送られていること
+	if task, ok := server.get(1); !ok || task.Title != "TODO" {
+		t.Fail()
+	}
+}
+
+// TestRetryRespectsRetryAfter ではRetry-Afterヘッダで指定された時間だけ待ってから再試行することを確認する。
+func TestRetryRespectsRetryAfter(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"1"}}
+	server := newFlakyServer(1, http.StatusServiceUnavailable, header)
+	defer server.Close()
+
+	protocol, host, port := server.target()
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Second}
+	start := time.Now()
+	var err error
+	withRetry(policy, func() {
+		_, err = GetTasks(protocol, host, port, "token")
+	})
+
+	if err != nil {
+		t.Fail()
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("elapsed: %s", elapsed)
+	}
+}
+
+// TestRetryGivesUpOnLongRetryAfter ではRetry-AfterがMaxDelayを超える場合は再試行しないことを確認する。
+func TestRetryGivesUpOnLongRetryAfter(t *testing.T) {
+	header := http.Header{"Retry-After": []string{strconv.Itoa(3600)}}
+	server := newFlakyServer(1, http.StatusServiceUnavailable, header)
+	defer server.Close()
+
+	protocol, host, port := server.target()
+	var err error
+	withRetry(fastRetry, func() {
+		_, err = GetTasks(protocol, host, port, "token")
+	})
+
+	if err == nil || server.attemptsOf("GET") != 1 {
+		t.Fail()
+	}
+}
+
+// TestBackoff では待ち時間が指数的に伸び、上限で頭打ちになることを確認する。
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		wait := policy.Backoff(attempt)
+		if wait < max/2 || wait > max {
+			t.Errorf("attempt %d: %s", attempt, wait)
+		}
+	}
+}
+
+// TestRetryAfterWithHTTPDate ではHTTP日付形式のRetry-Afterを解釈できることを確認する。
+func TestRetryAfterWithHTTPDate(t *testing.T) {
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	res := httptest.NewRecorder()
+	res.Header().Set("Retry-After", now.Add(30*time.Second).Format(http.TimeFormat))
+
+	wait, ok := retryAfter(res.Result(), now)
+	if !ok || wait != 30*time.Second {
+		t.Fail()
+	}
+}
+
+// TestRetryDeleteAppliedButResponseLost では最初のDELETEでタスクを削除できたもののレスポンスを受け取れず、
+// 再試行が404 Not Foundになった場合に、削除の成功として扱うことを確認する。
+func TestRetryDeleteAppliedButResponseLost(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "D", Status: "TODO"})
+	deletes := 0
+	server.beforeHandle = func(w http.ResponseWriter, r *http.Request, n int) bool {
+		if r.Method != "DELETE" {
+			return false
+		}
+		deletes++
+		if deletes > 1 {
+			return false
+		}
+		// 削除を反映してから接続を切断する
+		server.mu.Lock()
+		delete(server.tasks, 1)
+		server.mu.Unlock()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+		return true
+	}
+
+	protocol, host, port := server.target()
+	var task Task
+	var err error
+	withRetry(fastRetry, func() {
+		task, err = DeleteTask(protocol, host, port, "token", 1)
+	})
+	if err != nil || task.ID != 1 {
+		t.Errorf("DeleteTask() = %+v, %v", task, err)
+	}
+	if deletes != 2 {
+		t.Errorf("attempts: %d", deletes)
+	}
+
+	// 再送していないDELETEの404 Not Foundはこれまでどおりエラーとする
+	withRetry(fastRetry, func() {
+		_, err = DeleteTask(protocol, host, port, "token", 1)
+	})
+	if !IsMessage(err, TaskDeleteReturnedNotFoundStatusCode) || KindOf(err) != ErrorNotFound {
+		t.Errorf("DeleteTask() = %v, want %s", err, TaskDeleteReturnedNotFoundStatusCode)
+	}
+}
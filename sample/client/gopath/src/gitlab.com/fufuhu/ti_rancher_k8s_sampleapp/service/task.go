@@ -1,11 +1,12 @@
 package service
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -22,49 +23,53 @@ type CreatedTask struct {
 
 // TaskCreationReturnedStatusCodeUnexpected はタスク作成のリクエストを行った際に、
 // 201 Created 以外のレスポンスコードが返ってきた場合のエラーメッセージです。
-const TaskCreationReturnedStatusCodeUnexpected = "期待したレスポンスステータスコード(201 Created)ではありません。"
+const TaskCreationReturnedStatusCodeUnexpected MessageID = "task.create.status_unexpected"
 
 // ResponseBodyReadFailure はリクエストに対する
 // レスポンスでボディの読み込みに失敗した場合のエラーメセージです。
-const ResponseBodyReadFailure = "レスポンスボディの読み込みに失敗しました"
+const ResponseBodyReadFailure MessageID = "response.body_read_failure"
 
 // ResponseBodyParseFailure はリクエストに対する
 // レスポンスでボディのパースに失敗した場合のエラーメッセージです。
-const ResponseBodyParseFailure = "レスポンスボディのパースに失敗しました"
+const ResponseBodyParseFailure MessageID = "response.body_parse_failure"
 
 // CreateTask はToDoクライアントからToDoサーバへのTask作成を行います。
 func CreateTask(protocol string, host string, port int, token string, title string, description string) (CreatedTask, error) {
 	path := "/api/task"
 	url := protocol + "://" + host + ":" + strconv.Itoa(port) + path
 
-	client := &http.Client{}
-
 	authHeader := "JWT " + token
 
-	taskInfo := fmt.Sprintf(`{
-		"title": "%s",
-		"description": "%s"
-	}`, title, description)
-
-	req, err := http.NewRequest("POST", url, strings.NewReader(taskInfo))
-	req.Header.Set("Authorization", authHeader)
+	taskInfo, err := json.Marshal(map[string]string{
+		"title":       title,
+		"description": description,
+	})
+	if err != nil {
+		return CreatedTask{}, err
+	}
 
+	req, err := http.NewRequest("POST", url, bytes.NewReader(taskInfo))
 	if err != nil {
-		log.Fatal(err)
+		return CreatedTask{}, err
 	}
+	req.Header.Set("Authorization", authHeader)
+	req.Header.Set("Content-Type", "application/json")
 
 	// Task作成のリクエストを発行します。
-	res, err := client.Do(req)
+	res, err := send(req)
 
+	if err == ErrDryRun {
+		return CreatedTask{Title: title, Description: description}, err
+	}
 	if err != nil {
-		log.Fatal(err)
+		Log.Debug(T(RequestSendFailure), "url", url, "error", err)
+		return CreatedTask{}, err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusCreated {
-		log.Println(res)
-		log.Println(TaskCreationReturnedStatusCodeUnexpected)
-		err := errors.New(TaskCreationReturnedStatusCodeUnexpected)
+		Log.Debug(T(TaskCreationReturnedStatusCodeUnexpected), "status", res.Status)
+		err := newStatusError(TaskCreationReturnedStatusCodeUnexpected, res.StatusCode)
 
 		return CreatedTask{}, err
 	}
@@ -72,22 +77,19 @@ func CreateTask(protocol string, host string, port int, token string, title stri
 	body, err := ioutil.ReadAll(res.Body)
 
 	if err != nil {
-		log.Println(err)
-		log.Println(ResponseBodyReadFailure)
-		err := errors.New(ResponseBodyReadFailure)
+		Log.Debug(T(ResponseBodyReadFailure), "error", err)
+		err := NewError(ErrorServer, ResponseBodyReadFailure)
 		return CreatedTask{}, err
 	}
 
 	var task CreatedTask
 	if err := json.Unmarshal(body, &task); err != nil {
-		log.Println(err)
-		log.Println(ResponseBodyParseFailure)
-		err := errors.New(ResponseBodyParseFailure)
+		Log.Debug(T(ResponseBodyParseFailure), "error", err)
+		err := NewError(ErrorServer, ResponseBodyParseFailure)
 		return CreatedTask{}, err
 	}
 
-	log.Printf("Task created. TaskID: %d\n", task.ID)
-	log.Printf("Task Title: %s\n", task.Title)
+	Log.Debug(T(TaskCreated), "id", task.ID, "title", task.Title)
 	return task, err
 }
 
@@ -102,57 +104,56 @@ type Task struct {
 
 // TaskGetReturnedStatusCodeUnexpected はタスク取得リクエスト実行時に、
 // ステータスコードとして200 OK以外が返ってきた場合に表示するメッセージです。
-const TaskGetReturnedStatusCodeUnexpected = "期待したレスポンスステータスコード(200 OK)ではありません。"
+const TaskGetReturnedStatusCodeUnexpected MessageID = "task.get.status_unexpected"
 
 // TaskGetReturnedNotFoundStatusCode はタスク取得リクエスト実行時に、
 // ステータスコードとして404 Not Foundが返ってきた場合に表示するメッセージです。
-const TaskGetReturnedNotFoundStatusCode = "指定したタスクのIDに対応するタスクが見つかりません。"
+const TaskGetReturnedNotFoundStatusCode MessageID = "task.get.not_found"
 
 // GetTask は指定したtask_idの値に対応したTaskを返します。
 func GetTask(protocol string, host string, port int, token string, taskID int) ([]Task, error) {
 	path := "/api/task/" + strconv.Itoa(taskID)
 	url := protocol + "://" + host + ":" + strconv.Itoa(port) + path
 
-	client := &http.Client{}
-
 	authHeader := "JWT " + token
 
 	req, err := http.NewRequest("GET", url, strings.NewReader(""))
+	if err != nil {
+		return []Task{Task{}}, err
+	}
 	req.Header.Set("Authorization", authHeader)
 
-	// Task作成のリクエストを発行します。
-	res, err := client.Do(req)
+	// Task取得のリクエストを発行します。
+	res, err := send(req)
 
 	if err != nil {
-		log.Fatal(err)
+		Log.Debug(T(RequestSendFailure), "url", url, "error", err)
+		return []Task{Task{}}, err
 	}
+	defer res.Body.Close()
 
 	// レスポンスのステータスコードが200 OK以外だったときの処理
 	if res.StatusCode != http.StatusOK {
-		log.Println(TaskGetReturnedStatusCodeUnexpected)
+		Log.Debug(T(TaskGetReturnedStatusCodeUnexpected), "status", res.Status)
 		if res.StatusCode == http.StatusNotFound {
-			log.Println(TaskGetReturnedNotFoundStatusCode)
-			err := errors.New(TaskGetReturnedNotFoundStatusCode)
+			err := newStatusError(TaskGetReturnedNotFoundStatusCode, res.StatusCode)
 			return []Task{Task{}}, err
 		}
-		err := errors.New(TaskGetReturnedStatusCodeUnexpected)
+		err := newStatusError(TaskGetReturnedStatusCodeUnexpected, res.StatusCode)
 		return []Task{Task{}}, err
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
 
 	if err != nil {
-		log.Println(err)
-		log.Println(ResponseBodyReadFailure)
+		Log.Debug(T(ResponseBodyReadFailure), "error", err)
 		return []Task{Task{}}, err
 	}
 
 	var tasks []Task
 	if err := json.Unmarshal(body, &tasks); err != nil {
-		log.Printf("%s\n", body)
-		log.Println(err)
-		log.Println(ResponseBodyParseFailure)
-		err := errors.New(ResponseBodyParseFailure)
+		Log.Debug(T(ResponseBodyParseFailure), "error", err, "body", string(body))
+		err := NewError(ErrorServer, ResponseBodyParseFailure)
 		return []Task{Task{}}, err
 	}
 
@@ -164,103 +165,120 @@ func GetTasks(protocol string, host string, port int, token string) ([]Task, err
 	path := "/api/task"
 	url := protocol + "://" + host + ":" + strconv.Itoa(port) + path
 
-	client := &http.Client{}
-
 	authHeader := "JWT " + token
 
 	req, err := http.NewRequest("GET", url, strings.NewReader(""))
+	if err != nil {
+		return []Task{Task{}}, err
+	}
 	req.Header.Set("Authorization", authHeader)
 
 	// Task取得のリクエストを発行します。
-	res, err := client.Do(req)
+	res, err := send(req)
 
 	if err != nil {
-		log.Fatal(err)
+		Log.Debug(T(RequestSendFailure), "url", url, "error", err)
+		return []Task{Task{}}, err
 	}
+	defer res.Body.Close()
 
 	// レスポンスのステータスコードが200 OK以外だったときの処理
 	if res.StatusCode != http.StatusOK {
-		log.Println(TaskGetReturnedStatusCodeUnexpected)
-		err := errors.New(TaskGetReturnedStatusCodeUnexpected)
+		Log.Debug(T(TaskGetReturnedStatusCodeUnexpected), "status", res.Status)
+		err := newStatusError(TaskGetReturnedStatusCodeUnexpected, res.StatusCode)
 		return []Task{Task{}}, err
 	}
 
 	// レスポンスのステータスコードが200 OK以外だったときの処理
 	if res.StatusCode != http.StatusOK {
-		log.Println(TaskGetReturnedStatusCodeUnexpected)
+		Log.Debug(T(TaskGetReturnedStatusCodeUnexpected), "status", res.Status)
 		if res.StatusCode == http.StatusNotFound {
-			err := errors.New(TaskGetReturnedNotFoundStatusCode)
+			err := newStatusError(TaskGetReturnedNotFoundStatusCode, res.StatusCode)
 			return []Task{Task{}}, err
 		}
-		err := errors.New(TaskGetReturnedStatusCodeUnexpected)
+		err := newStatusError(TaskGetReturnedStatusCodeUnexpected, res.StatusCode)
 		return []Task{Task{}}, err
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
 
 	if err != nil {
-		log.Println(err)
-		log.Println(ResponseBodyReadFailure)
+		Log.Debug(T(ResponseBodyReadFailure), "error", err)
 		return []Task{Task{}}, err
 	}
 
 	var tasks []Task
 	if err := json.Unmarshal(body, &tasks); err != nil {
-		log.Printf("%s\n", body)
-		log.Println(err)
-		log.Println(ResponseBodyParseFailure)
-		err := errors.New(ResponseBodyParseFailure)
+		Log.Debug(T(ResponseBodyParseFailure), "error", err, "body", string(body))
+		err := NewError(ErrorServer, ResponseBodyParseFailure)
 		return []Task{Task{}}, err
 	}
 
 	return tasks, err
 }
 
-const TaskDeleteReturnedStatusCodeUnexpected = "指定されたIDに対応するTaskを削除しようとしましたが、想定外のステータスコードが返されました。"
+const TaskDeleteReturnedStatusCodeUnexpected MessageID = "task.delete.status_unexpected"
 
-const TaskDeleteReturnedNotFoundStatusCode = "削除の為に指定したIDに対応するTaskが見つかりませんでした。"
+const TaskDeleteReturnedNotFoundStatusCode MessageID = "task.delete.not_found"
+
+// TaskDeleteRetriedNotFound は再送したDELETEリクエストが404 Not Foundを返したため、
+// 以前に送信したリクエストで削除できたとみなす場合のメッセージです。
+const TaskDeleteRetriedNotFound MessageID = "task.delete.retried_not_found"
 
 // DeleteTask は指定されたIDをもつタスクの削除を試みます
 func DeleteTask(protocol string, host string, port int, token string, taskID int) (Task, error) {
 	path := "/api/task/" + strconv.Itoa(taskID)
 	url := protocol + "://" + host + ":" + strconv.Itoa(port) + path
 
-	client := &http.Client{}
-
 	authHeader := "JWT " + token
 
 	req, err := http.NewRequest("DELETE", url, strings.NewReader(""))
+	if err != nil {
+		return Task{}, err
+	}
 	req.Header.Set("Authorization", authHeader)
+	req, attempts := withAttemptCounter(req)
 
 	// Task削除のリクエストを発行します。
-	res, err := client.Do(req)
+	res, err := send(req)
+
+	if err == ErrDryRun {
+		return Task{ID: taskID}, err
+	}
+	if err != nil {
+		Log.Debug(T(RequestSendFailure), "url", url, "error", err)
+		return Task{}, err
+	}
+	defer res.Body.Close()
 
 	// レスポンスのステータスコードが200 OK以外だったときの処理
 	if res.StatusCode != http.StatusOK {
+		if res.StatusCode == http.StatusNotFound && *attempts > 1 {
+			// 以前に送信したリクエストで削除できたもののレスポンスを受け取れなかった場合です。
+			Log.Debug(T(TaskDeleteRetriedNotFound), "id", taskID, "attempts", *attempts)
+			return Task{ID: taskID}, nil
+		}
 		if res.StatusCode == http.StatusNotFound {
-			log.Println(TaskDeleteReturnedNotFoundStatusCode)
-			err := errors.New(TaskDeleteReturnedNotFoundStatusCode)
+			Log.Debug(T(TaskDeleteReturnedNotFoundStatusCode), "id", taskID)
+			err := newStatusError(TaskDeleteReturnedNotFoundStatusCode, res.StatusCode)
 			return Task{}, err
 		}
-		log.Println(TaskDeleteReturnedStatusCodeUnexpected)
-		err := errors.New(TaskDeleteReturnedStatusCodeUnexpected)
+		Log.Debug(T(TaskDeleteReturnedStatusCodeUnexpected), "status", res.Status)
+		err := newStatusError(TaskDeleteReturnedStatusCodeUnexpected, res.StatusCode)
 		return Task{}, err
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
 
 	if err != nil {
-		log.Println(err)
-		log.Println(ResponseBodyReadFailure)
+		Log.Debug(T(ResponseBodyReadFailure), "error", err)
 		return Task{}, err
 	}
 
 	var task Task
 	if err := json.Unmarshal(body, &task); err != nil {
-		log.Printf("%s\n", body)
-		log.Println(err)
-		log.Println(ResponseBodyParseFailure)
-		err := errors.New(ResponseBodyParseFailure)
+		Log.Debug(T(ResponseBodyParseFailure), "error", err, "body", string(body))
+		err := NewError(ErrorServer, ResponseBodyParseFailure)
 		return Task{}, err
 	}
 
@@ -268,111 +286,310 @@ func DeleteTask(protocol string, host string, port int, token string, taskID int
 }
 
 // TaskUpdateReturnedStatusCodeUnexpected は200,400, 404以外のステータスコードが返ってきたときに指定
-const TaskUpdateReturnedStatusCodeUnexpected = "指定されたIDに対応するTaskを更新しようとしましたが、想定外のステータスコードが返されました。"
+const TaskUpdateReturnedStatusCodeUnexpected MessageID = "task.update.status_unexpected"
 
 // TaskUpdateReturnedNotFoundStatusCode は指定したタスクが存在しない、または他のユーザに紐付いているなどの理由で
 // 404 Not Foundが返された場合に
-const TaskUpdateReturnedNotFoundStatusCode = "更新の為に指定したIDに対応するTaskが見つかりませんでした。"
+const TaskUpdateReturnedNotFoundStatusCode MessageID = "task.update.not_found"
+
+const TaskUpdateReturnedBadRequestStatusCode MessageID = "task.update.bad_request"
+
+// TaskUpdateConflict は更新対象のタスクを取得してから書き込むまでの間に、
+// 他のユーザなどによってタスクが変更されていた場合のエラーメッセージです。
+const TaskUpdateConflict MessageID = "task.update.conflict"
+
+// TaskUpdatePreconditionFailed は--if-matchや--expect-statusで指定した条件と
+// サーバ上のタスクの状態が一致しなかった場合のエラーメッセージです。
+const TaskUpdatePreconditionFailed MessageID = "task.update.precondition_failed"
+
+// タスクの操作の経過としてデバッグ用に出力するメッセージです。
+const (
+	TaskCreated        MessageID = "task.create.done"
+	TaskUpdated        MessageID = "task.update.done"
+	TaskUpdateRetrying MessageID = "task.update.retrying"
+)
+
+// ConflictPolicy は更新の競合を検知した際の振る舞いを表します。
+type ConflictPolicy string
+
+const (
+	// ConflictAbort は競合を検知した時点で更新を中止します。
+	ConflictAbort ConflictPolicy = "abort"
+	// ConflictRetry は競合相手の変更と更新したい項目が重ならない場合に限り、
+	// 最新のタスクを取得しなおして更新を再試行します。
+	ConflictRetry ConflictPolicy = "retry"
+)
+
+// UpdateOptions はタスク更新時の楽観的排他制御の条件を表します。
+type UpdateOptions struct {
+	IfMatch      string         // 更新前のタスクが持っているべきフィンガープリント(空の場合は検査しない)
+	ExpectStatus string         // 更新前のタスクが持っているべきステータス(空の場合は検査しない)
+	OnConflict   ConflictPolicy // 競合を検知した際の振る舞い
+	MaxRetries   int            // ConflictRetryの場合の最大再試行回数
+	// Metadata は概要のメタデータの変更です。nilでない場合は、概要のメタデータを残したまま本文(description)を置き換え、
+	// 再試行の際も最新のタスクの概要に変更を適用します。
+	Metadata *MetadataUpdate
+}
+
+// DefaultUpdateOptions はUpdateTaskが利用する更新時の条件です。
+var DefaultUpdateOptions = UpdateOptions{
+	OnConflict: ConflictRetry,
+	MaxRetries: 3,
+}
+
+// UpdateResult はタスク更新の結果を表します。
+type UpdateResult struct {
+	Task     Task // 更新後のタスク
+	Previous Task // 更新直前のタスク
+	Attempts int  // 書き込みを試みた回数
+}
+
+// TaskFingerprint はタスクの内容から算出したフィンガープリントを返します。
+// 取得時と書き込み直前のタスクが同一であるかの判定に利用します。
+func TaskFingerprint(task Task) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00%s\x00%s", task.ID, task.Title, task.Description, task.Status)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// changedTaskFields はbaseに対してtitle, description, statusのうち
+// 指定され、かつ値が変わる項目だけを返します。
+func changedTaskFields(base Task, title string, description string, status string) map[string]string {
+	fields := map[string]string{}
+	if title != "" && title != base.Title {
+		fields["title"] = title
+	}
+	if description != "" && description != base.Description {
+		fields["description"] = description
+	}
+	if status != "" && status != base.Status {
+		fields["status"] = status
+	}
+	return fields
+}
+
+// applyTaskFields はtaskにPATCHリクエストのボディと同じ形式の変更を適用したタスクを返します。
+func applyTaskFields(task Task, fields map[string]string) Task {
+	if value, ok := fields["title"]; ok {
+		task.Title = value
+	}
+	if value, ok := fields["description"]; ok {
+		task.Description = value
+	}
+	if value, ok := fields["status"]; ok {
+		task.Status = value
+	}
+	return task
+}
 
-const TaskUpdateReturnedBadRequestStatusCode = "更新の為に指定したステータス情報が不正です"
+// modifiedTaskFields はbeforeとafterの間で値が変わった項目名の一覧を返します。
+func modifiedTaskFields(before Task, after Task) map[string]bool {
+	fields := map[string]bool{}
+	if before.Title != after.Title {
+		fields["title"] = true
+	}
+	if before.Description != after.Description {
+		fields["description"] = true
+	}
+	if before.Status != after.Status {
+		fields["status"] = true
+	}
+	return fields
+}
 
 // UpdateTask は指定されたIDを持つタスクの情報更新を行います。
+// 空文字列を指定した項目は更新しません。
 func UpdateTask(protocol string, host string, port int, token string, taskID int, title string, description string, status string) (Task, error) {
-	path := "/api/task/" + strconv.Itoa(taskID)
-	url := protocol + "://" + host + ":" + strconv.Itoa(port) + path
+	result, err := UpdateTaskWithOptions(protocol, host, port, token, taskID, title, description, status, DefaultUpdateOptions)
+	return result.Task, err
+}
 
-	client := &http.Client{}
+// UpdateTaskWithOptions は楽観的排他制御を行いながらタスクの情報更新を行います。
+// タスクを取得して変更する項目だけを求め、書き込む直前にもう一度タスクを取得して
+// フィンガープリントを比較することで、取得後に行われた他の変更を上書きしないようにします。
+// なお、サーバ側に条件付き更新の仕組みがないため、書き込み直前の確認からPATCHまでの
+// わずかな間に行われた変更までは検知できません。
+func UpdateTaskWithOptions(protocol string, host string, port int, token string, taskID int, title string, description string, status string, options UpdateOptions) (UpdateResult, error) {
+	var result UpdateResult
 
-	task, err := GetTask(protocol, host, port, token, taskID)
+	base, err := getTaskForUpdate(protocol, host, port, token, taskID)
 	if err != nil {
-		if err.Error() == TaskGetReturnedNotFoundStatusCode {
-			log.Println(err)
-			err = errors.New(TaskUpdateReturnedNotFoundStatusCode)
-			log.Println(err)
-			return task[0], err
+		return result, err
+	}
+	result.Previous = base
+
+	if options.IfMatch != "" && options.IfMatch != TaskFingerprint(base) {
+		Log.Debug(T(TaskUpdatePreconditionFailed), "id", taskID, "fingerprint", TaskFingerprint(base))
+		return result, NewError(ErrorConflict, TaskUpdatePreconditionFailed)
+	}
+	if options.ExpectStatus != "" && options.ExpectStatus != base.Status {
+		Log.Debug(T(TaskUpdatePreconditionFailed), "id", taskID, "status", base.Status)
+		return result, NewError(ErrorConflict, TaskUpdatePreconditionFailed)
+	}
+
+	for {
+		fields := changedTaskFields(base, title, taskDescriptionFor(base, description, options), status)
+		if len(fields) == 0 {
+			// 変更すべき項目がなければサーバへの書き込みは行いません。
+			result.Task = base
+			return result, nil
 		}
-		if err.Error() == TaskGetReturnedStatusCodeUnexpected {
-			log.Println(err)
-			err = errors.New(TaskUpdateReturnedStatusCodeUnexpected)
-			log.Println(err)
-			return task[0], err
+
+		// 書き込み直前のサーバ上の状態と取得時の状態を比較します。
+		current, err := getTaskForUpdate(protocol, host, port, token, taskID)
+		if err != nil {
+			return result, err
 		}
-	}
 
-	authHeader := "JWT " + token
+		if TaskFingerprint(current) != TaskFingerprint(base) {
+			if !canRetryUpdate(base, current, fields, options, result.Attempts) {
+				Log.Debug(T(TaskUpdateConflict), "id", taskID)
+				return result, NewError(ErrorConflict, TaskUpdateConflict)
+			}
+			result.Attempts++
+			Log.Debug(T(TaskUpdateRetrying), "id", taskID, "attempts", result.Attempts)
+			base = current
+			result.Previous = base
+			continue
+		}
+
+		// サーバはPATCHのたびにステータス履歴を記録するため、statusは常に必須です。
+		// 変更しない場合は直前に確認した現在のステータスをそのまま送ります。
+		body := map[string]string{"status": current.Status}
+		for name, value := range fields {
+			body[name] = value
+		}
+
+		result.Attempts++
+		task, err := patchTask(protocol, host, port, token, taskID, body)
+		if err == ErrDryRun {
+			// ドライラン時は更新された場合のタスクを組み立てて返します。
+			result.Task = applyTaskFields(current, body)
+			return result, err
+		}
+		if err != nil {
+			return result, err
+		}
+		result.Task = task
 
-	if title == "" {
-		title = task[0].Title
+		Log.Debug(T(TaskUpdated), "id", task.ID, "attempts", result.Attempts)
+		return result, nil
 	}
+}
 
-	if description == "" {
-		description = task[0].Description
+// taskDescriptionFor は更新後の概要を返します。メタデータを変更する場合は、元のタスクの概要に変更を適用します。
+func taskDescriptionFor(base Task, description string, options UpdateOptions) string {
+	if options.Metadata == nil {
+		return description
 	}
+	return options.Metadata.Apply(base.Description, description)
+}
 
-	if status == "" {
-		status = task[0].Status
+// canRetryUpdate は競合を検知した際に、最新のタスクを元に更新を再試行してよいかを判定します。
+// 競合相手が変更した項目と、これから変更しようとしている項目が重なる場合は再試行しません。
+func canRetryUpdate(base Task, current Task, fields map[string]string, options UpdateOptions, attempts int) bool {
+	if options.OnConflict != ConflictRetry || attempts >= options.MaxRetries {
+		return false
 	}
+	// 利用者が更新前の状態を明示している場合は、その前提が崩れているため再試行しません。
+	if options.IfMatch != "" || options.ExpectStatus != "" {
+		return false
+	}
+	for name := range modifiedTaskFields(base, current) {
+		if _, ok := fields[name]; ok {
+			return false
+		}
+	}
+	return true
+}
 
-	taskInfo := fmt.Sprintf(`{
-		"title": "%s",
-		"description": "%s",
-		"status": "%s"
-	}`, title, description, status)
+// getTaskForUpdate は更新対象のタスクを取得し、エラーを更新時のエラーに読み替えます。
+func getTaskForUpdate(protocol string, host string, port int, token string, taskID int) (Task, error) {
+	tasks, err := GetTask(protocol, host, port, token, taskID)
+	if e, ok := err.(*Error); ok {
+		// 原因の種類とステータスコードは引き継ぎ、メッセージだけを読み替えます。
+		switch e.ID {
+		case TaskGetReturnedNotFoundStatusCode:
+			err = &Error{Kind: e.Kind, ID: TaskUpdateReturnedNotFoundStatusCode, StatusCode: e.StatusCode}
+		case TaskGetReturnedStatusCodeUnexpected:
+			err = &Error{Kind: e.Kind, ID: TaskUpdateReturnedStatusCodeUnexpected, StatusCode: e.StatusCode}
+		}
+	}
+	if err != nil {
+		return Task{}, err
+	}
+	if len(tasks) == 0 {
+		Log.Debug(T(TaskUpdateReturnedNotFoundStatusCode), "id", taskID)
+		return Task{}, NewError(ErrorNotFound, TaskUpdateReturnedNotFoundStatusCode)
+	}
+	return tasks[0], nil
+}
 
-	req, err := http.NewRequest("PATCH", url, strings.NewReader(taskInfo))
+// patchTask は指定された項目だけをPATCHリクエストで更新します。
+func patchTask(protocol string, host string, port int, token string, taskID int, fields map[string]string) (Task, error) {
+	path := "/api/task/" + strconv.Itoa(taskID)
+	url := protocol + "://" + host + ":" + strconv.Itoa(port) + path
+
+	authHeader := "JWT " + token
+
+	taskInfo, err := json.Marshal(fields)
+	if err != nil {
+		return Task{}, err
+	}
+
+	req, err := http.NewRequest("PATCH", url, bytes.NewReader(taskInfo))
+	if err != nil {
+		return Task{}, err
+	}
 	req.Header.Set("Authorization", authHeader)
+	req.Header.Set("Content-Type", "application/json")
 
 	// Task更新のリクエストを発行します。
-	res, err := client.Do(req)
+	res, err := send(req)
+	if err == ErrDryRun {
+		return Task{}, err
+	}
+	if err != nil {
+		Log.Debug(T(RequestSendFailure), "url", url, "error", err)
+		return Task{}, err
+	}
+	defer res.Body.Close()
 
 	// レスポンスのステータスコードが200 OK以外だったときの処理
 	if res.StatusCode != http.StatusOK {
-		// 404 Not Foundが返ってきた場合(基本的には到達不可能なコード)
+		// 404 Not Foundが返ってきた場合(取得直後に削除された場合など)
 		if res.StatusCode == http.StatusNotFound {
-			log.Println(TaskUpdateReturnedNotFoundStatusCode)
-			err := errors.New(TaskUpdateReturnedNotFoundStatusCode)
+			Log.Debug(T(TaskUpdateReturnedNotFoundStatusCode), "id", taskID)
+			err := newStatusError(TaskUpdateReturnedNotFoundStatusCode, res.StatusCode)
 			return Task{}, err
 		}
 
 		// 400 Bad Requestが返ってきた場合
 		if res.StatusCode == http.StatusBadRequest {
-			log.Println(TaskUpdateReturnedBadRequestStatusCode)
-			err := errors.New(TaskUpdateReturnedBadRequestStatusCode)
+			Log.Debug(T(TaskUpdateReturnedBadRequestStatusCode), "fields", fields)
+			err := newStatusError(TaskUpdateReturnedBadRequestStatusCode, res.StatusCode)
 			return Task{}, err
 		}
 
-		log.Println(TaskUpdateReturnedStatusCodeUnexpected)
-		err := errors.New(TaskUpdateReturnedStatusCodeUnexpected)
+		Log.Debug(T(TaskUpdateReturnedStatusCodeUnexpected), "status", res.Status)
+		err := newStatusError(TaskUpdateReturnedStatusCodeUnexpected, res.StatusCode)
 		return Task{}, err
 	}
 
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer res.Body.Close()
-
 	body, err := ioutil.ReadAll(res.Body)
 
 	if err != nil {
-		log.Println(err)
-		log.Println(ResponseBodyReadFailure)
-		err := errors.New(ResponseBodyReadFailure)
+		Log.Debug(T(ResponseBodyReadFailure), "error", err)
+		err := NewError(ErrorServer, ResponseBodyReadFailure)
 		return Task{}, err
 	}
 
 	var updatedTask Task
 	if err := json.Unmarshal(body, &updatedTask); err != nil {
-		log.Println(err)
-		log.Println(ResponseBodyParseFailure)
-		err := errors.New(ResponseBodyParseFailure)
+		Log.Debug(T(ResponseBodyParseFailure), "error", err)
+		err := NewError(ErrorServer, ResponseBodyParseFailure)
 		return Task{}, err
 	}
 
-	log.Printf("Task(ID=%d) is updated.\n", updatedTask.ID)
-
-	return updatedTask, err
+	return updatedTask, nil
 }
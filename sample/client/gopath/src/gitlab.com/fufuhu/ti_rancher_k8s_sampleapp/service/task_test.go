@@ -1,8 +1,16 @@
 package service
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -43,7 +51,7 @@ func TestCreateTaskWithWrongStatusCode(t *testing.T) {
 
 	_, err := CreateTask("http", testTarget, 8000, "WrongToken", TodoTitle, TodoDescription)
 
-	if err.Error() != TaskCreationReturnedStatusCodeUnexpected {
+	if !IsMessage(err, TaskCreationReturnedStatusCodeUnexpected) {
 		t.Fail()
 	}
 
@@ -110,7 +118,7 @@ func TestGetTaskWithoutTask(t *testing.T) {
 
 	_, err = GetTask("http", testTarget, 8000, loginConfig.Token, 1000)
 
-	if err.Error() != TaskGetReturnedNotFoundStatusCode {
+	if !IsMessage(err, TaskGetReturnedNotFoundStatusCode) {
 		log.Fatal(err)
 		t.Fail()
 	}
@@ -146,7 +154,7 @@ func TestGetTaskWithOtherUsersTask(t *testing.T) {
 	TargetTaskID := createdTask.ID
 	_, err = GetTask("http", testTarget, 8000, loginConfig.Token, TargetTaskID)
 
-	if err.Error() != TaskGetReturnedNotFoundStatusCode {
+	if !IsMessage(err, TaskGetReturnedNotFoundStatusCode) {
 		log.Fatal(err)
 		t.Fail()
 	}
@@ -163,7 +171,7 @@ func TestGetTaskWithWrongAuthInfo(t *testing.T) {
 
 	_, err := GetTask("http", testTarget, 8000, "WrongAuthInfo", 1000)
 
-	if err.Error() != TaskGetReturnedStatusCodeUnexpected {
+	if !IsMessage(err, TaskGetReturnedStatusCodeUnexpected) {
 		log.Fatal(err)
 		t.Fail()
 	}
@@ -254,7 +262,7 @@ func TestDeleteTask(t *testing.T) {
 	// 削除したタスクが取得できないことを確認
 	_, err = GetTask("http", testTarget, 8000, loginConfig.Token, deletedTask.ID)
 
-	if err.Error() != TaskGetReturnedNotFoundStatusCode {
+	if !IsMessage(err, TaskGetReturnedNotFoundStatusCode) {
 		t.Fail()
 	}
 }
@@ -275,7 +283,7 @@ func TestDeleteTaskWithoutTask(t *testing.T) {
 
 	_, err = DeleteTask("http", testTarget, 8000, loginConfig.Token, 10000)
 
-	if err.Error() != TaskDeleteReturnedNotFoundStatusCode {
+	if !IsMessage(err, TaskDeleteReturnedNotFoundStatusCode) {
 		log.Println(err)
 		t.Fail()
 	}
@@ -291,7 +299,7 @@ func TestDeleteTaskWithWrongAuth(t *testing.T) {
 
 	_, err := DeleteTask("http", testTarget, 8000, "WrontToken", 100000)
 
-	if err.Error() != TaskDeleteReturnedStatusCodeUnexpected {
+	if !IsMessage(err, TaskDeleteReturnedStatusCodeUnexpected) {
 		t.Fail()
 	}
 }
@@ -321,7 +329,7 @@ func TestDeleteTaskWithOtherUsersTask(t *testing.T) {
 
 	_, err = DeleteTask("http", testTarget, 8000, loginConfig.Token, createdTask.ID)
 
-	if err.Error() != TaskDeleteReturnedNotFoundStatusCode {
+	if !IsMessage(err, TaskDeleteReturnedNotFoundStatusCode) {
 		t.Fail()
 	}
 }
@@ -413,7 +421,7 @@ func TestUpdateTaskWithBadRequest(t *testing.T) {
 	// 更新を実施
 	_, err = UpdateTask("http", testTarget, 8000, loginConfig.Token, createdTask.ID, UpdatedTitle, UpdatedDescription, UpdatedStatus)
 
-	if err.Error() != TaskUpdateReturnedBadRequestStatusCode {
+	if !IsMessage(err, TaskUpdateReturnedBadRequestStatusCode) {
 		log.Println(err)
 		t.Fail()
 	}
@@ -440,7 +448,7 @@ func TestUpdateTaskWithoutTask(t *testing.T) {
 	// 更新を実施
 	_, err = UpdateTask("http", testTarget, 8000, loginConfig.Token, 1000000, UpdatedTitle, UpdatedDescription, UpdatedStatus)
 
-	if err.Error() != TaskUpdateReturnedNotFoundStatusCode {
+	if !IsMessage(err, TaskUpdateReturnedNotFoundStatusCode) {
 		log.Println(err)
 		t.Fail()
 	}
@@ -478,7 +486,7 @@ func TestUpdateTaskWithOtherUsersTask(t *testing.T) {
 	UpdatedStatus := "RUNNING"
 	_, err = UpdateTask("http", testTarget, 8000, loginConfig.Token, createdTask.ID, UpdatedTitle, UpdatedDescription, UpdatedStatus)
 
-	if err.Error() != TaskUpdateReturnedNotFoundStatusCode {
+	if !IsMessage(err, TaskUpdateReturnedNotFoundStatusCode) {
 		log.Println(err)
 		t.Fail()
 	}
@@ -514,8 +522,328 @@ func TestUpdateTaskWithWrongAuth(t *testing.T) {
 	// 誤った認証トークンを与えて更新を実施
 	_, err = UpdateTask("http", testTarget, 8000, WrongToken, createdTask.ID, UpdatedTitle, UpdatedDescription, UpdatedStatus)
 
-	if err.Error() != TaskUpdateReturnedStatusCodeUnexpected {
+	if !IsMessage(err, TaskUpdateReturnedStatusCodeUnexpected) {
 		t.Fail()
 	}
 
 }
+
+// fakeTodoServer はToDoサーバの代わりに利用するテスト用のサーバです。
+// タスクをメモリ上に保持し、受け取ったリクエストを記録します。
+type fakeTodoServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	tasks    map[int]Task
+	nextID   int
+	requests []fakeRequest
+	// beforeHandle はリクエストを処理する直前に呼ばれます。
+	// trueを返した場合はそのリクエストの処理を打ち切ります。
+	beforeHandle func(w http.ResponseWriter, r *http.Request, n int) bool
+}
+
+// fakeRequest はfakeTodoServerが受け取ったリクエストの記録です。
+type fakeRequest struct {
+	Method string
+	Path   string
+	Body   map[string]string
+}
+
+func newFakeTodoServer() *fakeTodoServer {
+	s := &fakeTodoServer{tasks: map[int]Task{}, nextID: 1}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// target はサービス層の関数に渡すprotocol, host, portを返します。
+func (s *fakeTodoServer) target() (string, string, int) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	return u.Scheme, u.Hostname(), port
+}
+
+func (s *fakeTodoServer) put(task Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[task.ID] = task
+	if task.ID >= s.nextID {
+		s.nextID = task.ID + 1
+	}
+}
+
+func (s *fakeTodoServer) get(id int) (Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[id]
+	return task, ok
+}
+
+// requestsOf は指定したメソッドのリクエストの記録を返します。
+func (s *fakeTodoServer) requestsOf(method string) []fakeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var requests []fakeRequest
+	for _, r := range s.requests {
+		if r.Method == method {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+func (s *fakeTodoServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	fields := map[string]string{}
+	json.Unmarshal(body, &fields)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, fakeRequest{Method: r.Method, Path: r.URL.Path, Body: fields})
+	n := len(s.requests)
+	s.mu.Unlock()
+
+	if s.beforeHandle != nil && s.beforeHandle(w, r, n) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON := func(status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	switch {
+	case r.URL.Path == "/api/ping":
+		writeJSON(http.StatusOK, PongMessage{Message: "pong"})
+	case r.URL.Path == "/api/auth":
+		writeJSON(http.StatusOK, JWTAuthMessage{Token: "token-" + fields["username"]})
+	case r.URL.Path == "/api/task" && r.Method == "GET":
+		tasks := []Task{}
+		for id := 1; id < s.nextID; id++ {
+			if task, ok := s.tasks[id]; ok {
+				tasks = append(tasks, task)
+			}
+		}
+		writeJSON(http.StatusOK, tasks)
+	case r.URL.Path == "/api/task" && r.Method == "POST":
+		task := Task{ID: s.nextID, Title: fields["title"], Description: fields["description"], Status: "TODO"}
+		s.tasks[task.ID] = task
+		s.nextID++
+		writeJSON(http.StatusCreated, CreatedTask{ID: task.ID, Title: task.Title, Description: task.Description})
+	case strings.HasPrefix(r.URL.Path, "/api/task/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/task/"))
+		task, ok := s.tasks[id]
+		if !ok {
+			writeJSON(http.StatusNotFound, []Task{})
+			return
+		}
+		switch r.Method {
+		case "GET":
+			writeJSON(http.StatusOK, []Task{task})
+		case "DELETE":
+			delete(s.tasks, id)
+			writeJSON(http.StatusOK, task)
+		case "PATCH":
+			status, ok := fields["status"]
+			if !ok || (status != "TODO" && status != "RUNNING" && status != "FINISHED" && status != "PENDING") {
+				writeJSON(http.StatusBadRequest, nil)
+				return
+			}
+			task.Status = status
+			if title, ok := fields["title"]; ok {
+				task.Title = title
+			}
+			if description, ok := fields["description"]; ok {
+				task.Description = description
+			}
+			s.tasks[id] = task
+			writeJSON(http.StatusOK, task)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// editConcurrentlyOnGet はn回目のGETリクエストを処理する直前に、
+// 他のユーザによるタスクの変更を模擬します。
+func editConcurrentlyOnGet(s *fakeTodoServer, nth int, edit func(task *Task)) {
+	gets := 0
+	s.beforeHandle = func(w http.ResponseWriter, r *http.Request, n int) bool {
+		if r.Method != "GET" {
+			return false
+		}
+		gets++
+		if gets == nth {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			for id, task := range s.tasks {
+				edit(&task)
+				s.tasks[id] = task
+			}
+		}
+		return false
+	}
+}
+
+// TestUpdateTaskSendsOnlyChangedFields では、変更された項目と必須のstatusだけが
+// PATCHリクエストで送られることを確認する。
+func TestUpdateTaskSendsOnlyChangedFields(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "TODO_DESCRIPTION", Status: "TODO"})
+
+	protocol, host, port := server.target()
+	task, err := UpdateTask(protocol, host, port, "token", 1, "UPDATED", "TODO_DESCRIPTION", "")
+	if err != nil {
+		log.Println(err)
+		t.Fail()
+	}
+
+	if task.Title != "UPDATED" || task.Description != "TODO_DESCRIPTION" || task.Status != "TODO" {
+		t.Fail()
+	}
+
+	patches := server.requestsOf("PATCH")
+	if len(patches) != 1 {
+		t.Fatalf("PATCH count: %d", len(patches))
+	}
+	if _, ok := patches[0].Body["description"]; ok {
+		t.Fail()
+	}
+	if patches[0].Body["title"] != "UPDATED" || patches[0].Body["status"] != "TODO" {
+		t.Fail()
+	}
+}
+
+// TestUpdateTaskWithoutChanges では、変更がない場合にPATCHリクエストが送られないことを確認する。
+func TestUpdateTaskWithoutChanges(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "TODO_DESCRIPTION", Status: "TODO"})
+
+	protocol, host, port := server.target()
+	if _, err := UpdateTask(protocol, host, port, "token", 1, "TODO", "", "TODO"); err != nil {
+		t.Fail()
+	}
+
+	if len(server.requestsOf("PATCH")) != 0 {
+		t.Fail()
+	}
+}
+
+// TestUpdateTaskAbortsOnConflict では、取得後に他のユーザがタスクを変更した場合に
+// ConflictAbortであれば更新を中止することを確認する。
+func TestUpdateTaskAbortsOnConflict(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "TODO_DESCRIPTION", Status: "TODO"})
+	editConcurrentlyOnGet(server, 2, func(task *Task) { task.Description = "EDITED_BY_TEAMMATE" })
+
+	protocol, host, port := server.target()
+	options := UpdateOptions{OnConflict: ConflictAbort}
+	_, err := UpdateTaskWithOptions(protocol, host, port, "token", 1, "UPDATED", "", "", options)
+
+	if err == nil || !IsMessage(err, TaskUpdateConflict) {
+		t.Fail()
+	}
+	if len(server.requestsOf("PATCH")) != 0 {
+		t.Fail()
+	}
+	if task, _ := server.get(1); task.Description != "EDITED_BY_TEAMMATE" || task.Title != "TODO" {
+		t.Fail()
+	}
+}
+
+// TestUpdateTaskRetriesOnConflict では、競合相手の変更と更新する項目が重ならない場合に、
+// ConflictRetryであれば両方の変更が残ることを確認する。
+func TestUpdateTaskRetriesOnConflict(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "TODO_DESCRIPTION", Status: "TODO"})
+	editConcurrentlyOnGet(server, 2, func(task *Task) { task.Status = "RUNNING" })
+
+	protocol, host, port := server.target()
+	options := UpdateOptions{OnConflict: ConflictRetry, MaxRetries: 3}
+	result, err := UpdateTaskWithOptions(protocol, host, port, "token", 1, "UPDATED", "", "", options)
+
+	if err != nil {
+		log.Println(err)
+		t.Fail()
+	}
+	if result.Attempts != 2 {
+		t.Fail()
+	}
+	if task, _ := server.get(1); task.Title != "UPDATED" || task.Status != "RUNNING" {
+		t.Fail()
+	}
+}
+
+// TestUpdateTaskDoesNotRetryOverlappingConflict では、競合相手が同じ項目を変更していた場合は
+// ConflictRetryであっても更新を中止することを確認する。
+func TestUpdateTaskDoesNotRetryOverlappingConflict(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "TODO_DESCRIPTION", Status: "TODO"})
+	editConcurrentlyOnGet(server, 2, func(task *Task) { task.Title = "EDITED_BY_TEAMMATE" })
+
+	protocol, host, port := server.target()
+	options := UpdateOptions{OnConflict: ConflictRetry, MaxRetries: 3}
+	_, err := UpdateTaskWithOptions(protocol, host, port, "token", 1, "UPDATED", "", "", options)
+
+	if err == nil || !IsMessage(err, TaskUpdateConflict) {
+		t.Fail()
+	}
+	if task, _ := server.get(1); task.Title != "EDITED_BY_TEAMMATE" {
+		t.Fail()
+	}
+}
+
+// TestUpdateTaskWithIfMatch では、--if-matchに指定したフィンガープリントが
+// サーバ上のタスクと一致する場合のみ更新されることを確認する。
+func TestUpdateTaskWithIfMatch(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	original := Task{ID: 1, Title: "TODO", Description: "TODO_DESCRIPTION", Status: "TODO"}
+	server.put(original)
+
+	protocol, host, port := server.target()
+	stale := UpdateOptions{IfMatch: TaskFingerprint(Task{ID: 1, Title: "OLD"}), OnConflict: ConflictAbort}
+	_, err := UpdateTaskWithOptions(protocol, host, port, "token", 1, "UPDATED", "", "", stale)
+	if err == nil || !IsMessage(err, TaskUpdatePreconditionFailed) {
+		t.Fail()
+	}
+
+	fresh := UpdateOptions{IfMatch: TaskFingerprint(original), OnConflict: ConflictAbort}
+	result, err := UpdateTaskWithOptions(protocol, host, port, "token", 1, "UPDATED", "", "", fresh)
+	if err != nil {
+		log.Println(err)
+		t.Fail()
+	}
+	if result.Task.Title != "UPDATED" || TaskFingerprint(result.Previous) != TaskFingerprint(original) {
+		t.Fail()
+	}
+}
+
+// TestUpdateTaskWithExpectStatus では、--expect-statusに指定したステータスと
+// サーバ上のタスクのステータスが異なる場合に更新されないことを確認する。
+func TestUpdateTaskWithExpectStatus(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: "TODO_DESCRIPTION", Status: "RUNNING"})
+
+	protocol, host, port := server.target()
+	options := UpdateOptions{ExpectStatus: "TODO", OnConflict: ConflictRetry, MaxRetries: 3}
+	_, err := UpdateTaskWithOptions(protocol, host, port, "token", 1, "", "", "FINISHED", options)
+
+	if err == nil || !IsMessage(err, TaskUpdatePreconditionFailed) {
+		t.Fail()
+	}
+	if len(server.requestsOf("PATCH")) != 0 {
+		t.Fail()
+	}
+}
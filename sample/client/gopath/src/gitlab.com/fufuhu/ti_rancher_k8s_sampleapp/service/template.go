@@ -0,0 +1,186 @@
+package service
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// TemplateExt はタスクのテンプレートのファイルの拡張子です。テンプレートの名前に付けてファイル名とします。
+const TemplateExt = ".yaml"
+
+// TemplateNameInvalid はテンプレートの名前に使えない文字が含まれる場合のエラーメッセージです。
+const TemplateNameInvalid MessageID = "template.name_invalid"
+
+// TemplateNotFound は指定した名前のテンプレートが無い場合のエラーメッセージです。
+const TemplateNotFound MessageID = "template.not_found"
+
+// TemplateInvalid はテンプレートのファイルを解釈できない場合のエラーメッセージです。
+const TemplateInvalid MessageID = "template.invalid"
+
+// TemplateNoTasks はテンプレートに作成するタスクが記述されていない場合のエラーメッセージです。
+const TemplateNoTasks MessageID = "template.no_tasks"
+
+// TemplateParamMissing はテンプレートの既定値の無いパラメータが指定されていない場合のエラーメッセージです。
+const TemplateParamMissing MessageID = "template.param_missing"
+
+// TemplateParamUnknown はテンプレートに無いパラメータが指定された場合のエラーメッセージです。
+const TemplateParamUnknown MessageID = "template.param_unknown"
+
+// TemplateRenderFailure はテンプレートのプレースホルダを展開できない場合のエラーメッセージです。
+const TemplateRenderFailure MessageID = "template.render_failure"
+
+// TemplateTitleEmpty はテンプレートを展開したタスクの名前が空の場合のエラーメッセージです。
+const TemplateTitleEmpty MessageID = "template.title_empty"
+
+// templateName はテンプレートの名前に使える文字です。テンプレートのディレクトリの外のファイルを指定できないよう制限します。
+var templateName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// TaskTemplate は繰り返し作成するタスクを記述したテンプレートです。
+// タスクの名前と概要にはtext/templateのプレースホルダ({{.Date}}、{{.Args.service}}など)を記述できます。
+type TaskTemplate struct {
+	Name        string `yaml:"-"`                     // テンプレートの名前(ファイル名から拡張子を除いたもの)
+	Path        string `yaml:"-"`                     // テンプレートのファイルのパス
+	Description string `yaml:"description,omitempty"` // テンプレートの説明(template listで表示します)
+	// Params はテンプレートで利用するパラメータ(.Args)とその既定値です。既定値が空のパラメータは指定が必須です。
+	// 記述しない場合は任意のパラメータを受け付けます。
+	Params map[string]string   `yaml:"params,omitempty"`
+	Tasks  []TaskTemplateEntry `yaml:"tasks"` // 作成するタスク
+}
+
+// TaskTemplateEntry はテンプレートから作成するタスク1件分です。
+type TaskTemplateEntry struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// TemplateData はテンプレートのプレースホルダに渡す値です。
+type TemplateData struct {
+	Date string            // 展開した日付(2006-01-02の形式)
+	Now  time.Time         // 展開した日時
+	Args map[string]string // --setで指定したパラメータ(既定値を含む)
+}
+
+// LoadTaskTemplate はディレクトリから指定した名前のテンプレートを読み込みます。
+func LoadTaskTemplate(dir string, name string) (TaskTemplate, error) {
+	if !templateName.MatchString(name) {
+		return TaskTemplate{}, NewError(ErrorUsage, TemplateNameInvalid, name)
+	}
+	path := filepath.Join(dir, name+TemplateExt)
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return TaskTemplate{}, NewError(ErrorUsage, TemplateNotFound, name, dir)
+	}
+	if err != nil {
+		return TaskTemplate{}, err
+	}
+
+	tmpl := TaskTemplate{Name: name, Path: path}
+	if err := yaml.UnmarshalStrict(content, &tmpl); err != nil {
+		return tmpl, WrapError(ErrorUsage, TemplateInvalid, err, path)
+	}
+	if len(tmpl.Tasks) == 0 {
+		return tmpl, NewError(ErrorUsage, TemplateNoTasks, path)
+	}
+	return tmpl, nil
+}
+
+// ListTaskTemplates はディレクトリにあるテンプレートを名前の順に読み込みます。
+// ディレクトリが無い場合は空の一覧を返します。読み込めないテンプレートはエラーとともに一覧に含めます。
+func ListTaskTemplates(dir string) ([]TaskTemplate, []error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var names []string
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), TemplateExt)
+		if !file.IsDir() && strings.HasSuffix(file.Name(), TemplateExt) && templateName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var templates []TaskTemplate
+	var errs []error
+	for _, name := range names {
+		tmpl, err := LoadTaskTemplate(dir, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		templates = append(templates, tmpl)
+	}
+	return templates, errs
+}
+
+// Render はテンプレートのプレースホルダを展開し、作成するタスクの一覧を返します。
+// タスクを作成する前にすべてのタスクを展開するため、パラメータの不足や展開の失敗があればタスクを1件も返しません。
+func (t TaskTemplate) Render(now time.Time, args map[string]string) ([]Task, error) {
+	data := TemplateData{Date: now.Format("2006-01-02"), Now: now, Args: map[string]string{}}
+	if t.Params != nil {
+		for name, value := range t.Params {
+			data.Args[name] = value
+		}
+		for name := range args {
+			if _, ok := t.Params[name]; !ok {
+				return nil, NewError(ErrorUsage, TemplateParamUnknown, name, t.Name)
+			}
+		}
+	}
+	for name, value := range args {
+		data.Args[name] = value
+	}
+	var missing []string
+	for name := range t.Params {
+		if data.Args[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, NewError(ErrorUsage, TemplateParamMissing, strings.Join(missing, ", "), t.Name)
+	}
+
+	tasks := make([]Task, len(t.Tasks))
+	for i, entry := range t.Tasks {
+		title, err := renderTemplateText(entry.Title, data)
+		if err != nil {
+			return nil, WrapError(ErrorUsage, TemplateRenderFailure, err, t.Name, i+1)
+		}
+		if strings.TrimSpace(title) == "" {
+			return nil, NewError(ErrorUsage, TemplateTitleEmpty, t.Name, i+1)
+		}
+		description, err := renderTemplateText(entry.Description, data)
+		if err != nil {
+			return nil, WrapError(ErrorUsage, TemplateRenderFailure, err, t.Name, i+1)
+		}
+		tasks[i] = Task{Title: title, Description: description}
+	}
+	return tasks, nil
+}
+
+// renderTemplateText はプレースホルダを展開します。指定されていないパラメータを参照した場合はエラーとします。
+func renderTemplateText(text string, data TemplateData) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
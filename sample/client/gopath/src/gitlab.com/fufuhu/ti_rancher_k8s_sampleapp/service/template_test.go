@@ -0,0 +1,96 @@
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestTaskTemplateRender ではテンプレートのプレースホルダに日付とパラメータを展開し、
+// パラメータの不足や不明なパラメータをエラーとすることを確認する。
+func TestTaskTemplateRender(t *testing.T) {
+	now := time.Date(2019, 4, 1, 9, 0, 0, 0, time.UTC)
+	tmpl := TaskTemplate{
+		Name:   "handover",
+		Params: map[string]string{"service": "", "owner": "oncall"},
+		Tasks: []TaskTemplateEntry{
+			{Title: "{{.Date}} {{.Args.service}}"},
+			{Title: "notes", Description: "{{.Args.owner}} {{.Now.Format \"01/02\"}}"},
+		},
+	}
+
+	tasks, err := tmpl.Render(now, map[string]string{"service": "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Task{{Title: "2019-04-01 api"}, {Title: "notes", Description: "oncall 04/01"}}
+	if len(tasks) != len(want) || tasks[0] != want[0] || tasks[1] != want[1] {
+		t.Errorf("Render() = %+v, want %+v", tasks, want)
+	}
+
+	for _, c := range []struct {
+		args map[string]string
+		id   MessageID
+	}{
+		{map[string]string{}, TemplateParamMissing},
+		{map[string]string{"service": "api", "team": "sre"}, TemplateParamUnknown},
+	} {
+		if _, err := tmpl.Render(now, c.args); !IsMessage(err, c.id) || KindOf(err) != ErrorUsage {
+			t.Errorf("Render(%v) = %v, want %s", c.args, err, c.id)
+		}
+	}
+
+	// paramsが無い場合は任意のパラメータを受け付けるが、指定されていないパラメータは参照できない
+	tmpl = TaskTemplate{Name: "free", Tasks: []TaskTemplateEntry{{Title: "{{.Args.service}}"}}}
+	if tasks, err := tmpl.Render(now, map[string]string{"service": "db"}); err != nil || tasks[0].Title != "db" {
+		t.Errorf("Render() = %+v, %v", tasks, err)
+	}
+	if _, err := tmpl.Render(now, nil); !IsMessage(err, TemplateRenderFailure) {
+		t.Errorf("Render() = %v, want %s", err, TemplateRenderFailure)
+	}
+	tmpl.Tasks = []TaskTemplateEntry{{Title: "{{if false}}x{{end}}"}}
+	if _, err := tmpl.Render(now, nil); !IsMessage(err, TemplateTitleEmpty) {
+		t.Errorf("Render() = %v, want %s", err, TemplateTitleEmpty)
+	}
+}
+
+// TestListTaskTemplates ではディレクトリのテンプレートを名前の順に読み込み、
+// 読み込めないテンプレートをエラーとして報告することを確認する。
+func TestListTaskTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"weekly.yaml":   "description: weekly\ntasks:\n- title: a\n- title: b\n",
+		"handover.yaml": "tasks:\n- title: a\n",
+		"empty.yaml":    "description: no tasks\n",
+		"typo.yaml":     "task:\n- title: a\n",
+		"README.md":     "not a template\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	templates, errs := ListTaskTemplates(dir)
+	if len(templates) != 2 || templates[0].Name != "handover" || templates[1].Name != "weekly" || len(templates[1].Tasks) != 2 {
+		t.Errorf("templates = %+v", templates)
+	}
+	if len(errs) != 2 || !IsMessage(errs[0], TemplateNoTasks) || !IsMessage(errs[1], TemplateInvalid) {
+		t.Errorf("errors = %v", errs)
+	}
+
+	if _, err := LoadTaskTemplate(dir, "missing"); !IsMessage(err, TemplateNotFound) {
+		t.Errorf("LoadTaskTemplate(missing) = %v", err)
+	}
+	if _, err := LoadTaskTemplate(dir, "../weekly"); !IsMessage(err, TemplateNameInvalid) {
+		t.Errorf("LoadTaskTemplate(../weekly) = %v", err)
+	}
+	if templates, errs := ListTaskTemplates(filepath.Join(dir, "missing")); templates != nil || errs != nil {
+		t.Errorf("missing dir = %v, %v", templates, errs)
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// TokenMalformed はJWTの形式が不正で内容を読み取れない場合のエラーメッセージです。
+const TokenMalformed MessageID = "token.malformed"
+
+// TokenClaims はToDoサーバが発行するJWTのペイロードのうち、
+// クライアントが参照する項目を表します。
+type TokenClaims struct {
+	UserID   int    `json:"user_id"`  // ユーザのID
+	Username string `json:"username"` // ユーザ名
+	Email    string `json:"email"`    // メールアドレス
+	Exp      int64  `json:"exp"`      // 有効期限(UNIX時間)
+}
+
+// ExpiresAt はトークンの有効期限を返します。有効期限を持たない場合はゼロ値を返します。
+func (c TokenClaims) ExpiresAt() time.Time {
+	if c.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(c.Exp, 0)
+}
+
+// ParseTokenClaims はJWTのペイロードを読み取ります。
+// 署名の検証はToDoサーバが行うため、ここでは行いません。
+func ParseTokenClaims(token string) (TokenClaims, error) {
+	var claims TokenClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, NewError(ErrorAuth, TokenMalformed)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return claims, NewError(ErrorAuth, TokenMalformed)
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, NewError(ErrorAuth, TokenMalformed)
+	}
+	return claims, nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+// TestParseTokenClaims ではJWTのペイロードからユーザ名と有効期限を読み取れることを確認する。
+func TestParseTokenClaims(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"user_id":1,"username":"test_user","exp":1560000000,"email":""}`))
+	token := "eyJ0eXAiOiJKV1QiLCJhbGciOiJIUzI1NiJ9." + payload + ".signature"
+
+	claims, err := ParseTokenClaims(token)
+	if err != nil {
+		t.Fail()
+	}
+	if claims.Username != "test_user" || claims.UserID != 1 {
+		t.Fail()
+	}
+	if claims.ExpiresAt().Unix() != 1560000000 {
+		t.Fail()
+	}
+}
+
+// TestParseTokenClaimsWithMalformedToken では形式が不正なトークンを与えた場合に
+// TokenMalformedに対応するエラーが返されることを確認する。
+func TestParseTokenClaimsWithMalformedToken(t *testing.T) {
+	_, err := ParseTokenClaims("WrongToken")
+	if err == nil || !IsMessage(err, TokenMalformed) {
+		t.Fail()
+	}
+}
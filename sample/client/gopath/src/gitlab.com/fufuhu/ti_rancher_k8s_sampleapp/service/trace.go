@@ -0,0 +1,328 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 出力するHTTP通信の情報の詳細さ(kubectlの-vに合わせています)です。
+const (
+	VerbosityEndpoint       = 1 // 接続先のエンドポイントの切り替え
+	VerbosityRequest        = 6 // リクエストの行、レスポンスのステータス、所要時間
+	VerbosityRequestHeaders = 7 // 上記に加えてリクエストヘッダ
+	VerbosityBodies         = 8 // 上記に加えてレスポンスヘッダと、先頭だけに切り詰めたボディ
+	VerbosityFullBodies     = 9 // 上記に加えてボディの全体
+)
+
+// 通信の内容とともに出力するメッセージです。
+const (
+	TraceRequestFailed    MessageID = "trace.request_failed"
+	TraceRequestHeaders   MessageID = "trace.request_headers"
+	TraceRequestBody      MessageID = "trace.request_body"
+	TraceResponseHeaders  MessageID = "trace.response_headers"
+	TraceResponseBody     MessageID = "trace.response_body"
+	TraceBodyTruncated    MessageID = "trace.body_truncated"
+	TraceNoResponse       MessageID = "trace.no_response"
+	TraceHARRecordFailure MessageID = "trace.har_record_failure"
+)
+
+// TruncatedBodySize はVerbosityBodiesでボディを出力する際の最大のバイト数です。
+const TruncatedBodySize = 1024
+
+// harCreatorName はHAR形式で出力する際に作成したアプリケーションとして記録する名前です。
+const harCreatorName = "todo-client"
+
+// TracingTransport はToDoサーバとのHTTP通信の内容を出力するRoundTripperです。
+// 認証ヘッダ、認証時のパスワード、取得した認証トークンは常に伏せて出力します。
+type TracingTransport struct {
+	Base      http.RoundTripper // 実際に通信を行うRoundTripper
+	Verbosity int               // 出力する情報の詳細さ
+	Output    io.Writer         // 通信の内容を出力する先
+	HAR       *HARRecorder      // HAR形式で通信を記録する先(nilの場合は記録しない)
+
+	mu sync.Mutex
+}
+
+// EnableTracing はHTTPClientの通信の内容をoutputに出力し、harが指定された場合はHAR形式で記録するようにします。
+func EnableTracing(verbosity int, output io.Writer, har *HARRecorder) {
+	if verbosity < VerbosityRequest && har == nil {
+		return
+	}
+	HTTPClient.Transport = &TracingTransport{
+		Base:      HTTPClient.Transport,
+		Verbosity: verbosity,
+		Output:    output,
+		HAR:       har,
+	}
+}
+
+// RoundTrip はリクエストを送信し、その内容とレスポンスを出力します。
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	res, err := t.Base.RoundTrip(req)
+	wait := time.Since(start)
+
+	var responseBody []byte
+	if err == nil {
+		// 出力のためにボディを読み出し、呼び出し元でも読めるように元に戻します。
+		responseBody, err = ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		res.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+		if err != nil {
+			res = nil
+		}
+	}
+	elapsed := time.Since(start)
+
+	t.trace(req, requestBody, res, responseBody, err, elapsed)
+	if t.HAR != nil {
+		if err := t.HAR.Record(req, requestBody, res, responseBody, start, wait, elapsed-wait); err != nil && t.Output != nil {
+			fmt.Fprintln(t.Output, T(TraceHARRecordFailure, err))
+		}
+	}
+	return res, err
+}
+
+// trace は通信の内容を詳細さに応じて出力します。
+func (t *TracingTransport) trace(req *http.Request, requestBody []byte, res *http.Response, responseBody []byte, err error, elapsed time.Duration) {
+	if t.Output == nil || t.Verbosity < VerbosityRequest {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err != nil {
+		fmt.Fprintf(&buf, "%s %s %s (%s): %v\n", req.Method, req.URL, T(TraceRequestFailed), roundDuration(elapsed), err)
+	} else {
+		fmt.Fprintf(&buf, "%s %s %s (%s)\n", req.Method, req.URL, res.Status, roundDuration(elapsed))
+	}
+
+	if t.Verbosity >= VerbosityRequestHeaders {
+		fmt.Fprintln(&buf, T(TraceRequestHeaders)+":")
+		writeHeaders(&buf, req.Header)
+	}
+	if t.Verbosity >= VerbosityBodies {
+		if len(requestBody) > 0 {
+			fmt.Fprintf(&buf, "%s: %s\n", T(TraceRequestBody), t.body(requestBody))
+		}
+		if res != nil {
+			fmt.Fprintln(&buf, T(TraceResponseHeaders)+":")
+			writeHeaders(&buf, res.Header)
+			fmt.Fprintf(&buf, "%s: %s\n", T(TraceResponseBody), t.body(responseBody))
+		}
+	}
+
+	// 並行したリクエストの出力が混ざらないようにまとめて出力します。
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	buf.WriteTo(t.Output)
+}
+
+// body は認証情報を伏せたボディを、詳細さに応じて切り詰めて返します。
+func (t *TracingTransport) body(body []byte) []byte {
+	redacted := RedactBody(body)
+	if t.Verbosity < VerbosityFullBodies && len(redacted) > TruncatedBodySize {
+		return append(redacted[:TruncatedBodySize:TruncatedBodySize], " ..."+T(TraceBodyTruncated)...)
+	}
+	return redacted
+}
+
+// writeHeaders はヘッダを名前の順に、認証情報を伏せて出力します。
+func writeHeaders(w io.Writer, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(w, "    %s: %s\n", name, RedactHeader(name, value))
+		}
+	}
+}
+
+// roundDuration は出力用に所要時間をミリ秒の単位に丸めます。
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d
+	}
+	return d.Round(time.Millisecond)
+}
+
+// HAR形式(HTTP Archive 1.2)で通信を記録するための構造体です。
+// http://www.softwareishard.com/blog/har-12-spec/ の必須項目のみを扱います。
+type (
+	harLog struct {
+		Log harContent `json:"log"`
+	}
+	harContent struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	harEntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+	}
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *harPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+	harResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		Content     harBody        `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+		Comment     string         `json:"comment,omitempty"`
+	}
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+	harBody struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+	harTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+// HARRecorder は通信の内容をHAR形式でファイルに記録します。
+// log.Fatalなどで途中で終了した場合も記録が残るように、通信のたびにファイル全体を書き直します。
+type HARRecorder struct {
+	Path string // 記録するファイルのパス
+
+	mu  sync.Mutex
+	log harLog
+}
+
+// NewHARRecorder はpathに記録するHARRecorderを返します。
+func NewHARRecorder(path string) *HARRecorder {
+	return &HARRecorder{
+		Path: path,
+		log: harLog{Log: harContent{
+			Version: "1.2",
+			Creator: harCreator{Name: harCreatorName, Version: "1.0"},
+			Entries: []harEntry{},
+		}},
+	}
+}
+
+// Record は1回分の通信を認証情報を伏せて記録します。通信に失敗した場合はresにnilを指定します。
+func (r *HARRecorder) Record(req *http.Request, requestBody []byte, res *http.Response, responseBody []byte, start time.Time, wait time.Duration, receive time.Duration) error {
+	entry := harEntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Time:            milliseconds(wait + receive),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(requestBody),
+		},
+		Timings: harTimings{Send: 0, Wait: milliseconds(wait), Receive: milliseconds(receive)},
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: value})
+		}
+	}
+	if len(requestBody) > 0 {
+		entry.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(RedactBody(requestBody))}
+	}
+
+	if res != nil {
+		entry.Response = harResponse{
+			Status:      res.StatusCode,
+			StatusText:  http.StatusText(res.StatusCode),
+			HTTPVersion: res.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(res.Header),
+			Content:     harBody{Size: len(responseBody), MimeType: res.Header.Get("Content-Type"), Text: string(RedactBody(responseBody))},
+			HeadersSize: -1,
+			BodySize:    len(responseBody),
+		}
+	} else {
+		// 通信に失敗した場合はHARの慣習に従いステータスを0として記録します。
+		entry.Response = harResponse{
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+			Comment:     T(TraceNoResponse),
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log.Log.Entries = append(r.log.Log.Entries, entry)
+
+	data, err := json.MarshalIndent(r.log, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.Path, data, 0600)
+}
+
+// harHeaders はヘッダを名前の順に、認証情報を伏せてHAR形式に変換します。
+func harHeaders(header http.Header) []harNameValue {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	headers := []harNameValue{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, harNameValue{Name: name, Value: RedactHeader(name, value)})
+		}
+	}
+	return headers
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withTracing は通信の内容の出力を有効にしてfを実行し、出力された内容を返します。
+func withTracing(verbosity int, har *HARRecorder, f func()) string {
+	var out bytes.Buffer
+	original := HTTPClient.Transport
+	EnableTracing(verbosity, &out, har)
+	defer func() {
+		HTTPClient.Transport = original
+	}()
+	f()
+	return out.String()
+}
+
+// TestRedactBodyWithToken では認証のレスポンスに含まれる認証トークンが伏せられることを確認する。
+func TestRedactBodyWithToken(t *testing.T) {
+	redacted := string(RedactBody([]byte(`{"token": "eyJhbGciOiJIUzI1NiJ9.e30.sig"}`)))
+
+	if redacted != `{"token": "<redacted>"}` {
+		log.Println(redacted)
+		t.Fail()
+	}
+}
+
+// TestTracingWithRequestVerbosity ではリクエストの行とステータスのみが出力されることを確認する。
+func TestTracingWithRequestVerbosity(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+
+	protocol, host, port := server.target()
+	out := withTracing(VerbosityRequest, nil, func() {
+		GetTasks(protocol, host, port, "secret-token")
+	})
+
+	if !strings.HasPrefix(out, "GET "+server.URL+"/api/task 200 OK (") {
+		log.Println(out)
+		t.Fail()
+	}
+	if strings.Contains(out, "Authorization") || strings.Count(out, "\n") != 1 {
+		log.Println(out)
+		t.Fail()
+	}
+}
+
+// TestTracingRedactsCredentials では最も詳細に出力した場合でも、
+// 認証ヘッダ、パスワード、認証トークンが出力されないことを確認する。
+func TestTracingRedactsCredentials(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+
+	protocol, host, port := server.target()
+	out := withTracing(VerbosityFullBodies, nil, func() {
+		Login(protocol, host, port, "test_user", "secret-password")
+		GetTasks(protocol, host, port, "secret-token")
+	})
+
+	for _, secret := range []string{"secret-password", "token-test_user", "secret-token"} {
+		if strings.Contains(out, secret) {
+			log.Println(out)
+			t.Fail()
+		}
+	}
+	if !strings.Contains(out, "    Authorization: "+RedactedValue) || !strings.Contains(out, `"password":"`+RedactedValue+`"`) {
+		log.Println(out)
+		t.Fail()
+	}
+	if !strings.Contains(out, T(TraceResponseHeaders)+":\n") || !strings.Contains(out, "    Content-Type: application/json\n") {
+		log.Println(out)
+		t.Fail()
+	}
+}
+
+// TestTracingTruncatesBodies ではVerbosityBodiesの場合にボディを切り詰めて出力することを確認する。
+func TestTracingTruncatesBodies(t *testing.T) {
+	server := newFakeTodoServer()
+	defer server.Close()
+	server.put(Task{ID: 1, Title: "TODO", Description: strings.Repeat("D", 2*TruncatedBodySize), Status: "TODO"})
+
+	protocol, host, port := server.target()
+	var tasks []Task
+	out := withTracing(VerbosityBodies, nil, func() {
+		tasks, _ = GetTasks(protocol, host, port, "token")
+	})
+
+	if !strings.Contains(out, " ..."+T(TraceBodyTruncated)) || strings.Contains(out, strings.Repeat("D", TruncatedBodySize+1)) {
+		log.Println(out)
+		t.Fail()
+	}
+	// 呼び出し元では切り詰められていないボディを読めること
+	if len(tasks) != 1 || len(tasks[0].Description) != 2*TruncatedBodySize {
+		t.Fail()
+	}
+}
+
+// TestHARRecorder では通信の内容が認証情報を伏せてHAR形式で記録されることを確認する。
+func TestHARRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := newFakeTodoServer()
+	defer server.Close()
+
+	protocol, host, port := server.target()
+	har := NewHARRecorder(filepath.Join(dir, "trace.har"))
+	withTracing(0, har, func() {
+		Login(protocol, host, port, "test_user", "secret-password")
+		CreateTask(protocol, host, port, "secret-token", "TODO", "D")
+	})
+
+	data, err := ioutil.ReadFile(har.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-password", "token-test_user", "secret-token"} {
+		if bytes.Contains(data, []byte(secret)) {
+			log.Println(string(data))
+			t.Fail()
+		}
+	}
+
+	var recorded harLog
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		t.Fatal(err)
+	}
+	entries := recorded.Log.Entries
+	if recorded.Log.Version != "1.2" || len(entries) != 2 {
+		t.Fatal(string(data))
+	}
+	if entries[1].Request.Method != "POST" || entries[1].Request.URL != server.URL+"/api/task" {
+		t.Fail()
+	}
+	if entries[1].Request.PostData == nil || entries[1].Request.PostData.Text != `{"description":"D","title":"TODO"}` {
+		t.Fail()
+	}
+	if entries[1].Response.Status != 201 || !strings.Contains(entries[1].Response.Content.Text, `"title":"TODO"`) {
+		t.Fail()
+	}
+}